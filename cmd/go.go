@@ -4,8 +4,10 @@ package cmd
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -47,10 +49,32 @@ func runGo(cmd *cobra.Command, args []string) {
 	envPrintCmd := promptCmd.NewEnvPrintCmd(authClient.IDToken)
 	envSetCmd := promptCmd.NewEnvSetCmd(authClient.IDToken)
 	envDelCmd := promptCmd.NewEnvDelCmd(authClient.IDToken)
+	lsCmd := promptCmd.NewLsCmd(foundryConf.CurrentDir)
 
-	cmds := []promptCmd.Cmd{watchCmd, watchAllCmd, exitCmd, envPrintCmd, envSetCmd, envDelCmd}
-	prompt = p.NewPrompt(cmds)
-	go prompt.Run()
+	cmds := []promptCmd.Cmd{watchCmd, watchAllCmd, exitCmd, envPrintCmd, envSetCmd, envDelCmd, lsCmd}
+	// Lines of .foundryrc are run as if they were typed when the prompt starts
+	initScript := filepath.Join(foundryConf.CurrentDir, ".foundryrc")
+	var err error
+	prompt, err = p.New(cmds, p.WithInitScript(initScript))
+	if err != nil {
+		logger.FdebuglnFatal("Creating the prompt failed", err)
+		logger.FatalLogln("Creating the prompt failed:", err)
+	}
+	if err := prompt.Validate(); err != nil {
+		logger.FdebuglnFatal("Invalid prompt commands", err)
+		logger.FatalLogln("Invalid prompt commands", err)
+	}
+	go func() {
+		// The prompt restores the terminal before it returns
+		err := prompt.Run()
+		var sigErr *p.SignalError
+		if errors.As(err, &sigErr) {
+			os.Exit(sigErr.ExitCode())
+		} else if err != nil {
+			logger.FatalLogln("The prompt failed:", err)
+		}
+		close(done)
+	}()
 
 	// Listen for messages from the WS connection
 	go connectionClient.Listen(listenCallback)
@@ -118,6 +142,14 @@ func runGo(cmd *cobra.Command, args []string) {
 				}
 				prompt.SetInfoln(pInfo, p.InfoLineSeverityNormal)
 				prompt.Writeln(pOut)
+			case args := <-lsCmd.RunCh:
+				pOut, pInfo, err := lsCmd.Run(connectionClient, args)
+				if err != nil {
+					prompt.SetInfoln(err.Error(), p.InfoLineSeverityError)
+					continue
+				}
+				prompt.SetInfoln(pInfo, p.InfoLineSeverityNormal)
+				prompt.Writeln(pOut)
 			case args := <-watchAllCmd.RunCh:
 				_, _, err := watchAllCmd.Run(connectionClient, args)
 				prompt.SetInfoln(err.Error(), p.InfoLineSeverityError)
@@ -125,6 +157,7 @@ func runGo(cmd *cobra.Command, args []string) {
 				_, _, err := watchCmd.Run(connectionClient, args)
 				prompt.SetInfoln(err.Error(), p.InfoLineSeverityError)
 			case args := <-exitCmd.RunCh:
+				prompt.Stop()
 				_, _, _ = exitCmd.Run(connectionClient, args)
 			case <-initialUploadCh:
 				files.Upload(connectionClient, foundryConf.CurrentDir, foundryConf.ServiceAccPath, promptNotifCh, foundryConf.Ignore...)
@@ -148,6 +181,96 @@ func runGo(cmd *cobra.Command, args []string) {
 	<-done
 }
 
+// runOnce runs line for 'foundry -c' and returns the exit code of its commands
+func runOnce(line string) int {
+	watchCmd := promptCmd.NewWatchCmd()
+	watchAllCmd := promptCmd.NewWatchAllCmd()
+	exitCmd := promptCmd.NewExitCmd()
+	envPrintCmd := promptCmd.NewEnvPrintCmd(authClient.IDToken)
+	envSetCmd := promptCmd.NewEnvSetCmd(authClient.IDToken)
+	envDelCmd := promptCmd.NewEnvDelCmd(authClient.IDToken)
+	lsCmd := promptCmd.NewLsCmd(foundryConf.CurrentDir)
+
+	once := []*onceCmd{
+		{Cmd: watchCmd, runCh: watchCmd.RunCh},
+		{Cmd: watchAllCmd, runCh: watchAllCmd.RunCh},
+		{Cmd: exitCmd, runCh: exitCmd.RunCh},
+		{Cmd: envPrintCmd, runCh: envPrintCmd.RunCh},
+		{Cmd: envSetCmd, runCh: envSetCmd.RunCh},
+		{Cmd: envDelCmd, runCh: envDelCmd.RunCh},
+		{Cmd: lsCmd, runCh: lsCmd.RunCh},
+	}
+	cmds := make([]promptCmd.Cmd, len(once))
+	for i, c := range once {
+		cmds[i] = c
+	}
+	pr, err := p.New(cmds)
+	if err != nil {
+		logger.FdebuglnFatal("Creating the prompt failed", err)
+		logger.FatalLogln("Creating the prompt failed:", err)
+	}
+	defer pr.Stop()
+	if err := pr.Validate(); err != nil {
+		logger.FdebuglnFatal("Invalid prompt commands", err)
+		logger.FatalLogln("Invalid prompt commands", err)
+	}
+	for _, c := range once {
+		c.out = pr
+	}
+
+	code, err := pr.ExecuteOnce(line)
+	if err != nil {
+		logger.Fdebugln("Running", line, "failed with", code, err)
+	}
+	return code
+}
+
+// onceCmd runs a command of 'foundry -c' right away. Unlike in 'foundry go'
+// there's no main loop reading the command's RunCh.
+type onceCmd struct {
+	promptCmd.Cmd
+	runCh promptCmd.RunChannelType
+	out   p.Interface
+}
+
+func (c *onceCmd) RunRequest(args promptCmd.Args) error {
+	// The command's RunRequest checks the arguments and sends them to runCh
+	sent := make(chan promptCmd.Args, 1)
+	stop := make(chan struct{})
+	go func() {
+		select {
+		case a := <-c.runCh:
+			sent <- a
+		case <-stop:
+		}
+	}()
+	err := c.Cmd.RunRequest(args)
+	close(stop)
+	if err != nil {
+		return err
+	}
+
+	pOut, pInfo, err := c.Cmd.Run(connectionClient, <-sent)
+	if err != nil {
+		return err
+	}
+	if pOut != "" {
+		c.out.Writeln(pOut)
+	}
+	// Nobody sees the info row
+	if pInfo != "" {
+		c.out.Writeln(pInfo + "\n")
+	}
+	return nil
+}
+
+func (c *onceCmd) Usage() string {
+	if u, ok := c.Cmd.(promptCmd.Usager); ok {
+		return u.Usage()
+	}
+	return ""
+}
+
 func ignored(s string, globs []glob.Glob) bool {
 	logger.Fdebugln("string to match:", s)
 	for _, g := range globs {
@@ -168,6 +291,11 @@ func listenCallback(data []byte, err error) {
 		logger.FatalLogln("WebSocket error", err)
 	}
 
+	handleResponse(prompt, data)
+}
+
+// handleResponse shows a message of the server in out
+func handleResponse(out p.Interface, data []byte) {
 	t := connMsg.ResponseMsgType{}
 	if err := json.Unmarshal(data, &t); err != nil {
 		logger.FdebuglnFatal("Unmarshaling response error", err)
@@ -183,7 +311,7 @@ func listenCallback(data []byte, err error) {
 			logger.FatalLogln("Parsing server log message error", err)
 		}
 
-		if _, err := prompt.Writeln(s.Content.Msg); err != nil {
+		if _, err := out.Writeln(s.Content.Msg); err != nil {
 			logger.FdebuglnFatal("Error writing output", err)
 			logger.FatalLogln("Error writing output", err)
 		}
@@ -203,6 +331,6 @@ func listenCallback(data []byte, err error) {
 			info = fmt.Sprintf("Displaying output from: %s.", strings.Join(s.Content.Run, ", "))
 		}
 
-		prompt.SetInfoln(info, p.InfoLineSeverityWarning)
+		out.SetInfoln(info, p.InfoLineSeverityWarning)
 	}
 }
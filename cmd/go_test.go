@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	p "foundry/cli/prompt"
+	promptCmd "foundry/cli/prompt/cmd"
+)
+
+func TestHandleResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		output   string // Part of the output, empty when nothing is written
+		info     string // The info row, empty when it isn't set
+		severity p.InfoLineSeverity
+	}{
+		{"log", `{"type":"log","content":{"msg":"function api started\n"}}`,
+			"function api started\n", "", p.InfoLineSeverityNormal},
+		{"watch all", `{"type":"watch","content":{"runAll":true}}`,
+			"", "All filters disabled. Will display output from all functions.", p.InfoLineSeverityWarning},
+		{"watch some", `{"type":"watch","content":{"runAll":false,"run":["api","worker"]}}`,
+			"", "Displaying output from: api, worker.", p.InfoLineSeverityWarning},
+		{"unknown type", `{"type":"ping"}`,
+			"", "", p.InfoLineSeverityNormal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := p.NewHeadless()
+			handleResponse(h, []byte(tt.data))
+
+			if tt.output != "" && !h.OutputContains(tt.output) {
+				t.Errorf("the output %q doesn't contain %q", h.Output(), tt.output)
+			}
+			if tt.output == "" && h.Output() != "" {
+				t.Errorf("the output %q, want none", h.Output())
+			}
+			if info, sev := h.LastInfo(); info != tt.info || (info != "" && sev != tt.severity) {
+				t.Errorf("the info row %q (%v), want %q (%v)", info, sev, tt.info, tt.severity)
+			}
+		})
+	}
+}
+
+// TestOnceCmd runs commands like 'foundry -c' does, nothing reads their
+// RunCh. A command used wrong isn't run.
+func TestOnceCmd(t *testing.T) {
+	h := p.NewHeadless()
+	exit := promptCmd.NewExitCmd()
+	c := &onceCmd{Cmd: exit, runCh: exit.RunCh, out: h}
+	if err := c.RunRequest(nil); err != nil {
+		t.Fatalf("exit failed: %s", err)
+	}
+
+	watch := promptCmd.NewWatchCmd()
+	c = &onceCmd{Cmd: watch, runCh: watch.RunCh, out: h}
+	if err := c.RunRequest(nil); !errors.Is(err, promptCmd.ErrUsage) {
+		t.Fatalf("watch without functions returned %v", err)
+	}
+	if c.Usage() != watch.Usage() {
+		t.Fatalf("the usage is %q", c.Usage())
+	}
+	if h.Output() != "" {
+		t.Fatalf("the output is %q", h.Output())
+	}
+}
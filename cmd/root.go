@@ -10,6 +10,7 @@ import (
 	"foundry/cli/auth"
 	conn "foundry/cli/connection"
 	"foundry/cli/logger"
+	p "foundry/cli/prompt"
 
 	"github.com/gobwas/glob"
 	"github.com/spf13/cobra"
@@ -32,12 +33,19 @@ var (
 	authClient       *auth.Auth
 	connectionClient *conn.Connection
 	foundryConf      = FoundryConf{}
+	// The line of -c and the exit code of its commands
+	onceLine     = ""
+	onceExitCode = 0
 
 	rootCmd = &cobra.Command{
 		Use:     "foundry",
 		Short:   "Better serverless dev",
 		Example: "foundry --help",
 		Run: func(cmd *cobra.Command, args []string) {
+			if onceLine != "" {
+				onceExitCode = runOnce(onceLine)
+				return
+			}
 			logger.Logln("No subcommand was specified. To see all commands type 'foundry --help	'")
 		},
 	}
@@ -50,6 +58,7 @@ func init() {
 
 	cobra.OnInitialize(func() { cobraInitCallback(cmd) })
 
+	rootCmd.Flags().StringVarP(&onceLine, "command", "c", "", "run the prompt's commands without the prompt and exit with their status -c 'env-print && ls'")
 	AddRootFlags(rootCmd)
 
 	// TODO: Can this be in cobraInitCallback instead of here?
@@ -150,9 +159,9 @@ func cobraInitCallback(cmd string) {
 			time.Sleep(time.Second)
 		}
 
-		// TODO: Now only 'go' command can use connectionClient variable
+		// TODO: Now only 'go' command and -c can use connectionClient variable
 		// This should be handled better
-		if cmd == "go" {
+		if cmd == "go" || onceLine != "" {
 			// Create a new connection to the cloud env
 			fmt.Println("Connecting to your cloud environment...")
 			c, err := conn.New(authClient.IDToken)
@@ -171,6 +180,9 @@ func Execute() {
 			connectionClient.Close()
 		}
 		logger.Close()
+		if onceExitCode != p.ExitOK {
+			os.Exit(onceExitCode)
+		}
 	}()
 
 	if err := rootCmd.Execute(); err != nil {
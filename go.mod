@@ -8,14 +8,14 @@ require (
 	github.com/fsnotify/fsnotify v1.4.9
 	github.com/gobwas/glob v0.2.3
 	github.com/golang/gddo v0.0.0-20200324184333-3c2cc9a6329d
+	github.com/google/pprof v0.0.0-20200430221834-fc25d7d30c6d
 	github.com/gorilla/websocket v1.4.2
-	github.com/mattn/go-runewidth v0.0.8 // indirect
+	github.com/mattn/go-runewidth v0.0.8
 	github.com/mattn/go-tty v0.0.3 // indirect
 	github.com/mlejva/go-prompt v0.2.4-0.20200408092807-6312c0dbbff2
 	github.com/pkg/term v0.0.0-20190109203006-aa71e9d9e942 // indirect
 	github.com/spf13/cobra v0.0.7
 	github.com/spf13/viper v1.6.2
+	go.uber.org/goleak v1.1.10
 	gopkg.in/yaml.v2 v2.2.8
 )
-
-// replace github.com/mlejva/go-prompt v0.2.4-0.20200325150717-647876a5db0d => /Users/vasekmlejnsky/Developer/foundry/go-prompt
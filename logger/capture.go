@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// TestingT is the part of *testing.T that CaptureForTest needs
+type TestingT interface {
+	Helper()
+	Fatalf(format string, args ...interface{})
+	Cleanup(func())
+}
+
+// Capture collects log messages of all levels while a test runs
+type Capture struct {
+	mut     sync.Mutex
+	records []Record
+}
+
+// The active capture. Guarded by ring.mut.
+var capture *Capture
+
+// CaptureForTest collects all log messages until the test finishes.
+// Nothing is written to the log destination meanwhile. Only one capture
+// can be active at a time, so tests using it must not run in parallel.
+func CaptureForTest(t TestingT) *Capture {
+	t.Helper()
+
+	c := &Capture{}
+	ring.mut.Lock()
+	if capture != nil {
+		ring.mut.Unlock()
+		t.Fatalf("logger: CaptureForTest called while another capture is active; tests capturing logs can't run in parallel")
+		return nil
+	}
+	capture = c
+	atomic.StoreInt32(&capturing, 1)
+	ring.mut.Unlock()
+
+	outMutex.Lock()
+	savedOut, savedFile := out, file
+	out, file = ioutil.Discard, nil
+	outMutex.Unlock()
+
+	t.Cleanup(func() {
+		outMutex.Lock()
+		out, file = savedOut, savedFile
+		outMutex.Unlock()
+
+		ring.mut.Lock()
+		capture = nil
+		atomic.StoreInt32(&capturing, 0)
+		ring.mut.Unlock()
+	})
+	return c
+}
+
+func (c *Capture) add(r Record) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.records = append(c.records, r)
+}
+
+// Entries returns all captured messages, oldest first
+func (c *Capture) Entries() []Record {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return append([]Record(nil), c.records...)
+}
+
+// AtLevel returns the captured messages of level l or higher
+func (c *Capture) AtLevel(l Level) []Record {
+	var records []Record
+	for _, r := range c.Entries() {
+		if r.Level >= l {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// Contains reports whether any captured message contains substr
+func (c *Capture) Contains(substr string) bool {
+	for _, r := range c.Entries() {
+		if strings.Contains(r.Message, substr) {
+			return true
+		}
+	}
+	return false
+}
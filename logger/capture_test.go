@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCaptureForTest(t *testing.T) {
+	logged := captureOutput(t)
+	SetLevel(LevelInfo)
+
+	c := CaptureForTest(t)
+	Debug("cache miss")
+	Info("deploying api")
+	With("fn", "api").Warn("slow upload")
+	Error("upload failed")
+
+	entries := c.Entries()
+	want := []struct {
+		level Level
+		msg   string
+	}{
+		{LevelDebug, "cache miss"},
+		{LevelInfo, "deploying api"},
+		{LevelWarn, "slow upload fn=api"},
+		{LevelError, "upload failed"},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("captured %d messages, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		if entries[i].Level != w.level || entries[i].Message != w.msg {
+			t.Errorf("message %d is %v %q, want %v %q", i, entries[i].Level, entries[i].Message, w.level, w.msg)
+		}
+	}
+
+	if !c.Contains("slow upload") || c.Contains("deploying worker") {
+		t.Error("Contains doesn't match the captured messages")
+	}
+	atWarn := c.AtLevel(LevelWarn)
+	if len(atWarn) != 2 || atWarn[0].Level != LevelWarn || atWarn[1].Level != LevelError {
+		t.Errorf("AtLevel(LevelWarn) = %+v, want the warning and the error", atWarn)
+	}
+	if s := logged.String(); s != "" {
+		t.Errorf("the captured messages were written to the log too: %q", s)
+	}
+}
+
+// TestCaptureRestoresOutput checks that the messages go to the log again
+// once the test using the capture finishes
+func TestCaptureRestoresOutput(t *testing.T) {
+	logged := captureOutput(t)
+
+	ft := &fakeT{}
+	c := CaptureForTest(ft)
+	Error("while captured")
+	ft.cleanup()
+	Error("after the capture")
+
+	if !c.Contains("while captured") || c.Contains("after the capture") {
+		t.Fatalf("the capture got %+v", c.Entries())
+	}
+	s := logged.String()
+	if strings.Contains(s, "while captured") || !strings.Contains(s, "after the capture") {
+		t.Fatalf("the log got %q", s)
+	}
+}
+
+func TestNestedCaptureFails(t *testing.T) {
+	outer := &fakeT{}
+	CaptureForTest(outer)
+	defer outer.cleanup()
+
+	inner := &fakeT{}
+	if c := CaptureForTest(inner); c != nil {
+		t.Fatal("the nested capture was started")
+	}
+	if len(inner.fatals) != 1 || !strings.Contains(inner.fatals[0], "another capture is active") {
+		t.Fatalf("the nested capture failed with %q", inner.fatals)
+	}
+	if len(inner.cleanups) != 0 {
+		t.Fatal("the failed capture registered a cleanup")
+	}
+
+	// The next test can capture once the first one is done
+	outer.cleanup()
+	next := &fakeT{}
+	defer next.cleanup()
+	if CaptureForTest(next) == nil || len(next.fatals) != 0 {
+		t.Fatalf("capturing after the first capture ended failed with %q", next.fatals)
+	}
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ColorMode says whether the console format colors the level tags
+type ColorMode int32
+
+const (
+	// ColorAuto colors the tags when the output is a terminal and NO_COLOR isn't set
+	ColorAuto ColorMode = iota
+	ColorAlways
+	ColorNever
+)
+
+const cyan = "\x1b[36m"
+
+var (
+	colorMode int32
+	// Set by setDestination for the current output
+	consoleOut int32
+	ttyOut     int32
+)
+
+// SetColorMode sets whether the console format colors the level tags.
+// It's safe to call at any time.
+func SetColorMode(m ColorMode) {
+	atomic.StoreInt32(&colorMode, int32(m))
+}
+
+// levelTag is how a level is shown in front of a log line
+type levelTag struct {
+	label string // In the files, always colored so `tail -f` is readable
+	name  string // In the console format, padded to the same width
+	color string
+}
+
+var (
+	debugTag = levelTag{debugLevelPrefix, "DEBUG", cyan}
+	infoTag  = levelTag{infoLevelPrefix, "INFO", ""}
+	warnTag  = levelTag{warningPrefix, "WARN", yellow}
+	errorTag = levelTag{errorPrefix, "ERROR", red}
+	fatalTag = levelTag{fatalPrefix, "FATAL", bold + red}
+)
+
+// setDestination remembers whether w is the console. outMutex must be held.
+func setDestination(w io.Writer) {
+	var console, tty int32
+	if f, ok := w.(*os.File); ok && (f == os.Stderr || f == os.Stdout) {
+		console = 1
+		if info, err := f.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			tty = 1
+		}
+	}
+	atomic.StoreInt32(&consoleOut, console)
+	atomic.StoreInt32(&ttyOut, tty)
+}
+
+// currentFormat resolves FormatAuto for the current destination
+func currentFormat() Format {
+	f := Format(atomic.LoadInt32(&format))
+	if f != FormatAuto {
+		return f
+	}
+	if atomic.LoadInt32(&consoleOut) == 1 {
+		return FormatConsole
+	}
+	return FormatHuman
+}
+
+func consoleColors() bool {
+	switch ColorMode(atomic.LoadInt32(&colorMode)) {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	return atomic.LoadInt32(&ttyOut) == 1
+}
+
+// consolePrefix returns the prefix of the console format, "15:04:05.000 WARN ".
+// The level names are padded so the messages start in the same column.
+func consolePrefix(t levelTag) string {
+	var b strings.Builder
+	if GetPrefixFlags()&PrefixWallTime != 0 {
+		b.WriteString(time.Now().Format("15:04:05.000"))
+		b.WriteByte(' ')
+	}
+
+	color := consoleColors() && t.color != ""
+	if color {
+		b.WriteString(t.color)
+	}
+	b.WriteString(t.name)
+	if color {
+		b.WriteString(endSeq)
+	}
+	b.WriteString(strings.Repeat(" ", 5-len(t.name)))
+	return b.String()
+}
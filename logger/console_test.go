@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+// TestConsoleGolden logs in the console format as if stderr was a
+// terminal and as if it was redirected, with every color mode and with
+// NO_COLOR. The lines are compared with testdata/console.golden.
+func TestConsoleGolden(t *testing.T) {
+	logged := captureOutput(t)
+	SetLevel(LevelDebug)
+	SetFormat(FormatAuto)
+	defer SetColorMode(ColorAuto)
+	if v, ok := os.LookupEnv("NO_COLOR"); ok {
+		defer os.Setenv("NO_COLOR", v)
+	} else {
+		defer os.Unsetenv("NO_COLOR")
+	}
+
+	cases := []struct {
+		name    string
+		tty     bool
+		mode    ColorMode
+		noColor bool
+	}{
+		{"tty", true, ColorAuto, false},
+		{"tty NO_COLOR", true, ColorAuto, true},
+		{"tty never", true, ColorNever, false},
+		{"redirected", false, ColorAuto, false},
+		{"redirected always", false, ColorAlways, false},
+	}
+	for _, c := range cases {
+		// What setDestination finds out for stderr
+		atomic.StoreInt32(&consoleOut, 1)
+		tty := int32(0)
+		if c.tty {
+			tty = 1
+		}
+		atomic.StoreInt32(&ttyOut, tty)
+		SetColorMode(c.mode)
+		if c.noColor {
+			os.Setenv("NO_COLOR", "1")
+		} else {
+			os.Unsetenv("NO_COLOR")
+		}
+
+		Debug(c.name)
+		Info(c.name)
+		Warn(c.name)
+		With("fn", "api", "err", "upload failed").Error(c.name)
+	}
+	checkGolden(t, "console.golden", logged.String())
+}
@@ -0,0 +1,29 @@
+package logger
+
+import "context"
+
+type ctxKey struct{}
+
+// NewContext returns a copy of ctx that carries the entry e.
+// Everything logged with Ctx of the new context has e's fields.
+func NewContext(ctx context.Context, e *Entry) context.Context {
+	return context.WithValue(ctx, ctxKey{}, e)
+}
+
+// WithContext returns a copy of ctx whose entry has more fields
+// given as pairs of a key and a value
+func WithContext(ctx context.Context, kv ...interface{}) context.Context {
+	return NewContext(ctx, Ctx(ctx).With(kv...))
+}
+
+// Ctx returns the entry carried by ctx or an entry without fields.
+// Goroutines started with the context log with the same fields,
+// e.g. every message of a prompt command has the command's execution id.
+func Ctx(ctx context.Context) *Entry {
+	if ctx != nil {
+		if e, ok := ctx.Value(ctxKey{}).(*Entry); ok {
+			return e
+		}
+	}
+	return &Entry{}
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCtx(t *testing.T) {
+	c := CaptureForTest(t)
+
+	Ctx(nil).Info("no context")
+	Ctx(context.Background()).Info("no entry")
+	ctx := WithContext(context.Background(), "exec", "3fa2-1")
+	nested := WithContext(ctx, "job", "upload")
+	Ctx(ctx).Info("in the command")
+	Ctx(nested).With("bytes", 12).Info("in the job")
+	Ctx(ctx).Info("the command again")
+
+	want := []string{
+		"no context",
+		"no entry",
+		"in the command exec=3fa2-1",
+		"in the job exec=3fa2-1 job=upload bytes=12",
+		"the command again exec=3fa2-1",
+	}
+	entries := c.Entries()
+	if len(entries) != len(want) {
+		t.Fatalf("logged %+v, want %q", entries, want)
+	}
+	for i, w := range want {
+		if entries[i].Message != w {
+			t.Errorf("message %d is %q, want %q", i, entries[i].Message, w)
+		}
+	}
+}
@@ -4,114 +4,117 @@ package logger
 
 import (
 	"fmt"
-	"os"
-	"runtime"
-	"time"
+	runtimeDebug "runtime/debug"
 )
 
-type PrefixType int
+// Debug builds log everything unless FOUNDRY_LOG_LEVEL says otherwise
+const defaultLevel = LevelDebug
 
-const (
-	DebugPrefix PrefixType = iota
-	ErrorPrefix
-	FatalPrefix
-)
-
-var (
-	debugFile *os.File
-)
+// Set when a debug session was started with InitDebug
+var debugSession bool
 
+// InitDebug starts a debug session that logs to the file at path.
+// FdebuglnFatal panics during a debug session.
 func InitDebug(path string) error {
 	if path == "" {
 		return nil
 	}
 
-	dfile, err := os.Create(path)
-	if err != nil {
+	if err := SetFile(path); err != nil {
 		return err
 	}
-	debugFile = dfile
+	debugSession = true
 
 	Fdebugln("################## STARTING SESSION")
 	return nil
 }
 
-func Close() {
-	if debugFile == nil {
-		return
-	}
-	debugFile.Close()
-}
-
 func Fdebugln(v ...interface{}) {
-	if debugFile == nil {
+	record(LevelDebug, v, true, nil)
+
+	if !Enabled(LevelDebug) {
 		return
 	}
 
-	str := fmt.Sprintf("%s %s", prefix(DebugPrefix), fmt.Sprintln(v...))
-	fmt.Fprint(debugFile, str)
+	writeOut(fmt.Sprintf("%s %s", prefix(DebugPrefix), fmt.Sprintln(v...)))
 }
 
 func FdebuglnError(v ...interface{}) {
-	if debugFile == nil {
+	record(LevelError, v, true, nil)
+
+	if !Enabled(LevelError) {
 		return
 	}
 
-	str := fmt.Sprintf("%s %s", prefix(ErrorPrefix), fmt.Sprintln(v...))
-	fmt.Fprint(debugFile, str)
+	writeOut(fmt.Sprintf("%s %s", prefix(ErrorPrefix), fmt.Sprintln(v...)))
 }
 
 func FdebuglnFatal(v ...interface{}) {
-	if debugFile == nil {
-		return
-	}
+	record(LevelError, v, true, nil)
 
 	str := fmt.Sprintf("%s %s", prefix(FatalPrefix), fmt.Sprintln(v...))
-	fmt.Fprint(debugFile, str)
-	// fmt.FPrint(debugFile, runtimeDebug.Stack())
+	writeOut(str)
+	if !debugSession {
+		return
+	}
+	writeOut(string(runtimeDebug.Stack()))
+	if h := getFatalHandler(); h != nil {
+		// A panic would leave the terminal as the handler's owner set it up
+		h(str)
+	}
 	panic(str)
 }
 
-// Doesn't write to the debug file
+// Writes to stdout or the sink set by SetDebugSink instead of the log destination
 func Debugln(v ...interface{}) {
+	if !Enabled(LevelDebug) {
+		return
+	}
 	str := fmt.Sprintf("%s %s", prefix(DebugPrefix), fmt.Sprintln(v...))
-	fmt.Print(str)
+	writeDebugSink(str)
 }
 
-// Doesn't write to the debug file
+// Writes to stdout or the sink set by SetDebugSink instead of the log destination
 func DebuglnError(v ...interface{}) {
+	if !Enabled(LevelError) {
+		return
+	}
 	str := fmt.Sprintf("%s %s", prefix(ErrorPrefix), fmt.Sprintln(v...))
-	fmt.Print(str)
+	writeDebugSink(str)
 }
 
-// Doesn't write to the debug file
+// Doesn't write to the log destination
 func DebuglnFatal(v ...interface{}) {
 	str := fmt.Sprintf("%s %s", prefix(FatalPrefix), fmt.Sprintln(v...))
 	panic(str)
 }
 
-func prefix(t PrefixType) (prefix string) {
-	h, m, s := time.Now().Clock()
-	timePrefix := fmt.Sprintf("%d:%02d:%02d", h, m, s)
+func debugEnabled() bool {
+	return true
+}
 
-	bold := "\x1b[1m"
-	red := "\x1b[31m"
-	endSeq := "\x1b[0m"
+// writeDebugEntry writes a line encoded by an Entry to the debug file.
+// Lines in the human format get the same prefix as Fdebugln.
+// skip is the number of extra stack frames above the Entry method.
+func writeDebugEntry(t PrefixType, skip int, line string, human bool) {
+	if human {
+		line = fmt.Sprintf("%s %s", callerPrefix(t, 4+skip), line)
+	}
+	writeOut(line + "\n")
+}
+
+func prefix(t PrefixType) string {
+	return callerPrefix(t, 3)
+}
 
+// skip is the number of stack frames to ascend to get to the logging call
+func callerPrefix(t PrefixType, skip int) string {
 	switch t {
-	case DebugPrefix:
-		prefix = fmt.Sprintf("%sDEBUG%s", bold, endSeq)
 	case FatalPrefix:
-		prefix = fmt.Sprintf("%s%sFATAL%s", red, bold, endSeq)
+		return entryPrefix(fatalTag, skip)
 	case ErrorPrefix:
-		prefix = fmt.Sprintf("%s%sERROR%s", red, bold, endSeq)
+		return entryPrefix(errorTag, skip)
 	default:
-		prefix = fmt.Sprintf("%sDEBUG%s", bold, endSeq)
+		return entryPrefix(debugTag, skip)
 	}
-
-	// We're using 2, to ascend 2 stack frames
-	pc, _, line, _ := runtime.Caller(2)
-	debugInfo := fmt.Sprintf("[%s:%d]", runtime.FuncForPC(pc).Name(), line)
-
-	return fmt.Sprintf("%s %s %s", timePrefix, prefix, debugInfo)
 }
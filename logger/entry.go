@@ -0,0 +1,265 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Format is how entries with fields are encoded
+type Format int32
+
+const (
+	// FormatAuto is FormatConsole when the messages go to stderr
+	// or stdout and FormatHuman otherwise
+	FormatAuto Format = iota
+	// FormatHuman writes "message key=value key=value"
+	FormatHuman
+	// FormatJSON writes one JSON object per line
+	FormatJSON
+	// FormatConsole is FormatHuman with a short aligned prefix
+	// and level colors for reading in a terminal
+	FormatConsole
+)
+
+var format int32
+
+// ParseFormat returns the format with a name s ("auto", "human", "json" or "console")
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "auto":
+		return FormatAuto, nil
+	case "console":
+		return FormatConsole, nil
+	case "human", "text":
+		return FormatHuman, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatAuto, fmt.Errorf("unknown log format '%s'", s)
+	}
+}
+
+// SetFormat sets how entries with fields are encoded. It's safe to call at any time.
+func SetFormat(f Format) {
+	atomic.StoreInt32(&format, int32(f))
+}
+
+type field struct {
+	key string
+	val interface{}
+}
+
+// Entry is a log message with fields attached to it
+type Entry struct {
+	fields []field
+	skip   int // Extra stack frames to ascend to get to the logging call
+
+	// Set by Sampled
+	suppressed int64
+	muted      bool
+}
+
+// With returns an entry with fields given as pairs of a key and a value
+func With(kv ...interface{}) *Entry {
+	return (&Entry{}).With(kv...)
+}
+
+// WithFields returns an entry with fields from m sorted by key
+func WithFields(m map[string]interface{}) *Entry {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	e := &Entry{fields: make([]field, 0, len(keys))}
+	for _, k := range keys {
+		e.fields = append(e.fields, field{k, m[k]})
+	}
+	return e
+}
+
+// With returns a copy of the entry with more fields.
+// A key that isn't a string is formatted with fmt.
+func (e *Entry) With(kv ...interface{}) *Entry {
+	if e.muted {
+		return e
+	}
+
+	n := &Entry{
+		fields:     make([]field, len(e.fields), len(e.fields)+(len(kv)+1)/2),
+		skip:       e.skip,
+		suppressed: e.suppressed,
+	}
+	copy(n.fields, e.fields)
+
+	for i := 0; i < len(kv); i += 2 {
+		key, ok := kv[i].(string)
+		if !ok {
+			key = fmt.Sprint(kv[i])
+		}
+		var val interface{} = "!MISSING"
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+		n.fields = append(n.fields, field{key, val})
+	}
+	return n
+}
+
+// Skip returns a copy of the entry that reports the caller n stack frames
+// above the logging call. Helpers that log on behalf of their caller use it.
+func (e *Entry) Skip(n int) *Entry {
+	if e.muted {
+		return e
+	}
+
+	c := e.With()
+	c.skip += n
+	return c
+}
+
+func (e *Entry) Debug(args ...interface{}) { e.log(LevelDebug, debugTag, args) }
+func (e *Entry) Info(args ...interface{})  { e.log(LevelInfo, infoTag, args) }
+func (e *Entry) Warn(args ...interface{})  { e.log(LevelWarn, warnTag, args) }
+func (e *Entry) Error(args ...interface{}) { e.log(LevelError, errorTag, args) }
+
+// Fdebug writes the entry to the debug file like Fdebugln
+func (e *Entry) Fdebug(args ...interface{}) { e.fdebug(LevelDebug, DebugPrefix, args) }
+
+// FdebugError writes the entry to the debug file like FdebuglnError
+func (e *Entry) FdebugError(args ...interface{}) { e.fdebug(LevelError, ErrorPrefix, args) }
+
+func (e *Entry) log(l Level, t levelTag, args []interface{}) {
+	if e.muted {
+		return
+	}
+	record(l, args, false, e)
+
+	// Check the level first so the discarded entries aren't encoded
+	if !Enabled(l) {
+		return
+	}
+
+	line, human := e.encode(l, fmt.Sprint(args...))
+	if human {
+		line = entryPrefix(t, 2+e.skip) + " " + line
+	}
+
+	writeOut(line + "\n")
+}
+
+func (e *Entry) fdebug(l Level, t PrefixType, args []interface{}) {
+	if e.muted {
+		return
+	}
+	record(l, args, false, e)
+
+	if !debugEnabled() || !Enabled(l) {
+		return
+	}
+
+	line, human := e.encode(l, fmt.Sprint(args...))
+	writeDebugEntry(t, e.skip, line, human)
+}
+
+// encode returns the entry as a single line in the current format
+func (e *Entry) encode(l Level, msg string) (line string, human bool) {
+	if currentFormat() == FormatJSON {
+		return e.encodeJSON(l, msg), false
+	}
+	return e.encodeHuman(msg), true
+}
+
+func (e *Entry) encodeHuman(msg string) string {
+	var b strings.Builder
+	b.WriteString(e.withSuppressed(msg))
+	for _, f := range e.fields {
+		b.WriteByte(' ')
+		b.WriteString(f.key)
+		b.WriteByte('=')
+		b.WriteString(humanValue(f.val))
+	}
+	return b.String()
+}
+
+func humanValue(v interface{}) string {
+	var s string
+	switch v := v.(type) {
+	case nil:
+		return "<nil>"
+	case error:
+		s = v.Error()
+	case time.Duration:
+		return v.String()
+	case string:
+		s = v
+	case fmt.Stringer:
+		s = v.String()
+	default:
+		s = fmt.Sprintf("%+v", v)
+	}
+
+	// Quote the values that couldn't be told apart from the next field
+	if s == "" || strings.ContainsAny(s, " =\"\t\r\n") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+func (e *Entry) encodeJSON(l Level, msg string) string {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, time.Now().Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, l.String())
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, e.withSuppressed(msg))
+
+	for _, f := range e.fields {
+		key := f.key
+		switch key {
+		case "time", "level", "msg":
+			// Don't hide the entry's own keys
+			key = "fields." + key
+		}
+		b.WriteByte(',')
+		writeJSON(&b, key)
+		b.WriteByte(':')
+		writeJSON(&b, jsonValue(f.val))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case json.Marshaler:
+		return v
+	case error:
+		return v.Error()
+	case time.Duration:
+		return v.String()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return v
+}
+
+// writeJSON writes v to b. Values that can't be encoded as JSON
+// (channels, functions...) are written as strings formatted with fmt.
+func writeJSON(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(fmt.Sprintf("%+v", v))
+	}
+	b.Write(data)
+}
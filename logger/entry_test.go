@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type upload struct {
+	Name  string
+	Sizes []int
+	Inner struct{ OK bool }
+}
+
+// fieldTests are the values encodeHuman and encodeJSON must handle
+var fieldTests = []struct {
+	name  string
+	kv    []interface{}
+	human string
+	json  string
+}{
+	{"string", []interface{}{"cmd", "deploy"}, `msg cmd=deploy`, `"cmd":"deploy"`},
+	{"quoted string", []interface{}{"input", "deploy api"}, `msg input="deploy api"`, `"input":"deploy api"`},
+	{"empty string", []interface{}{"input", ""}, `msg input=""`, `"input":""`},
+	{"number", []interface{}{"rows", 24}, `msg rows=24`, `"rows":24`},
+	{"error", []interface{}{"err", errors.New("upload failed")}, `msg err="upload failed"`, `"err":"upload failed"`},
+	{"nil", []interface{}{"err", nil}, `msg err=<nil>`, `"err":null`},
+	{"duration", []interface{}{"duration", 12400 * time.Millisecond}, `msg duration=12.4s`, `"duration":"12.4s"`},
+	{"stringer", []interface{}{"ip", net.IPv4(127, 0, 0, 1)}, `msg ip=127.0.0.1`, `"ip":"127.0.0.1"`},
+	{"nested struct", []interface{}{"upload", upload{Name: "api", Sizes: []int{1, 2}}},
+		`msg upload="{Name:api Sizes:[1 2] Inner:{OK:false}}"`, `"upload":{"Name":"api","Sizes":[1,2],"Inner":{"OK":false}}`},
+	{"not encodable", []interface{}{"ch", make(chan int)}, ``, `"ch":"0x`},
+	{"missing value", []interface{}{"cmd"}, `msg cmd=!MISSING`, `"cmd":"!MISSING"`},
+	{"key not a string", []interface{}{1, "one"}, `msg 1=one`, `"1":"one"`},
+	{"entry's own key", []interface{}{"level", "high"}, `msg level=high`, `"fields.level":"high"`},
+}
+
+func TestEncodeHuman(t *testing.T) {
+	for _, tt := range fieldTests {
+		if tt.human == "" {
+			continue
+		}
+		if got := With(tt.kv...).encodeHuman("msg"); got != tt.human {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.human)
+		}
+	}
+}
+
+func TestEncodeJSON(t *testing.T) {
+	const start = `{"time":"`
+	for _, tt := range fieldTests {
+		got := With(tt.kv...).encodeJSON(LevelWarn, "msg")
+		// The time is the only part that changes
+		end := strings.Index(got[len(start):], `"`) + len(start)
+		if !strings.HasPrefix(got, start) || end < len(start) {
+			t.Fatalf("%s: the entry doesn't start with the time: %s", tt.name, got)
+		}
+		if _, err := time.Parse(time.RFC3339Nano, got[len(start):end]); err != nil {
+			t.Errorf("%s: %s", tt.name, err)
+		}
+		rest := got[end:]
+		want := `","level":"warn","msg":"msg",` + tt.json
+		if !strings.HasPrefix(rest, want) {
+			t.Errorf("%s: got %s, want %s", tt.name, rest, want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package logger
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.buf.String()
+}
+
+// captureOutput sends the log messages to the returned buffer until the
+// test finishes. The level and the format are set back by the cleanup too.
+func captureOutput(t testing.TB) *syncBuffer {
+	t.Helper()
+	buf := &syncBuffer{}
+
+	outMutex.Lock()
+	savedOut, savedFile := out, file
+	out, file = buf, nil
+	setDestination(buf)
+	outMutex.Unlock()
+	savedLevel, savedFormat := GetLevel(), Format(atomic.LoadInt32(&format))
+
+	t.Cleanup(func() {
+		outMutex.Lock()
+		out, file = savedOut, savedFile
+		setDestination(out)
+		outMutex.Unlock()
+		SetLevel(savedLevel)
+		SetFormat(savedFormat)
+	})
+	return buf
+}
+
+// fakeT is a TestingT keeping the failures instead of ending the test
+type fakeT struct {
+	fatals   []string
+	cleanups []func()
+}
+
+func (f *fakeT) Helper() {}
+
+func (f *fakeT) Fatalf(format string, args ...interface{}) {
+	f.fatals = append(f.fatals, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeT) Cleanup(fn func()) { f.cleanups = append(f.cleanups, fn) }
+
+// cleanup runs the cleanups in the reverse order like testing.T, once
+func (f *fakeT) cleanup() {
+	for i := len(f.cleanups) - 1; i >= 0; i-- {
+		f.cleanups[i]()
+	}
+	f.cleanups = nil
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// The parts of the log lines that change from run to run
+var volatile = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`\d\d:\d\d:\d\d\.\d\d\d`), "15:04:05.000"},
+	{regexp.MustCompile(`\+\d+\.\d{6}s`), "+1.234567s"},
+	{regexp.MustCompile(`(\[\w+\.go):\d+\]`), "$1:1]"},
+	{regexp.MustCompile(`\[g\d+\]`), "[g1]"},
+}
+
+// checkGolden compares got with the file name in testdata after replacing
+// the times, line numbers and goroutine ids. With -update it writes the
+// file instead.
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	for _, v := range volatile {
+		got = v.re.ReplaceAllString(got, v.repl)
+	}
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Fatalf("the output differs from %s:\n got %q\nwant %q", golden, got, want)
+	}
+}
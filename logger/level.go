@@ -0,0 +1,107 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level is the severity of a log message. Messages below
+// the current level set by SetLevel are discarded. The console
+// printers like SuccessLogln are for the user and print at every level.
+type Level int32
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Environment variable with the initial log level (debug, info, warn or error)
+const LevelEnv = "FOUNDRY_LOG_LEVEL"
+
+var (
+	level = int32(defaultLevel)
+
+	debugLevelPrefix = fmt.Sprintf("%sDEBUG%s", bold, endSeq)
+	infoLevelPrefix  = fmt.Sprintf("%sINFO%s", bold, endSeq)
+)
+
+func init() {
+	s := os.Getenv(LevelEnv)
+	if s == "" {
+		return
+	}
+
+	l, err := ParseLevel(s)
+	if err != nil {
+		WarningLogln(fmt.Sprintf("Ignoring %s: %s", LevelEnv, err))
+		return
+	}
+	SetLevel(l)
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return fmt.Sprintf("level(%d)", int32(l))
+	}
+}
+
+// ParseLevel returns the level with a name s (case insensitive)
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	default:
+		return LevelInfo, fmt.Errorf("unknown log level '%s'", s)
+	}
+}
+
+// SetLevel sets the minimum level of messages that are logged.
+// It's safe to call at any time from any goroutine.
+func SetLevel(l Level) {
+	atomic.StoreInt32(&level, int32(l))
+}
+
+// GetLevel returns the current minimum level of logged messages
+func GetLevel() Level {
+	return Level(atomic.LoadInt32(&level))
+}
+
+// Enabled reports whether messages of level l are logged
+func Enabled(l Level) bool {
+	return int32(l) >= atomic.LoadInt32(&level)
+}
+
+func logLevel(l Level, t levelTag, args []interface{}) {
+	record(l, args, false, nil)
+
+	// Check the level first so the discarded messages aren't formatted
+	if !Enabled(l) {
+		return
+	}
+
+	writeOut(fmt.Sprintf("%s %s\n", entryPrefix(t, 2), fmt.Sprint(args...)))
+}
+
+func Debug(args ...interface{}) { logLevel(LevelDebug, debugTag, args) }
+func Info(args ...interface{})  { logLevel(LevelInfo, infoTag, args) }
+func Warn(args ...interface{})  { logLevel(LevelWarn, warnTag, args) }
+func Error(args ...interface{}) { logLevel(LevelError, errorTag, args) }
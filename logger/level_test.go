@@ -0,0 +1,146 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// TestLevels logs a message at every level, with and without fields, for
+// every level set. Only the messages at or above it get to the output.
+func TestLevels(t *testing.T) {
+	levels := []Level{LevelDebug, LevelInfo, LevelWarn, LevelError}
+	logs := map[Level][]func(msg string){
+		LevelDebug: {func(m string) { Debug(m) }, func(m string) { With("k", "v").Debug(m) }},
+		LevelInfo:  {func(m string) { Info(m) }, func(m string) { With("k", "v").Info(m) }},
+		LevelWarn:  {func(m string) { Warn(m) }, func(m string) { With("k", "v").Warn(m) }},
+		LevelError: {func(m string) { Error(m) }, func(m string) { With("k", "v").Error(m) }},
+	}
+	for _, set := range levels {
+		t.Run(set.String(), func(t *testing.T) {
+			logged := captureOutput(t)
+			SetFormat(FormatHuman)
+			SetLevel(set)
+			for _, l := range levels {
+				for i, log := range logs[l] {
+					log(fmt.Sprintf("message %s %d", l, i))
+				}
+			}
+
+			out := logged.String()
+			for _, l := range levels {
+				for i := range logs[l] {
+					msg := fmt.Sprintf("message %s %d", l, i)
+					if got, want := strings.Contains(out, msg), l >= set; got != want {
+						t.Errorf("%q logged: %v, want %v", msg, got, want)
+					}
+				}
+			}
+			if !Enabled(set) || (set > LevelDebug && Enabled(set-1)) {
+				t.Errorf("Enabled doesn't match level %s", set)
+			}
+		})
+	}
+}
+
+// TestConsolePrintersIgnoreLevel prints with the console printers at
+// every level. They're for the user, the level is for the diagnostics.
+func TestConsolePrintersIgnoreLevel(t *testing.T) {
+	saved := GetLevel()
+	t.Cleanup(func() { SetLevel(saved) })
+	for _, set := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		t.Run(set.String(), func(t *testing.T) {
+			SetLevel(set)
+			out := captureStdout(t, func() {
+				SuccessLogln("deployed")
+				WarningLogln("slow")
+				ErrorLogln("failed")
+			})
+			for _, want := range []string{successPrefix + " deployed\n", warningPrefix + " slow\n", errorPrefix + " failed\n"} {
+				if !strings.Contains(out, want) {
+					t.Errorf("%q wasn't printed: %q", want, out)
+				}
+			}
+		})
+	}
+}
+
+// captureStdout returns what fn prints to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	saved := os.Stdout
+	os.Stdout = w
+	fn()
+	os.Stdout = saved
+	w.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		s    string
+		want Level
+		err  bool
+	}{
+		{"debug", LevelDebug, false},
+		{"INFO", LevelInfo, false},
+		{" warn ", LevelWarn, false},
+		{"Warning", LevelWarn, false},
+		{"error", LevelError, false},
+		{"loud", LevelInfo, true},
+		{"", LevelInfo, true},
+	}
+	for _, tt := range tests {
+		l, err := ParseLevel(tt.s)
+		if l != tt.want || (err != nil) != tt.err {
+			t.Errorf("ParseLevel(%q) = %s, %v, want %s and an error: %v", tt.s, l, err, tt.want, tt.err)
+		}
+	}
+}
+
+// TestLevelEnv runs the test binary again with FOUNDRY_LOG_LEVEL set.
+// The child prints the level the package's init set.
+func TestLevelEnv(t *testing.T) {
+	if os.Getenv("LOGGER_TEST_PRINT_LEVEL") == "1" {
+		fmt.Printf("level=%s\n", GetLevel())
+		return
+	}
+
+	tests := []struct {
+		env  string
+		want Level
+		warn string
+	}{
+		{"debug", LevelDebug, ""},
+		{" WARNING ", LevelWarn, ""},
+		{"error", LevelError, ""},
+		{"loud", defaultLevel, "Ignoring FOUNDRY_LOG_LEVEL: unknown log level 'loud'"},
+	}
+	for _, tt := range tests {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestLevelEnv$")
+		// The race detector would wait a second before the child exits
+		cmd.Env = append(os.Environ(), "LOGGER_TEST_PRINT_LEVEL=1", LevelEnv+"="+tt.env, "GORACE=atexit_sleep_ms=0")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%s=%q: the child failed: %s\n%s", LevelEnv, tt.env, err, out)
+		}
+		if !strings.Contains(string(out), "level="+tt.want.String()+"\n") {
+			t.Errorf("%s=%q: the child printed %q, want level %s", LevelEnv, tt.env, out, tt.want)
+		}
+		if tt.warn != "" && !strings.Contains(string(out), tt.warn) {
+			t.Errorf("%s=%q: the child didn't warn %q: %q", LevelEnv, tt.env, tt.warn, out)
+		}
+	}
+}
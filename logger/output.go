@@ -0,0 +1,260 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// Environment variable with the path of the log file. "stderr" logs to stderr.
+	FileEnv = "FOUNDRY_LOG_FILE"
+
+	DefaultMaxFileSize  = 10 * 1024 * 1024
+	DefaultRotatedFiles = 3
+)
+
+var (
+	outMutex sync.Mutex
+	// Where the log messages go. Nil until the first message
+	// is logged or the destination is set explicitly.
+	out  io.Writer
+	file *logFile // Set when out is a file opened by the logger
+
+	maxFileSize  int64 = DefaultMaxFileSize
+	rotatedFiles       = DefaultRotatedFiles
+
+	// Where Debugln and DebuglnError write, stdout when nil. It has its own
+	// mutex, the sink may block and must not hold up the other messages.
+	sinkMutex sync.Mutex
+	debugSink io.Writer
+)
+
+func init() {
+	outputFromEnv()
+}
+
+// outputFromEnv sets the destination named by FOUNDRY_LOG_FILE
+func outputFromEnv() {
+	path := os.Getenv(FileEnv)
+	switch strings.ToLower(path) {
+	case "":
+		return
+	case "stderr":
+		SetOutput(os.Stderr)
+		return
+	}
+
+	if err := SetFile(path); err != nil {
+		WarningLogln(fmt.Sprintf("Ignoring %s: %s", FileEnv, err))
+	}
+}
+
+// DefaultFile returns the path of the log file used when
+// no other destination is set with SetOutput, SetFile or FOUNDRY_LOG_FILE
+func DefaultFile() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "foundrycli", "foundry.log"), nil
+}
+
+// SetOutput sets where the log messages are written.
+// A log file opened by the logger before is closed.
+func SetOutput(w io.Writer) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	closeFile()
+	out = w
+	setDestination(w)
+}
+
+// SetFile makes the logger append messages to the file at path.
+// Missing parent directories are created. The file is rotated once
+// it's bigger than the size set by SetRotation.
+func SetFile(path string) error {
+	f, err := openLogFile(path)
+	if err != nil {
+		return err
+	}
+
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	closeFile()
+	file = f
+	out = f
+	setDestination(f)
+	return nil
+}
+
+// SetRotation sets the size in bytes after which the log file is rotated
+// and how many rotated files (path.1 is the newest) are kept
+func SetRotation(maxSize int64, keep int) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	maxFileSize = maxSize
+	rotatedFiles = keep
+}
+
+// Close flushes and closes the log file opened by the logger. The messages
+// logged after it go to the default file like before the first message.
+// A destination set by SetOutput stays, the logger didn't open it.
+func Close() {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	if file == nil {
+		return
+	}
+	closeFile()
+	out = nil
+	setDestination(nil)
+}
+
+// FilePath returns the path of the file the messages are written to
+// or an empty string when the destination isn't a file
+func FilePath() string {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	if file != nil {
+		return file.path
+	}
+	if out == nil {
+		// The default file is opened with the first message
+		path, _ := DefaultFile()
+		return path
+	}
+	return ""
+}
+
+// writeOut writes s to the log destination
+func writeOut(s string) {
+	outMutex.Lock()
+	defer outMutex.Unlock()
+
+	if out == nil {
+		out = ioutil.Discard
+		if path, err := DefaultFile(); err == nil {
+			if f, err := openLogFile(path); err == nil {
+				file = f
+				out = f
+			}
+		}
+	}
+	io.WriteString(out, s)
+}
+
+// SetDebugSink makes Debugln and DebuglnError write to w instead of
+// stdout, e.g. into a prompt that draws over stdout. A nil w sets stdout
+// back. w must not log, the messages would loop.
+func SetDebugSink(w io.Writer) {
+	sinkMutex.Lock()
+	defer sinkMutex.Unlock()
+
+	debugSink = w
+}
+
+// DebugBuild returns true if the program was built with the debug tag.
+// Other builds discard the debug messages whatever the level is.
+func DebugBuild() bool {
+	return debugEnabled()
+}
+
+// writeDebugSink writes s where Debugln writes
+func writeDebugSink(s string) {
+	sinkMutex.Lock()
+	w := debugSink
+	sinkMutex.Unlock()
+
+	if w == nil {
+		fmt.Print(s)
+		return
+	}
+	io.WriteString(w, s)
+}
+
+// closeFile closes the log file opened by the logger. outMutex must be held.
+func closeFile() {
+	if file == nil {
+		return
+	}
+	file.close()
+	file = nil
+}
+
+// logFile is a log file that is rotated when it grows too big
+type logFile struct {
+	path string
+	f    *os.File
+	size int64
+}
+
+func openLogFile(path string) (*logFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &logFile{path: path, f: f, size: info.Size()}, nil
+}
+
+// Write is only called with outMutex held
+func (l *logFile) Write(p []byte) (int, error) {
+	if maxFileSize > 0 && l.size > 0 && l.size+int64(len(p)) > maxFileSize {
+		if err := l.rotate(); err != nil {
+			// Keep logging to the big file rather than losing the messages
+			// and try again once another maxFileSize bytes are written
+			fmt.Fprintln(os.Stderr, "Error rotating the log file:", err)
+			l.size = 0
+		}
+	}
+
+	n, err := l.f.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+// rotate renames path.N-1 to path.N ... path to path.1 and starts a new file
+func (l *logFile) rotate() error {
+	if rotatedFiles > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", l.path, rotatedFiles))
+		for i := rotatedFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", l.path, i), fmt.Sprintf("%s.%d", l.path, i+1))
+		}
+		if err := os.Rename(l.path, l.path+".1"); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	l.f.Close()
+	l.f = f
+	l.size = 0
+	return nil
+}
+
+func (l *logFile) close() {
+	l.f.Sync()
+	l.f.Close()
+}
@@ -0,0 +1,165 @@
+package logger
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readLog returns the content of the log file at path, empty if it's missing
+func readLog(t *testing.T, path string) string {
+	t.Helper()
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestSetFile logs to a file in directories that don't exist yet and to
+// a file with older messages. The messages are appended.
+func TestSetFile(t *testing.T) {
+	captureOutput(t)
+	t.Cleanup(Close)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "sessions", "today", "foundry.log")
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile: %s", err)
+	}
+	if FilePath() != path {
+		t.Fatalf("FilePath() = %q", FilePath())
+	}
+	Error("first session")
+	// Opening it again keeps the first session's message
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile: %s", err)
+	}
+	Error("second session")
+	if got := readLog(t, path); !strings.Contains(got, "first session") || !strings.Contains(got, "second session") {
+		t.Fatalf("the log has %q", got)
+	}
+
+	old := filepath.Join(dir, "old.log")
+	if err := ioutil.WriteFile(old, []byte("yesterday\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetFile(old); err != nil {
+		t.Fatalf("SetFile: %s", err)
+	}
+	Error("today")
+	if got := readLog(t, old); !strings.HasPrefix(got, "yesterday\n") || !strings.Contains(got, "today") {
+		t.Fatalf("the log has %q", got)
+	}
+
+	// A file where the directory should be
+	if err := SetFile(filepath.Join(old, "foundry.log")); err == nil {
+		t.Fatal("SetFile under a file succeeded")
+	}
+	if FilePath() != old {
+		t.Fatalf("the failed SetFile changed the file to %q", FilePath())
+	}
+}
+
+// TestRotation logs more than the rotation size several times. The newest
+// rotated file is path.1 and only the set number of them is kept.
+func TestRotation(t *testing.T) {
+	captureOutput(t)
+	t.Cleanup(Close)
+	SetRotation(100, 2)
+	t.Cleanup(func() { SetRotation(DefaultMaxFileSize, DefaultRotatedFiles) })
+
+	path := filepath.Join(t.TempDir(), "foundry.log")
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile: %s", err)
+	}
+	// Every message is bigger than half of the size, each one rotates
+	for i := 1; i <= 5; i++ {
+		Error(fmt.Sprintf("message %d %s", i, strings.Repeat("x", 50)))
+	}
+
+	for file, want := range map[string]string{
+		path:        "message 5",
+		path + ".1": "message 4",
+		path + ".2": "message 3",
+	} {
+		if got := readLog(t, file); !strings.Contains(got, want) || strings.Count(got, "message") != 1 {
+			t.Errorf("%s has %q, want only %q", filepath.Base(file), got, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("a third rotated file was kept: %v", err)
+	}
+}
+
+// TestFileEnv sets the destination with FOUNDRY_LOG_FILE like it's done
+// when the program starts
+func TestFileEnv(t *testing.T) {
+	captureOutput(t)
+	t.Cleanup(Close)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "env", "foundry.log")
+	t.Setenv(FileEnv, path)
+	outputFromEnv()
+	Error("from the env")
+	if FilePath() != path || !strings.Contains(readLog(t, path), "from the env") {
+		t.Fatalf("the messages go to %q, the file has %q", FilePath(), readLog(t, path))
+	}
+
+	t.Setenv(FileEnv, "STDERR")
+	outputFromEnv()
+	outMutex.Lock()
+	toStderr := out == os.Stderr
+	outMutex.Unlock()
+	if !toStderr || FilePath() != "" {
+		t.Fatalf("FOUNDRY_LOG_FILE=STDERR doesn't log to stderr, the file is %q", FilePath())
+	}
+
+	t.Setenv(FileEnv, filepath.Join(path, "foundry.log"))
+	warning := captureStdout(t, outputFromEnv)
+	if !strings.Contains(warning, "Ignoring "+FileEnv) {
+		t.Fatalf("an invalid path printed %q", warning)
+	}
+}
+
+// TestClose closes the log file and logs after. The messages go to the
+// default file, a destination set by SetOutput is kept.
+func TestClose(t *testing.T) {
+	buf := captureOutput(t)
+	t.Cleanup(Close)
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+	def, err := DefaultFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(dir, "foundry.log")
+	if err := SetFile(path); err != nil {
+		t.Fatalf("SetFile: %s", err)
+	}
+	Error("before close")
+	Close()
+	if FilePath() != def {
+		t.Fatalf("FilePath() = %q after Close, want the default %q", FilePath(), def)
+	}
+	Error("after close")
+	if got := readLog(t, path); !strings.Contains(got, "before close") || strings.Contains(got, "after close") {
+		t.Fatalf("the closed file has %q", got)
+	}
+	if got := readLog(t, def); !strings.Contains(got, "after close") {
+		t.Fatalf("the default file has %q", got)
+	}
+
+	SetOutput(buf)
+	Close()
+	Error("to the writer")
+	if !strings.Contains(buf.String(), "to the writer") {
+		t.Fatalf("the writer set by SetOutput got %q after Close", buf.String())
+	}
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// PrefixFlags select what's written in front of every human-readable log line
+type PrefixFlags int32
+
+const (
+	// PrefixWallTime writes the wall clock time with milliseconds ("15:04:05.000")
+	PrefixWallTime PrefixFlags = 1 << iota
+	// PrefixElapsed writes the monotonic time since the program started ("+1.234567s")
+	PrefixElapsed
+	// PrefixCaller writes the file name and line of the logging call ("[prompt.go:120]")
+	PrefixCaller
+	// PrefixGoroutine writes the id of the logging goroutine ("[g12]").
+	// Looking the id up is slow, so it's off by default.
+	PrefixGoroutine
+
+	DefaultPrefixFlags = PrefixWallTime | PrefixElapsed | PrefixCaller
+)
+
+var (
+	prefixFlags = int32(DefaultPrefixFlags)
+	startTime   = time.Now()
+)
+
+// SetPrefixFlags sets what's written in front of every human-readable log line.
+// It's safe to call at any time.
+func SetPrefixFlags(f PrefixFlags) {
+	atomic.StoreInt32(&prefixFlags, int32(f))
+}
+
+// GetPrefixFlags returns what's written in front of every human-readable log line
+func GetPrefixFlags() PrefixFlags {
+	return PrefixFlags(atomic.LoadInt32(&prefixFlags))
+}
+
+// entryPrefix returns the line prefix with a level label in the format
+// "15:04:05.000 +1.234567s LABEL [file.go:10] [g1]" or the shorter
+// prefix of the console format.
+// skip is the number of stack frames above the caller of entryPrefix
+// to get to the logging call. Callers check the level first,
+// so the caller isn't looked up for discarded messages.
+func entryPrefix(t levelTag, skip int) string {
+	if currentFormat() == FormatConsole {
+		return consolePrefix(t)
+	}
+
+	flags := GetPrefixFlags()
+	now := time.Now()
+
+	var b strings.Builder
+	if flags&PrefixWallTime != 0 {
+		b.WriteString(now.Format("15:04:05.000"))
+		b.WriteByte(' ')
+	}
+	if flags&PrefixElapsed != 0 {
+		// The difference uses the monotonic clock so it isn't affected by clock changes
+		fmt.Fprintf(&b, "+%.6fs ", now.Sub(startTime).Seconds())
+	}
+	b.WriteString(t.label)
+	if flags&PrefixCaller != 0 {
+		if _, file, line, ok := runtime.Caller(skip + 1); ok {
+			fmt.Fprintf(&b, " [%s:%d]", filepath.Base(file), line)
+		}
+	}
+	if flags&PrefixGoroutine != 0 {
+		fmt.Fprintf(&b, " [g%d]", goroutineID())
+	}
+	return b.String()
+}
+
+// goroutineID parses the id from the header of the goroutine's stack trace.
+// It returns 0 if the header can't be parsed.
+func goroutineID() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// The header is "goroutine 12 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
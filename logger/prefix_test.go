@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// TestPrefixGolden logs at every level with every prefix flag alone, with
+// none and with all. The lines are compared with testdata/prefix.golden.
+func TestPrefixGolden(t *testing.T) {
+	logged := captureOutput(t)
+	SetLevel(LevelDebug)
+	SetFormat(FormatHuman)
+	defer SetPrefixFlags(GetPrefixFlags())
+
+	flags := []struct {
+		name  string
+		flags PrefixFlags
+	}{
+		{"default", DefaultPrefixFlags},
+		{"none", 0},
+		{"wall time", PrefixWallTime},
+		{"elapsed", PrefixElapsed},
+		{"caller", PrefixCaller},
+		{"goroutine", PrefixGoroutine},
+		{"all", PrefixWallTime | PrefixElapsed | PrefixCaller | PrefixGoroutine},
+	}
+	for _, f := range flags {
+		SetPrefixFlags(f.flags)
+		Debug("flags: " + f.name)
+		Info("flags: " + f.name)
+		Warn("flags: " + f.name)
+		Error("flags: " + f.name)
+		With("fn", "api").Info("flags: " + f.name)
+		With("fn", "api").Warn("flags: " + f.name)
+	}
+	checkGolden(t, "prefix.golden", logged.String())
+}
+
+// TestPrefixCaller checks the line of the logging call, which the golden
+// file doesn't keep. A helper skipping a frame reports its caller's line.
+func TestPrefixCaller(t *testing.T) {
+	logged := captureOutput(t)
+	SetLevel(LevelDebug)
+	SetFormat(FormatHuman)
+	defer SetPrefixFlags(GetPrefixFlags())
+	SetPrefixFlags(PrefixCaller)
+
+	logHelper := func() { With().Skip(1).Info("from the helper") }
+	Info("direct")
+	_, _, line, _ := runtime.Caller(0)
+	logHelper()
+	_, _, helperLine, _ := runtime.Caller(0)
+
+	out := logged.String()
+	for _, want := range []string{
+		fmt.Sprintf("[prefix_test.go:%d] direct\n", line-1),
+		fmt.Sprintf("[prefix_test.go:%d] from the helper\n", helperLine-1),
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("logged %q, want %q", out, want)
+		}
+	}
+}
+
+// TestGoroutineID checks that the ids of different goroutines differ
+func TestGoroutineID(t *testing.T) {
+	const n = 10
+	ids := make(map[uint64]bool)
+	var mut sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id := goroutineID()
+			mut.Lock()
+			ids[id] = true
+			mut.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(ids) != n || ids[0] {
+		t.Fatalf("got the ids %v for %d goroutines", ids, n)
+	}
+	if goroutineID() == 0 {
+		t.Fatal("the id of the test's goroutine wasn't parsed")
+	}
+}
@@ -2,11 +2,16 @@
 
 package logger
 
+// Release builds skip debug messages unless FOUNDRY_LOG_LEVEL says otherwise
+const defaultLevel = LevelInfo
+
 func InitDebug(path string) error    { return nil }
-func Close()                         {}
 func Fdebugln(v ...interface{})      {}
 func FdebuglnError(v ...interface{}) {}
 func FdebuglnFatal(v ...interface{}) {}
 func Debugln(v ...interface{})       {}
 func DebuglnError(v ...interface{})  {}
 func DebuglnFatal(v ...interface{})  {}
+
+func debugEnabled() bool                                              { return false }
+func writeDebugEntry(t PrefixType, skip int, line string, human bool) {}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// RingSize is how many of the most recent log messages are kept in memory
+const RingSize = 500
+
+// MaxRecordLen is the length in bytes a message in memory is cut to
+const MaxRecordLen = 1024
+
+// Record is a log message kept in memory
+type Record struct {
+	Time    time.Time
+	Level   Level
+	Message string
+}
+
+var ring struct {
+	mut     sync.Mutex
+	records [RingSize]Record
+	next    int
+	count   int
+}
+
+var (
+	// The minimum level of messages kept in the ring
+	ringLevel = int32(LevelDebug)
+	// Set while CaptureForTest collects the messages
+	capturing int32
+)
+
+// SetRingLevel sets the minimum level of messages kept in memory. The
+// ring keeps all levels unless it's set. Messages below the level aren't
+// formatted for it. It's safe to call at any time from any goroutine.
+func SetRingLevel(l Level) {
+	atomic.StoreInt32(&ringLevel, int32(l))
+}
+
+// record keeps a message in the ring no matter what the level of the log
+// destination is. The message is formatted now, the arguments may change
+// after the logging call.
+func record(l Level, args []interface{}, ln bool, entry *Entry) {
+	kept := int32(l) >= atomic.LoadInt32(&ringLevel)
+	if !kept && atomic.LoadInt32(&capturing) == 0 {
+		return
+	}
+	r := Record{Time: time.Now(), Level: l, Message: formatRecord(args, ln, entry)}
+
+	ring.mut.Lock()
+	if capture != nil {
+		capture.add(r)
+	}
+	if kept {
+		ring.records[ring.next] = r
+		ring.next = (ring.next + 1) % RingSize
+		if ring.count < RingSize {
+			ring.count++
+		}
+	}
+	ring.mut.Unlock()
+}
+
+// Recent returns up to n most recent log messages of all levels, oldest first
+func Recent(n int) []Record {
+	if n > RingSize {
+		n = RingSize
+	}
+
+	ring.mut.Lock()
+	defer ring.mut.Unlock()
+	if n > ring.count {
+		n = ring.count
+	}
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = ring.records[(ring.next-n+i+RingSize)%RingSize]
+	}
+	return records
+}
+
+// formatRecord formats the message like fmt.Sprintln (without the newline)
+// if ln is set or like fmt.Sprint, cut to MaxRecordLen
+func formatRecord(args []interface{}, ln bool, entry *Entry) string {
+	var msg string
+	if ln {
+		msg = strings.TrimSuffix(fmt.Sprintln(args...), "\n")
+	} else {
+		msg = fmt.Sprint(args...)
+	}
+	if entry != nil {
+		msg = entry.encodeHuman(msg)
+	}
+	if len(msg) <= MaxRecordLen {
+		return msg
+	}
+	cut := MaxRecordLen - len("…")
+	for cut > 0 && !utf8.RuneStart(msg[cut]) {
+		cut--
+	}
+	return msg[:cut] + "…"
+}
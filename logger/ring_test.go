@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+// TestRingWraparound logs more messages than the ring keeps. Only the
+// newest are returned, oldest first.
+func TestRingWraparound(t *testing.T) {
+	captureOutput(t)
+	SetLevel(LevelError)
+
+	const extra = 123
+	for i := 0; i < RingSize+extra; i++ {
+		Debug(fmt.Sprintf("message %d", i))
+	}
+
+	records := Recent(RingSize + 10)
+	if len(records) != RingSize {
+		t.Fatalf("got %d records, want %d", len(records), RingSize)
+	}
+	for i, r := range records {
+		if want := fmt.Sprintf("message %d", extra+i); r.Message != want || r.Level != LevelDebug {
+			t.Fatalf("record %d is %s %q, want debug %q", i, r.Level, r.Message, want)
+		}
+	}
+	last := Recent(3)
+	if len(last) != 3 || last[2].Message != fmt.Sprintf("message %d", RingSize+extra-1) {
+		t.Fatalf("Recent(3) = %+v", last)
+	}
+	if len(Recent(0)) != 0 {
+		t.Fatal("Recent(0) returned records")
+	}
+}
+
+// TestRingKeepsLoggedArgs changes the arguments after logging them. The
+// dump has them as they were when they were logged.
+func TestRingKeepsLoggedArgs(t *testing.T) {
+	captureOutput(t)
+	SetLevel(LevelError)
+
+	regions := []string{"eu", "us"}
+	target := &struct{ Name string }{"api"}
+	With("regions", regions).Info("deploying", target)
+	regions[0] = "ap"
+	target.Name = "web"
+
+	last := Recent(1)
+	if want := `deploying&{api} regions="[eu us]"`; len(last) != 1 || last[0].Message != want {
+		t.Fatalf("Recent(1) = %+v, want %q", last, want)
+	}
+}
+
+// TestRingCutsLongMessages logs a message longer than MaxRecordLen with a
+// multi-byte character at the cut
+func TestRingCutsLongMessages(t *testing.T) {
+	captureOutput(t)
+	SetLevel(LevelError)
+
+	Debug(strings.Repeat("a", MaxRecordLen-3) + strings.Repeat("本", 10))
+
+	msg := Recent(1)[0].Message
+	if len(msg) > MaxRecordLen || !utf8.ValidString(msg) || !strings.HasSuffix(msg, "a…") {
+		t.Fatalf("the message was cut to %d bytes ending with %q", len(msg), msg[len(msg)-10:])
+	}
+}
+
+// TestRingLevel keeps only the messages at SetRingLevel or higher
+func TestRingLevel(t *testing.T) {
+	captureOutput(t)
+	SetLevel(LevelError)
+	SetRingLevel(LevelWarn)
+	t.Cleanup(func() { SetRingLevel(LevelDebug) })
+
+	Warn("kept warning")
+	Debug("skipped debug")
+	Info("skipped info")
+
+	if last := Recent(1); len(last) != 1 || last[0].Message != "kept warning" {
+		t.Fatalf("Recent(1) = %+v", last)
+	}
+}
+
+// TestRingDumpWhileLogging dumps the ring while several goroutines log.
+// Run it with -race. Every dump has the messages of each goroutine in
+// the order they were logged.
+func TestRingDumpWhileLogging(t *testing.T) {
+	captureOutput(t)
+	SetLevel(LevelError)
+
+	const writers, messages = 4, 5000
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < messages; i++ {
+				With("n", i).Info(fmt.Sprintf("writer %d", w))
+			}
+		}(w)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	parsed := 0
+	for finished := false; !finished; {
+		select {
+		case <-done:
+			// One more dump after the writers
+			finished = true
+		default:
+		}
+
+		last := make(map[string]int)
+		for _, r := range Recent(RingSize) {
+			var w string
+			var n int
+			if _, err := fmt.Sscanf(r.Message, "writer %s n=%d", &w, &n); err != nil {
+				// Messages logged before the test
+				continue
+			}
+			if prev, ok := last[w]; ok && n <= prev {
+				t.Fatalf("writer %s's message %d came after %d", w, n, prev)
+			}
+			last[w] = n
+			parsed++
+		}
+	}
+	if parsed == 0 {
+		t.Fatal("no dump had the writers' messages")
+	}
+}
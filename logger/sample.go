@@ -0,0 +1,69 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// samplers holds a *sampler for every key passed to Sampled
+var samplers sync.Map
+
+// Sampled returns an entry that's logged at most once per every for the key.
+// The entries in between are dropped and counted, the next logged one ends with
+// "(suppressed N similar messages)". It's meant for hot paths, so a dropped
+// entry ignores its fields and messages without formatting them.
+// The interval given with the first call for a key is kept.
+func Sampled(key string, every time.Duration) *Entry {
+	s, ok := samplers.Load(key)
+	if !ok {
+		s, _ = samplers.LoadOrStore(key, &sampler{every: int64(every)})
+	}
+
+	suppressed, ok := s.(*sampler).allow()
+	if !ok {
+		return mutedEntry
+	}
+	return &Entry{suppressed: suppressed}
+}
+
+// mutedEntry drops everything logged with it
+var mutedEntry = &Entry{muted: true}
+
+type sampler struct {
+	every      int64 // Nanoseconds
+	next       int64 // Nanoseconds since startTime when the next entry is allowed
+	suppressed int64
+}
+
+// allow reports whether an entry can be logged now and how many
+// were suppressed since the last allowed one
+func (s *sampler) allow() (suppressed int64, ok bool) {
+	// The monotonic clock so the clock changes don't stop the logging
+	now := int64(time.Since(startTime))
+	for {
+		next := atomic.LoadInt64(&s.next)
+		if now < next {
+			atomic.AddInt64(&s.suppressed, 1)
+			return 0, false
+		}
+		if atomic.CompareAndSwapInt64(&s.next, next, now+s.every) {
+			// Entries suppressed concurrently with the swap are counted
+			// by the next allowed one so none is lost
+			return atomic.SwapInt64(&s.suppressed, 0), true
+		}
+	}
+}
+
+// withSuppressed appends the number of suppressed entries to msg
+func (e *Entry) withSuppressed(msg string) string {
+	switch e.suppressed {
+	case 0:
+		return msg
+	case 1:
+		return msg + " (suppressed 1 similar message)"
+	default:
+		return fmt.Sprintf("%s (suppressed %d similar messages)", msg, e.suppressed)
+	}
+}
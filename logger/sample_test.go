@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSamplerCounts calls allow from many goroutines at once. Only one
+// call is allowed and the next allowed one gets all the others counted.
+func TestSamplerCounts(t *testing.T) {
+	const goroutines, calls = 8, 1000
+	s := &sampler{every: int64(time.Hour)}
+
+	var allowed int64
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < calls; i++ {
+				if _, ok := s.allow(); ok {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed != 1 {
+		t.Fatalf("%d calls were allowed in the interval, want 1", allowed)
+	}
+
+	atomic.StoreInt64(&s.next, 0)
+	suppressed, ok := s.allow()
+	if !ok || suppressed != goroutines*calls-1 {
+		t.Fatalf("allow after the interval = %d, %v, want %d, true", suppressed, ok, goroutines*calls-1)
+	}
+}
+
+func TestSampledSuffix(t *testing.T) {
+	logged := captureOutput(t)
+	SetLevel(LevelInfo)
+	SetFormat(FormatHuman)
+
+	key := t.Name()
+	Sampled(key, time.Hour).Info("chunk printed")
+	Sampled(key, time.Hour).Info("chunk printed")
+	Sampled(key, time.Hour).Info("chunk printed")
+	s, _ := samplers.Load(key)
+	atomic.StoreInt64(&s.(*sampler).next, 0)
+	Sampled(key, time.Hour).Info("chunk printed")
+
+	lines := strings.Split(strings.TrimSpace(logged.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("logged %d lines, want 2: %q", len(lines), lines)
+	}
+	if strings.Contains(lines[0], "suppressed") || !strings.HasSuffix(lines[1], "chunk printed (suppressed 2 similar messages)") {
+		t.Fatalf("logged %q", lines)
+	}
+}
+
+// BenchmarkSampled compares logging a chunk like print did on every chunk
+// at the debug level, the same through Sampled, and at a disabled level
+func BenchmarkSampled(b *testing.B) {
+	captureOutput(b)
+	SetOutput(ioutil.Discard)
+	text := strings.Repeat("function deployed, 42 ms\n", 160)
+
+	for _, bench := range []struct {
+		name  string
+		level Level
+		entry func() *Entry
+	}{
+		{"debug", LevelDebug, func() *Entry { return &Entry{} }},
+		{"debug-sampled", LevelDebug, func() *Entry { return Sampled("bench", time.Second) }},
+		{"disabled", LevelInfo, func() *Entry { return &Entry{} }},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			SetLevel(bench.level)
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				bench.entry().With("bytes", len(text), "text", text).Debug("Printing chunk")
+			}
+		})
+	}
+}
@@ -3,6 +3,15 @@ package logger
 import (
 	"fmt"
 	"os"
+	"sync"
+)
+
+type PrefixType int
+
+const (
+	DebugPrefix PrefixType = iota
+	ErrorPrefix
+	FatalPrefix
 )
 
 const (
@@ -17,8 +26,29 @@ var (
 	successPrefix = fmt.Sprintf("%s%sSUCCESS%s", bold, green, endSeq)
 	warningPrefix = fmt.Sprintf("%s%sWARNING%s", bold, yellow, endSeq)
 	errorPrefix   = fmt.Sprintf("%s%sERROR%s", bold, red, endSeq)
+	fatalPrefix   = fmt.Sprintf("%s%sFATAL%s", red, bold, endSeq)
+)
+
+var (
+	fatalMutex   sync.Mutex
+	fatalHandler func(msg string)
 )
 
+// SetFatalHandler sets a function that FatalLogln calls with the message
+// instead of printing it. The handler is expected to clean up and exit,
+// if it returns the program exits anyway. Nil restores the default.
+func SetFatalHandler(fn func(msg string)) {
+	fatalMutex.Lock()
+	defer fatalMutex.Unlock()
+	fatalHandler = fn
+}
+
+func getFatalHandler() func(msg string) {
+	fatalMutex.Lock()
+	defer fatalMutex.Unlock()
+	return fatalHandler
+}
+
 func ErrorLogln(args ...interface{}) {
 	t := fmt.Sprintf("%s %s", errorPrefix, fmt.Sprint(args...))
 	fmt.Println(t)
@@ -26,7 +56,11 @@ func ErrorLogln(args ...interface{}) {
 
 func FatalLogln(args ...interface{}) {
 	t := fmt.Sprintf("%s %s", errorPrefix, fmt.Sprint(args...))
-	fmt.Println(t)
+	if h := getFatalHandler(); h != nil {
+		h(t)
+	} else {
+		fmt.Println(t)
+	}
 	os.Exit(1)
 }
 
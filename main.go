@@ -11,5 +11,14 @@ func main() {
 		logger.FatalLogln("Couldn't init config", err)
 	}
 
+	if f := config.GetString("logFormat"); f != "" {
+		format, err := logger.ParseFormat(f)
+		if err != nil {
+			logger.WarningLogln("Invalid 'logFormat' in the config file: ", err)
+		} else {
+			logger.SetFormat(format)
+		}
+	}
+
 	cmd.Execute()
 }
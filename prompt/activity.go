@@ -0,0 +1,75 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// How long the activity mark stays after the last output was printed
+const activityIdleDelay = 500 * time.Millisecond
+
+// WithActivityIndicator shows mark dimmed at the end of the last output line
+// while output is being printed, e.g. "…" or "▍". It's gone once nothing was
+// printed for a while, so a stream that stopped is easy to tell from one
+// that's still coming. The output continues over the mark.
+func WithActivityIndicator(mark string) Option {
+	return func(p *Prompt) error {
+		if mark == "" {
+			return errors.New("the activity mark can't be empty")
+		}
+		if w := runewidth.StringWidth(mark); w > 3 {
+			return fmt.Errorf("the activity mark %q can take at most 3 columns, it takes %d", mark, w)
+		}
+		p.activityMark = mark
+		return nil
+	}
+}
+
+// renderActivity draws the mark right after the output. savedPos stays
+// where the output continues. renderMutex must be held.
+func (p *Prompt) renderActivity() {
+	if p.activityMark == "" {
+		return
+	}
+	p.activityAt = time.Now()
+	if p.savedPos.Col+runewidth.StringWidth(p.activityMark) > p.totalColumns {
+		// No room left on the row, the output wraps there
+		p.activityPos = CursorPos{}
+		return
+	}
+	p.writer.CursorGoTo(p.savedPos.Row, p.savedPos.Col)
+	p.writer.WriteRawStr("\x1b[0;2m" + p.activityMark + "\x1b[0m")
+	p.activityPos = p.savedPos
+}
+
+// clearActivity erases the mark. Once the output moved elsewhere, e.g. after
+// the clear keybind or the pager, the mark was drawn over and only forgotten.
+// The cursor stays on the mark's row. renderMutex must be held.
+func (p *Prompt) clearActivity() {
+	if p.activityPos == (CursorPos{}) {
+		return
+	}
+	if p.activityPos == p.savedPos {
+		p.writer.CursorGoTo(p.activityPos.Row, p.activityPos.Col)
+		p.writer.EraseEndOfLine()
+	}
+	p.activityPos = CursorPos{}
+}
+
+// idleActivity erases the mark once the output has been idle long enough.
+// It's called by the renderer when there's nothing to print.
+func (p *Prompt) idleActivity() {
+	p.lockRender()
+	defer p.unlockRender()
+	if p.activityPos == (CursorPos{}) || p.pager != nil || time.Since(p.activityAt) < activityIdleDelay {
+		return
+	}
+	p.clearActivity()
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
@@ -1,50 +1,512 @@
 package prompt
 
 import (
-	"bytes"
-	"foundry/cli/logger"
+	"context"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
-// Buffer is a thread safe wrapper for buffer
+const (
+	// Default maximum number of bytes in a single chunk delivered by Buffer.Read.
+	// See BenchmarkChunkSize, smaller chunks don't print a line any sooner.
+	DefaultChunkSize = 16 * 1024
+	MinChunkSize     = 256
+	MaxChunkSize     = 1024 * 1024
+)
+
+// Stream tags where a piece of output came from
+type Stream int
+
+const (
+	StreamDefault Stream = iota
+	StreamStderr
+)
+
+func (s Stream) String() string {
+	switch s {
+	case StreamDefault:
+		return "default"
+	case StreamStderr:
+		return "stderr"
+	default:
+		return fmt.Sprintf("stream(%d)", int(s))
+	}
+}
+
+// Chunk is a piece of output that the Buffer hands over to the renderer
+type Chunk struct {
+	Data     []byte
+	Stream   Stream
+	Priority bool
+	Time     time.Time // When the first byte of the chunk was written
+	// OutOfOrder is true when the chunk was delivered ahead
+	// of normal data that had been written before it
+	OutOfOrder bool
+}
+
+// OverflowPolicy decides what happens to writes once the Buffer holds
+// more than its limit of bytes that haven't been read yet
+type OverflowPolicy int
+
+const (
+	// OverflowGrow keeps buffering without any limit
+	OverflowGrow OverflowPolicy = iota
+	// OverflowBlock makes writers wait until the reader frees up space
+	OverflowBlock
+	// OverflowDrop discards writes that don't fit. Priority writes are never dropped.
+	OverflowDrop
+)
+
+// Buffer is a thread safe queue of output chunks
 type Buffer struct {
-	buf bytes.Buffer
-	mut sync.Mutex
+	queue      []Chunk
+	queueBytes int
+	// High priority chunks. They are delivered before anything
+	// in queue but keep their relative order among themselves.
+	prio      []Chunk
+	prioBytes int
+	// The first chunk in queue is the rest of a chunk that was
+	// split because it was too big. It's delivered before prio.
+	split bool
+	// Read took a chunk out of the queues and sends it without mut.
+	// It's older than everything still queued.
+	sending bool
+	mut     sync.Mutex
+
+	chunkSize int
+
+	limit  int
+	policy OverflowPolicy
+	// Closed and replaced every time the reader frees up space
+	space chan struct{}
+	// Gets a value when something is queued, Read waits for it when idle
+	ready chan struct{}
+
+	pool sync.Pool // Reused chunk data
+
+	// Writes that come less than coalesce apart are delivered as one chunk
+	coalesce  time.Duration
+	lastWrite time.Time
+
+	stats     *bufferStats
+	spool     *spool
+	outputLog *spool // Set by EnableOutputLog
+	log       Logger // Guarded by mut
 }
 
 // NewBuffer returns a pointer to new Buffer
 func NewBuffer() *Buffer {
-	return &Buffer{buf: bytes.Buffer{}}
+	return &Buffer{
+		chunkSize: DefaultChunkSize,
+		space:     make(chan struct{}),
+		ready:     make(chan struct{}, 1),
+		stats:     &bufferStats{},
+		log:       newFoundryLogger(),
+	}
 }
 
+// setLogger sets where the buffer's diagnostics go
+func (b *Buffer) setLogger(l Logger) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.log = l
+}
+
+// SetOverflowPolicy limits the number of unread bytes the buffer
+// holds and sets what happens to writes that exceed the limit
+func (b *Buffer) SetOverflowPolicy(limit int, policy OverflowPolicy) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.limit = limit
+	b.policy = policy
+}
+
+// SetCoalesce makes Read hold back a chunk that isn't full yet until
+// there were no writes for d, so rapid small writes are rendered together.
+// A chunk is never held back for more than maxCoalesceDelay times d.
+func (b *Buffer) SetCoalesce(d time.Duration) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.coalesce = d
+}
+
+// SetChunkSize sets the maximum number of bytes in a single chunk delivered by Read
+func (b *Buffer) SetChunkSize(n int) error {
+	if n < MinChunkSize || n > MaxChunkSize {
+		return fmt.Errorf("chunk size must be between %d and %d bytes, got %d", MinChunkSize, MaxChunkSize, n)
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	b.chunkSize = n
+	return nil
+}
+
+// Write queues p to the default stream
 func (b *Buffer) Write(p []byte) (n int, err error) {
+	return b.WriteCtx(context.Background(), p)
+}
+
+// WriteCtx is like Write but when the buffer blocks on overflow
+// the caller can stop waiting by cancelling ctx
+func (b *Buffer) WriteCtx(ctx context.Context, p []byte) (n int, err error) {
+	return b.WriteStream(ctx, StreamDefault, p)
+}
+
+type streamWriter struct {
+	b      *Buffer
+	stream Stream
+}
+
+func (w streamWriter) Write(p []byte) (n int, err error) {
+	return w.b.WriteStream(context.Background(), w.stream, p)
+}
+
+// StreamWriter returns an io.Writer that tags everything written to it with stream
+func (b *Buffer) StreamWriter(stream Stream) io.Writer {
+	return streamWriter{b: b, stream: stream}
+}
+
+// WriteStream queues p tagged with the stream it came from
+func (b *Buffer) WriteStream(ctx context.Context, stream Stream, p []byte) (n int, err error) {
+	for {
+		b.mut.Lock()
+		n += b.write(stream, p[n:])
+		if n == len(p) {
+			b.mut.Unlock()
+			return n, nil
+		}
+		space := b.space
+		b.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-space:
+		}
+	}
+}
+
+// WriteString is like Write but saves the conversion of s to []byte
+func (b *Buffer) WriteString(s string) (n int, err error) {
+	return b.WriteStringCtx(context.Background(), s)
+}
+
+// WriteStringCtx is like WriteCtx but saves the conversion of s to []byte
+func (b *Buffer) WriteStringCtx(ctx context.Context, s string) (n int, err error) {
+	for {
+		b.mut.Lock()
+		n += b.writeString(StreamDefault, s[n:])
+		if n == len(s) {
+			b.mut.Unlock()
+			return n, nil
+		}
+		space := b.space
+		b.mut.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case <-space:
+		}
+	}
+}
+
+// WriteLines queues lines, each followed by a new line, as a single write.
+// The lines are rendered together without output of other writers between them.
+func (b *Buffer) WriteLines(lines []string) (n int, err error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+	return b.WriteString(strings.Join(lines, "\n") + "\n")
+}
+
+// admit decides how many of n bytes can be queued by the overflow policy.
+// consumed is the number of bytes the writer can consider written
+// (dropped bytes count as written). A write is either queued whole or
+// not at all, so it isn't torn apart by other writers. Only writes bigger
+// than the limit itself are queued in parts. b.mut must be held.
+func (b *Buffer) admit(n int) (accepted int, consumed int) {
+	if b.limit == 0 || b.policy == OverflowGrow {
+		return n, n
+	}
+
+	free := b.limit - b.queueBytes - b.prioBytes
+	if free < 0 {
+		free = 0
+	}
+	if n <= free {
+		return n, n
+	}
+
+	if b.policy == OverflowDrop {
+		b.stats.dropped(n)
+		return 0, n
+	}
+	if n <= b.limit {
+		// Wait until the whole write fits
+		return 0, 0
+	}
+	return free, free
+}
+
+// tail returns the chunk that n more bytes of stream should be appended to.
+// Small consecutive writes to the same stream are merged so the renderer
+// doesn't have to handle each one separately. b.mut must be held.
+func (b *Buffer) tail(stream Stream, n int) *Chunk {
+	if last := len(b.queue) - 1; last >= 0 && b.queue[last].Stream == stream && len(b.queue[last].Data)+n <= b.chunkSize {
+		return &b.queue[last]
+	}
+
+	b.stats.publish()
+	b.queue = append(b.queue, Chunk{Data: b.getData(n), Stream: stream, Time: time.Now()})
+	return &b.queue[len(b.queue)-1]
+}
+
+// queued updates the counters after n bytes were queued. b.mut must be held.
+func (b *Buffer) queued(n int) {
+	b.queueBytes += n
+	if b.coalesce > 0 {
+		b.lastWrite = time.Now()
+	}
+	b.stats.written(n, b.queueBytes+b.prioBytes)
+	b.signalReady()
+}
+
+// write saves as much of p as the overflow policy allows and returns
+// how many bytes of p were consumed. b.mut must be held.
+func (b *Buffer) write(stream Stream, p []byte) int {
+	accepted, consumed := b.admit(len(p))
+	if accepted == 0 {
+		return consumed
+	}
+	p = p[:accepted]
+
+	b.copyToSpools(p)
+
+	c := b.tail(stream, len(p))
+	c.Data = append(c.Data, p...)
+	b.queued(len(p))
+	return consumed
+}
+
+// writeString is the same as write for strings. b.mut must be held.
+func (b *Buffer) writeString(stream Stream, s string) int {
+	accepted, consumed := b.admit(len(s))
+	if accepted == 0 {
+		return consumed
+	}
+	s = s[:accepted]
+
+	b.copyStringToSpools(s)
+
+	c := b.tail(stream, len(s))
+	c.Data = append(c.Data, s...)
+	b.queued(len(s))
+	return consumed
+}
+
+// getData returns an empty slice with room for at least n bytes.
+// Chunk sized slices are reused once the renderer is done with them.
+func (b *Buffer) getData(n int) []byte {
+	if n <= b.chunkSize {
+		if d, ok := b.pool.Get().(*[]byte); ok && cap(*d) == b.chunkSize {
+			return (*d)[:0]
+		}
+		return make([]byte, 0, b.chunkSize)
+	}
+	return make([]byte, 0, n)
+}
+
+// empty returns true if nothing written waits to be read
+func (b *Buffer) empty() bool {
 	b.mut.Lock()
 	defer b.mut.Unlock()
-	return b.buf.Write(p)
+	return len(b.queue) == 0 && len(b.prio) == 0 && !b.sending
 }
 
-func (b *Buffer) Read(bufCh chan<- []byte, stopCh <-chan struct{}) {
+// Release gives the data of a delivered chunk back to the buffer for reuse.
+// The data must not be used after that.
+func (b *Buffer) Release(c Chunk) {
+	b.mut.Lock()
+	chunkSize := b.chunkSize
+	b.mut.Unlock()
+	if cap(c.Data) != chunkSize || c.Priority {
+		return
+	}
+	d := c.Data[:0]
+	b.pool.Put(&d)
+}
+
+// WritePriority queues p ahead of all normal data that hasn't been read yet.
+// It never blocks or drops data because of the overflow policy.
+func (b *Buffer) WritePriority(p []byte) (n int, err error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	// The caller is free to reuse p once we return
+	data := make([]byte, len(p))
+	copy(data, p)
+
+	b.copyToSpools(data)
+	b.prio = append(b.prio, Chunk{Data: data, Stream: StreamStderr, Priority: true, Time: time.Now()})
+	b.prioBytes += len(data)
+
+	b.stats.written(len(data), b.queueBytes+b.prioBytes)
+	b.signalReady()
+	return len(p), nil
+}
+
+// signalReady wakes up Read waiting for something to deliver. It never
+// blocks, a value already waiting in ready is enough.
+func (b *Buffer) signalReady() {
+	select {
+	case b.ready <- struct{}{}:
+	default:
+	}
+}
+
+// signalSpace wakes up all writers waiting for free space. b.mut must be held.
+func (b *Buffer) signalSpace() {
+	if b.limit == 0 {
+		return
+	}
+	close(b.space)
+	b.space = make(chan struct{})
+}
+
+// How many times the coalesce duration a chunk can be held back at most
+const maxCoalesceDelay = 4
+
+// holding reports whether Read should wait for more writes before
+// delivering the next chunk. b.mut must be held.
+func (b *Buffer) holding(now time.Time) bool {
+	if b.coalesce == 0 || len(b.prio) > 0 || len(b.queue) != 1 || b.split {
+		return false
+	}
+
+	c := b.queue[0]
+	return len(c.Data) < b.chunkSize &&
+		now.Sub(b.lastWrite) < b.coalesce &&
+		now.Sub(c.Time) < maxCoalesceDelay*b.coalesce
+}
+
+// holdTime returns how long the chunk held back by holding can still
+// wait for more writes. b.mut must be held.
+func (b *Buffer) holdTime(now time.Time) time.Duration {
+	d := b.coalesce - now.Sub(b.lastWrite)
+	if max := maxCoalesceDelay*b.coalesce - now.Sub(b.queue[0].Time); max < d {
+		d = max
+	}
+	return d
+}
+
+// next removes the chunk that should be rendered next from the queues.
+// b.mut must be held.
+func (b *Buffer) next() (c Chunk, ok bool) {
+	b.stats.publish()
+	if len(b.prio) > 0 && !b.split {
+		c = b.prio[0]
+		c.OutOfOrder = b.queueBytes > 0
+		b.prio[0] = Chunk{}
+		b.prio = b.prio[1:]
+		b.prioBytes -= len(c.Data)
+		return c, true
+	}
+
+	if len(b.queue) == 0 {
+		return c, false
+	}
+
+	c = b.queue[0]
+	if len(c.Data) > b.chunkSize {
+		// Don't split a multi-byte character between two chunks
+		n := b.chunkSize
+		for n > 0 && !utf8.RuneStart(c.Data[n]) {
+			n--
+		}
+		if n == 0 {
+			// Not a valid UTF-8 anyway
+			n = b.chunkSize
+		}
+		b.queue[0].Data = c.Data[n:]
+		c.Data = c.Data[:n:n]
+		b.split = true
+	} else {
+		b.queue[0] = Chunk{}
+		b.queue = b.queue[1:]
+		b.split = false
+	}
+	b.queueBytes -= len(c.Data)
+	return c, true
+}
+
+// Read sends the chunks to bufCh until stopCh is closed. It sends without
+// holding mut, writers and Release don't wait for the consumer. When there's
+// nothing to send it waits for the next write.
+func (b *Buffer) Read(bufCh chan<- Chunk, stopCh <-chan struct{}) {
 	for {
 		select {
 		case <-stopCh:
 			return
 		default:
-			b.mut.Lock()
+		}
 
-			buf := make([]byte, 1024)
-			n, err := b.buf.Read(buf)
+		b.mut.Lock()
+		var (
+			c    Chunk
+			ok   bool
+			hold time.Duration
+		)
+		if now := time.Now(); b.holding(now) {
+			hold = b.holdTime(now)
+		} else {
+			c, ok = b.next()
+		}
+		if ok {
+			b.signalSpace()
+			b.sending = true
+		}
+		b.mut.Unlock()
 
-			if err == nil {
-				bufCh <- buf[:n]
-			} else if err != io.EOF {
-				logger.FdebuglnFatal("Error reading from prompt buffer", err)
-				logger.FatalLogln("Error reading from prompt buffer", err)
+		if ok {
+			sent := true
+			select {
+			case bufCh <- c:
+			case <-stopCh:
+				sent = false
 			}
-
+			b.mut.Lock()
+			b.sending = false
 			b.mut.Unlock()
+			if !sent {
+				return
+			}
+			b.stats.delivered()
+			continue
+		}
+
+		// A write between the unlock and here left a value in ready
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if hold > 0 {
+			timer = time.NewTimer(hold)
+			timeout = timer.C
+		}
+		select {
+		case <-b.ready:
+		case <-timeout:
+		case <-stopCh:
+		}
+		if timer != nil {
+			timer.Stop()
 		}
-		time.Sleep(time.Millisecond * 10)
 	}
 }
@@ -0,0 +1,414 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+)
+
+func chunkTexts(chunks []Chunk) []string {
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = string(c.Data)
+	}
+	return texts
+}
+
+func TestBufferPriorityInterleaving(t *testing.T) {
+	b := NewBuffer()
+	b.WriteString("normal 1\n")
+	b.WritePriority([]byte("error 1\n"))
+	b.WriteStream(context.Background(), StreamStderr, []byte("stderr 1\n"))
+	b.WritePriority([]byte("error 2\n"))
+	b.WriteString("normal 2\n")
+
+	chunks := readChunks(b)
+	got := chunkTexts(chunks)
+	want := []string{"error 1\n", "error 2\n", "normal 1\n", "stderr 1\n", "normal 2\n"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks %q, want %q", got, want)
+	}
+	for i, c := range chunks {
+		if c.Priority != (i < 2) {
+			t.Errorf("chunk %q has Priority %v", c.Data, c.Priority)
+		}
+		if c.OutOfOrder != (i < 2) {
+			t.Errorf("chunk %q has OutOfOrder %v", c.Data, c.OutOfOrder)
+		}
+	}
+}
+
+func TestBufferPriorityInOrderWhenNothingQueued(t *testing.T) {
+	b := NewBuffer()
+	b.WriteString("normal\n")
+	readChunks(b)
+
+	b.WritePriority([]byte("error\n"))
+	chunks := readChunks(b)
+	if len(chunks) != 1 || chunks[0].OutOfOrder {
+		t.Fatalf("an error after all the normal output is shown out of order: %+v", chunks)
+	}
+}
+
+func TestBufferPriorityDoesntCutASplitChunk(t *testing.T) {
+	b := NewBuffer()
+	if err := b.SetChunkSize(MinChunkSize); err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("a", MinChunkSize*2)
+	b.WriteString(big)
+
+	b.mut.Lock()
+	first, _ := b.next()
+	b.mut.Unlock()
+	b.WritePriority([]byte("error\n"))
+
+	rest := readChunks(b)
+	if got := string(first.Data) + string(rest[0].Data); got != big {
+		t.Fatalf("the error was delivered between the parts of a split chunk")
+	}
+	if string(rest[1].Data) != "error\n" {
+		t.Fatalf("chunks after the split one %q", chunkTexts(rest[1:]))
+	}
+}
+
+func TestBufferDropPolicyKeepsPriority(t *testing.T) {
+	b := NewBuffer()
+	b.SetOverflowPolicy(10, OverflowDrop)
+
+	b.WriteString("0123456789")
+	// Full, the priority writes are kept anyway
+	if n, err := b.WritePriority([]byte("error 1\n")); n != 8 || err != nil {
+		t.Fatalf("WritePriority = %d, %v", n, err)
+	}
+	// Dropped, a dropped write counts as written
+	if n, err := b.WriteString("dropped"); n != 7 || err != nil {
+		t.Fatalf("WriteString = %d, %v", n, err)
+	}
+	b.WritePriority([]byte("error 2\n"))
+
+	got := chunkTexts(readChunks(b))
+	want := []string{"error 1\n", "error 2\n", "0123456789"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("chunks %q, want %q", got, want)
+	}
+	if dropped := b.stats.bytesDropped; dropped != 7 {
+		t.Fatalf("%d bytes dropped, want 7", dropped)
+	}
+}
+
+func TestBufferPriorityTakesFromTheLimit(t *testing.T) {
+	b := NewBuffer()
+	b.SetOverflowPolicy(10, OverflowDrop)
+
+	b.WritePriority([]byte("0123456789"))
+	b.WriteString("dropped")
+	got := chunkTexts(readChunks(b))
+	if len(got) != 1 || got[0] != "0123456789" {
+		t.Fatalf("chunks %q, the normal write should be dropped", got)
+	}
+}
+
+func TestCommandErrorSkipsQueuedOutput(t *testing.T) {
+	fail := &usageCmd{
+		fakeCmd: &fakeCmd{name: "fail", run: func(cmd.Args) error {
+			return fmt.Errorf("%w: no target", cmd.ErrUsage)
+		}},
+		usage: "fail TARGET",
+	}
+	p, _ := newTestPrompt(t, []cmd.Cmd{fail}, "")
+	p.Writeln("queued output\n")
+
+	if err := p.Execute("fail"); !errors.Is(err, cmd.ErrUsage) {
+		t.Fatalf("Execute = %v", err)
+	}
+	chunks := readChunks(p.outBuf)
+	if len(chunks) != 2 || !chunks[0].Priority || !chunks[0].OutOfOrder {
+		t.Fatalf("the error isn't delivered ahead of the queued output: %q", chunkTexts(chunks))
+	}
+	msg := string(chunks[0].Data)
+	if !strings.HasPrefix(msg, "invalid usage: no target (exec ") || !strings.HasSuffix(msg, "\nfail TARGET\n") {
+		t.Fatalf("error chunk %q", msg)
+	}
+}
+
+func TestOutOfOrderMark(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithColorLevel(ColorNone))
+	s := p.decorate(Chunk{Data: []byte("error\n"), Stream: StreamStderr, Priority: true, OutOfOrder: true})
+	if s != "⚠ shown out of order error\n" {
+		t.Fatalf("decorate = %q", s)
+	}
+}
+
+// TestBlockingBufferStress has writers flood a blocking buffer drained by a
+// slow consumer. Everything must arrive without ever queueing more than the
+// limit.
+func TestBlockingBufferStress(t *testing.T) {
+	const limit = 8 * 1024
+	const writers, lines = 4, 500
+	line := strings.Repeat("x", 99) + "\n"
+
+	b := NewBuffer()
+	b.SetOverflowPolicy(limit, OverflowBlock)
+	bufCh := make(chan Chunk)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go b.Read(bufCh, stopCh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < lines; j++ {
+				b.WriteString(line)
+			}
+		}()
+	}
+
+	received := make(chan int)
+	go func() {
+		total := 0
+		for total < writers*lines*len(line) {
+			c := <-bufCh
+			total += len(c.Data)
+			b.Release(c)
+			time.Sleep(time.Millisecond)
+		}
+		received <- total
+	}()
+
+	select {
+	case <-received:
+	case <-time.After(30 * time.Second):
+		t.Fatal("the writers and the consumer are stuck")
+	}
+	wg.Wait()
+
+	b.mut.Lock()
+	depth := b.stats.maxQueueDepth
+	b.mut.Unlock()
+	if depth > limit {
+		t.Fatalf("%d bytes were queued, the limit is %d", depth, limit)
+	}
+}
+
+func TestBlockingBufferWriteGivesUp(t *testing.T) {
+	b := NewBuffer()
+	b.SetOverflowPolicy(10, OverflowBlock)
+	if n, err := b.WriteString("0123456789"); n != 10 || err != nil {
+		t.Fatalf("the write that fits: %d, %v", n, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	n, err := b.WriteStringCtx(ctx, "more")
+	if n != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("the write over the limit: %d, %v", n, err)
+	}
+	if texts := chunkTexts(readChunks(b)); len(texts) != 1 || texts[0] != "0123456789" {
+		t.Fatalf("queued %q", texts)
+	}
+}
+
+func TestWritelnCtxGivesUp(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithOutputLimit(10, OverflowBlock))
+	p.Writeln("0123456789")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := p.WritelnCtx(ctx, "blocked")
+		done <- err
+	}()
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("WritelnCtx returned %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("WritelnCtx didn't give up")
+	}
+	readChunks(p.outBuf)
+}
+
+// TestBufferStreamOrder writes every sequence of up to four writes to three
+// streams. The chunks must keep the order of the writes and their tags, and
+// only consecutive writes to the same stream may share a chunk.
+func TestBufferStreamOrder(t *testing.T) {
+	streams := []Stream{StreamDefault, StreamStderr, Stream(2)}
+
+	type piece struct {
+		stream Stream
+		text   string
+	}
+	var check func(seq []Stream)
+	check = func(seq []Stream) {
+		if len(seq) > 0 {
+			b := NewBuffer()
+			var want []piece
+			for i, s := range seq {
+				text := fmt.Sprintf("%d:%s ", i, s)
+				b.WriteStream(context.Background(), s, []byte(text))
+				if n := len(want) - 1; n >= 0 && want[n].stream == s {
+					want[n].text += text
+				} else {
+					want = append(want, piece{s, text})
+				}
+			}
+
+			var got []piece
+			for _, c := range readChunks(b) {
+				got = append(got, piece{c.Stream, string(c.Data)})
+			}
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("writes to %v delivered as %v, want %v", seq, got, want)
+			}
+		}
+		if len(seq) == 4 {
+			return
+		}
+		for _, s := range streams {
+			check(append(seq[:len(seq):len(seq)], s))
+		}
+	}
+	check(nil)
+}
+
+// TestBufferStreamOrderAcrossSplits writes more than a chunk to each stream
+func TestBufferStreamOrderAcrossSplits(t *testing.T) {
+	b := NewBuffer()
+	if err := b.SetChunkSize(MinChunkSize); err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Repeat("o", 2*MinChunkSize+1)
+	b.WriteStream(context.Background(), StreamDefault, []byte(big))
+	b.WriteStream(context.Background(), StreamStderr, []byte("e"))
+	b.WriteStream(context.Background(), StreamDefault, []byte(big))
+
+	var out strings.Builder
+	for _, c := range readChunks(b) {
+		if len(c.Data) > MinChunkSize {
+			t.Fatalf("a chunk of %d bytes", len(c.Data))
+		}
+		if c.Stream == StreamStderr != (string(c.Data) == "e") {
+			t.Fatalf("%q is tagged %s", c.Data, c.Stream)
+		}
+		out.Write(c.Data)
+	}
+	if out.String() != big+"e"+big {
+		t.Fatal("the data came out of order")
+	}
+}
+
+// BenchmarkBufferWrite is the single stream case that doesn't need the tags.
+// It uses only Write, so it also runs on the buffer before there were tags.
+func BenchmarkBufferWrite(b *testing.B) {
+	line := []byte("2020/04/10 12:00:00 function deployed, 42 ms\n")
+	buf := NewBuffer()
+	b.SetBytes(int64(len(line)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		buf.Write(line)
+		if i%1000 == 999 {
+			b.StopTimer()
+			buf = NewBuffer()
+			b.StartTimer()
+		}
+	}
+}
+
+// TestWriteDoesntWaitForTheConsumer blocks Read on a channel nobody reads.
+// Unless the buffer blocks on overflow, writes must still return.
+func TestWriteDoesntWaitForTheConsumer(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowGrow, OverflowDrop} {
+		b := NewBuffer()
+		b.SetOverflowPolicy(10, policy)
+		stopCh := make(chan struct{})
+		go b.Read(make(chan Chunk), stopCh)
+
+		b.WriteString("first")
+		waitFor(t, "Read to send the chunk", func() bool {
+			b.mut.Lock()
+			defer b.mut.Unlock()
+			return b.sending
+		})
+		done := make(chan struct{})
+		go func() {
+			b.WriteString("second write, over the limit")
+			b.Release(Chunk{Data: make([]byte, 0, DefaultChunkSize)})
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("the write waited for the consumer with policy %d", policy)
+		}
+		close(stopCh)
+	}
+}
+
+// TestReadWaitsForWrites leaves Read idle and writes one chunk at a time.
+// Each must be delivered right after its write, not at the next poll.
+func TestReadWaitsForWrites(t *testing.T) {
+	b := NewBuffer()
+	bufCh := make(chan Chunk)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go b.Read(bufCh, stopCh)
+
+	var slow int
+	for i := 0; i < 20; i++ {
+		time.Sleep(2 * time.Millisecond)
+		start := time.Now()
+		b.WriteString(fmt.Sprintf("line %d\n", i))
+		select {
+		case c := <-bufCh:
+			if got := string(c.Data); got != fmt.Sprintf("line %d\n", i) {
+				t.Fatalf("got %q after write %d", got, i)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("write %d wasn't delivered", i)
+		}
+		if time.Since(start) > 5*time.Millisecond {
+			slow++
+		}
+	}
+	if slow > 5 {
+		t.Fatalf("%d of 20 writes took longer than 5ms to be delivered", slow)
+	}
+}
+
+// TestReadHoldsCoalescedWrites writes twice in a quick succession with
+// coalescing on. Read delivers both in one chunk once the writes stop.
+func TestReadHoldsCoalescedWrites(t *testing.T) {
+	const coalesce = 50 * time.Millisecond
+	b := NewBuffer()
+	b.SetCoalesce(coalesce)
+	bufCh := make(chan Chunk)
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	go b.Read(bufCh, stopCh)
+
+	b.WriteString("a")
+	b.WriteString("b")
+	last := time.Now()
+	select {
+	case c := <-bufCh:
+		if string(c.Data) != "ab" {
+			t.Fatalf("got %q, want both writes together", c.Data)
+		}
+		if d := time.Since(last); d < coalesce-5*time.Millisecond {
+			t.Fatalf("the chunk was delivered %s after the last write, before the writes stopped for %s", d, coalesce)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the held chunk wasn't delivered once the writes stopped")
+	}
+}
@@ -0,0 +1,8 @@
+//go:build debug
+// +build debug
+
+package prompt
+
+// debugBuild is true when the tests run with the debug tag,
+// logger.Fdebugln writes only then
+const debugBuild = true
@@ -0,0 +1,149 @@
+package prompt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"foundry/cli/logger"
+)
+
+// builtinCmd is a command handled by the prompt itself
+// instead of being dispatched to the registered cmd.Cmd list
+type builtinCmd struct {
+	name   string
+	desc   string
+	hidden bool // Hidden commands aren't listed anywhere
+	run    func(p *Prompt, args []string) error
+}
+
+var builtinCmds = []*builtinCmd{
+	{
+		name:   "stats",
+		desc:   "Print output pipeline metrics",
+		hidden: true,
+		run:    runStats,
+	},
+	{
+		name:   "debuglog",
+		desc:   "Print the most recent log messages",
+		hidden: true,
+		run:    runDebugLog,
+	},
+	{
+		name:   "pprof",
+		desc:   "Write a goroutine, heap or mutex profile or print the goroutines",
+		hidden: true,
+		run:    runPprof,
+	},
+	{
+		name: "loglevel",
+		desc: "Print or set the log level (debug, info, warn or error)",
+		run:  runLogLevel,
+	},
+	{
+		name: "debug",
+		desc: "Print debug messages in the output or stop, debug on or debug off",
+		run:  runDebug,
+	},
+	{
+		name: "transcript",
+		desc: "Print the last lines of the output again, a screenful or the given number",
+		run:  runTranscript,
+	},
+	{
+		name: "record",
+		desc: "Record the session to an asciinema cast file, record start [path] or record stop",
+		run:  runRecord,
+	},
+	{
+		name: "replay",
+		desc: "Play a cast file in the output, replay FILE [--speed N|max]",
+		run:  runReplay,
+	},
+	{
+		name: "set",
+		desc: "Print the settings or change one, e.g. set color info yellow",
+		run:  runSet,
+	},
+}
+
+func (p *Prompt) getBuiltin(s string) *builtinCmd {
+	for _, c := range builtinCmds {
+		if c.name == s {
+			return c
+		}
+	}
+	return nil
+}
+
+func runStats(p *Prompt, args []string) error {
+	m := p.Metrics()
+
+	msg := "\n"
+	msg += fmt.Sprintf("bytes written:     %d\n", m.BytesWritten)
+	msg += fmt.Sprintf("bytes dropped:     %d\n", m.BytesDropped)
+	msg += fmt.Sprintf("max queue depth:   %d\n", m.MaxQueueDepth)
+	msg += fmt.Sprintf("chunks delivered:  %d\n", m.ChunksDelivered)
+	msg += fmt.Sprintf("render passes:     %d\n", m.RenderPasses)
+	msg += fmt.Sprintf("flush calls:       %d\n", m.FlushCalls)
+	msg += fmt.Sprintf("render time:       %s\n", m.RenderTime)
+	msg += fmt.Sprintf("events dropped:    %d\n", m.EventsDropped)
+
+	_, err := p.Writeln(msg)
+	return err
+}
+
+func runLogLevel(p *Prompt, args []string) error {
+	if len(args) == 0 {
+		p.SetInfoln(fmt.Sprintf("Log level is '%s'", logger.GetLevel()), InfoLineSeverityNormal)
+		return nil
+	}
+
+	l, err := logger.ParseLevel(args[0])
+	if err != nil || len(args) > 1 {
+		return fmt.Errorf("usage: loglevel [debug|info|warn|error]")
+	}
+	logger.SetLevel(l)
+
+	msg := fmt.Sprintf("Log level set to '%s'", l)
+	if l == logger.LevelDebug {
+		if path := logger.FilePath(); path != "" {
+			msg += fmt.Sprintf(", logging to %s", path)
+		}
+	}
+	p.SetInfoln(msg, InfoLineSeverityNormal)
+	return nil
+}
+
+// Number of log messages debuglog prints without an argument
+const defaultDebugLogLines = 50
+
+func runDebugLog(p *Prompt, args []string) error {
+	n := defaultDebugLogLines
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 || len(args) > 1 {
+			return fmt.Errorf("usage: debuglog [number of messages, up to %d]", logger.RingSize)
+		}
+	}
+
+	records := logger.Recent(n)
+	if len(records) == 0 {
+		p.SetInfoln("No log messages yet", InfoLineSeverityNormal)
+		return nil
+	}
+
+	// Dimmed so the messages can't be mistaken for output of commands
+	dim, endSeq := "\x1b[2m", "\x1b[0m"
+	if p.colorLevel == ColorNone {
+		dim, endSeq = "", ""
+	}
+
+	lines := make([]string, len(records))
+	for i, r := range records {
+		lines[i] = fmt.Sprintf("%s%s %-5s %s%s", dim, r.Time.Format("15:04:05.000"), strings.ToUpper(r.Level.String()), r.Message, endSeq)
+	}
+	_, err := p.WriteLines(lines)
+	return err
+}
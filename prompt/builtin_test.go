@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"foundry/cli/logger"
+)
+
+// infoText returns the text of the info row
+func infoText(p *Prompt) string {
+	p.lockRender()
+	defer p.unlockRender()
+	return p.infoText
+}
+
+// TestLogLevelCommand flips the level with the loglevel command and logs
+// after each change. The log file gets the debug messages only while the
+// level is debug.
+func TestLogLevelCommand(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "foundry.log")
+	if err := logger.SetFile(path); err != nil {
+		t.Fatal(err)
+	}
+	level := logger.GetLevel()
+	t.Cleanup(func() {
+		logger.SetOutput(ioutil.Discard)
+		logger.SetLevel(level)
+	})
+	p, _ := newTestPrompt(t, nil, "")
+
+	steps := []struct {
+		line  string
+		info  string
+		debug bool
+	}{
+		{"loglevel info", "Log level set to 'info'", false},
+		{"loglevel", "Log level is 'info'", false},
+		{"loglevel DEBUG", "Log level set to 'debug', logging to " + path, true},
+		{"loglevel warn", "Log level set to 'warn'", false},
+		{"loglevel debug", "Log level set to 'debug'", true},
+	}
+	for i, s := range steps {
+		if err := p.Execute(s.line); err != nil {
+			t.Fatalf("%q: %s", s.line, err)
+		}
+		if got := infoText(p); !strings.Contains(got, s.info) {
+			t.Fatalf("%q: the info row is %q, want %q", s.line, got, s.info)
+		}
+
+		fdebug := fmt.Sprintf("fdebug after step %d", i)
+		leveled := fmt.Sprintf("debug after step %d", i)
+		logger.Fdebugln(fdebug)
+		logger.Debug(leveled)
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logged := string(data)
+		if got, want := strings.Contains(logged, fdebug), s.debug && debugBuild; got != want {
+			t.Errorf("%q: Fdebugln logged: %v, want %v", s.line, got, want)
+		}
+		if got := strings.Contains(logged, leveled); got != s.debug {
+			t.Errorf("%q: Debug logged: %v, want %v", s.line, got, s.debug)
+		}
+	}
+}
+
+func TestLogLevelCommandUsage(t *testing.T) {
+	level := logger.GetLevel()
+	defer logger.SetLevel(level)
+	p, _ := newTestPrompt(t, nil, "")
+
+	for _, line := range []string{"loglevel loud", "loglevel debug info"} {
+		err := p.Execute(line)
+		if err == nil || err.Error() != "usage: loglevel [debug|info|warn|error]" {
+			t.Errorf("%q failed with %v, want the usage with the levels", line, err)
+		}
+		if logger.GetLevel() != level {
+			t.Errorf("%q changed the level to %s", line, logger.GetLevel())
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package prompt
+
+import "fmt"
+
+// BusyPolicy decides what happens to a line submitted while
+// another line's command still runs
+type BusyPolicy int
+
+const (
+	// BusyReject doesn't run the line, the info row says a command
+	// is already running. It's the default.
+	BusyReject BusyPolicy = iota
+	// BusyQueue runs the line once the command is done
+	BusyQueue
+)
+
+const busyMessage = "a command is already running"
+
+// WithBusyPolicy sets what happens to a line submitted while a command runs.
+// Only the lines the user submits are guarded, Execute isn't. Commands
+// and scripts call it while their line runs.
+func WithBusyPolicy(policy BusyPolicy) Option {
+	return func(p *Prompt) error {
+		if policy < BusyReject || policy > BusyQueue {
+			return fmt.Errorf("unknown busy policy %d", policy)
+		}
+		p.busyPolicy = policy
+		return nil
+	}
+}
+
+// acquireBusy marks the prompt busy with a submitted line. It returns
+// false if the line doesn't run.
+func (p *Prompt) acquireBusy() bool {
+	select {
+	case p.busy <- struct{}{}:
+		return true
+	default:
+	}
+	if p.busyPolicy == BusyReject {
+		p.log.Debugf("Line rejected, a command is already running")
+		p.SetInfoln(busyMessage, InfoLineSeverityWarning)
+		return false
+	}
+
+	p.log.Debugf("Line queued until the running command is done")
+	select {
+	case p.busy <- struct{}{}:
+		return true
+	case <-p.stopCh:
+		return false
+	}
+}
+
+func (p *Prompt) releaseBusy() {
+	<-p.busy
+}
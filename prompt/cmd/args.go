@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The Arg helpers convert the argument at index i. A missing or invalid
+// argument is an error wrapping ErrUsage, a command returns it from
+// RunRequest and the prompt prints the command's usage.
+
+// arg returns the argument at index i
+func arg(args Args, i int, what string) (string, error) {
+	if i < 0 || i >= len(args) {
+		return "", fmt.Errorf("%w: missing %s as argument %d", ErrUsage, what, i+1)
+	}
+	return args[i], nil
+}
+
+// ArgInt returns the argument at index i as an integer
+func ArgInt(args Args, i int) (int, error) {
+	s, err := arg(args, i, "a number")
+	if err != nil {
+		return 0, err
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: argument %d '%s' isn't a whole number", ErrUsage, i+1, s)
+	}
+	return n, nil
+}
+
+// ArgDuration returns the argument at index i as a duration, e.g. "1m30s"
+func ArgDuration(args Args, i int) (time.Duration, error) {
+	s, err := arg(args, i, "a duration")
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: argument %d '%s' isn't a duration like 30s or 1m30s", ErrUsage, i+1, s)
+	}
+	return d, nil
+}
+
+// ArgExistingFile returns the argument at index i if it's a path of a file
+// that exists. A directory isn't a file.
+func ArgExistingFile(args Args, i int) (string, error) {
+	s, err := arg(args, i, "a file")
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(s)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", fmt.Errorf("%w: argument %d, the file '%s' doesn't exist", ErrUsage, i+1, s)
+		}
+		return "", fmt.Errorf("%w: argument %d, can't read the file '%s': %s", ErrUsage, i+1, s, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%w: argument %d '%s' is a directory, not a file", ErrUsage, i+1, s)
+	}
+	return s, nil
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArgInt(t *testing.T) {
+	tests := []struct {
+		args Args
+		i    int
+		want int
+		err  string
+	}{
+		{Args{"42"}, 0, 42, ""},
+		{Args{"api", "-3"}, 1, -3, ""},
+		{Args{"+7"}, 0, 7, ""},
+		{Args{"3.5"}, 0, 0, "argument 1 '3.5' isn't a whole number"},
+		{Args{"api", "ten"}, 1, 0, "argument 2 'ten' isn't a whole number"},
+		{Args{"99999999999999999999"}, 0, 0, "isn't a whole number"},
+		{Args{""}, 0, 0, "argument 1 '' isn't a whole number"},
+		{Args{"api"}, 1, 0, "missing a number as argument 2"},
+		{nil, 0, 0, "missing a number as argument 1"},
+		{Args{"1"}, -1, 0, "missing a number as argument 0"},
+	}
+	for _, tt := range tests {
+		n, err := ArgInt(tt.args, tt.i)
+		checkArgErr(t, "ArgInt", tt.args, tt.i, err, tt.err)
+		if n != tt.want {
+			t.Errorf("ArgInt(%q, %d) = %d, want %d", tt.args, tt.i, n, tt.want)
+		}
+	}
+}
+
+func TestArgDuration(t *testing.T) {
+	tests := []struct {
+		args Args
+		i    int
+		want time.Duration
+		err  string
+	}{
+		{Args{"30s"}, 0, 30 * time.Second, ""},
+		{Args{"api", "1m30s"}, 1, 90 * time.Second, ""},
+		{Args{"250ms"}, 0, 250 * time.Millisecond, ""},
+		{Args{"0"}, 0, 0, ""},
+		{Args{"30"}, 0, 0, "argument 1 '30' isn't a duration like 30s or 1m30s"},
+		{Args{"a minute"}, 0, 0, "isn't a duration"},
+		{Args{"api"}, 1, 0, "missing a duration as argument 2"},
+	}
+	for _, tt := range tests {
+		d, err := ArgDuration(tt.args, tt.i)
+		checkArgErr(t, "ArgDuration", tt.args, tt.i, err, tt.err)
+		if d != tt.want {
+			t.Errorf("ArgDuration(%q, %d) = %s, want %s", tt.args, tt.i, d, tt.want)
+		}
+	}
+}
+
+func TestArgExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "deploy.yml")
+	if err := ioutil.WriteFile(file, []byte("name: api\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.yml")
+
+	tests := []struct {
+		args Args
+		i    int
+		want string
+		err  string
+	}{
+		{Args{file}, 0, file, ""},
+		{Args{"api", file}, 1, file, ""},
+		{Args{missing}, 0, "", "argument 1, the file '" + missing + "' doesn't exist"},
+		{Args{dir}, 0, "", "argument 1 '" + dir + "' is a directory, not a file"},
+		{Args{"api"}, 1, "", "missing a file as argument 2"},
+	}
+	for _, tt := range tests {
+		s, err := ArgExistingFile(tt.args, tt.i)
+		checkArgErr(t, "ArgExistingFile", tt.args, tt.i, err, tt.err)
+		if s != tt.want {
+			t.Errorf("ArgExistingFile(%q, %d) = %q, want %q", tt.args, tt.i, s, tt.want)
+		}
+	}
+}
+
+// checkArgErr checks that err is nil if want is empty. Otherwise it must
+// wrap ErrUsage, so the prompt prints the usage, and contain want.
+func checkArgErr(t *testing.T, fn string, args Args, i int, err error, want string) {
+	t.Helper()
+	switch {
+	case want == "" && err != nil:
+		t.Errorf("%s(%q, %d) failed: %s", fn, args, i, err)
+	case want != "" && err == nil:
+		t.Errorf("%s(%q, %d) didn't fail", fn, args, i)
+	case want != "" && (!errors.Is(err, ErrUsage) || !strings.Contains(err.Error(), want)):
+		t.Errorf("%s(%q, %d) returned %q, want a usage error with %q", fn, args, i, err, want)
+	}
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	c "foundry/cli/connection"
 
@@ -10,10 +12,63 @@ import (
 type Args []string
 type RunChannelType chan Args
 
+// ErrUsage is returned (possibly wrapped) by RunRequest when the command
+// was called with invalid arguments. The prompt then prints the command's
+// usage if it implements Usager.
+var ErrUsage = errors.New("invalid usage")
+
 type Cmd interface {
 	Run(conn *c.Connection, args Args) (promptOutput string, promptInfo string, err error)
-	RunRequest(args Args)
+	RunRequest(args Args) error
 	ToSuggest() goprompt.Suggest
 	Name() string
 	fmt.Stringer
 }
+
+// Usager is implemented by commands that can describe how they should be used
+type Usager interface {
+	Usage() string
+}
+
+// Aliaser is implemented by commands that can be run under other names too
+type Aliaser interface {
+	Aliases() []string
+}
+
+// Names returns the command's name followed by all its aliases
+func Names(c Cmd) []string {
+	names := []string{c.Name()}
+	if a, ok := c.(Aliaser); ok {
+		names = append(names, a.Aliases()...)
+	}
+	return names
+}
+
+// ArgCompleter is implemented by commands that suggest their arguments.
+// CompleteArgs gets the arguments before the cursor and the part of the
+// argument being typed, which is empty after a space. It's called for
+// every key the user presses, the prompt waits for it only a short while
+// and cancels ctx then. Suggestions replace the typed part of the argument.
+type ArgCompleter interface {
+	CompleteArgs(ctx context.Context, args Args, word string) []goprompt.Suggest
+}
+
+// ContextRunner is implemented by commands that want the context of their
+// execution. The prompt then calls RunRequestContext instead of RunRequest.
+// Logging with logger.Ctx(ctx) tags the messages with the execution id.
+type ContextRunner interface {
+	RunRequestContext(ctx context.Context, args Args) error
+}
+
+type execIDKey struct{}
+
+// WithExecID returns a copy of ctx with the id of a command's execution
+func WithExecID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, execIDKey{}, id)
+}
+
+// ExecID returns the id of the command's execution carried by ctx or an empty string
+func ExecID(ctx context.Context) string {
+	id, _ := ctx.Value(execIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// CompletePath suggests the entries of a directory for an ArgCompleter.
+// word is the part of the path being typed, it's looked up in root. Only directories are suggested if dirsOnly is set. Directories
+// end with a '/' so the user can continue with their entries. Hidden
+// entries are suggested only after the user typed the dot.
+func CompletePath(ctx context.Context, root, word string, dirsOnly bool) []goprompt.Suggest {
+	dir, prefix := "", word
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		dir, prefix = word[:i+1], word[i+1:]
+	}
+
+	// Reading a directory can't be interrupted, don't start when it's too late
+	if ctx.Err() != nil {
+		return nil
+	}
+	entries, err := ioutil.ReadDir(filepath.Join(root, dir))
+	if err != nil {
+		return nil
+	}
+
+	var s []goprompt.Suggest
+	for _, e := range entries {
+		name := e.Name()
+		if !strings.HasPrefix(name, prefix) || (strings.HasPrefix(name, ".") && !strings.HasPrefix(prefix, ".")) {
+			continue
+		}
+		if e.IsDir() {
+			name += "/"
+		} else if dirsOnly {
+			continue
+		}
+		s = append(s, goprompt.Suggest{Text: dir + name})
+	}
+	return s
+}
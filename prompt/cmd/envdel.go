@@ -67,8 +67,16 @@ func (c *EnvDelCmd) Run(conn *c.Connection, args Args) (promptOutput string, pro
 	return "", "Deleted " + strings.Join(args, ", "), err
 }
 
-func (c *EnvDelCmd) RunRequest(args Args) {
+func (c *EnvDelCmd) RunRequest(args Args) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no envs to delete specified", ErrUsage)
+	}
 	c.RunCh <- args
+	return nil
+}
+
+func (c *EnvDelCmd) Usage() string {
+	return "Usage: env-delete NAME [NAME...]"
 }
 
 func (c *EnvDelCmd) ToSuggest() goprompt.Suggest {
@@ -57,8 +57,9 @@ func (c *EnvPrintCmd) Run(conn *c.Connection, args Args) (promptOutput string, p
 	return msg, "", nil
 }
 
-func (c *EnvPrintCmd) RunRequest(args Args) {
+func (c *EnvPrintCmd) RunRequest(args Args) error {
 	c.RunCh <- args
+	return nil
 }
 
 func (c *EnvPrintCmd) ToSuggest() goprompt.Suggest {
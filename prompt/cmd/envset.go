@@ -64,8 +64,16 @@ func (c *EnvSetCmd) Run(conn *c.Connection, args Args) (promptOutput string, pro
 	return "", "Variables set", nil
 }
 
-func (c *EnvSetCmd) RunRequest(args Args) {
+func (c *EnvSetCmd) RunRequest(args Args) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: no envs specified", ErrUsage)
+	}
 	c.RunCh <- args
+	return nil
+}
+
+func (c *EnvSetCmd) Usage() string {
+	return "Usage: env-set NAME=VALUE [NAME=VALUE...]"
 }
 
 func (c *EnvSetCmd) ToSuggest() goprompt.Suggest {
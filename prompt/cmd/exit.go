@@ -3,7 +3,6 @@ package cmd
 import (
 	"fmt"
 	c "foundry/cli/connection"
-	"os"
 
 	goprompt "github.com/mlejva/go-prompt"
 )
@@ -24,13 +23,14 @@ func NewExitCmd() *ExitCmd {
 
 // Implement Cmd interface
 
+// Run doesn't do anything, the caller stops the prompt and its Run returns
 func (c *ExitCmd) Run(conn *c.Connection, args Args) (promptOutput string, promptInfo string, err error) {
-	os.Exit(0)
-	return "", "", err
+	return "", "", nil
 }
 
-func (c *ExitCmd) RunRequest(args Args) {
+func (c *ExitCmd) RunRequest(args Args) error {
 	c.RunCh <- args
+	return nil
 }
 
 func (c *ExitCmd) ToSuggest() goprompt.Suggest {
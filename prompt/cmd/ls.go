@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	c "foundry/cli/connection"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// LsCmd lists the files in a directory of the project. Its argument
+// completes to the directories as the user types it.
+type LsCmd struct {
+	Text  string
+	Desc  string
+	RunCh RunChannelType
+	Root  string
+}
+
+func NewLsCmd(root string) *LsCmd {
+	return &LsCmd{
+		Text:  "ls",
+		Desc:  "List files in a directory of your project",
+		RunCh: make(chan Args),
+		Root:  root,
+	}
+}
+
+// Implement Cmd interface
+func (c *LsCmd) Run(conn *c.Connection, args Args) (promptOutput string, promptInfo string, err error) {
+	dir := c.Root
+	if len(args) > 0 {
+		dir = filepath.Join(c.Root, args[0])
+	}
+
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return "", "", fmt.Errorf("error listing the directory: %w", err)
+	}
+	if len(entries) == 0 {
+		return "", "The directory is empty", nil
+	}
+
+	var b strings.Builder
+	for _, e := range entries {
+		b.WriteString(e.Name())
+		if e.IsDir() {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), "", nil
+}
+
+func (c *LsCmd) RunRequest(args Args) error {
+	if len(args) > 1 {
+		return fmt.Errorf("%w: expected at most one directory", ErrUsage)
+	}
+	c.RunCh <- args
+	return nil
+}
+
+func (c *LsCmd) ToSuggest() goprompt.Suggest {
+	return goprompt.Suggest{Text: c.Text, Description: c.Desc}
+}
+
+func (c *LsCmd) Name() string {
+	return c.Text
+}
+
+func (c *LsCmd) String() string {
+	return fmt.Sprintf("%s - %s", c.Text, c.Desc)
+}
+
+func (c *LsCmd) Usage() string {
+	return "Usage: ls [DIRECTORY]"
+}
+
+// CompleteArgs suggests the project's directories for the only argument
+func (c *LsCmd) CompleteArgs(ctx context.Context, args Args, word string) []goprompt.Suggest {
+	if len(args) > 0 {
+		return nil
+	}
+	return CompletePath(ctx, c.Root, word, true)
+}
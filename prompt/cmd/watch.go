@@ -50,8 +50,19 @@ func (c *WatchCmd) Run(conn *c.Connection, args Args) (promptOutput string, prom
 	return "", "", err
 }
 
-func (c *WatchCmd) RunRequest(args Args) {
+func (c *WatchCmd) RunRequest(args Args) error {
+	if c.Text == "watch" && len(args) == 0 {
+		return fmt.Errorf("%w: 'watch' requires function name(s) as arguments", ErrUsage)
+	}
 	c.RunCh <- args
+	return nil
+}
+
+func (c *WatchCmd) Usage() string {
+	if c.Text == "watch:all" {
+		return "Usage: watch:all"
+	}
+	return "Usage: watch <function> [<function>...]"
 }
 
 func (c *WatchCmd) ToSuggest() goprompt.Suggest {
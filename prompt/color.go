@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ColorLevel is how many colors the terminal can show
+type ColorLevel int
+
+const (
+	ColorNone ColorLevel = iota
+	Color16
+	Color256
+	ColorTrueColor
+)
+
+// DetectColorLevel guesses the color level of the terminal from $NO_COLOR, $COLORTERM and $TERM
+func DetectColorLevel() ColorLevel {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return ColorNone
+	}
+
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorTrueColor
+	}
+
+	term := os.Getenv("TERM")
+	switch {
+	case term == "" || term == "dumb":
+		return ColorNone
+	case strings.Contains(term, "truecolor") || strings.Contains(term, "24bit"):
+		return ColorTrueColor
+	case strings.Contains(term, "256color"):
+		return Color256
+	}
+	return Color16
+}
+
+// WithColorLevel overrides the detected color level of the terminal
+func WithColorLevel(level ColorLevel) Option {
+	return func(p *Prompt) error {
+		if level < ColorNone || level > ColorTrueColor {
+			return fmt.Errorf("unknown color level %d", level)
+		}
+		p.colorLevel = level
+		return nil
+	}
+}
+
+// ColorLevel returns the color level the prompt renders output with
+func (p *Prompt) ColorLevel() ColorLevel {
+	return p.colorLevel
+}
+
+// Fg returns an escape code that sets the text color to the RGB color
+// or to the closest color the level supports
+func (l ColorLevel) Fg(r, g, b uint8) string {
+	return l.rgb(38, r, g, b)
+}
+
+// Bg is like Fg for the background color
+func (l ColorLevel) Bg(r, g, b uint8) string {
+	return l.rgb(48, r, g, b)
+}
+
+// Fg256 returns an escape code that sets the text color to the color n
+// of the 256 color palette or to the closest color the level supports
+func (l ColorLevel) Fg256(n uint8) string {
+	return l.palette(38, n)
+}
+
+// Bg256 is like Fg256 for the background color
+func (l ColorLevel) Bg256(n uint8) string {
+	return l.palette(48, n)
+}
+
+// Code 38 is for text and 48 for background
+func (l ColorLevel) rgb(code int, r, g, b uint8) string {
+	switch l {
+	case ColorTrueColor:
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", code, r, g, b)
+	case Color256:
+		return fmt.Sprintf("\x1b[%d;5;%dm", code, rgbTo256(r, g, b))
+	case Color16:
+		return basicColor(code, rgbTo16(r, g, b))
+	}
+	return ""
+}
+
+func (l ColorLevel) palette(code int, n uint8) string {
+	switch l {
+	case ColorTrueColor, Color256:
+		return fmt.Sprintf("\x1b[%d;5;%dm", code, n)
+	case Color16:
+		if n < 16 {
+			return basicColor(code, n)
+		}
+		r, g, b := paletteToRGB(n)
+		return basicColor(code, rgbTo16(r, g, b))
+	}
+	return ""
+}
+
+// basicColor returns the escape code of the color n of the 16 color palette
+func basicColor(code int, n uint8) string {
+	// 30-37 and 40-47 are the normal colors, 90-97 and 100-107 the bright ones
+	base := code - 8
+	if n >= 8 {
+		base += 60
+		n -= 8
+	}
+	return fmt.Sprintf("\x1b[%dm", base+int(n))
+}
+
+// Colors of the 16 color palette as xterm shows them
+var basicPalette = [16][3]uint8{
+	{0, 0, 0}, {205, 0, 0}, {0, 205, 0}, {205, 205, 0},
+	{0, 0, 238}, {205, 0, 205}, {0, 205, 205}, {229, 229, 229},
+	{127, 127, 127}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{92, 92, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// Levels of a single component in the 6x6x6 color cube of the 256 color palette
+var cubeLevels = [6]uint8{0, 95, 135, 175, 215, 255}
+
+func rgbTo16(r, g, b uint8) uint8 {
+	best, bestDist := 0, -1
+	for i, c := range basicPalette {
+		dr, dg, db := int(r)-int(c[0]), int(g)-int(c[1]), int(b)-int(c[2])
+		if dist := dr*dr + dg*dg + db*db; bestDist < 0 || dist < bestDist {
+			best, bestDist = i, dist
+		}
+	}
+	return uint8(best)
+}
+
+func rgbTo256(r, g, b uint8) uint8 {
+	if r == g && g == b {
+		// The grayscale ramp 232-255 is finer than the gray colors of the cube
+		switch {
+		case r < 8:
+			return 16
+		case r > 248:
+			return 231
+		}
+		return 232 + uint8((int(r)-8)*24/247)
+	}
+
+	cube := func(v uint8) uint8 {
+		switch {
+		case v < 48:
+			return 0
+		case v < 115:
+			return 1
+		}
+		return (v - 35) / 40
+	}
+	return 16 + 36*cube(r) + 6*cube(g) + cube(b)
+}
+
+func paletteToRGB(n uint8) (r, g, b uint8) {
+	switch {
+	case n < 16:
+		c := basicPalette[n]
+		return c[0], c[1], c[2]
+	case n < 232:
+		n -= 16
+		return cubeLevels[n/36], cubeLevels[n/6%6], cubeLevels[n%6]
+	}
+	v := 8 + 10*(n-232)
+	return v, v, v
+}
@@ -0,0 +1,126 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// DefaultCompletionTimeout is how long typing waits for a command's ArgCompleter
+const DefaultCompletionTimeout = 100 * time.Millisecond
+
+// WithCompletionTimeout sets how long the input waits for the suggestions
+// of a command's ArgCompleter. Completion runs for every key the user
+// presses, a slower completer would make the typing lag, so its
+// suggestions are dropped after d.
+func WithCompletionTimeout(d time.Duration) Option {
+	return func(p *Prompt) error {
+		if d <= 0 {
+			return fmt.Errorf("completion timeout must be positive, got %s", d)
+		}
+		p.completionTimeout = d
+		return nil
+	}
+}
+
+// suggest returns the commands whose names start with the word under the
+// cursor or the suggestions of the command's ArgCompleter for its arguments.
+// The cursor can be anywhere in the line after the user edited it, the word
+// it's at the end of is completed.
+func (p *Prompt) suggest(d goprompt.Document) []goprompt.Suggest {
+	// go-prompt replaces only the part of the word before the cursor,
+	// in the middle of a word the rest of it would stay after the suggestion
+	if d.GetWordAfterCursorUntilSeparator(" ") != "" {
+		return nil
+	}
+
+	before := strings.TrimLeft(d.TextBeforeCursor(), " ")
+	word := d.GetWordBeforeCursorUntilSeparator(" ")
+	if strings.Contains(before, " ") {
+		// The cursor is past the command name
+		return p.afterDelimiter(p.suggestArgs(before, word), word)
+	}
+
+	if word == "" {
+		return nil
+	}
+	return p.afterDelimiter(goprompt.FilterHasPrefix(p.commandSuggestions(), word, true), word)
+}
+
+// afterDelimiter returns the suggestions for the whole word as the part
+// after its last delimiter, go-prompt replaces only that part. The ones
+// that don't start with the same part before it are dropped.
+func (p *Prompt) afterDelimiter(suggestions []goprompt.Suggest, word string) []goprompt.Suggest {
+	i := strings.LastIndexAny(word, p.completionDelimiters)
+	if i < 0 {
+		return suggestions
+	}
+	head := word[:i+1]
+	var s []goprompt.Suggest
+	for _, sg := range suggestions {
+		if strings.HasPrefix(sg.Text, head) {
+			sg.Text = sg.Text[len(head):]
+			s = append(s, sg)
+		}
+	}
+	return s
+}
+
+// suggestArgs asks the command in the line for the suggestions of
+// the argument being typed
+func (p *Prompt) suggestArgs(before, word string) []goprompt.Suggest {
+	fields := strings.Fields(before)
+	c, ok := p.getCommand(fields[0]).(cmd.ArgCompleter)
+	if !ok {
+		return nil
+	}
+	args := fields[1:]
+	if word != "" {
+		args = args[:len(args)-1]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.completionTimeout)
+	defer cancel()
+
+	// The channel is buffered so a late completer doesn't block forever
+	ch := make(chan []goprompt.Suggest, 1)
+	go func() {
+		var s []goprompt.Suggest
+		err := p.safeRun(fields[0]+" completion", func() error {
+			s = c.CompleteArgs(ctx, args, word)
+			return nil
+		})
+		if err != nil {
+			s = nil
+		}
+		ch <- s
+	}()
+
+	select {
+	case s := <-ch:
+		return s
+	case <-ctx.Done():
+		p.logWith("cmd", fields[0], "word", word).Debugf("Completion timed out")
+		return nil
+	}
+}
+
+// commandSuggestions lists the registered commands and the visible built-in ones
+func (p *Prompt) commandSuggestions() []goprompt.Suggest {
+	cmds := p.commands()
+	s := make([]goprompt.Suggest, 0, len(cmds)+len(builtinCmds))
+	for _, c := range cmds {
+		s = append(s, c.ToSuggest())
+	}
+	for _, b := range builtinCmds {
+		if !b.hidden {
+			s = append(s, goprompt.Suggest{Text: b.name, Description: b.desc})
+		}
+	}
+	return s
+}
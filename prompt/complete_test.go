@@ -0,0 +1,146 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// argCmd is a fakeCmd completing its arguments from a list
+type argCmd struct {
+	*fakeCmd
+	suggest []string
+
+	mut  sync.Mutex
+	args []string // The arguments of the last completion
+}
+
+func (c *argCmd) CompleteArgs(ctx context.Context, args cmd.Args, word string) []goprompt.Suggest {
+	c.mut.Lock()
+	c.args = args
+	c.mut.Unlock()
+	var s []goprompt.Suggest
+	for _, text := range c.suggest {
+		s = append(s, goprompt.Suggest{Text: text})
+	}
+	return goprompt.FilterHasPrefix(s, word, false)
+}
+
+// document returns the line with the cursor where | is
+func document(line string) goprompt.Document {
+	i := strings.Index(line, "|")
+	b := goprompt.NewBuffer()
+	b.InsertText(line[:i]+line[i+1:], false, true)
+	b.CursorLeft(len([]rune(line[i+1:])))
+	return *b.Document()
+}
+
+func suggestionTexts(s []goprompt.Suggest) string {
+	texts := make([]string, len(s))
+	for i, sg := range s {
+		texts[i] = sg.Text
+	}
+	return strings.Join(texts, " ")
+}
+
+// TestCompleteAtCursor completes lines with the cursor in different
+// places, like after the user moved it back to edit the line
+func TestCompleteAtCursor(t *testing.T) {
+	logs := &argCmd{fakeCmd: &fakeCmd{name: "logs"}, suggest: []string{"api", "worker", "web"}}
+	cmds := []cmd.Cmd{&fakeCmd{name: "deploy"}, &fakeCmd{name: "delete"}, logs}
+
+	tests := []struct {
+		line string
+		want string
+		args string
+	}{
+		// The built-in commands are suggested after the registered ones
+		{line: "de|", want: "deploy delete debug"},
+		{line: "dep|", want: "deploy"},
+		{line: "  de|", want: "deploy delete debug"},
+		// The rest of the line after the cursor doesn't matter
+		{line: "de| api --force", want: "deploy delete debug"},
+		{line: "lo| api", want: "logs loglevel"},
+		// In the middle of a word the rest of it would stay after the suggestion
+		{line: "dep|loy", want: ""},
+		{line: "logs w|eb", want: ""},
+		{line: "|deploy", want: ""},
+		{line: "de |", want: ""},
+		{line: "logs |", want: "api worker web"},
+		{line: "logs w|", want: "worker web"},
+		{line: "logs w| api", want: "worker web"},
+		{line: "logs api w|", want: "worker web", args: "api"},
+		{line: "logs api  |  --tail", want: "api worker web", args: "api"},
+		{line: "logs api| w", want: "api", args: ""},
+		{line: "deploy a|", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			p, _ := newTestPrompt(t, cmds, "")
+			logs.mut.Lock()
+			logs.args = nil
+			logs.mut.Unlock()
+
+			got := suggestionTexts(p.suggest(document(tt.line)))
+			if got != tt.want {
+				t.Errorf("the suggestions are %q, want %q", got, tt.want)
+			}
+			logs.mut.Lock()
+			args := strings.Join(logs.args, " ")
+			logs.mut.Unlock()
+			if args != tt.args {
+				t.Errorf("the completer got the arguments %q, want %q", args, tt.args)
+			}
+		})
+	}
+}
+
+// TestCompleteDelimitedToken completes arguments like api:restart with ':'
+// as a word delimiter. The suggestions are the part after the last ':'
+// of the word, go-prompt replaces only that part.
+func TestCompleteDelimitedToken(t *testing.T) {
+	service := &argCmd{fakeCmd: &fakeCmd{name: "service"}, suggest: []string{"api:restart", "api:stop", "api:logs:tail", "worker:restart"}}
+	cmds := []cmd.Cmd{service}
+
+	tests := []struct {
+		line string
+		opts []Option
+		want string
+	}{
+		{line: "service |", opts: []Option{WithWordDelimiters(":")}, want: "api:restart api:stop api:logs:tail worker:restart"},
+		{line: "service wo|", opts: []Option{WithWordDelimiters(":")}, want: "worker:restart"},
+		{line: "service api:|", opts: []Option{WithWordDelimiters(":")}, want: "restart stop logs:tail"},
+		{line: "service api:r|", opts: []Option{WithWordDelimiters(":")}, want: "restart"},
+		{line: "service api:logs:|", opts: []Option{WithWordDelimiters(":")}, want: "tail"},
+		{line: "service web:|", opts: []Option{WithWordDelimiters(":")}, want: ""},
+		// Without the delimiter the whole argument is suggested
+		{line: "service api:r|", want: "api:restart"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			p, _ := newTestPrompt(t, cmds, "", tt.opts...)
+			if got := suggestionTexts(p.suggest(document(tt.line))); got != tt.want {
+				t.Errorf("the suggestions are %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCompleteDelimitedTokenInGoPrompt picks a suggestion with Tab in
+// go-prompt's loop. Only the part after the ':' is replaced.
+func TestCompleteDelimitedTokenInGoPrompt(t *testing.T) {
+	service := &argCmd{fakeCmd: &fakeCmd{name: "service"}, suggest: []string{"api:restart", "api:stop"}}
+	p, parser, _, _ := newTTYPrompt(t, []cmd.Cmd{service}, WithWordDelimiters(":"))
+	go p.Run()
+	<-p.Ready()
+
+	parser.keys <- []byte("service api:s")
+	parser.keys <- []byte("\t")
+	parser.keys <- []byte(" ")
+	waitFor(t, "the completed argument", func() bool { return p.input() == "service api:stop " })
+}
@@ -0,0 +1,63 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// promptContext is the prefix and the commands PushContext
+// replaced, PopContext brings them back
+type promptContext struct {
+	prefix string
+	cmds   []cmd.Cmd
+}
+
+// PushContext makes cmds the commands the user can run and prefix the text
+// in front of the input, e.g. for an interactive editor started by a command.
+// The built-in commands stay. PopContext returns to the previous prefix and
+// commands, usually one of cmds calls it once the user is done. Contexts
+// can be nested. cmds are checked the same way as by Validate.
+func (p *Prompt) PushContext(prefix string, cmds []cmd.Cmd) error {
+	if strings.ContainsAny(prefix, "\r\n") {
+		return fmt.Errorf("the prefix %q can't span more lines", prefix)
+	}
+	if err := p.validateCmds(cmds); err != nil {
+		return err
+	}
+
+	p.lockRender()
+	defer p.unlockRender()
+	p.contexts = append(p.contexts, promptContext{prefix: p.promptPrefix, cmds: p.cmds})
+	p.promptPrefix = prefix
+	p.cmds = cmds
+	p.logWith("depth", len(p.contexts), "prefix", prefix).Debugf("Context pushed")
+	p.repaint()
+	return nil
+}
+
+// PopContext returns to the prefix and the commands from before the last
+// PushContext. A prefix set by SetPrefix in the meantime is replaced too.
+func (p *Prompt) PopContext() error {
+	p.lockRender()
+	defer p.unlockRender()
+	if len(p.contexts) == 0 {
+		return errors.New("there's no context to pop")
+	}
+	last := p.contexts[len(p.contexts)-1]
+	p.contexts = p.contexts[:len(p.contexts)-1]
+	p.promptPrefix = last.prefix
+	p.cmds = last.cmds
+	p.logWith("depth", len(p.contexts), "prefix", last.prefix).Debugf("Context popped")
+	p.repaint()
+	return nil
+}
+
+// commands returns the commands of the current context
+func (p *Prompt) commands() []cmd.Cmd {
+	p.lockRender()
+	defer p.unlockRender()
+	return p.cmds
+}
@@ -0,0 +1,99 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestPushPopContext starts an editor from a command. Its commands replace
+// the program's until quit pops the context, nested contexts come back in
+// order and the prompt row shows the prefix of the current one.
+func TestPushPopContext(t *testing.T) {
+	var p *Prompt
+	var ran []string
+	record := func(name string) func(cmd.Args) error {
+		return func(cmd.Args) error {
+			ran = append(ran, name)
+			return nil
+		}
+	}
+	save := &fakeCmd{name: "save", run: record("save")}
+	quit := &fakeCmd{name: "quit", run: func(cmd.Args) error {
+		ran = append(ran, "quit")
+		return p.PopContext()
+	}}
+	confirm := &fakeCmd{name: "yes", run: func(cmd.Args) error {
+		ran = append(ran, "yes")
+		return p.PopContext()
+	}}
+	edit := &fakeCmd{name: "edit", run: func(cmd.Args) error {
+		ran = append(ran, "edit")
+		return p.PushContext("edit> ", []cmd.Cmd{save, quit})
+	}}
+	deploy := &fakeCmd{name: "deploy", run: record("deploy")}
+	p, _ = newTestPrompt(t, []cmd.Cmd{edit, deploy}, "", WithScreenSnapshot())
+	startPrompt(t, p)
+
+	promptRow := func() string {
+		return strings.Split(p.Snapshot(), "\n")[23]
+	}
+	run := func(line string, wantErr bool) {
+		t.Helper()
+		if err := p.Execute(line); (err != nil) != wantErr {
+			t.Fatalf("Execute(%q) returned %v", line, err)
+		}
+	}
+
+	run("edit", false)
+	if row := promptRow(); row != "edit>" {
+		t.Fatalf("in the editor the prompt row is %q", row)
+	}
+	run("save", false)
+	run("deploy", true)
+	// The built-in commands stay
+	run("loglevel debug", false)
+
+	if err := p.PushContext("sure?> ", []cmd.Cmd{confirm}); err != nil {
+		t.Fatalf("PushContext: %s", err)
+	}
+	run("save", true)
+	run("yes", false)
+	if row := promptRow(); row != "edit>" {
+		t.Fatalf("after the nested context the prompt row is %q", row)
+	}
+
+	// Pop replaces the prefix set in the context
+	p.SetPrefix("edit*> ")
+	run("quit", false)
+	if row := promptRow(); row != ">" {
+		t.Fatalf("after quitting the editor the prompt row is %q", row)
+	}
+	run("save", true)
+	run("deploy", false)
+
+	if got, want := strings.Join(ran, " "), "edit save yes quit deploy"; got != want {
+		t.Errorf("the commands that ran are %q, want %q", got, want)
+	}
+	if err := p.PopContext(); err == nil {
+		t.Error("PopContext without a context didn't fail")
+	}
+}
+
+func TestPushContextRejects(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	if err := p.PushContext("a\nb> ", nil); err == nil {
+		t.Error("PushContext accepted a prefix of two lines")
+	}
+	dup := []cmd.Cmd{&fakeCmd{name: "save"}, &fakeCmd{name: "write", aliases: []string{"save"}}}
+	if err := p.PushContext("edit> ", dup); err == nil {
+		t.Error("PushContext accepted two commands of the same name")
+	}
+	if err := p.PushContext("edit> ", []cmd.Cmd{&fakeCmd{name: "stats"}}); err == nil {
+		t.Error("PushContext accepted a command named like a built-in one")
+	}
+	if err := p.PopContext(); err == nil {
+		t.Error("a rejected context was pushed")
+	}
+}
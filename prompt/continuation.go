@@ -0,0 +1,83 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// defaultContinuationPrefix replaces the prefix while a line continues
+const defaultContinuationPrefix = "… "
+
+// WithContinuationPrefix sets what replaces the prefix while a line that
+// ended with a backslash waits for the next one. It may be colored like
+// the prefix, the cursor keeps to its display width.
+func WithContinuationPrefix(prefix string) Option {
+	return func(p *Prompt) error {
+		if strings.ContainsAny(prefix, "\r\n") {
+			return fmt.Errorf("the continuation prefix %q can't span more lines", prefix)
+		}
+		p.contPrefix = prefix
+		return nil
+	}
+}
+
+// continueLine keeps a line that ended with a backslash, the next
+// line continues it. It returns false if the line ended otherwise.
+func (p *Prompt) continueLine(s string) bool {
+	if !strings.HasSuffix(s, `\`) {
+		return false
+	}
+	p.lockRender()
+	defer p.unlockRender()
+	p.continued = append(p.continued, strings.TrimSuffix(s, `\`))
+	p.repaint()
+	return true
+}
+
+// joinContinued returns s with the lines it continues in front of it,
+// joined by single spaces
+func (p *Prompt) joinContinued(s string) string {
+	p.lockRender()
+	defer p.unlockRender()
+	if len(p.continued) == 0 {
+		return s
+	}
+	var parts []string
+	for _, l := range append(p.continued, s) {
+		if l = strings.TrimSpace(l); l != "" {
+			parts = append(parts, l)
+		}
+	}
+	p.continued = nil
+	p.repaint()
+	return strings.Join(parts, " ")
+}
+
+// dropContinued forgets the lines waiting for their continuation.
+// It returns false if there were none.
+func (p *Prompt) dropContinued() bool {
+	p.lockRender()
+	n := len(p.continued)
+	p.continued = nil
+	if n > 0 {
+		p.repaint()
+	}
+	p.unlockRender()
+	if n == 0 {
+		return false
+	}
+	p.SetInfoln("The continued line was dropped", InfoLineSeverityNormal)
+	return true
+}
+
+// currentPrefix returns the prefix in front of the input, with the mark of
+// vi's normal mode, or the search's term. renderMutex must be held.
+func (p *Prompt) currentPrefix() string {
+	if p.search.active {
+		return p.search.prefix(p.searchFormat)
+	}
+	if len(p.continued) > 0 {
+		return p.viPrefix(p.contPrefix)
+	}
+	return p.viPrefix(p.promptPrefix)
+}
@@ -0,0 +1,113 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestContinuedQuotes submits lines ending with a backslash inside quotes,
+// nested ones too. The joined line keeps the quotes, the words are split
+// once the last line comes and the history has the joined line.
+func TestContinuedQuotes(t *testing.T) {
+	tests := []struct {
+		name    string
+		lines   []string
+		joined  string
+		words   []string
+		wantErr bool
+	}{
+		{
+			name:   "single in double",
+			lines:  []string{`run --env "FOO='a \`, `b'" --force`},
+			joined: `run --env "FOO='a b'" --force`,
+			words:  []string{"run", "--env", "FOO='a b'", "--force"},
+		},
+		{
+			name:   "double in single",
+			lines:  []string{`echo 'say "hi \`, `   there" \`, `now'`},
+			joined: `echo 'say "hi there" now'`,
+			words:  []string{"echo", `say "hi there" now`},
+		},
+		{
+			name:   "quotes opened and closed on different lines",
+			lines:  []string{`run "a \`, `'b' c" 'd \`, `e'`},
+			joined: `run "a 'b' c" 'd e'`,
+			words:  []string{"run", "a 'b' c", "d e"},
+		},
+		{
+			name:   "escaped quote",
+			lines:  []string{`echo "say \"hi \`, `there\""`},
+			joined: `echo "say \"hi there\""`,
+			words:  []string{"echo", `say "hi there"`},
+		},
+		{
+			name:    "never closed",
+			lines:   []string{`echo "open \`, `still open`},
+			joined:  `echo "open still open`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var words []string
+			handler := func(name string, args []string) error {
+				words = append([]string{name}, args...)
+				return nil
+			}
+			p, _ := newTestPrompt(t, nil, "", WithCommandNotFoundHandler(handler), WithScreenSnapshot())
+			startPrompt(t, p)
+
+			for i, l := range tt.lines {
+				p.executor(l)
+				if i == len(tt.lines)-1 {
+					break
+				}
+				if words != nil {
+					t.Fatalf("line %d ran %q", i+1, words)
+				}
+				if row := strings.Split(p.Snapshot(), "\n")[23]; row != "…" {
+					t.Fatalf("after line %d the prompt row is %q", i+1, row)
+				}
+			}
+
+			if tt.wantErr {
+				if words != nil || !strings.Contains(infoText(p), "the \" quote isn't closed") {
+					t.Errorf("ran %q with the info row %q", words, infoText(p))
+				}
+			} else if !reflect.DeepEqual(words, tt.words) {
+				t.Errorf("the words are %q, want %q", words, tt.words)
+			}
+			p.history.mut.Lock()
+			lines := p.history.lines
+			p.history.mut.Unlock()
+			if !reflect.DeepEqual(lines, []string{tt.joined}) {
+				t.Errorf("the history is %q, want %q", lines, tt.joined)
+			}
+			if row := strings.Split(p.Snapshot(), "\n")[23]; row != ">" {
+				t.Errorf("after the last line the prompt row is %q", row)
+			}
+		})
+	}
+}
+
+// TestContinuedRegisteredCommand continues the line of a registered
+// command. Its arguments are split on spaces only, the quotes stay.
+func TestContinuedRegisteredCommand(t *testing.T) {
+	var got cmd.Args
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		got = args
+		return nil
+	}}
+	p, _ := newTestPrompt(t, []cmd.Cmd{deploy}, "")
+	startPrompt(t, p)
+
+	p.executor(`deploy api \`)
+	p.executor(`--message "fix \`)
+	p.executor(`the 'cache'"`)
+	if want := (cmd.Args{"api", "--message", `"fix`, "the", `'cache'"`}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("the arguments are %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,148 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CtrlCMode decides what Ctrl+C does
+type CtrlCMode int
+
+const (
+	// CtrlCExit ends the prompt right away. It's the default.
+	CtrlCExit CtrlCMode = iota
+	// CtrlCConfirm ends the prompt when Ctrl+C is pressed again
+	// within CtrlCConfirmWindow. The first press shows a hint.
+	CtrlCConfirm
+	// CtrlCCancel cancels the context of the running command,
+	// the prompt keeps running. Use the exit command to quit.
+	CtrlCCancel
+)
+
+// CtrlCConfirmWindow is how long CtrlCConfirm waits for the second Ctrl+C
+const CtrlCConfirmWindow = 2 * time.Second
+
+const ctrlCHint = "Press Ctrl+C again to quit"
+
+// WithCtrlC sets what Ctrl+C does
+func WithCtrlC(mode CtrlCMode) Option {
+	return func(p *Prompt) error {
+		if mode < CtrlCExit || mode > CtrlCCancel {
+			return fmt.Errorf("unknown Ctrl+C mode %d", mode)
+		}
+		p.ctrlCMode = mode
+		return nil
+	}
+}
+
+// ctrlCState is what Ctrl+C needs to know about the prompt
+type ctrlCState struct {
+	mut     sync.Mutex
+	until   time.Time   // Until when a Ctrl+C confirms the exit
+	timer   *time.Timer // Clears the hint once the confirmation expires
+	running string      // Name of the running command
+	cancel  context.CancelFunc
+}
+
+// startExec remembers the command that runs now so Ctrl+C can cancel it
+func (p *Prompt) startExec(name string, cancel context.CancelFunc) {
+	p.ctrlC.mut.Lock()
+	defer p.ctrlC.mut.Unlock()
+	p.ctrlC.running = name
+	p.ctrlC.cancel = cancel
+}
+
+func (p *Prompt) endExec() {
+	p.ctrlC.mut.Lock()
+	defer p.ctrlC.mut.Unlock()
+	p.ctrlC.running = ""
+	p.ctrlC.cancel = nil
+}
+
+// onCtrlC is called when the user presses Ctrl+C
+func (p *Prompt) onCtrlC() {
+	// go-prompt cleared the input, the search ends with it
+	p.lockRender()
+	searching := p.endSearch()
+	p.unlockRender()
+	if searching || p.dropPasted() || p.dropContinued() {
+		return
+	}
+	switch p.ctrlCMode {
+	case CtrlCConfirm:
+		p.confirmExit()
+	case CtrlCCancel:
+		p.cancelExec()
+	default:
+		p.requestExit("ctrl+c", nil)
+	}
+}
+
+// interruptExec handles SIGINT while a command runs. go-prompt gives the
+// terminal back to the cooked mode during a command so Ctrl+C comes as
+// the signal then. It returns false if the prompt should end instead.
+func (p *Prompt) interruptExec() bool {
+	if p.ctrlCMode == CtrlCExit {
+		return false
+	}
+	p.ctrlC.mut.Lock()
+	running := p.ctrlC.cancel != nil
+	p.ctrlC.mut.Unlock()
+	if !running {
+		return false
+	}
+	p.onCtrlC()
+	return true
+}
+
+func (p *Prompt) confirmExit() {
+	p.ctrlC.mut.Lock()
+	now := time.Now()
+	if now.Before(p.ctrlC.until) {
+		p.ctrlC.timer.Stop()
+		p.ctrlC.mut.Unlock()
+		p.requestExit("ctrl+c", nil)
+		return
+	}
+
+	p.ctrlC.until = now.Add(CtrlCConfirmWindow)
+	if p.ctrlC.timer != nil {
+		p.ctrlC.timer.Stop()
+	}
+	p.ctrlC.timer = time.AfterFunc(CtrlCConfirmWindow, func() { p.clearInfo(ctrlCHint) })
+	p.ctrlC.mut.Unlock()
+
+	p.SetInfoln(ctrlCHint, InfoLineSeverityNormal)
+}
+
+func (p *Prompt) cancelExec() {
+	p.ctrlC.mut.Lock()
+	name, cancel := p.ctrlC.running, p.ctrlC.cancel
+	p.ctrlC.mut.Unlock()
+
+	if cancel == nil {
+		p.SetInfoln("No command is running", InfoLineSeverityNormal)
+		return
+	}
+	cancel()
+	p.logWith("cmd", name).Debugf("Command cancelled with Ctrl+C")
+	p.SetInfoln(fmt.Sprintf("Cancelled '%s'", name), InfoLineSeverityNormal)
+}
+
+// clearInfo clears the info row if it still shows text
+func (p *Prompt) clearInfo(text string) {
+	select {
+	case <-p.stopCh:
+		return
+	default:
+	}
+
+	p.lockRender()
+	same := p.infoText == text
+	p.unlockRender()
+	if same {
+		p.SetInfoln("", InfoLineSeverityNormal)
+	}
+}
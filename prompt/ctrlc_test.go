@@ -0,0 +1,138 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// exited reports whether something asked the prompt to end
+// or it's ending already
+func exited(p *Prompt) bool {
+	select {
+	case <-p.stopCh:
+		return true
+	default:
+		return len(p.exitCh) > 0
+	}
+}
+
+// TestCtrlCExit presses Ctrl+C in go-prompt's loop. The prompt ends
+// without an error, like it does by default.
+func TestCtrlCExit(t *testing.T) {
+	p, parser, _, _ := newTTYPrompt(t, nil)
+	events, cancel := p.Subscribe(PromptEventTypeExit)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	<-p.Ready()
+
+	parser.keys <- []byte{0x03}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run returned %v after Ctrl+C", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ctrl+C didn't end the prompt")
+	}
+	if e := <-events; e.Data.(ExitEvent).Reason != "ctrl+c" {
+		t.Fatalf("the prompt ended because of %q", e.Data.(ExitEvent).Reason)
+	}
+}
+
+// TestCtrlCConfirm drives the confirmation's state machine: the first
+// Ctrl+C shows the hint, which clears itself once the window is over,
+// and only a second Ctrl+C within the window ends the prompt
+func TestCtrlCConfirm(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithCtrlC(CtrlCConfirm))
+	startPrompt(t, p)
+	// expire ends the confirmation window now
+	expire := func() {
+		p.ctrlC.mut.Lock()
+		p.ctrlC.until = time.Now()
+		p.ctrlC.timer.Reset(0)
+		p.ctrlC.mut.Unlock()
+	}
+
+	p.onCtrlC()
+	if exited(p) || infoText(p) != ctrlCHint {
+		t.Fatalf("after the first Ctrl+C exited is %v and the info row %q", exited(p), infoText(p))
+	}
+	expire()
+	waitFor(t, "the hint to clear", func() bool { return infoText(p) == "" })
+
+	// Too late, it counts as the first one again
+	p.onCtrlC()
+	if exited(p) || infoText(p) != ctrlCHint {
+		t.Fatalf("after the window ended exited is %v and the info row %q", exited(p), infoText(p))
+	}
+	// A message shown since the hint isn't cleared with it
+	p.SetInfoln("deploying api", InfoLineSeverityNormal)
+	expire()
+	time.Sleep(20 * time.Millisecond)
+	if text := infoText(p); text != "deploying api" {
+		t.Fatalf("the expired hint cleared %q", text)
+	}
+
+	p.onCtrlC()
+	p.onCtrlC()
+	waitFor(t, "the second Ctrl+C within the window to end the prompt", func() bool { return exited(p) })
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Run returned %v", err)
+	}
+}
+
+// TestCtrlCCancel cancels the running command with Ctrl+C. The prompt
+// keeps running and takes the next command.
+func TestCtrlCCancel(t *testing.T) {
+	started := make(chan struct{}, 1)
+	result := make(chan error, 1)
+	wait := &ctxCmd{fakeCmd: &fakeCmd{name: "wait"}, runCtx: func(ctx context.Context, args cmd.Args) error {
+		started <- struct{}{}
+		select {
+		case <-ctx.Done():
+			result <- ctx.Err()
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+			result <- nil
+			return nil
+		}
+	}}
+	p, _ := newTestPrompt(t, []cmd.Cmd{wait}, "", WithCtrlC(CtrlCCancel))
+	startPrompt(t, p)
+
+	p.onCtrlC()
+	if exited(p) || infoText(p) != "No command is running" {
+		t.Fatalf("with no command exited is %v and the info row %q", exited(p), infoText(p))
+	}
+
+	for i := 0; i < 2; i++ {
+		go p.Execute("wait")
+		<-started
+		p.onCtrlC()
+		if err := <-result; !errors.Is(err, context.Canceled) {
+			t.Fatalf("the command returned %v, want it cancelled", err)
+		}
+		waitFor(t, "the command to end", func() bool {
+			p.ctrlC.mut.Lock()
+			defer p.ctrlC.mut.Unlock()
+			return p.ctrlC.cancel == nil
+		})
+		if exited(p) {
+			t.Fatal("Ctrl+C ended the prompt")
+		}
+		if text := infoText(p); text != "Cancelled 'wait'" {
+			t.Fatalf("the info row is %q", text)
+		}
+	}
+}
+
+func TestCtrlCInvalidMode(t *testing.T) {
+	if _, err := New(nil, WithCtrlC(CtrlCMode(7))); err == nil {
+		t.Fatal("New accepted an unknown Ctrl+C mode")
+	}
+}
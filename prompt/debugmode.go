@@ -0,0 +1,99 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"foundry/cli/logger"
+)
+
+// debugMode is turned on and off by the debug command. While it's on the
+// log level is debug and the messages of logger.Debugln are printed in the
+// output instead of over the prompt's rows on stdout.
+type debugMode struct {
+	mut   sync.Mutex
+	on    bool
+	level logger.Level // The level before debug on, set back by debug off
+}
+
+// debugSink passes the messages of logger.Debugln to the output
+type debugSink struct {
+	p *Prompt
+}
+
+func (s *debugSink) Write(b []byte) (int, error) {
+	return s.p.outBuf.Write(b)
+}
+
+func (d *debugMode) enable(p *Prompt) bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if d.on {
+		return false
+	}
+	d.on = true
+	d.level = logger.GetLevel()
+	logger.SetLevel(logger.LevelDebug)
+	logger.SetDebugSink(&debugSink{p: p})
+	return true
+}
+
+func (d *debugMode) disable() bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if !d.on {
+		return false
+	}
+	d.on = false
+	logger.SetDebugSink(nil)
+	// Unless the loglevel command changed the level meanwhile
+	if logger.GetLevel() == logger.LevelDebug {
+		logger.SetLevel(d.level)
+	}
+	return true
+}
+
+func (d *debugMode) enabled() bool {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	return d.on
+}
+
+func runDebug(p *Prompt, args []string) error {
+	if len(args) > 1 {
+		return errors.New("usage: debug [on|off]")
+	}
+	if len(args) == 0 {
+		state := "off"
+		if p.debug.enabled() {
+			state = "on"
+		}
+		p.SetInfoln(fmt.Sprintf("Debug messages are %s", state), InfoLineSeverityNormal)
+		return nil
+	}
+
+	switch args[0] {
+	case "on":
+		if !p.debug.enable(p) {
+			p.SetInfoln("Debug messages are on already", InfoLineSeverityNormal)
+			return nil
+		}
+		p.log.Debugf("Debug messages are printed in the output")
+		if !logger.DebugBuild() {
+			p.SetInfoln("Log level set to 'debug', but only debug builds print debug messages", InfoLineSeverityWarning)
+			return nil
+		}
+		p.SetInfoln("Debug messages on, they're printed in the output until 'debug off'", InfoLineSeverityNormal)
+	case "off":
+		if !p.debug.disable() {
+			p.SetInfoln("Debug messages are off already", InfoLineSeverityNormal)
+			return nil
+		}
+		p.log.Debugf("Debug messages aren't printed in the output anymore")
+		p.SetInfoln(fmt.Sprintf("Debug messages off, log level is '%s'", logger.GetLevel()), InfoLineSeverityNormal)
+	default:
+		return errors.New("usage: debug [on|off]")
+	}
+	return nil
+}
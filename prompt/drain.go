@@ -0,0 +1,122 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DefaultDrainTimeout is how long Stop() waits for pending output to be printed
+const DefaultDrainTimeout = time.Second * 2
+
+// How long the renderer waits for a chunk before it looks after the
+// activity mark and the idle events
+const renderIdleWait = 10 * time.Millisecond
+
+// consumeOne prints a single chunk from bufCh, waiting up to wait for one.
+// Returns false if there was nothing to print.
+func (p *Prompt) consumeOne(wait time.Duration) bool {
+	p.consumeMutex.Lock()
+	defer p.consumeMutex.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	// The output waits while the pager is open
+	if done := p.paging(); done != nil {
+		select {
+		case <-done:
+		case <-timer.C:
+		case <-p.stopCh:
+		}
+		return false
+	}
+	// and while the terminal is behind. It waits without renderMutex, the
+	// keys don't wait for the terminal.
+	p.term.waitBelow(termQueueLimit, p.stopCh)
+
+	select {
+	case c := <-p.bufCh:
+		p.print(c)
+		return true
+	case <-timer.C:
+		return false
+	case <-p.stopCh:
+		return false
+	}
+}
+
+// Drain prints all output that has been written so far. It returns
+// once there is nothing left to print or when ctx is done. Before
+// the initial render or while the pager is open it waits first.
+func (p *Prompt) Drain(ctx context.Context) error {
+	p.assertNotRenderLocked()
+
+	p.consumeMutex.Lock()
+	defer p.consumeMutex.Unlock()
+	return p.drainLocked(ctx)
+}
+
+// drainLocked is Drain with consumeMutex held
+func (p *Prompt) drainLocked(ctx context.Context) error {
+	// Nothing can be printed before the initial render
+	select {
+	case <-p.readyCh:
+	case <-p.initFailedCh:
+		return errors.New("the initial render failed")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	if done := p.paging(); done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	b := p.outBuf
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		p.term.waitBelow(termQueueLimit, ctx.Done())
+
+		b.mut.Lock()
+		if b.sending {
+			b.mut.Unlock()
+			// Read took a chunk out of the buffer that must be printed
+			// before the rest. It's sent once there's room in bufCh.
+			select {
+			case c := <-p.bufCh:
+				p.print(c)
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		// Read only starts sending a chunk with b.mut held, so bufCh
+		// can't get a new chunk while we look into the buffer
+		select {
+		case c := <-p.bufCh:
+			b.mut.Unlock()
+			p.print(c)
+			continue
+		default:
+		}
+
+		c, ok := b.next()
+		if ok {
+			b.signalSpace()
+			b.stats.delivered()
+		}
+		b.mut.Unlock()
+
+		if !ok {
+			return nil
+		}
+		p.print(c)
+	}
+}
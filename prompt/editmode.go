@@ -0,0 +1,216 @@
+package prompt
+
+import (
+	"fmt"
+	"unicode"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// EditMode decides how the input is edited
+type EditMode int
+
+const (
+	// EditEmacs uses go-prompt's emacs keys like Ctrl+A and Ctrl+E. It's the default.
+	EditEmacs EditMode = iota
+	// EditVi adds vi's normal mode. Escape switches to it, i, a, A, I, c
+	// and cw back to the insert mode. The normal mode moves with h, l, b,
+	// w, 0 and $ and deletes with x, dd, dw, D and C. The emacs keys stay.
+	EditVi
+)
+
+func (m EditMode) String() string {
+	switch m {
+	case EditEmacs:
+		return "emacs"
+	case EditVi:
+		return "vi"
+	default:
+		return fmt.Sprintf("editmode(%d)", int(m))
+	}
+}
+
+// viNormalMark is in front of the prefix while vi's normal mode is on
+const viNormalMark = "[N] "
+
+// WithEditMode sets how the input is edited, the set command changes it later
+func WithEditMode(mode EditMode) Option {
+	return func(p *Prompt) error {
+		if mode < EditEmacs || mode > EditVi {
+			return fmt.Errorf("unknown edit mode %d", mode)
+		}
+		p.editMode = mode
+		return nil
+	}
+}
+
+// viState is where vi's editing is. Guarded by renderMutex.
+type viState struct {
+	normal  bool
+	pending rune // 'd' or 'c' waiting for what to delete
+}
+
+// editModeOptions returns the go-prompt key binds of the edit modes. Every
+// printable character is bound, in the normal mode and during the history
+// search it isn't inserted.
+func (p *Prompt) editModeOptions() []goprompt.Option {
+	binds := make([]goprompt.ASCIICodeBind, 0, '~'-' '+1)
+	for c := byte(' '); c <= '~'; c++ {
+		r := rune(c)
+		binds = append(binds, goprompt.ASCIICodeBind{
+			ASCIICode: []byte{c},
+			Fn: func(buf *goprompt.Buffer) {
+				if !p.searchKey(buf, r) && !p.viKey(buf, r) {
+					buf.InsertText(string(r), false, true)
+				}
+			},
+		})
+	}
+	escape := goprompt.KeyBind{
+		Key: goprompt.Escape,
+		Fn: func(buf *goprompt.Buffer) {
+			if !p.cancelSearch(buf) {
+				p.viEscape(buf)
+			}
+		},
+	}
+	return []goprompt.Option{goprompt.OptionAddASCIICodeBind(binds...), goprompt.OptionAddKeyBind(escape)}
+}
+
+// viEscape switches to vi's normal mode
+func (p *Prompt) viEscape(buf *goprompt.Buffer) {
+	p.lockRender()
+	defer p.unlockRender()
+	if p.editMode != EditVi {
+		return
+	}
+	p.vi.pending = 0
+	if !p.vi.normal {
+		p.vi.normal = true
+		// Like in vi the cursor moves onto the last inserted character
+		buf.CursorLeft(1)
+	}
+}
+
+// viKey runs r as a command of vi's normal mode. It returns false if r
+// should be inserted instead.
+func (p *Prompt) viKey(buf *goprompt.Buffer, r rune) bool {
+	p.lockRender()
+	defer p.unlockRender()
+	if p.editMode != EditVi || !p.vi.normal {
+		return false
+	}
+
+	if pending := p.vi.pending; pending != 0 {
+		p.vi.pending = 0
+		switch {
+		case r == pending:
+			// dd and cc take the whole line
+			setInput(buf, "")
+		case r == 'w' && pending == 'c':
+			// cw changes to the end of the word, without the space after it
+			buf.Delete(wordEnd(buf) - cursorIndex(buf))
+		case r == 'w':
+			buf.Delete(nextWord(buf) - cursorIndex(buf))
+		case r == '$':
+			buf.Delete(len([]rune(buf.Document().TextAfterCursor())))
+		default:
+			return true
+		}
+		if pending == 'c' {
+			p.vi.normal = false
+		}
+		return true
+	}
+
+	switch r {
+	case 'h':
+		buf.CursorLeft(1)
+	case 'l':
+		buf.CursorRight(1)
+	case '0':
+		buf.CursorLeft(cursorIndex(buf))
+	case '$':
+		buf.CursorRight(len([]rune(buf.Document().TextAfterCursor())))
+	case 'w':
+		buf.CursorRight(nextWord(buf) - cursorIndex(buf))
+	case 'b':
+		buf.CursorLeft(cursorIndex(buf) - prevWord(buf))
+	case 'x':
+		buf.Delete(1)
+	case 'D':
+		buf.Delete(len([]rune(buf.Document().TextAfterCursor())))
+	case 'C':
+		buf.Delete(len([]rune(buf.Document().TextAfterCursor())))
+		p.vi.normal = false
+	case 'd', 'c':
+		p.vi.pending = r
+	case 'i':
+		p.vi.normal = false
+	case 'a':
+		buf.CursorRight(1)
+		p.vi.normal = false
+	case 'A':
+		buf.CursorRight(len([]rune(buf.Document().TextAfterCursor())))
+		p.vi.normal = false
+	case 'I':
+		buf.CursorLeft(cursorIndex(buf))
+		p.vi.normal = false
+	}
+	// Other characters do nothing in the normal mode
+	return true
+}
+
+// viPrefix returns prefix with the mark of vi's normal mode
+// if it's on. renderMutex must be held.
+func (p *Prompt) viPrefix(prefix string) string {
+	if p.editMode == EditVi && p.vi.normal {
+		return viNormalMark + prefix
+	}
+	return prefix
+}
+
+// cursorIndex returns the index of the rune under the cursor
+func cursorIndex(buf *goprompt.Buffer) int {
+	return len([]rune(buf.Document().TextBeforeCursor()))
+}
+
+// nextWord returns the index of the start of the next word, or the end
+func nextWord(buf *goprompt.Buffer) int {
+	text := []rune(buf.Text())
+	i := cursorIndex(buf)
+	for i < len(text) && !unicode.IsSpace(text[i]) {
+		i++
+	}
+	for i < len(text) && unicode.IsSpace(text[i]) {
+		i++
+	}
+	return i
+}
+
+// wordEnd returns the index right after the word under the cursor
+func wordEnd(buf *goprompt.Buffer) int {
+	text := []rune(buf.Text())
+	i := cursorIndex(buf)
+	for i < len(text) && unicode.IsSpace(text[i]) {
+		i++
+	}
+	for i < len(text) && !unicode.IsSpace(text[i]) {
+		i++
+	}
+	return i
+}
+
+// prevWord returns the index of the start of the word
+// before the cursor, or the start of the line
+func prevWord(buf *goprompt.Buffer) int {
+	text := []rune(buf.Text())
+	i := cursorIndex(buf)
+	for i > 0 && unicode.IsSpace(text[i-1]) {
+		i--
+	}
+	for i > 0 && !unicode.IsSpace(text[i-1]) {
+		i--
+	}
+	return i
+}
@@ -0,0 +1,130 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// pressKeys sends keys to the binds of editModeOptions like go-prompt
+// does, an escape to the escape's and other characters to theirs
+func pressKeys(p *Prompt, buf *goprompt.Buffer, keys string) {
+	for _, r := range keys {
+		if r == '\x1b' {
+			if !p.cancelSearch(buf) {
+				p.viEscape(buf)
+			}
+			continue
+		}
+		if !p.searchKey(buf, r) && !p.viKey(buf, r) {
+			buf.InsertText(string(r), false, true)
+		}
+	}
+}
+
+// TestViEditing drives the buffer through sequences of vi's keys and
+// checks the input, the cursor and the mode after them
+func TestViEditing(t *testing.T) {
+	tests := []struct {
+		name   string
+		keys   string
+		text   string
+		cursor int
+		normal bool
+	}{
+		{"escape", "deploy api\x1b", "deploy api", 9, true},
+		{"escape twice", "deploy api\x1b\x1b", "deploy api", 9, true},
+		{"start", "deploy api\x1b0", "deploy api", 0, true},
+		{"end", "deploy api\x1b0$", "deploy api", 10, true},
+		{"left and right", "deploy api\x1bhhhl", "deploy api", 7, true},
+		{"next word", "deploy api --force\x1b0ww", "deploy api --force", 11, true},
+		{"previous word", "deploy api --force\x1bbb", "deploy api --force", 7, true},
+		{"word at the end", "deploy\x1b0ww", "deploy", 6, true},
+		{"delete", "deploy api\x1bhhx", "deploy pi", 7, true},
+		{"delete the line", "deploy api\x1bdd", "", 0, true},
+		{"delete a word", "deploy api\x1b0dw", "api", 0, true},
+		{"delete to the end", "deploy api --force\x1b0wD", "deploy ", 7, true},
+		{"d$", "deploy api --force\x1b0wd$", "deploy ", 7, true},
+		{"change a word", "deploy api\x1b0cwlogs", "logs api", 4, false},
+		{"change a word then escape", "deploy api\x1b0wcwweb\x1b", "deploy web", 9, true},
+		{"change to the end", "deploy api --force\x1b0wCweb", "deploy web", 10, false},
+		{"change the line", "deploy api\x1bcclogs", "logs", 4, false},
+		{"insert", "deploy api\x1b0wiold-", "deploy old-api", 11, false},
+		{"append", "deploy ap\x1bai", "deploy api", 10, false},
+		{"append at the end", "deploy api\x1b0A --force", "deploy api --force", 18, false},
+		{"insert at the start", "api\x1bIdeploy ", "deploy api", 7, false},
+		{"unknown after d", "deploy\x1bdq", "deploy", 5, true},
+		{"unknown", "deploy\x1bz", "deploy", 5, true},
+		{"escape cancels d", "deploy\x1bd\x1bx", "deplo", 5, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "", WithEditMode(EditVi))
+			buf := goprompt.NewBuffer()
+			pressKeys(p, buf, tt.keys)
+
+			if text, cursor := buf.Text(), cursorIndex(buf); text != tt.text || cursor != tt.cursor {
+				t.Errorf("the input is %q with the cursor at %d, want %q at %d", text, cursor, tt.text, tt.cursor)
+			}
+			p.lockRender()
+			normal, prefix := p.vi.normal, p.currentPrefix()
+			p.unlockRender()
+			if normal != tt.normal {
+				t.Errorf("the normal mode is %v, want %v", normal, tt.normal)
+			}
+			if hasMark := strings.HasPrefix(prefix, viNormalMark); hasMark != tt.normal {
+				t.Errorf("the prefix is %q", prefix)
+			}
+		})
+	}
+}
+
+// TestEmacsEditing presses the same keys in the emacs mode. Escape
+// does nothing and vi's keys are inserted.
+func TestEmacsEditing(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	buf := goprompt.NewBuffer()
+	pressKeys(p, buf, "deploy\x1b0dw")
+	if text, cursor := buf.Text(), cursorIndex(buf); text != "deploy0dw" || cursor != 9 {
+		t.Fatalf("the input is %q with the cursor at %d", text, cursor)
+	}
+	p.lockRender()
+	prefix := p.currentPrefix()
+	p.unlockRender()
+	if prefix != "> " {
+		t.Fatalf("the prefix is %q", prefix)
+	}
+}
+
+// TestEditModeInGoPrompt edits with the emacs keys of go-prompt's loop,
+// switches to vi with the set command and edits with vi's keys. The
+// inserted '|' shows where the cursor was.
+func TestEditModeInGoPrompt(t *testing.T) {
+	p, parser, _, _ := newTTYPrompt(t, nil)
+	go p.Run()
+	<-p.Ready()
+	press := func(keys ...string) {
+		for _, k := range keys {
+			parser.keys <- []byte(k)
+		}
+	}
+
+	// Ctrl+A, Ctrl+E, Ctrl+W
+	press("deploy api", "\x01", "|", "\x05", "\x17", "web|")
+	waitFor(t, "the emacs editing", func() bool { return p.input() == "|deploy web|" })
+	press("\x15")
+	waitFor(t, "the cleared input", func() bool { return p.input() == "" })
+
+	if err := p.Execute("set editmode vi"); err != nil {
+		t.Fatalf("set editmode vi: %s", err)
+	}
+	press("deploy api --force", "\x1b")
+	waitFor(t, "the normal mode", func() bool {
+		p.lockRender()
+		defer p.unlockRender()
+		return p.vi.normal
+	})
+	press("b", "b", "d", "w", "i", "|")
+	waitFor(t, "the vi editing", func() bool { return p.input() == "deploy |--force" })
+}
@@ -0,0 +1,71 @@
+package prompt
+
+// escapeState tracks where in a terminal escape code the printed text is.
+// The escape codes don't take any space on the screen.
+type escapeState int
+
+const (
+	escNone   escapeState = iota
+	escIntro              // After ESC
+	escCSI                // Control sequence, "ESC [" ... final byte
+	escOSC                // Operating system command, "ESC ]" ... BEL or "ESC \"
+	escOSCEnd             // After ESC inside of an OSC, '\' ends the OSC
+)
+
+// next returns the state after the rune r.
+// escIntro is returned each time a new escape code starts.
+func (e escapeState) next(r rune) escapeState {
+	if r == '\u001b' {
+		if e == escOSC {
+			return escOSCEnd
+		}
+		return escIntro
+	}
+
+	switch e {
+	case escIntro:
+		switch r {
+		case '[':
+			return escCSI
+		case ']':
+			return escOSC
+		}
+		// Two character escape code
+		return escNone
+	case escCSI:
+		// Final byte of a control sequence
+		if r >= 0x40 && r <= 0x7e {
+			return escNone
+		}
+		return escCSI
+	case escOSC:
+		if r == '\a' {
+			return escNone
+		}
+		return escOSC
+	}
+	// escOSCEnd is finished by '\', anything else is malformed and ends it too
+	return escNone
+}
+
+// StripANSI returns b without the terminal escape codes, the colors, the
+// cursor moves and the links, e.g. for output that goes to a file
+func StripANSI(b []byte) []byte {
+	var esc escapeState
+	return stripANSI(nil, b, &esc)
+}
+
+// stripANSI appends b without the escape codes to out, out can be b[:0].
+// esc is where the previous call ended, a code split between two writes is
+// removed as a whole.
+func stripANSI(out, b []byte, esc *escapeState) []byte {
+	for _, c := range b {
+		// Escape codes are ASCII, the bytes of other characters are kept as they are
+		if *esc != escNone || c == 0x1b {
+			*esc = esc.next(rune(c))
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package prompt
+
+import "testing"
+
+// TestStripANSISplit strips output cut into two writes at every byte, in
+// the middle of the escape codes too, and byte by byte. The code is
+// removed as a whole whatever the cut.
+func TestStripANSISplit(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"color", "\x1b[1;32mdeployed\x1b[0m api", "deployed api"},
+		{"cursor move", "50%\x1b[2K\x1b[10;1H100%", "50%100%"},
+		{"two character code", "a\x1bcb\x1b7c", "abc"},
+		{"link", "see \x1b]8;;https://example.com\x1b\\docs\x1b]8;;\x1b\\ now", "see docs now"},
+		{"title ended by BEL", "\x1b]0;foundry\x07✓ done", "✓ done"},
+		{"private mode", "\x1b[?25l\x1b[?2004hdeploy\x1b[?25h", "deploy"},
+		{"wide characters", "本番\x1b[31m🚀\x1b[0m", "本番🚀"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(StripANSI([]byte(tt.in))); got != tt.want {
+				t.Fatalf("StripANSI(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+			for i := 1; i < len(tt.in); i++ {
+				var esc escapeState
+				out := stripANSI(nil, []byte(tt.in[:i]), &esc)
+				out = stripANSI(out, []byte(tt.in[i:]), &esc)
+				if string(out) != tt.want || esc != escNone {
+					t.Fatalf("cut after %q it's %q, want %q", tt.in[:i], out, tt.want)
+				}
+			}
+			var esc escapeState
+			var out []byte
+			for i := 0; i < len(tt.in); i++ {
+				out = stripANSI(out, []byte{tt.in[i]}, &esc)
+			}
+			if string(out) != tt.want {
+				t.Fatalf("byte by byte it's %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package prompt
+
+import "time"
+
+const (
+	// The terminal was resized, Data is a ResizeEvent
+	PromptEventTypeResize PromptEventType = "resize"
+	// A command finished, Data is a CommandEvent
+	PromptEventTypeCommand PromptEventType = "command"
+	// A chunk of output was rendered, Data is an OutputEvent
+	PromptEventTypeOutput PromptEventType = "output"
+	// The prompt is shutting down, Data is an ExitEvent
+	PromptEventTypeExit PromptEventType = "exit"
+	// Output started to be printed after the output was idle, Data is nil
+	PromptEventTypeBusy PromptEventType = "busy"
+	// All the output written so far is printed and flushed, Data is nil
+	PromptEventTypeIdle PromptEventType = "idle"
+)
+
+// RerenderEvent is the Data of the rerender events
+type RerenderEvent struct {
+	Rows, Cols int
+	Initial    bool // The first render when the prompt starts
+}
+
+// ResizeEvent is the Data of the resize events
+type ResizeEvent struct {
+	Rows, Cols int
+}
+
+// CommandEvent is the Data of the command events
+type CommandEvent struct {
+	Name     string
+	Args     []string
+	Duration time.Duration
+	Err      error // Returned by the command, nil when it succeeded
+}
+
+// OutputEvent is the Data of the output events
+type OutputEvent struct {
+	Bytes int // Rendered bytes, including escape codes
+}
+
+// ExitEvent is the Data of the exit events
+type ExitEvent struct {
+	Reason string // e.g. "ctrl+c", "signal terminated" or "context canceled"
+}
+
+func NewRerenderEvent(rows, cols int, initial bool) PromptEvent {
+	return PromptEvent{Type: PromptEventTypeRerender, Data: RerenderEvent{rows, cols, initial}}
+}
+
+func NewResizeEvent(rows, cols int) PromptEvent {
+	return PromptEvent{Type: PromptEventTypeResize, Data: ResizeEvent{rows, cols}}
+}
+
+func NewCommandEvent(name string, args []string, d time.Duration, err error) PromptEvent {
+	return PromptEvent{Type: PromptEventTypeCommand, Data: CommandEvent{name, args, d, err}}
+}
+
+func NewOutputEvent(bytes int) PromptEvent {
+	return PromptEvent{Type: PromptEventTypeOutput, Data: OutputEvent{bytes}}
+}
+
+func NewExitEvent(reason string) PromptEvent {
+	return PromptEvent{Type: PromptEventTypeExit, Data: ExitEvent{reason}}
+}
+
+func NewBusyEvent() PromptEvent {
+	return PromptEvent{Type: PromptEventTypeBusy}
+}
+
+func NewIdleEvent() PromptEvent {
+	return PromptEvent{Type: PromptEventTypeIdle}
+}
@@ -0,0 +1,111 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// statusBar is an example consumer of the events. It keeps a summary of
+// the session that a program could show in the info row.
+type statusBar struct {
+	mut         sync.Mutex
+	rows, cols  int
+	commands    int
+	failed      int
+	last        string
+	outputBytes int
+	exitReason  string
+}
+
+// update applies e and reports whether the text of the bar changed
+func (s *statusBar) update(e PromptEvent) bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	switch d := e.Data.(type) {
+	case RerenderEvent:
+		changed := d.Rows != s.rows || d.Cols != s.cols
+		s.rows, s.cols = d.Rows, d.Cols
+		return changed
+	case ResizeEvent:
+		s.rows, s.cols = d.Rows, d.Cols
+		return true
+	case CommandEvent:
+		s.commands++
+		s.last = d.Name
+		if d.Err != nil {
+			s.failed++
+			s.last += " (" + d.Err.Error() + ")"
+		}
+		return true
+	case OutputEvent:
+		s.outputBytes += d.Bytes
+	case ExitEvent:
+		s.exitReason = d.Reason
+	}
+	return false
+}
+
+func (s *statusBar) String() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return fmt.Sprintf("%dx%d | %d commands, %d failed | last: %s", s.rows, s.cols, s.commands, s.failed, s.last)
+}
+
+// printed returns the number of rendered bytes
+func (s *statusBar) printed() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.outputBytes
+}
+
+// TestStatusBarFromEvents drives a status bar off the events of a session
+// with a command that succeeds and one that fails
+func TestStatusBarFromEvents(t *testing.T) {
+	var p *Prompt
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		if len(args) == 0 {
+			return errors.New("no function given")
+		}
+		_, err := p.Writeln("deployed " + strings.Join(args, " ") + "\n")
+		return err
+	}}
+	p, out := newTestPrompt(t, []cmd.Cmd{deploy}, "deploy api\rdeploy\r")
+
+	// Subscribed before the prompt starts, no event is missed
+	events, cancel := p.Subscribe()
+	bar := &statusBar{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for e := range events {
+			if bar.update(e) {
+				p.SetInfoln(bar.String(), InfoLineSeverityNormal)
+			}
+		}
+	}()
+	startPrompt(t, p)
+
+	want := "24x80 | 2 commands, 1 failed | last: deploy (no function given)"
+	waitFor(t, "the status bar", func() bool { return infoText(p) == want })
+	waitFor(t, "the output", func() bool {
+		return strings.Contains(out.String(), "deployed api") && bar.printed() > 0
+	})
+
+	p.Stop()
+	if err := p.Wait(); err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+	cancel()
+	<-done
+	if bar.exitReason != "stopped" {
+		t.Errorf("the exit reason is %q, want stopped", bar.exitReason)
+	}
+	if !strings.Contains(out.String(), want) {
+		t.Errorf("the status bar wasn't rendered: %q", out.String())
+	}
+}
@@ -0,0 +1,43 @@
+package prompt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync/atomic"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+)
+
+var (
+	// Distinguishes the ids of sessions that log to the same file
+	sessionID = newSessionID()
+	execCount int64
+)
+
+func newSessionID() string {
+	b := make([]byte, 2)
+	if _, err := rand.Read(b); err != nil {
+		return "0000"
+	}
+	return hex.EncodeToString(b)
+}
+
+// newExecContext returns the context of a single command execution. Its id is
+// unique within the process, e.g. "3fa2-12", and is added to all the messages
+// logged with logger.Ctx(ctx), so grepping the log for it finds only them.
+func newExecContext() (ctx context.Context, id string) {
+	id = fmt.Sprintf("%s-%d", sessionID, atomic.AddInt64(&execCount, 1))
+	ctx = logger.WithContext(context.Background(), "exec", id)
+	return cmd.WithExecID(ctx, id), id
+}
+
+// runRequest passes the execution context to the commands that want it
+func runRequest(ctx context.Context, c cmd.Cmd, args cmd.Args) error {
+	if r, ok := c.(cmd.ContextRunner); ok {
+		return r.RunRequestContext(ctx, args)
+	}
+	return c.RunRequest(args)
+}
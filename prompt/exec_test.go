@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+)
+
+// ctxCmd is a fakeCmd that gets the context of its execution
+type ctxCmd struct {
+	*fakeCmd
+	runCtx func(ctx context.Context, args cmd.Args) error
+}
+
+func (c *ctxCmd) RunRequestContext(ctx context.Context, args cmd.Args) error {
+	return c.runCtx(ctx, args)
+}
+
+func TestExecIDsAreUnique(t *testing.T) {
+	const goroutines, execs = 8, 200
+	ids := make(chan string, goroutines*execs)
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < execs; i++ {
+				ctx, id := newExecContext()
+				if got := cmd.ExecID(ctx); got != id {
+					t.Errorf("the context carries the id %q, want %q", got, id)
+				}
+				ids <- id
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Fatalf("the id %q was given twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestExecIDInNestedGoroutines runs a command that logs from a goroutine
+// started by another goroutine. Each message has the id of its execution.
+func TestExecIDInNestedGoroutines(t *testing.T) {
+	c := logger.CaptureForTest(t)
+	var mut sync.Mutex
+	var ids []string
+	job := &ctxCmd{fakeCmd: &fakeCmd{name: "job"}, runCtx: func(ctx context.Context, args cmd.Args) error {
+		mut.Lock()
+		ids = append(ids, cmd.ExecID(ctx))
+		mut.Unlock()
+
+		done := make(chan struct{})
+		go func() {
+			go func() {
+				logger.Ctx(ctx).Info("nested " + args[0])
+				close(done)
+			}()
+		}()
+		<-done
+		return nil
+	}}
+	p, _ := newTestPrompt(t, []cmd.Cmd{job}, "")
+	for _, arg := range []string{"a", "b", "c"} {
+		if err := p.Execute("job " + arg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(ids) != 3 || ids[0] == ids[1] || ids[1] == ids[2] || ids[0] == ids[2] {
+		t.Fatalf("the executions got the ids %q", ids)
+	}
+	for i, arg := range []string{"a", "b", "c"} {
+		if want := "nested " + arg + " exec=" + ids[i]; !c.Contains(want) {
+			t.Errorf("%q wasn't logged, the messages are %+v", want, c.Entries())
+		}
+	}
+}
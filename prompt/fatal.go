@@ -0,0 +1,102 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"foundry/cli/logger"
+)
+
+// restoreTerminal puts the terminal back to the state the shell expects.
+// It's used when the prompt dies, usually in the middle of a render with
+// renderMutex held, so it doesn't take the lock or use the writer.
+func (p *Prompt) restoreTerminal() {
+	if atomic.LoadInt32(&p.plain) == 1 {
+		return
+	}
+	// The last drawing comes first
+	p.term.drain(time.Second)
+	// Reset colors, show the cursor and move it below the prompt row
+	fmt.Fprint(p.termOut, "\x1b[0m\x1b[?25h")
+	p.bracketedPaste(false)
+	if p.promptRow > 0 {
+		fmt.Fprintf(p.termOut, "\x1b[%d;1H\n", p.promptRow)
+	}
+
+	// Back to the cooked mode so the shell echoes again
+	if p.parser != nil {
+		p.parser.TearDown()
+	}
+}
+
+// The logger's fatal handler is global. A prompt that stops only
+// removes it if no prompt that ran after it set its own.
+var (
+	fatalMutex  sync.Mutex
+	fatalPrompt *Prompt
+)
+
+func (p *Prompt) setFatalHandler() {
+	fatalMutex.Lock()
+	defer fatalMutex.Unlock()
+	fatalPrompt = p
+	logger.SetFatalHandler(p.fatal)
+}
+
+func (p *Prompt) clearFatalHandler() {
+	fatalMutex.Lock()
+	defer fatalMutex.Unlock()
+	if fatalPrompt == p {
+		fatalPrompt = nil
+		logger.SetFatalHandler(nil)
+	}
+}
+
+// fatal is the logger's fatal handler while the prompt runs. The program's
+// code calls logger.FatalLogln, which exits once the handler returns.
+func (p *Prompt) fatal(msg string) {
+	p.restoreTerminal()
+	fmt.Fprintln(os.Stderr, msg)
+	p.history.flush()
+	logger.Close()
+}
+
+// logPanic writes the panic value and the stack to the log
+func (p *Prompt) logPanic(where string, r interface{}) {
+	p.logWith("where", where, "panic", r).Errorf("Recovered from a panic")
+	p.log.Errorf("Stack of the panic:\n%s", debug.Stack())
+}
+
+// recoverGoroutine must be deferred at the start of the prompt's goroutines.
+// A panic makes Run restore the terminal and return an error instead of the
+// program crashing in raw mode with the stack trace scattered around the screen.
+func (p *Prompt) recoverGoroutine(where string) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	p.logPanic(where, r)
+
+	// The log is closed by the time the caller sees the error
+	if path := logger.FilePath(); path != "" {
+		p.requestExit("panic", fmt.Errorf("internal error in %s, details in %s", where, path))
+	} else {
+		p.requestExit("panic", fmt.Errorf("internal error in %s: %v\n%s", where, r, debug.Stack()))
+	}
+}
+
+// safeRun calls fn and turns its panic into an error so a broken
+// command doesn't take the whole prompt down
+func (p *Prompt) safeRun(where string, fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.logPanic(where, r)
+			err = fmt.Errorf("internal error in '%s': %v", where, r)
+		}
+	}()
+	return fn()
+}
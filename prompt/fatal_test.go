@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+)
+
+// TestPanickingCommand runs a command that panics. The prompt must show
+// the error and keep running the next lines.
+func TestPanickingCommand(t *testing.T) {
+	c := logger.CaptureForTest(t)
+	crash := &fakeCmd{name: "crash", run: func(cmd.Args) error {
+		var m map[string]int
+		m["api"]++
+		return nil
+	}}
+	var p *Prompt
+	deploy := &fakeCmd{name: "deploy", run: func(cmd.Args) error {
+		_, err := p.Writeln("deployed\n")
+		return err
+	}}
+	p, out := newTestPrompt(t, []cmd.Cmd{crash, deploy}, "crash now\rdeploy\r")
+	startPrompt(t, p)
+
+	waitFor(t, "the deploy after the panic", func() bool {
+		return strings.Contains(out.String(), "deployed\n")
+	})
+	screen := out.String()
+	if i := strings.Index(screen, "internal error in 'crash': assignment to entry in nil map"); i < 0 || i > strings.Index(screen, "deployed\n") {
+		t.Fatalf("the panic wasn't shown before the next command ran: %q", screen)
+	}
+	select {
+	case <-p.doneCh:
+		t.Fatalf("the prompt ended with %v", p.runErr)
+	default:
+	}
+	if !c.Contains("Recovered from a panic") || !c.Contains("Stack of the panic") {
+		t.Errorf("the panic wasn't logged: %+v", c.Entries())
+	}
+}
+
+// panicWriter panics once it gets a line with boom
+type panicWriter struct{ *syncBuffer }
+
+func (w panicWriter) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), "boom") {
+		panic("the terminal exploded")
+	}
+	return w.syncBuffer.Write(b)
+}
+
+// TestPanickingRenderer panics in the goroutine rendering the output.
+// Run must restore the terminal and return an error instead of the
+// panic crashing the program.
+func TestPanickingRenderer(t *testing.T) {
+	logger.CaptureForTest(t)
+	p, parser, _, term := newTTYPrompt(t, nil, WithConsoleWriter(&ioWriter{w: panicWriter{&syncBuffer{}}}))
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	<-p.Ready()
+
+	p.Writeln("boom\n")
+	var err error
+	select {
+	case err = <-errCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't return after the panic")
+	}
+	if err == nil || !strings.Contains(err.Error(), "internal error in output renderer") {
+		t.Fatalf("Run returned %v, want the internal error", err)
+	}
+	var sigErr *SignalError
+	if errors.As(err, &sigErr) {
+		t.Fatalf("Run returned a SignalError: %v", err)
+	}
+	if restored := term.String(); !strings.Contains(restored, "\x1b[0m\x1b[?25h") {
+		t.Errorf("the terminal wasn't restored: %q", restored)
+	}
+	if parser.isRaw() {
+		t.Error("the terminal was left in the raw mode")
+	}
+}
@@ -0,0 +1,69 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"syscall"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// terminalGone reports whether err from reading or writing the terminal
+// means that it isn't there anymore, e.g. the ssh connection dropped or
+// the terminal window was closed
+func terminalGone(err error) bool {
+	return errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrClosedPipe) ||
+		errors.Is(err, syscall.EIO) ||
+		errors.Is(err, syscall.ENXIO) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, syscall.EBADF)
+}
+
+// lostTerminal ends the prompt once the terminal is gone. There's nobody
+// to show an error to so Run returns nil like after Stop. Nothing is
+// written to the terminal from now on.
+func (p *Prompt) lostTerminal(err error) {
+	if !atomic.CompareAndSwapInt32(&p.termLost, 0, 1) {
+		return
+	}
+	if err != nil {
+		p.logWith("err", err).Debugf("The terminal is gone, shutting down")
+	} else {
+		p.log.Debugf("The terminal hung up, shutting down")
+	}
+	p.requestExit("terminal lost", nil)
+}
+
+// ttyParser watches go-prompt's terminal parser for the terminal going away
+type ttyParser struct {
+	goprompt.ConsoleParser
+	p *Prompt
+}
+
+func (t *ttyParser) Read() ([]byte, error) {
+	b, err := t.ConsoleParser.Read()
+	// The terminal is read in the non-blocking mode, no input is EAGAIN
+	// and reading nothing without an error is the end of the input
+	if err == nil && len(b) == 0 {
+		err = io.EOF
+	}
+	if err != nil && terminalGone(err) {
+		t.p.lostTerminal(fmt.Errorf("reading the terminal failed: %w", err))
+	}
+	return b, err
+}
+
+func (t *ttyParser) GetWinSize() (size *goprompt.WinSize) {
+	defer func() {
+		if r := recover(); r != nil {
+			// go-prompt panics when it can't get the size. Any size does,
+			// nothing is shown without the terminal.
+			t.p.lostTerminal(fmt.Errorf("getting the terminal size failed: %v", r))
+			size = &goprompt.WinSize{Row: defaultReaderRows, Col: defaultReaderCols}
+		}
+	}()
+	return t.p.checkSize(t.ConsoleParser.GetWinSize())
+}
@@ -0,0 +1,62 @@
+package prompt
+
+import (
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// SetHeaderln shows s on the first row of the terminal. The header stays
+// there while the output scrolls below it, e.g. to show which environment
+// the session is connected to. An empty s removes the header.
+func (p *Prompt) SetHeaderln(s string) error {
+	p.lockRender()
+	defer p.unlockRender()
+
+	had := p.headerText != ""
+	p.headerText = strings.TrimSpace(s)
+	p.log.Debugf("Header text: %s", p.headerText)
+	if p.totalRows == 0 {
+		// The initial render draws it
+		return nil
+	}
+
+	if p.headerText == "" {
+		if had {
+			p.writer.CursorGoTo(1, 1)
+			p.writer.EraseLine()
+		}
+	} else {
+		if start := p.outputStart(); p.currentPos.Row < start.Row {
+			// The output didn't get past the first row yet, it continues below the header
+			p.currentPos = start
+			p.savedPos = start
+			p.freeRows = p.totalRows - (start.Row - 1)
+		}
+		p.renderHeader()
+	}
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	return p.flush()
+}
+
+// outputStart returns where the output starts, below the header if there
+// is one. renderMutex must be held.
+func (p *Prompt) outputStart() CursorPos {
+	start := CursorOutputStart()
+	if p.headerText != "" {
+		start.Row++
+	}
+	return start
+}
+
+// renderHeader draws the header on the first row. renderMutex must be held.
+func (p *Prompt) renderHeader() {
+	if p.headerText == "" {
+		return
+	}
+	p.writer.CursorGoTo(1, 1)
+	p.writer.EraseLine()
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, true)
+	p.writer.WriteRawStr(clip(p.headerText, p.totalColumns))
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+}
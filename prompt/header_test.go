@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestHeaderLayout checks where the output starts and how many rows it
+// has before it scrolls, with and without a header
+func TestHeaderLayout(t *testing.T) {
+	tests := []struct {
+		name      string
+		rows      int
+		header    string
+		margin    int
+		start     CursorPos
+		free      int
+		threshold int
+	}{
+		{name: "no header", rows: 24, start: CursorPos{1, 1}, free: 24, threshold: 2},
+		{name: "header", rows: 24, header: "prod", start: CursorPos{2, 1}, free: 23, threshold: 2},
+		{name: "margin", rows: 24, margin: 5, start: CursorPos{1, 1}, free: 24, threshold: 7},
+		{name: "header and margin", rows: 24, header: "prod", margin: 5, start: CursorPos{2, 1}, free: 23, threshold: 7},
+		// The margin leaves one row for the output and one for the cursor
+		{name: "margin too big", rows: 24, margin: 50, start: CursorPos{1, 1}, free: 24, threshold: 22},
+		{name: "header and margin too big", rows: 24, header: "prod", margin: 50, start: CursorPos{2, 1}, free: 23, threshold: 21},
+		{name: "tiny with header", rows: 5, header: "prod", margin: 5, start: CursorPos{2, 1}, free: 4, threshold: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "", WithTerminalSize(uint16(tt.rows), 80), WithScrollMargin(tt.margin))
+			p.SetHeaderln(tt.header)
+			if _, _, err := p.rerenderLocked(true); err != nil {
+				t.Fatalf("the initial render failed: %s", err)
+			}
+
+			p.lockRender()
+			defer p.unlockRender()
+			if start := p.outputStart(); start != tt.start || p.currentPos != tt.start {
+				t.Errorf("the output starts at %v with the cursor at %v, want %v", start, p.currentPos, tt.start)
+			}
+			if p.freeRows != tt.free {
+				t.Errorf("%d free rows, want %d", p.freeRows, tt.free)
+			}
+			if threshold := p.scrollThreshold(); threshold != tt.threshold {
+				t.Errorf("the output scrolls at %d free rows, want %d", threshold, tt.threshold)
+			}
+			if p.promptRow != tt.rows || p.infoRow != tt.rows-1 {
+				t.Errorf("the info row is %d and the prompt row %d", p.infoRow, p.promptRow)
+			}
+		})
+	}
+}
+
+// TestHeaderStaysWhileScrolling prints more lines than the terminal has
+// rows. The header stays on the first row and the output scrolls below it.
+func TestHeaderStaysWhileScrolling(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithScreenSnapshot())
+	p.SetHeaderln("connected to prod")
+	startPrompt(t, p)
+	for i := 1; i <= 40; i++ {
+		p.Writeln(fmt.Sprintf("line %d\n", i))
+	}
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+
+	rows := strings.Split(p.Snapshot(), "\n")
+	if rows[0] != "connected to prod" {
+		t.Fatalf("the first row is %q", rows[0])
+	}
+	// 20 output rows below the header, the free row, the info row and the prompt
+	for i, row := range rows[1:21] {
+		if want := fmt.Sprintf("line %d", 21+i); row != want {
+			t.Errorf("row %d is %q, want %q", i+2, row, want)
+		}
+	}
+	if rows[23] != ">" {
+		t.Errorf("the prompt row is %q", rows[23])
+	}
+}
+
+// TestHeaderSetAfterStart sets the header once the prompt runs. The output
+// that didn't start yet moves below it, removing it clears the first row.
+func TestHeaderSetAfterStart(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithScreenSnapshot())
+	startPrompt(t, p)
+
+	p.SetHeaderln("connected to prod")
+	p.lockRender()
+	pos, free := p.currentPos, p.freeRows
+	p.unlockRender()
+	if pos != (CursorPos{2, 1}) || free != 23 {
+		t.Fatalf("the cursor is at %v with %d free rows, want row 2 and 23", pos, free)
+	}
+
+	p.Writeln("line 1\n")
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	p.SetHeaderln("")
+	rows := strings.Split(p.Snapshot(), "\n")
+	if rows[0] != "" || rows[1] != "line 1" {
+		t.Fatalf("the first rows are %q", rows[:2])
+	}
+}
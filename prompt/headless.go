@@ -0,0 +1,141 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// Headless is an Interface without a terminal. The output is kept in memory
+// as it was written and the info row as plain text, the lines run by Exec
+// are recorded. It runs registered commands like the prompt but
+// not its built-in ones. It's safe for use by more goroutines.
+type Headless struct {
+	mut          sync.Mutex
+	cmds         []cmd.Cmd
+	out          bytes.Buffer
+	info         string
+	infoSeverity InfoLineSeverity
+	executed     []string
+	stopped      bool
+
+	subs subscribers
+}
+
+// NewHeadless returns a Headless running cmds
+func NewHeadless(cmds ...cmd.Cmd) *Headless {
+	return &Headless{cmds: cmds}
+}
+
+func (h *Headless) Writeln(s string) (n int, err error) {
+	h.mut.Lock()
+	h.out.WriteString(s)
+	h.mut.Unlock()
+	h.subs.emit(NewOutputEvent(len(s)))
+	return len(s), nil
+}
+
+func (h *Headless) Writef(format string, args ...interface{}) (n int, err error) {
+	return h.Writeln(fmt.Sprintf(format, args...))
+}
+
+func (h *Headless) SetInfoln(s string, severity InfoLineSeverity) error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.info = strings.TrimSpace(s)
+	h.infoSeverity = severity
+	return nil
+}
+
+func (h *Headless) SetErrorln(s string) error {
+	return h.SetInfoln(s, InfoLineSeverityError)
+}
+
+// ClearOutput forgets the output written so far, like the screen erases it
+func (h *Headless) ClearOutput() error {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.out.Reset()
+	return nil
+}
+
+// Exec records s and runs the registered command it names. A failed or
+// unknown command is shown on the info row and returned like by the prompt.
+func (h *Headless) Exec(s string) error {
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil
+	}
+	h.mut.Lock()
+	h.executed = append(h.executed, s)
+	var c cmd.Cmd
+	for _, hc := range h.cmds {
+		if hc.Name() == fields[0] {
+			c = hc
+		}
+	}
+	h.mut.Unlock()
+
+	if c == nil {
+		msg := fmt.Sprintf("Unknown command '%s'", fields[0])
+		h.SetInfoln(msg, InfoLineSeverityNormal)
+		return &notFoundError{msg: msg}
+	}
+	start := time.Now()
+	err := c.RunRequest(fields[1:])
+	h.subs.emit(NewCommandEvent(c.Name(), fields[1:], time.Since(start), err))
+	if err != nil {
+		h.SetInfoln(err.Error(), InfoLineSeverityError)
+	}
+	return err
+}
+
+func (h *Headless) Subscribe(types ...PromptEventType) (<-chan PromptEvent, func()) {
+	return h.subs.subscribe(types)
+}
+
+// Stop only records that it was called
+func (h *Headless) Stop() {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.stopped = true
+}
+
+// Output returns the output written since the last ClearOutput
+func (h *Headless) Output() string {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.out.String()
+}
+
+// OutputContains returns true if the output contains s
+func (h *Headless) OutputContains(s string) bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return bytes.Contains(h.out.Bytes(), []byte(s))
+}
+
+// LastInfo returns the text on the info row and its severity
+func (h *Headless) LastInfo() (string, InfoLineSeverity) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.info, h.infoSeverity
+}
+
+// Executed returns the lines run by Exec, oldest first
+func (h *Headless) Executed() []string {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return append([]string(nil), h.executed...)
+}
+
+// Stopped returns true once Stop was called
+func (h *Headless) Stopped() bool {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return h.stopped
+}
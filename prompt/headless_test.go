@@ -0,0 +1,96 @@
+package prompt
+
+import (
+	"errors"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+func TestHeadlessOutput(t *testing.T) {
+	h := NewHeadless()
+	h.Writeln("deploying\n")
+	h.Writef("%d functions deployed\n", 3)
+
+	for _, s := range []string{"deploying\n", "3 functions deployed"} {
+		if !h.OutputContains(s) {
+			t.Errorf("the output %q doesn't contain %q", h.Output(), s)
+		}
+	}
+	if h.OutputContains("failed") {
+		t.Errorf("the output %q contains text never written", h.Output())
+	}
+
+	h.ClearOutput()
+	if h.OutputContains("deploying") || h.Output() != "" {
+		t.Fatalf("the output %q stayed after ClearOutput", h.Output())
+	}
+}
+
+func TestHeadlessLastInfo(t *testing.T) {
+	h := NewHeadless()
+	if info, _ := h.LastInfo(); info != "" {
+		t.Fatalf("the info row %q before anything was set", info)
+	}
+
+	h.SetInfoln("  watching  ", InfoLineSeverityWarning)
+	if info, sev := h.LastInfo(); info != "watching" || sev != InfoLineSeverityWarning {
+		t.Fatalf("LastInfo() = %q, %v, want the trimmed text as a warning", info, sev)
+	}
+	h.SetErrorln("connection lost")
+	if info, sev := h.LastInfo(); info != "connection lost" || sev != InfoLineSeverityError {
+		t.Fatalf("LastInfo() = %q, %v, want the error", info, sev)
+	}
+}
+
+// TestHeadlessExec runs a registered command, a failing one and an unknown
+// one. Each is recorded and the failures end up on the info row.
+func TestHeadlessExec(t *testing.T) {
+	failed := errors.New("deploy failed")
+	var got cmd.Args
+	h := NewHeadless(
+		&fakeCmd{name: "logs", run: func(args cmd.Args) error {
+			got = args
+			return nil
+		}},
+		&fakeCmd{name: "deploy", run: func(cmd.Args) error { return failed }},
+	)
+	events, cancel := h.Subscribe(PromptEventTypeCommand)
+	defer cancel()
+
+	if err := h.Exec("logs -f api"); err != nil {
+		t.Fatalf("Exec: %s", err)
+	}
+	if len(got) != 2 || got[0] != "-f" || got[1] != "api" {
+		t.Fatalf("the command got %q", got)
+	}
+	if e := <-events; e.Data.(CommandEvent).Name != "logs" {
+		t.Fatalf("the event %+v, want the logs command", e)
+	}
+
+	if err := h.Exec("deploy"); err != failed {
+		t.Fatalf("Exec returned %v, want the error of the command", err)
+	}
+	if info, sev := h.LastInfo(); info != failed.Error() || sev != InfoLineSeverityError {
+		t.Fatalf("LastInfo() = %q, %v, want the error of the command", info, sev)
+	}
+
+	if err := h.Exec("stats"); err == nil {
+		t.Fatal("Exec of an unknown command didn't fail")
+	}
+	if info, _ := h.LastInfo(); info != "Unknown command 'stats'" {
+		t.Fatalf("LastInfo() = %q, want the unknown command", info)
+	}
+
+	executed := h.Executed()
+	if len(executed) != 3 || executed[0] != "logs -f api" || executed[2] != "stats" {
+		t.Fatalf("Executed() = %q", executed)
+	}
+	if h.Stopped() {
+		t.Fatal("Stopped before Stop")
+	}
+	h.Stop()
+	if !h.Stopped() {
+		t.Fatal("not Stopped after Stop")
+	}
+}
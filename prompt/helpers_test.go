@@ -0,0 +1,278 @@
+package prompt
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"foundry/cli/connection"
+	"foundry/cli/prompt/cmd"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// fakeCmd is a command that runs run, or does nothing if it's nil
+type fakeCmd struct {
+	name    string
+	aliases []string
+	run     func(args cmd.Args) error
+}
+
+func (c *fakeCmd) Run(*connection.Connection, cmd.Args) (string, string, error) {
+	return "", "", nil
+}
+
+func (c *fakeCmd) RunRequest(args cmd.Args) error {
+	if c.run == nil {
+		return nil
+	}
+	return c.run(args)
+}
+
+func (c *fakeCmd) ToSuggest() goprompt.Suggest {
+	return goprompt.Suggest{Text: c.name, Description: "A fake command"}
+}
+
+func (c *fakeCmd) Name() string      { return c.name }
+func (c *fakeCmd) String() string    { return c.name }
+func (c *fakeCmd) Aliases() []string { return c.aliases }
+
+// usageCmd is a fakeCmd with a usage
+type usageCmd struct {
+	*fakeCmd
+	usage string
+}
+
+func (c *usageCmd) Usage() string { return c.usage }
+
+// syncBuffer is a bytes.Buffer that is safe for concurrent use
+type syncBuffer struct {
+	mut sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.buf.String()
+}
+
+// fakeWriter is a console writer keeping everything flushed to it
+func fakeWriter() (goprompt.ConsoleWriter, *syncBuffer) {
+	out := &syncBuffer{}
+	return &ioWriter{w: out}, out
+}
+
+// newTestPrompt returns a prompt of a 24x80 terminal reading the keys from
+// input and rendering to the returned buffer. It's stopped by the cleanup.
+func newTestPrompt(t testing.TB, cmds []cmd.Cmd, input string, opts ...Option) (*Prompt, *syncBuffer) {
+	t.Helper()
+	w, out := fakeWriter()
+	opts = append([]Option{
+		WithInputReader(strings.NewReader(input)),
+		WithTerminalSize(24, 80),
+		WithConsoleWriter(w),
+	}, opts...)
+	p, err := New(cmds, opts...)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	t.Cleanup(p.Stop)
+	return p, out
+}
+
+// startPrompt runs p and waits for the initial render
+func startPrompt(t testing.TB, p *Prompt) {
+	t.Helper()
+	go p.Run()
+	select {
+	case <-p.Ready():
+	case <-time.After(5 * time.Second):
+		t.Fatal("the initial render didn't happen")
+	}
+}
+
+// waitFor polls cond until it's true or fails the test after a while
+func waitFor(t testing.TB, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// readChunks takes all the chunks out of b in the order Read delivers them
+func readChunks(b *Buffer) []Chunk {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	var chunks []Chunk
+	for {
+		c, ok := b.next()
+		if !ok {
+			return chunks
+		}
+		chunks = append(chunks, c)
+	}
+}
+
+// fakeLogger keeps the messages logged to it
+type fakeLogger struct {
+	mut    sync.Mutex
+	debugs []string
+	errors []string
+}
+
+func (l *fakeLogger) Debugf(format string, args ...interface{}) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.debugs = append(l.debugs, fmt.Sprintf(format, args...))
+}
+
+func (l *fakeLogger) Errorf(format string, args ...interface{}) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	l.errors = append(l.errors, fmt.Sprintf(format, args...))
+}
+
+// errorContaining returns the first error message containing substr
+func (l *fakeLogger) errorContaining(substr string) (string, bool) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	for _, msg := range l.errors {
+		if strings.Contains(msg, substr) {
+			return msg, true
+		}
+	}
+	return "", false
+}
+
+// fakeParser is a terminal for go-prompt's loop. Its Read doesn't block
+// like of the real terminal in the non-blocking mode, no key is EAGAIN.
+type fakeParser struct {
+	keys chan []byte
+
+	mut       sync.Mutex
+	raw       bool
+	teardowns int
+	reads     int
+}
+
+func newFakeParser() *fakeParser {
+	return &fakeParser{keys: make(chan []byte, 64)}
+}
+
+func (f *fakeParser) Setup() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.raw = true
+	return nil
+}
+
+func (f *fakeParser) TearDown() error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.raw = false
+	f.teardowns++
+	return nil
+}
+
+func (f *fakeParser) Read() ([]byte, error) {
+	f.mut.Lock()
+	f.reads++
+	f.mut.Unlock()
+	select {
+	case b := <-f.keys:
+		return b, nil
+	default:
+		return nil, syscall.EAGAIN
+	}
+}
+
+func (f *fakeParser) GetWinSize() *goprompt.WinSize {
+	return &goprompt.WinSize{Row: 24, Col: 80}
+}
+
+// isRaw reports whether the terminal is in the raw mode
+func (f *fakeParser) isRaw() bool {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.raw
+}
+
+// readCount returns how many times go-prompt read the terminal
+func (f *fakeParser) readCount() int {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.reads
+}
+
+// withTermOut replaces the process's stdout, which gets what go-prompt
+// draws and the sequences restoring the terminal
+func withTermOut(w io.Writer) Option {
+	return func(p *Prompt) error {
+		p.termOut = w
+		return nil
+	}
+}
+
+// newTTYPrompt returns a prompt run by go-prompt's loop like on a real
+// terminal. It reads the keys sent to the parser, the output is rendered
+// to out and what goes to the terminal around the writer to term.
+func newTTYPrompt(t testing.TB, cmds []cmd.Cmd, opts ...Option) (p *Prompt, parser *fakeParser, out, term *syncBuffer) {
+	t.Helper()
+	parser = newFakeParser()
+	term = &syncBuffer{}
+	opts = append([]Option{WithConsoleParser(parser), withTermOut(term)}, opts...)
+	p, out = newTestPrompt(t, cmds, "", opts...)
+	return p, parser, out, term
+}
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with the file name in testdata.
+// With -update it writes got to the file instead.
+func checkGolden(t testing.TB, name, got string) {
+	t.Helper()
+	golden := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		i := 0
+		for i < len(got) && i < len(want) && got[i] == want[i] {
+			i++
+		}
+		t.Fatalf("the output differs from %s at byte %d:\n got %q\nwant %q", golden, i, tail(got, i), tail(string(want), i))
+	}
+}
+
+// tail returns up to 80 bytes of s from i
+func tail(s string, i int) string {
+	if len(s) > i+80 {
+		return s[i : i+80]
+	}
+	return s[i:]
+}
@@ -0,0 +1,167 @@
+package prompt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Number of lines loaded from the history file when the prompt starts
+const maxHistoryLines = 1000
+
+// WithHistoryFile keeps the lines the user runs in the file at path, every
+// line is appended once it runs. The last lines of the file are there for
+// the up arrow when the prompt starts.
+func WithHistoryFile(path string) Option {
+	return func(p *Prompt) error {
+		if path == "" {
+			return fmt.Errorf("the history file path can't be empty")
+		}
+		p.history.path = path
+		return nil
+	}
+}
+
+// WithHistoryFlushInterval makes the lines wait in memory and be appended to
+// the history file together at most every d, and when the prompt ends, also
+// on SIGINT or SIGTERM. Fewer writes cost the lines of the last d if the
+// process is killed with SIGKILL or crashes.
+func WithHistoryFlushInterval(d time.Duration) Option {
+	return func(p *Prompt) error {
+		if d <= 0 {
+			return fmt.Errorf("history flush interval must be positive, got %s", d)
+		}
+		p.history.interval = d
+		return nil
+	}
+}
+
+// history is the state of the history file
+type history struct {
+	mut      sync.Mutex
+	path     string
+	interval time.Duration
+	lines    []string    // The loaded lines and the ones run since, for the search
+	pending  []string    // Lines waiting for the flush
+	timer    *time.Timer // Flushes the pending lines, nil if there are none
+	log      Logger
+}
+
+// load returns the last lines of the history file. A missing file
+// is an empty history.
+func (h *history) load() []string {
+	if h.path == "" {
+		return nil
+	}
+	b, err := ioutil.ReadFile(h.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			h.log.Errorf("Reading the history file %s failed: %s", h.path, err)
+		}
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > maxHistoryLines {
+		lines = lines[len(lines)-maxHistoryLines:]
+	}
+	h.mut.Lock()
+	// Lines run before Run are newer than the file's
+	h.lines = append(lines[:len(lines):len(lines)], h.lines...)
+	h.mut.Unlock()
+	return lines
+}
+
+// snapshot returns the loaded lines and the ones run since
+func (h *history) snapshot() []string {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return append([]string(nil), h.lines...)
+}
+
+// add saves a line the user ran
+func (h *history) add(line string) {
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+	// A line break would make two lines of the history
+	line = strings.Replace(line, "\n", " ", -1)
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.lines = append(h.lines, line)
+	if len(h.lines) > maxHistoryLines {
+		h.lines = h.lines[len(h.lines)-maxHistoryLines:]
+	}
+	if h.path == "" {
+		return
+	}
+	h.pending = append(h.pending, line)
+	if h.interval == 0 {
+		h.flushLocked()
+		return
+	}
+	if h.timer == nil {
+		h.timer = time.AfterFunc(h.interval, h.flush)
+	}
+}
+
+// find returns the newest line older than the one at index before that
+// contains term and isn't skip, and its index. The index is -1 if there's
+// none.
+func (h *history) find(term, skip string, before int) (int, string) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	if before > len(h.lines) {
+		before = len(h.lines)
+	}
+	for i := before - 1; i >= 0; i-- {
+		if l := h.lines[i]; l != skip && strings.Contains(l, term) {
+			return i, l
+		}
+	}
+	return -1, ""
+}
+
+// size returns the number of lines the search looks through
+func (h *history) size() int {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	return len(h.lines)
+}
+
+// flush appends the pending lines to the history file
+func (h *history) flush() {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.flushLocked()
+}
+
+func (h *history) flushLocked() {
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	if len(h.pending) == 0 {
+		return
+	}
+
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err == nil {
+		_, err = f.WriteString(strings.Join(h.pending, "\n") + "\n")
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		// The lines stay pending, the next flush tries again
+		h.log.Errorf("Writing %d lines to the history file %s failed: %s", len(h.pending), h.path, err)
+		return
+	}
+	h.pending = h.pending[:0]
+}
@@ -0,0 +1,51 @@
+package prompt
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithIdleTimeout calls onIdle once there was no input for d.
+// The timer restarts with every keystroke. onIdle runs on its own goroutine.
+func WithIdleTimeout(d time.Duration, onIdle func()) Option {
+	return func(p *Prompt) error {
+		if d <= 0 {
+			return fmt.Errorf("idle timeout must be positive, got %s", d)
+		}
+		if onIdle == nil {
+			return fmt.Errorf("idle timeout callback can't be nil")
+		}
+		p.idleTimeout = d
+		p.onIdle = onIdle
+		return nil
+	}
+}
+
+func (p *Prompt) startIdleTimer() {
+	if p.onIdle == nil {
+		return
+	}
+
+	p.idleMutex.Lock()
+	defer p.idleMutex.Unlock()
+	p.idleTimer = time.AfterFunc(p.idleTimeout, p.onIdle)
+}
+
+func (p *Prompt) resetIdleTimer() {
+	p.idleMutex.Lock()
+	defer p.idleMutex.Unlock()
+
+	if p.idleTimer != nil {
+		p.idleTimer.Reset(p.idleTimeout)
+	}
+}
+
+func (p *Prompt) stopIdleTimer() {
+	p.idleMutex.Lock()
+	defer p.idleMutex.Unlock()
+
+	if p.idleTimer != nil {
+		p.idleTimer.Stop()
+		p.idleTimer = nil
+	}
+}
@@ -0,0 +1,175 @@
+package prompt
+
+import (
+	"bufio"
+	"io"
+	"unicode/utf8"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// Size reported by the reader parser when WithTerminalSize isn't used
+const (
+	defaultReaderRows = 24
+	defaultReaderCols = 80
+)
+
+// WithInputReader makes the prompt read keystrokes from r instead of the
+// terminal, e.g. "watch\r" types the watch command and presses enter.
+// Together with WithConsoleWriter and WithTerminalSize the whole prompt
+// can run without a terminal. Only basic line editing is supported.
+func WithInputReader(r io.Reader) Option {
+	return func(p *Prompt) error {
+		p.parser = &readerParser{r: bufio.NewReader(r)}
+		return nil
+	}
+}
+
+// WithTerminalSize makes the prompt render for a terminal of the given
+// size instead of asking the terminal for its size
+func WithTerminalSize(rows, cols uint16) Option {
+	return func(p *Prompt) error {
+		p.fixedSize = &goprompt.WinSize{Row: rows, Col: cols}
+		return nil
+	}
+}
+
+// winSize returns the current size of the terminal
+func (p *Prompt) winSize() *goprompt.WinSize {
+	if p.fixedSize != nil {
+		return p.fixedSize
+	}
+	if p.remote != nil {
+		// The basic line editing's parser doesn't know the size
+		return p.checkSize(p.remote.winSize())
+	}
+	return p.parser.GetWinSize()
+}
+
+// runScripted is a minimal replacement of go-prompt's loop for scripted
+// input. It supports typing text, backspace, enter, Ctrl+C which does
+// what the prompt's CtrlCMode says and clears the line, Ctrl+D which
+// ends the prompt on an empty line and the keys bound by Bind. The loop
+// ends when the reader has nothing more to read.
+func (p *Prompt) runScripted(rp *readerParser) {
+	defer p.recoverGoroutine("scripted input")
+
+	var line []rune
+	for {
+		select {
+		case <-p.stopCh:
+			return
+		default:
+		}
+
+		key, err := rp.Read()
+		if err != nil {
+			return
+		}
+		if s, ok := p.takePreload(); ok {
+			line = []rune(s)
+		}
+		if p.pagerKey(key) {
+			continue
+		}
+
+		switch goprompt.GetKey(key) {
+		case goprompt.Enter, goprompt.ControlJ, goprompt.ControlM:
+			s := string(line)
+			line = line[:0]
+			p.completer(goprompt.Document{})
+			p.executor(s)
+			continue
+		case goprompt.Backspace, goprompt.ControlH:
+			if len(line) > 0 {
+				line = line[:len(line)-1]
+			}
+		case goprompt.ControlC:
+			p.onCtrlC()
+			line = line[:0]
+			continue
+		case goprompt.ControlD:
+			// Like in a shell, only on an empty line. The cursor is always
+			// at the end so there's nothing to delete otherwise.
+			if len(line) == 0 {
+				p.requestExit("ctrl+d", nil)
+				return
+			}
+			continue
+		case goprompt.NotDefined:
+			line = append(line, []rune(string(key))...)
+		default:
+			// Moving the cursor and other editing keys aren't supported
+			p.runKeyBind(goprompt.GetKey(key))
+			continue
+		}
+		p.completer(goprompt.Document{Text: string(line)})
+	}
+}
+
+// readerParser is a goprompt.ConsoleParser that reads from an io.Reader
+type readerParser struct {
+	r *bufio.Reader
+}
+
+func (rp *readerParser) Setup() error    { return nil }
+func (rp *readerParser) TearDown() error { return nil }
+
+func (rp *readerParser) GetWinSize() *goprompt.WinSize {
+	return &goprompt.WinSize{Row: defaultReaderRows, Col: defaultReaderCols}
+}
+
+// Read returns a single key. go-prompt recognizes keys only when
+// every key comes from its own Read call, text can come in one piece.
+func (rp *readerParser) Read() ([]byte, error) {
+	b, err := rp.r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	key := []byte{b}
+
+	switch {
+	case b == 0x1b:
+		// Escape sequence of a special key like an arrow
+		next, err := rp.r.ReadByte()
+		if err != nil {
+			return key, nil
+		}
+		key = append(key, next)
+		if next != '[' && next != 'O' {
+			return key, nil
+		}
+		for {
+			c, err := rp.r.ReadByte()
+			if err != nil {
+				return key, nil
+			}
+			key = append(key, c)
+			if c >= 0x40 && c <= 0x7e {
+				return key, nil
+			}
+		}
+	case b < ' ' || b == 0x7f:
+		// Control keys like enter or backspace
+		return key, nil
+	}
+
+	// Text up to the next control key
+	for len(key) < 256 {
+		next, err := rp.r.Peek(1)
+		if err != nil || next[0] < ' ' || next[0] == 0x7f {
+			break
+		}
+		c, _ := rp.r.ReadByte()
+		key = append(key, c)
+	}
+	// Don't end in the middle of a multi-byte character
+	for !utf8.Valid(key) {
+		c, err := rp.r.ReadByte()
+		if err != nil {
+			break
+		}
+		key = append(key, c)
+	}
+	return key, nil
+}
@@ -0,0 +1,49 @@
+package prompt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestScriptedInput drives the prompt through keys of a script like a user
+// typing them. The command's output and the info row end up on the screen.
+func TestScriptedInput(t *testing.T) {
+	var (
+		mut sync.Mutex
+		ran []string
+		p   *Prompt
+	)
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		mut.Lock()
+		ran = append(ran, strings.Join(args, " "))
+		mut.Unlock()
+		_, err := p.Writeln("deployed " + strings.Join(args, " ") + "\n")
+		return err
+	}}
+	// A typo fixed by backspace, an unknown command and a second deploy
+	script := "deploy apj\x7fi\rdeploi\rdeploy worker\r"
+	p, out := newTestPrompt(t, []cmd.Cmd{deploy}, script)
+	startPrompt(t, p)
+
+	waitFor(t, "the output of both deploys", func() bool {
+		return strings.Contains(out.String(), "deployed worker")
+	})
+	mut.Lock()
+	got := strings.Join(ran, "|")
+	mut.Unlock()
+	if got != "api|worker" {
+		t.Fatalf("deploy ran with %q, want api and then worker", got)
+	}
+
+	screen := out.String()
+	first, second := strings.Index(screen, "deployed api\n"), strings.Index(screen, "deployed worker\n")
+	if first < 0 || second < first {
+		t.Fatalf("the output isn't on the screen in order: %q", screen)
+	}
+	if !strings.Contains(screen, "Unknown command 'deploi'") {
+		t.Fatalf("the info row didn't show the unknown command: %q", screen)
+	}
+}
@@ -0,0 +1,20 @@
+package prompt
+
+// Interface is what code that prints output or runs lines needs of the
+// prompt. Taking it instead of *Prompt lets the code run with Headless,
+// without a terminal.
+type Interface interface {
+	Writeln(s string) (n int, err error)
+	Writef(format string, args ...interface{}) (n int, err error)
+	SetInfoln(s string, severity InfoLineSeverity) error
+	SetErrorln(s string) error
+	ClearOutput() error
+	Exec(s string) error
+	Subscribe(types ...PromptEventType) (<-chan PromptEvent, func())
+	Stop()
+}
+
+var (
+	_ Interface = (*Prompt)(nil)
+	_ Interface = (*Headless)(nil)
+)
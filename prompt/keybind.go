@@ -0,0 +1,124 @@
+package prompt
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// reservedKeys are the keys bound by the prompt or go-prompt itself
+var reservedKeys = []goprompt.Key{
+	goprompt.Enter, goprompt.ControlJ, goprompt.ControlM, goprompt.ControlC, goprompt.ControlD, goprompt.ControlZ,
+	goprompt.ControlBackslash,
+	goprompt.Tab, goprompt.ControlI, goprompt.BackTab,
+	goprompt.Up, goprompt.Down, goprompt.ControlP, goprompt.ControlN,
+	goprompt.Left, goprompt.Right, goprompt.Home, goprompt.End,
+	goprompt.Backspace, goprompt.Delete, goprompt.ControlH,
+	goprompt.ControlA, goprompt.ControlB, goprompt.ControlE, goprompt.ControlF,
+	goprompt.ControlK, goprompt.ControlL, goprompt.ControlU, goprompt.ControlW,
+	goprompt.ControlY, goprompt.Escape, goprompt.ControlR,
+}
+
+// Bind calls action when the user presses key, e.g. F5 to run the last
+// command again. It has to be called before Run. The keys the prompt
+// already uses for editing, history and completion can't be bound again.
+// action runs on the input goroutine, there's no input while it runs and
+// it must not block. It can call any of the prompt's methods, such as
+// Writeln, SetInfoln, Execute or ClearOutput. Longer work belongs on its
+// own goroutine.
+func (p *Prompt) Bind(key goprompt.Key, action func(p *Prompt)) error {
+	if action == nil {
+		return fmt.Errorf("action of the key %s can't be nil", key)
+	}
+	if key == goprompt.NotDefined || key == goprompt.Ignore {
+		return fmt.Errorf("key %s can't be bound", key)
+	}
+	if atomic.LoadInt32(&p.started) != 0 {
+		return fmt.Errorf("key %s has to be bound before the prompt runs", key)
+	}
+	for _, r := range reservedKeys {
+		if r == key {
+			return fmt.Errorf("key %s is reserved, the reserved keys are %s", key, reservedKeyNames())
+		}
+	}
+	if _, ok := p.keyBinds[key]; ok {
+		return fmt.Errorf("key %s is already bound", key)
+	}
+
+	if p.keyBinds == nil {
+		p.keyBinds = map[goprompt.Key]func(p *Prompt){}
+	}
+	p.keyBinds[key] = action
+	return nil
+}
+
+func reservedKeyNames() string {
+	names := make([]string, len(reservedKeys))
+	for i, k := range reservedKeys {
+		names[i] = k.String()
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// keyBindOptions returns the go-prompt options of the keys bound by Bind
+func (p *Prompt) keyBindOptions() []goprompt.Option {
+	opts := make([]goprompt.Option, 0, len(p.keyBinds))
+	for key := range p.keyBinds {
+		key := key
+		opts = append(opts, goprompt.OptionAddKeyBind(goprompt.KeyBind{
+			Key: key,
+			Fn: func(*goprompt.Buffer) {
+				p.runKeyBind(key)
+			},
+		}))
+	}
+	return opts
+}
+
+// runKeyBind calls the action bound to key. It returns false if there's none.
+func (p *Prompt) runKeyBind(key goprompt.Key) bool {
+	action, ok := p.keyBinds[key]
+	if !ok {
+		return false
+	}
+	p.logWith("key", key).Debugf("Running the key's action")
+	err := p.safeRun("key "+key.String(), func() error {
+		action(p)
+		return nil
+	})
+	if err != nil {
+		p.SetInfoln(err.Error(), InfoLineSeverityError)
+	}
+	return true
+}
+
+// ClearOutput erases the output on the screen, the next output is printed
+// at the top. The header, info row and prompt row stay.
+func (p *Prompt) ClearOutput() error {
+	p.assertNotRenderLocked()
+	p.lockRender()
+	defer p.unlockRender()
+
+	if p.totalRows == 0 {
+		// Nothing was printed yet
+		return nil
+	}
+	if p.pager != nil {
+		p.closePager()
+	}
+	start := p.outputStart()
+	for r := start.Row; r < p.infoRow; r++ {
+		p.writer.CursorGoTo(r, 1)
+		p.writer.EraseLine()
+	}
+	p.currentPos = start
+	p.savedPos = start
+	p.layout()
+
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	return p.flush()
+}
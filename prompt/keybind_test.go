@@ -0,0 +1,92 @@
+package prompt
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// TestBindSamples binds F5 to run the last deploy again, Ctrl+T to toggle
+// the timestamps and F2 to an action that panics, then presses them in
+// go-prompt's loop
+func TestBindSamples(t *testing.T) {
+	var deploys int32
+	var p *Prompt
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		n := atomic.AddInt32(&deploys, 1)
+		_, err := p.Writef("deploy %d of %s\n", n, strings.Join(args, " "))
+		return err
+	}}
+	p, parser, out, _ := newTTYPrompt(t, []cmd.Cmd{deploy})
+
+	timestamps := false
+	binds := map[goprompt.Key]func(p *Prompt){
+		goprompt.F5: func(p *Prompt) { p.Execute("deploy api") },
+		goprompt.ControlT: func(p *Prompt) {
+			timestamps = !timestamps
+			if timestamps {
+				p.SetInfoln("timestamps on", InfoLineSeverityNormal)
+			} else {
+				p.SetInfoln("timestamps off", InfoLineSeverityNormal)
+			}
+		},
+		goprompt.F2: func(p *Prompt) { panic("broken action") },
+	}
+	for key, action := range binds {
+		if err := p.Bind(key, action); err != nil {
+			t.Fatalf("Bind(%s): %s", key, err)
+		}
+	}
+	go p.Run()
+	<-p.Ready()
+
+	f5 := []byte("\x1b[15~")
+	parser.keys <- f5
+	parser.keys <- f5
+	waitFor(t, "two deploys", func() bool { return strings.Contains(out.String(), "deploy 2 of api") })
+
+	parser.keys <- []byte{0x14}
+	waitFor(t, "the timestamps on", func() bool { return infoText(p) == "timestamps on" })
+	parser.keys <- []byte{0x14}
+	waitFor(t, "the timestamps off", func() bool { return infoText(p) == "timestamps off" })
+
+	parser.keys <- []byte("\x1bOQ")
+	waitFor(t, "the panic of the action", func() bool {
+		return strings.Contains(infoText(p), "internal error in 'key F2': broken action")
+	})
+
+	// The prompt still runs the keys
+	parser.keys <- f5
+	waitFor(t, "the deploy after the panic", func() bool { return strings.Contains(out.String(), "deploy 3 of api") })
+}
+
+func TestBindRejects(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	noop := func(*Prompt) {}
+
+	err := p.Bind(goprompt.ControlC, noop)
+	if err == nil || !strings.Contains(err.Error(), "is reserved") || !strings.Contains(err.Error(), "ControlR") {
+		t.Errorf("binding Ctrl+C returned %v, want the list of the reserved keys", err)
+	}
+	if err := p.Bind(goprompt.F5, nil); err == nil {
+		t.Error("Bind accepted a nil action")
+	}
+	if err := p.Bind(goprompt.NotDefined, noop); err == nil {
+		t.Error("Bind accepted NotDefined")
+	}
+	if err := p.Bind(goprompt.F5, noop); err != nil {
+		t.Fatalf("Bind(F5): %s", err)
+	}
+	if err := p.Bind(goprompt.F5, noop); err == nil || !strings.Contains(err.Error(), "already bound") {
+		t.Errorf("binding F5 again returned %v", err)
+	}
+
+	startPrompt(t, p)
+	if err := p.Bind(goprompt.F6, noop); err == nil || !strings.Contains(err.Error(), "before the prompt runs") {
+		t.Errorf("binding F6 while the prompt runs returned %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// MaxInfoRows is the most rows WithInfoRows accepts
+const MaxInfoRows = 5
+
+// WithInfoRows gives the info text n rows above the prompt row instead of
+// one. Every line of the text gets its own row, the last row gets the
+// rest of them. The output has n-1 rows less.
+func WithInfoRows(n int) Option {
+	return func(p *Prompt) error {
+		if n < 1 || n > MaxInfoRows {
+			return fmt.Errorf("info rows must be between 1 and %d, got %d", MaxInfoRows, n)
+		}
+		p.infoRows = n
+		return nil
+	}
+}
+
+// Layout is the arrangement of the prompt's rows, see WithLayout
+type Layout struct {
+	InfoRows     int    // See WithInfoRows, 0 keeps one row
+	ScrollMargin int    // See WithScrollMargin
+	MaxWidth     int    // See WithMaxWidth, 0 is the whole terminal
+	Header       string // Shown from the initial render, see SetHeaderln
+}
+
+// WithLayout sets all of the layout at once
+func WithLayout(l Layout) Option {
+	return func(p *Prompt) error {
+		opts := []Option{WithScrollMargin(l.ScrollMargin)}
+		if l.InfoRows != 0 {
+			opts = append(opts, WithInfoRows(l.InfoRows))
+		}
+		if l.MaxWidth != 0 {
+			opts = append(opts, WithMaxWidth(l.MaxWidth))
+		}
+		if strings.ContainsAny(l.Header, "\r\n") {
+			return errors.New("the header can't span more lines")
+		}
+		for _, opt := range opts {
+			if err := opt(p); err != nil {
+				return err
+			}
+		}
+		p.headerText = strings.TrimSpace(l.Header)
+		return nil
+	}
+}
+
+// infoLines returns the text of every info row. renderMutex must be held.
+func (p *Prompt) infoLines() []string {
+	lines := strings.SplitN(p.infoText, "\n", p.infoRows)
+	for i, l := range lines {
+		if p.totalColumns >= 1 {
+			lines[i] = clip(l, p.totalColumns)
+		}
+	}
+	return lines
+}
+
+// renderInfo draws the info text on the info rows. renderMutex must be held.
+func (p *Prompt) renderInfo() {
+	lines := p.infoLines()
+	for i := 0; i < p.infoRows; i++ {
+		p.writer.CursorGoTo(p.infoRow+i, 1)
+		p.writer.EraseLine()
+		p.writer.SetColor(p.infoColor, goprompt.DefaultColor, true)
+		if i < len(lines) {
+			p.writer.WriteRawStr(lines[i])
+		}
+	}
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+}
@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var (
+	hyperlinksOnce      sync.Once
+	hyperlinksSupported bool
+)
+
+// Link returns text that the terminal shows as a clickable link to url
+// (OSC 8 hyperlink). Only text takes space on the screen. Terminals that
+// don't support hyperlinks get the url in parentheses after text instead.
+func Link(text, url string) string {
+	if !HyperlinksSupported() {
+		if text == url || text == "" {
+			return url
+		}
+		return text + " (" + url + ")"
+	}
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// HyperlinksSupported reports whether the terminal is known to render OSC 8 hyperlinks.
+// Set FORCE_HYPERLINK to 1 or 0 to override the detection.
+func HyperlinksSupported() bool {
+	hyperlinksOnce.Do(func() {
+		hyperlinksSupported = detectHyperlinks()
+	})
+	return hyperlinksSupported
+}
+
+func detectHyperlinks() bool {
+	if force, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return force != "0"
+	}
+
+	if os.Getenv("WT_SESSION") != "" || os.Getenv("KITTY_WINDOW_ID") != "" {
+		return true
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "Hyper":
+		return true
+	}
+
+	// GNOME Terminal and other VTE based terminals since 0.50
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+
+	term := os.Getenv("TERM")
+	return strings.Contains(term, "kitty") || strings.Contains(term, "alacritty")
+}
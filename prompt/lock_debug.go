@@ -0,0 +1,45 @@
+//go:build debug
+// +build debug
+
+package prompt
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// Goroutine currently holding renderMutex for each Prompt
+var renderOwners sync.Map
+
+func (p *Prompt) lockRender() {
+	p.renderMutex.Lock()
+	renderOwners.Store(p, goroutineID())
+}
+
+func (p *Prompt) unlockRender() {
+	renderOwners.Delete(p)
+	p.renderMutex.Unlock()
+}
+
+// assertNotRenderLocked panics when the calling goroutine holds renderMutex.
+// Writing output there can deadlock once the output buffer blocks.
+func (p *Prompt) assertNotRenderLocked() {
+	if id, ok := renderOwners.Load(p); ok && id.(uint64) == goroutineID() {
+		panic("prompt: output written while holding renderMutex")
+	}
+}
+
+// goroutineID parses the ID of the calling goroutine out of its stack trace.
+// It's slow and relies on the format of runtime.Stack, which is why only the
+// debug build tracks the owner of renderMutex.
+func goroutineID() uint64 {
+	b := make([]byte, 64)
+	b = b[:runtime.Stack(b, false)]
+	// The stack starts with "goroutine 123 [running]:"
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	b = b[:bytes.IndexByte(b, ' ')]
+	id, _ := strconv.ParseUint(string(b), 10, 64)
+	return id
+}
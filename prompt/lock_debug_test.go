@@ -0,0 +1,32 @@
+//go:build debug
+// +build debug
+
+package prompt
+
+import (
+	"testing"
+)
+
+func TestWritelnUnderRenderMutexPanics(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Writeln didn't panic while holding renderMutex")
+		}
+	}()
+	p.lockRender()
+	defer p.unlockRender()
+	p.Writeln("deadlock")
+}
+
+func TestWritelnFromAnotherGoroutineDoesntPanic(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	p.lockRender()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Writeln("fine")
+	}()
+	<-done
+	p.unlockRender()
+}
@@ -0,0 +1,8 @@
+//go:build !debug
+// +build !debug
+
+package prompt
+
+func (p *Prompt) lockRender()            { p.renderMutex.Lock() }
+func (p *Prompt) unlockRender()          { p.renderMutex.Unlock() }
+func (p *Prompt) assertNotRenderLocked() {}
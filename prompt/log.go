@@ -0,0 +1,121 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"foundry/cli/logger"
+)
+
+// Logger receives the prompt's internal diagnostics. The default one
+// writes to foundry/cli/logger, WithLogger replaces it.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// FieldLogger is implemented by loggers that keep the fields attached to
+// a message structured. Other loggers get the fields appended as "key=value".
+type FieldLogger interface {
+	Logger
+	With(kv ...interface{}) Logger
+}
+
+// WithLogger sends the prompt's diagnostics to l instead of foundry/cli/logger
+func WithLogger(l Logger) Option {
+	return func(p *Prompt) error {
+		if l == nil {
+			return errors.New("logger can't be nil")
+		}
+		p.log = l
+		return nil
+	}
+}
+
+// logWith returns the prompt's logger with fields given as pairs of a key and a value
+func (p *Prompt) logWith(kv ...interface{}) Logger {
+	return withFields(p.log, kv...)
+}
+
+func withFields(l Logger, kv ...interface{}) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.With(kv...)
+	}
+	return &fieldsLogger{l: l, fields: formatFields(kv)}
+}
+
+func formatFields(kv []interface{}) string {
+	var b strings.Builder
+	for i := 0; i < len(kv); i += 2 {
+		var val interface{} = "!MISSING"
+		if i+1 < len(kv) {
+			val = kv[i+1]
+		}
+		fmt.Fprintf(&b, " %v=%v", kv[i], val)
+	}
+	return b.String()
+}
+
+// fieldsLogger appends the fields to the messages of a logger that isn't a FieldLogger
+type fieldsLogger struct {
+	l      Logger
+	fields string
+}
+
+func (f *fieldsLogger) Debugf(format string, args ...interface{}) {
+	f.l.Debugf("%s", fmt.Sprintf(format, args...)+f.fields)
+}
+
+func (f *fieldsLogger) Errorf(format string, args ...interface{}) {
+	f.l.Errorf("%s", fmt.Sprintf(format, args...)+f.fields)
+}
+
+func (f *fieldsLogger) With(kv ...interface{}) Logger {
+	return &fieldsLogger{l: f.l, fields: f.fields + formatFields(kv)}
+}
+
+// foundryLogger is the default Logger that writes to foundry/cli/logger.
+// Debug messages are written only by debug builds, errors by all of them.
+type foundryLogger struct {
+	e *logger.Entry
+}
+
+func newFoundryLogger() *foundryLogger {
+	// The log lines show the prompt's code that logged instead of this file
+	return &foundryLogger{e: logger.With().Skip(1)}
+}
+
+func (f *foundryLogger) Debugf(format string, args ...interface{}) {
+	f.e.Fdebug(fmt.Sprintf(format, args...))
+}
+
+func (f *foundryLogger) Errorf(format string, args ...interface{}) {
+	f.e.Error(fmt.Sprintf(format, args...))
+}
+
+func (f *foundryLogger) With(kv ...interface{}) Logger {
+	return &foundryLogger{e: f.e.With(kv...)}
+}
+
+// logLimiter lets through at most one message per interval
+// and counts the others. It isn't safe for concurrent use.
+type logLimiter struct {
+	every      time.Duration
+	next       time.Time
+	suppressed int
+}
+
+// allow reports whether a message can be logged now and how many
+// were suppressed since the last one that was
+func (l *logLimiter) allow() (suppressed int, ok bool) {
+	now := time.Now()
+	if now.Before(l.next) {
+		l.suppressed++
+		return 0, false
+	}
+	l.next = now.Add(l.every)
+	suppressed, l.suppressed = l.suppressed, 0
+	return suppressed, true
+}
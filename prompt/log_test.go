@@ -0,0 +1,95 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+)
+
+// entryLogger is a FieldLogger writing to foundry/cli/logger by its
+// leveled methods, so the debug messages are written by release builds too
+type entryLogger struct {
+	e *logger.Entry
+}
+
+func (l entryLogger) Debugf(format string, args ...interface{}) {
+	l.e.Debug(fmt.Sprintf(format, args...))
+}
+
+func (l entryLogger) Errorf(format string, args ...interface{}) {
+	l.e.Error(fmt.Sprintf(format, args...))
+}
+
+func (l entryLogger) With(kv ...interface{}) Logger {
+	return entryLogger{e: l.e.With(kv...)}
+}
+
+// The parts of the JSON entries that change from run to run
+var logVolatile = []struct {
+	re   *regexp.Regexp
+	repl string
+}{
+	{regexp.MustCompile(`"time":"[^"]*"`), `"time":"TIME"`},
+	{regexp.MustCompile(`"exec":"[^"]*"`), `"exec":"ID"`},
+	{regexp.MustCompile(`"duration":"[^"]*"`), `"duration":"DURATION"`},
+	{regexp.MustCompile(`\(exec [^)]*\)`), `(exec ID)`},
+}
+
+// TestLogJSONGolden renders, prints a chunk and runs a failing, a working
+// and an unknown command with the log in JSON. The entries of the
+// rerender, print and the executor are compared with testdata/log.golden.
+func TestLogJSONGolden(t *testing.T) {
+	logged := &syncBuffer{}
+	logger.SetOutput(logged)
+	logger.SetFormat(logger.FormatJSON)
+	level := logger.GetLevel()
+	logger.SetLevel(logger.LevelDebug)
+	t.Cleanup(func() {
+		logger.SetOutput(ioutil.Discard)
+		logger.SetFormat(logger.FormatAuto)
+		logger.SetLevel(level)
+	})
+
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		if len(args) > 0 && args[0] == "api" {
+			return errors.New("upload failed")
+		}
+		return nil
+	}}
+	p, _ := newTestPrompt(t, []cmd.Cmd{deploy}, "", WithLogger(entryLogger{e: logger.With()}))
+	if _, _, err := p.rerenderLocked(true); err != nil {
+		t.Fatalf("the initial render failed: %s", err)
+	}
+	p.print(Chunk{Data: []byte("deployed \x1b[32mworker\x1b[0m\n"), Stream: StreamDefault})
+	p.Execute("deploy api --force")
+	p.Execute("deploy worker")
+	p.Execute("deploi")
+
+	got := logged.String()
+	for _, v := range logVolatile {
+		got = v.re.ReplaceAllString(got, v.repl)
+	}
+	checkGolden(t, "log.golden", got)
+}
+
+// TestStopKeepsLogOutput stops a prompt and logs after. Stop closes only
+// a log file the logger opened, the writer set by SetOutput keeps the
+// messages.
+func TestStopKeepsLogOutput(t *testing.T) {
+	logged := &syncBuffer{}
+	logger.SetOutput(logged)
+	t.Cleanup(func() { logger.SetOutput(ioutil.Discard) })
+
+	p, _ := newTestPrompt(t, nil, "")
+	p.Stop()
+	logger.Error("after the prompt stopped")
+	if !strings.Contains(logged.String(), "after the prompt stopped") {
+		t.Fatalf("the log got %q", logged.String())
+	}
+}
@@ -0,0 +1,112 @@
+package prompt
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is a snapshot of the output pipeline counters
+type Metrics struct {
+	BytesWritten    int64 // Bytes written to the output buffer
+	BytesDropped    int64 // Bytes that never made it to the output buffer
+	MaxQueueDepth   int64 // The highest number of bytes waiting in the output buffer
+	ChunksDelivered int64 // Chunks handed over from the output buffer to the renderer
+	RenderPasses    int64 // Number of print() and rerender() calls
+	FlushCalls      int64 // Number of writer flushes
+	RenderTime      time.Duration
+	EventsDropped   int64 // Events that didn't fit in a subscriber's channel
+}
+
+// bufferStats is kept by Buffer. The counters are accessed atomically, they
+// stay at the top of the struct so that they are 64-bit aligned on 32-bit
+// platforms too. An atomic add for every write would cost the write path
+// more than the rest of it, so the writes are counted in the plain fields
+// guarded by Buffer.mut and published to the counters once per chunk.
+// A nil bufferStats counts nothing, TestMetricsOverhead compares the write
+// path without the counters.
+type bufferStats struct {
+	bytesWritten    int64
+	bytesDropped    int64
+	maxQueueDepth   int64
+	chunksDelivered int64
+
+	unpublished int // Bytes written since the last publish
+	depth       int // The highest queue depth since the last publish
+}
+
+// written counts n bytes queued when depth bytes wait in the buffer.
+// Buffer.mut must be held.
+func (s *bufferStats) written(n int, depth int) {
+	if s == nil {
+		return
+	}
+	s.unpublished += n
+	if depth > s.depth {
+		s.depth = depth
+	}
+}
+
+// publish adds the writes counted since the last publish to the counters.
+// Buffer.mut must be held.
+func (s *bufferStats) publish() {
+	if s == nil {
+		return
+	}
+	if s.unpublished > 0 {
+		atomic.AddInt64(&s.bytesWritten, int64(s.unpublished))
+		s.unpublished = 0
+	}
+	if int64(s.depth) > atomic.LoadInt64(&s.maxQueueDepth) {
+		atomic.StoreInt64(&s.maxQueueDepth, int64(s.depth))
+	}
+	s.depth = 0
+}
+
+// dropped counts n bytes dropped by the overflow policy
+func (s *bufferStats) dropped(n int) {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.bytesDropped, int64(n))
+}
+
+func (s *bufferStats) delivered() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.chunksDelivered, 1)
+}
+
+// renderStats is kept by Prompt. All fields are accessed atomically, the
+// int64 ones at the top of the struct like in bufferStats.
+type renderStats struct {
+	renderPasses int64
+	flushCalls   int64
+	renderTime   int64 // In nanoseconds
+
+	eventsDropped int64
+}
+
+func (s *renderStats) observeRender(start time.Time) {
+	atomic.AddInt64(&s.renderPasses, 1)
+	atomic.AddInt64(&s.renderTime, int64(time.Since(start)))
+}
+
+// Metrics returns a copy of the current output pipeline counters
+func (p *Prompt) Metrics() Metrics {
+	b := p.outBuf
+	b.mut.Lock()
+	b.stats.publish()
+	b.mut.Unlock()
+	bs, rs := b.stats, p.stats
+	return Metrics{
+		BytesWritten:    atomic.LoadInt64(&bs.bytesWritten),
+		BytesDropped:    atomic.LoadInt64(&bs.bytesDropped),
+		MaxQueueDepth:   atomic.LoadInt64(&bs.maxQueueDepth),
+		ChunksDelivered: atomic.LoadInt64(&bs.chunksDelivered),
+		RenderPasses:    atomic.LoadInt64(&rs.renderPasses),
+		FlushCalls:      atomic.LoadInt64(&rs.flushCalls),
+		RenderTime:      time.Duration(atomic.LoadInt64(&rs.renderTime)),
+		EventsDropped:   atomic.LoadInt64(&rs.eventsDropped),
+	}
+}
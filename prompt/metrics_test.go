@@ -0,0 +1,98 @@
+package prompt
+
+import (
+	"testing"
+	"time"
+)
+
+// benchLine is a line of a command's log written by the write path benchmarks
+const benchLine = "2020/04/10 12:00:00 function deployed, 42 ms\n"
+
+// maxMetricsOverhead is how much slower the counters may make the write path
+const maxMetricsOverhead = 0.05
+
+// BenchmarkWriteln compares the write path with and without the counters.
+// The buffer is emptied every 1000 lines like by the renderer.
+func BenchmarkWriteln(b *testing.B) {
+	for _, bench := range []struct {
+		name    string
+		metrics bool
+	}{
+		{"metrics", true},
+		{"nometrics", false},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			p, _ := newTestPrompt(b, nil, "")
+			if !bench.metrics {
+				p.outBuf.stats = nil
+			}
+			b.SetBytes(int64(len(benchLine)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.Writeln(benchLine)
+				if i%1000 == 999 {
+					b.StopTimer()
+					for _, c := range readChunks(p.outBuf) {
+						p.outBuf.Release(c)
+					}
+					b.StartTimer()
+				}
+			}
+		})
+	}
+}
+
+// TestMetricsOverhead times batches of writes with and without the counters
+// like BenchmarkWriteln and fails if the counters cost more than
+// maxMetricsOverhead. The fastest batches are compared, the slower ones
+// were slowed down by something else.
+func TestMetricsOverhead(t *testing.T) {
+	if testing.Short() || raceEnabled {
+		t.Skip("the timing needs a normal build")
+	}
+
+	const writes, batches = 10000, 50
+	with, _ := newTestPrompt(t, nil, "")
+	without, _ := newTestPrompt(t, nil, "")
+	without.outBuf.stats = nil
+	batch := func(p *Prompt) time.Duration {
+		start := time.Now()
+		for i := 0; i < writes; i++ {
+			p.Writeln(benchLine)
+			if i%1000 == 999 {
+				for _, c := range readChunks(p.outBuf) {
+					p.outBuf.Release(c)
+				}
+			}
+		}
+		return time.Since(start)
+	}
+
+	var best [2]time.Duration
+	for i := 0; i < batches; i++ {
+		for j, p := range []*Prompt{with, without} {
+			if d := batch(p); best[j] == 0 || d < best[j] {
+				best[j] = d
+			}
+		}
+	}
+	if overhead := float64(best[0]-best[1]) / float64(best[1]); overhead > maxMetricsOverhead {
+		t.Fatalf("%d writes took %s with the counters and %s without, %.0f%% more", writes, best[0], best[1], overhead*100)
+	}
+	t.Logf("%d writes took %s with the counters and %s without", writes, best[0], best[1])
+}
+
+func TestMetricsCount(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	p.outBuf.SetOverflowPolicy(10, OverflowDrop)
+	p.Writeln("12345")
+	p.Writeln("1234567890")
+	p.ErrWriteln("error")
+	readChunks(p.outBuf)
+
+	m := p.Metrics()
+	if m.BytesWritten != 10 || m.BytesDropped != 10 || m.MaxQueueDepth != 10 {
+		t.Fatalf("metrics %+v", m)
+	}
+}
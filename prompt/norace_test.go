@@ -0,0 +1,8 @@
+//go:build !race
+// +build !race
+
+package prompt
+
+// raceEnabled is true when the tests run with -race, the detector makes
+// the timings meaningless
+const raceEnabled = false
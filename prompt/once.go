@@ -0,0 +1,231 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// Exit codes of ExecuteOnce, the same as a shell uses
+const (
+	ExitOK       = 0
+	ExitFailed   = 1
+	ExitUsage    = 2
+	ExitNotFound = 127
+)
+
+// notFoundError is returned by Execute for a command that isn't registered
+// when there's no CommandNotFoundHandler
+type notFoundError struct {
+	msg string
+}
+
+func (e *notFoundError) Error() string { return e.msg }
+
+// ExecuteOnce runs line through the executor without the interactive
+// prompt, e.g. for scripts and CI. The commands of the line can be chained
+// like in a shell: the one after && runs only if the one before succeeded,
+// the one after ; runs anyway. The output goes to stdout and stderr as
+// it's written, without the info row, the prompt row or any cursor movement.
+// The exit code is of the last command that ran: ExitOK if it succeeded,
+// ExitUsage if it was used wrong, ExitNotFound for an unknown command and
+// ExitFailed otherwise, err says why. A line that can't be split into
+// commands is a usage error too. The prompt can't be run after, nor before.
+func (p *Prompt) ExecuteOnce(line string) (exitCode int, err error) {
+	if !atomic.CompareAndSwapInt32(&p.started, 0, 1) {
+		return ExitFailed, errors.New("the prompt already ran, create a new one")
+	}
+	atomic.StoreInt32(&p.plain, 1)
+	defer p.outBuf.closeSpools(DefaultDrainTimeout)
+
+	done := make(chan struct{})
+	printed := make(chan struct{})
+	go func() {
+		defer close(printed)
+		p.printPlain(done)
+	}()
+	parts, err := splitChain(line)
+	if err != nil {
+		p.ErrWriteln(err.Error() + "\n")
+	}
+	for _, part := range parts {
+		if part.and && err != nil {
+			continue
+		}
+		err = p.Execute(part.line)
+		if err != nil && p.getCommand(strings.Fields(part.line)[0]) == nil {
+			// The commands' errors are printed already, the rest only go to the info row
+			p.ErrWriteln(err.Error() + "\n")
+		}
+	}
+	close(done)
+	<-printed
+
+	var nf *notFoundError
+	switch {
+	case err == nil:
+		return ExitOK, nil
+	case errors.Is(err, cmd.ErrUsage):
+		return ExitUsage, err
+	case errors.As(err, &nf):
+		return ExitNotFound, err
+	}
+	return ExitFailed, err
+}
+
+// chainPart is one command of a line run by ExecuteOnce
+type chainPart struct {
+	line string
+	and  bool // Runs only if the one before succeeded
+}
+
+// splitChain splits line at && and ; that aren't quoted or escaped
+func splitChain(line string) ([]chainPart, error) {
+	var parts []chainPart
+	var part strings.Builder
+	and := false
+	end := func(op string) error {
+		s := strings.TrimSpace(part.String())
+		part.Reset()
+		if s == "" {
+			return fmt.Errorf("%w: there's no command before '%s'", cmd.ErrUsage, op)
+		}
+		parts = append(parts, chainPart{line: s, and: and})
+		and = op == "&&"
+		return nil
+	}
+
+	var quote rune
+	text := []rune(line)
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+		switch {
+		case r == '\\' && quote != '\'' && i+1 < len(text):
+			part.WriteRune(r)
+			i++
+			r = text[i]
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';':
+			if err := end(";"); err != nil {
+				return nil, err
+			}
+			continue
+		case r == '&' && i+1 < len(text) && text[i+1] == '&':
+			if err := end("&&"); err != nil {
+				return nil, err
+			}
+			i++
+			continue
+		}
+		part.WriteRune(r)
+	}
+	if s := strings.TrimSpace(part.String()); s != "" {
+		parts = append(parts, chainPart{line: s, and: and})
+	} else if and {
+		return nil, fmt.Errorf("%w: there's no command after '&&'", cmd.ErrUsage)
+	}
+	return parts, nil
+}
+
+// runPlain is Run when neither stdout nor stderr is a terminal. The lines
+// of stdin run one after another without the info row or the prompt row
+// and the output is printed plainly like by ExecuteOnce, the errors go to
+// stderr. It ends at the end of stdin.
+func (p *Prompt) runPlain(ctx context.Context) error {
+	atomic.StoreInt32(&p.plain, 1)
+	p.log.Debugf("No terminal, running the lines of stdin")
+
+	done := make(chan struct{})
+	printed := make(chan struct{})
+	go func() {
+		defer close(printed)
+		p.printPlain(done)
+	}()
+	go func() {
+		defer p.recoverGoroutine("stdin reader")
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			select {
+			case <-p.stopCh:
+				return
+			default:
+			}
+			line := p.sanitize(sc.Text())
+			if err := p.Execute(line); err != nil {
+				// Nobody sees the info row
+				p.ErrWriteln(err.Error() + "\n")
+			}
+		}
+		p.requestExit("end of input", nil)
+	}()
+
+	err := p.wait(ctx)
+	close(done)
+	<-printed
+	return err
+}
+
+// printPlain writes the output to stdout and stderr as it comes until
+// done is closed and everything written before is printed. The escape
+// codes are left out for a pipe or a file.
+func (p *Prompt) printPlain(done <-chan struct{}) {
+	b := p.outBuf
+	// Where the last output of each stream ended in an escape code
+	streams := [2]struct {
+		w     *os.File
+		strip bool
+		esc   escapeState
+	}{
+		{w: os.Stdout, strip: !isTerminal(os.Stdout)},
+		{w: os.Stderr, strip: !isTerminal(os.Stderr)},
+	}
+	for {
+		b.mut.Lock()
+		c, ok := b.next()
+		if ok {
+			b.signalSpace()
+			b.stats.delivered()
+		}
+		b.mut.Unlock()
+
+		if ok {
+			out := &streams[0]
+			if c.Stream == StreamStderr {
+				out = &streams[1]
+			}
+			data := c.Data
+			if out.strip {
+				data = stripANSI(nil, data, &out.esc)
+			}
+			if _, err := out.w.Write(data); err != nil {
+				p.logWith("err", err).Errorf("Writing the output failed")
+			}
+			b.Release(c)
+			continue
+		}
+
+		select {
+		case <-done:
+			// The last output was written before done was closed
+			b.mut.Lock()
+			empty := len(b.queue) == 0 && len(b.prio) == 0
+			b.mut.Unlock()
+			if empty {
+				return
+			}
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
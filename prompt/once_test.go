@@ -0,0 +1,148 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// captureStdio sends stdout and stderr to pipes until the returned
+// function or the cleanup is called. The function returns what was
+// written to them.
+func captureStdio(t *testing.T) func() (stdout, stderr string) {
+	t.Helper()
+	var pipes [2]struct {
+		w   *os.File
+		got chan string
+	}
+	for i := range pipes {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		pipes[i].w, pipes[i].got = w, make(chan string, 1)
+		go func(got chan<- string) {
+			b, _ := ioutil.ReadAll(r)
+			r.Close()
+			got <- string(b)
+		}(pipes[i].got)
+	}
+	savedOut, savedErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = pipes[0].w, pipes[1].w
+
+	var once sync.Once
+	restore := func() {
+		once.Do(func() {
+			os.Stdout, os.Stderr = savedOut, savedErr
+			pipes[0].w.Close()
+			pipes[1].w.Close()
+		})
+	}
+	t.Cleanup(restore)
+	return func() (string, string) {
+		restore()
+		return <-pipes[0].got, <-pipes[1].got
+	}
+}
+
+// onceCmds are a deploy command writing the deployed target, a fail
+// command failing and a scale command that needs a number
+func onceCmds(p **Prompt, ran *[]string) []cmd.Cmd {
+	return []cmd.Cmd{
+		&fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+			*ran = append(*ran, "deploy")
+			_, err := (*p).Writeln("\x1b[32mdeployed\x1b[0m " + strings.Join(args, " ") + "\n")
+			return err
+		}},
+		&fakeCmd{name: "fail", run: func(args cmd.Args) error {
+			*ran = append(*ran, "fail")
+			return errors.New("the build failed")
+		}},
+		&fakeCmd{name: "scale", run: func(args cmd.Args) error {
+			*ran = append(*ran, "scale")
+			return fmt.Errorf("%w: scale needs the number of instances", cmd.ErrUsage)
+		}},
+	}
+}
+
+// TestExecuteOnceExitCodes runs lines with one command and chains of
+// them. The exit code is of the last command that ran.
+func TestExecuteOnceExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		code int
+		ran  string // The commands that ran
+	}{
+		{"ok", "deploy api", ExitOK, "deploy"},
+		{"failed", "fail", ExitFailed, "fail"},
+		{"usage", "scale", ExitUsage, "scale"},
+		{"not found", "biuld", ExitNotFound, ""},
+		{"empty", "  ", ExitOK, ""},
+		{"and", "deploy api && deploy web", ExitOK, "deploy deploy"},
+		{"and after a failure", "fail && deploy api && deploy web", ExitFailed, "fail"},
+		{"and after an unknown command", "biuld && deploy api", ExitNotFound, ""},
+		{"semicolon after a failure", "fail; deploy api", ExitOK, "fail deploy"},
+		{"and after a semicolon", "fail && deploy api ; deploy web && scale", ExitUsage, "fail deploy scale"},
+		{"trailing semicolon", "deploy api;", ExitOK, "deploy"},
+		{"quoted operators", `deploy "a && b" 'c;d' e\;f`, ExitOK, "deploy"},
+		{"nothing before and", "&& deploy api", ExitUsage, ""},
+		{"nothing between", "deploy api ; ; deploy web", ExitUsage, ""},
+		{"nothing after and", "deploy api &&", ExitUsage, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p *Prompt
+			var ran []string
+			p, _ = newTestPrompt(t, onceCmds(&p, &ran), "")
+			output := captureStdio(t)
+
+			code, err := p.ExecuteOnce(tt.line)
+			stdout, stderr := output()
+			if code != tt.code {
+				t.Fatalf("the exit code is %d (%v), want %d; stdout %q, stderr %q", code, err, tt.code, stdout, stderr)
+			}
+			if (err == nil) != (code == ExitOK) {
+				t.Fatalf("the exit code %d came with the error %v", code, err)
+			}
+			if got := strings.Join(ran, " "); got != tt.ran {
+				t.Fatalf("ran %q, want %q", got, tt.ran)
+			}
+			if code != ExitOK && !strings.Contains(stderr, err.Error()) {
+				t.Fatalf("stderr %q doesn't say %q", stderr, err)
+			}
+		})
+	}
+}
+
+// TestExecuteOncePlainOutput checks the output of a chain has no escape
+// codes, neither of the commands nor of the prompt's rendering
+func TestExecuteOncePlainOutput(t *testing.T) {
+	var p *Prompt
+	var ran []string
+	p, _ = newTestPrompt(t, onceCmds(&p, &ran), "")
+	output := captureStdio(t)
+
+	code, _ := p.ExecuteOnce("deploy api && deploy web; biuld; fail")
+	stdout, stderr := output()
+	if code != ExitFailed {
+		t.Fatalf("the exit code is %d", code)
+	}
+	if stdout != "deployed api\ndeployed web\n" {
+		t.Fatalf("stdout got %q", stdout)
+	}
+	for _, want := range []string{"Unknown command 'biuld'", "the build failed"} {
+		if !strings.Contains(stderr, want) {
+			t.Fatalf("stderr %q doesn't say %q", stderr, want)
+		}
+	}
+	if strings.Contains(stdout+stderr, "\x1b[") {
+		t.Fatalf("the output has escape codes: %q %q", stdout, stderr)
+	}
+}
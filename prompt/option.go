@@ -0,0 +1,174 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// Option configures a Prompt. New and NewPrompt accept any number of options.
+type Option func(p *Prompt) error
+
+// WithPrefix replaces the "> " in front of the user's input
+func WithPrefix(prefix string) Option {
+	return func(p *Prompt) error {
+		if strings.ContainsAny(prefix, "\r\n") {
+			return fmt.Errorf("the prefix %q can't span more lines", prefix)
+		}
+		p.promptPrefix = prefix
+		return nil
+	}
+}
+
+// CommandNotFoundHandler is called by the executor when a user
+// types a command that isn't registered
+type CommandNotFoundHandler func(name string, args []string) error
+
+// WithCommandNotFoundHandler replaces the default "Unknown command" message.
+// An error returned from the handler is shown on the info row. The handler
+// gets the line split into words like by a shell, without the expansions:
+// quotes keep spaces in a word and a backslash escapes the next character.
+// Registered commands get the line split on spaces.
+func WithCommandNotFoundHandler(fn CommandNotFoundHandler) Option {
+	return func(p *Prompt) error {
+		p.notFoundHandler = fn
+		return nil
+	}
+}
+
+// WithConsoleWriter replaces the writer that renders the output, info row
+// and prompt row to the terminal. Useful to capture what Execute renders.
+func WithConsoleWriter(w goprompt.ConsoleWriter) Option {
+	return func(p *Prompt) error {
+		if w == nil {
+			return errors.New("the console writer can't be nil")
+		}
+		p.writer = w
+		return nil
+	}
+}
+
+// WithConsoleParser replaces the terminal go-prompt reads the input from
+// and asks for its size, e.g. for a terminal other than the process's own
+func WithConsoleParser(parser goprompt.ConsoleParser) Option {
+	return func(p *Prompt) error {
+		if parser == nil {
+			return errors.New("the console parser can't be nil")
+		}
+		p.parser = parser
+		return nil
+	}
+}
+
+// WithGoPromptOptions passes more options to go-prompt, e.g. OptionMaxSuggestion
+// or OptionHistory. They're applied before the prompt's own options, which
+// override the same options given here: OptionPrefix, OptionLivePrefix,
+// OptionPrefixTextColor, OptionParser, OptionWriter,
+// OptionSetExitCheckerOnInput, OptionBreakLineCallback,
+// OptionSwitchKeyBindMode, OptionHistory with a history and
+// OptionCompletionWordSeparator with WithWordDelimiters. Use WithPrefix,
+// WithConsoleParser and WithConsoleWriter instead. Key binds added here
+// run next to the ones the prompt sets and Bind.
+func WithGoPromptOptions(opts ...goprompt.Option) Option {
+	return func(p *Prompt) error {
+		for i, opt := range opts {
+			if opt == nil {
+				return fmt.Errorf("go-prompt option %d is nil", i)
+			}
+		}
+		p.goPromptOpts = append(p.goPromptOpts, opts...)
+		return nil
+	}
+}
+
+// WithOutputSpool copies all output to a temporary file as it's written.
+// The file is rotated once it's bigger than maxSize bytes.
+func WithOutputSpool(maxSize int64) Option {
+	return func(p *Prompt) error {
+		return p.outBuf.EnableSpool(maxSize)
+	}
+}
+
+// WithOutputLog appends all output to the file at path as it's written, e.g.
+// for a transcript of the session. With stripANSI the colors and other
+// escape codes are left out. The file is closed once the prompt stops.
+func WithOutputLog(path string, stripANSI bool) Option {
+	return func(p *Prompt) error {
+		if path == "" {
+			return errors.New("the output log path can't be empty")
+		}
+		return p.outBuf.EnableOutputLog(path, stripANSI)
+	}
+}
+
+// WithOutputLimit caps the number of bytes waiting to be rendered and sets
+// what happens to writes over the cap. With OverflowBlock, Writeln blocks
+// until the renderer catches up; use WritelnCtx to be able to give up.
+func WithOutputLimit(limit int, policy OverflowPolicy) Option {
+	return func(p *Prompt) error {
+		if limit <= 0 {
+			return fmt.Errorf("output limit must be positive, got %d", limit)
+		}
+		p.outBuf.SetOverflowPolicy(limit, policy)
+		return nil
+	}
+}
+
+// WithNormalizeNewlines makes the renderer treat "\r\n" line endings
+// (common in output of Windows programs) as a single line break and a lone
+// '\r' as a return to the start of the line. The bytes sent to the terminal
+// stay the same, only the layout bookkeeping changes.
+func WithNormalizeNewlines() Option {
+	return func(p *Prompt) error {
+		p.normalizeNewlines = true
+		return nil
+	}
+}
+
+const (
+	DefaultChannelDepth = 128
+	MinChannelDepth     = 1
+	MaxChannelDepth     = 4096
+)
+
+// WithChunkSize sets the maximum number of bytes printed in a single render
+// pass (one flush). Small chunks keep the prompt responsive but cost a syscall
+// per chunk, which adds up on slow remote terminals (SSH). Big chunks need fewer
+// syscalls but the output arrives in laggy bursts. The default of
+// DefaultChunkSize suits local terminals and most remote ones; try 64KB over
+// very slow links.
+func WithChunkSize(n int) Option {
+	return func(p *Prompt) error {
+		return p.outBuf.SetChunkSize(n)
+	}
+}
+
+// WithWriteCoalesce merges writes that come less than d apart into a single
+// render pass and flush. It helps commands that print thousands of tiny
+// pieces of text, at the cost of up to a few times d of latency. 5ms is
+// a good start.
+func WithWriteCoalesce(d time.Duration) Option {
+	return func(p *Prompt) error {
+		if d < 0 {
+			return fmt.Errorf("write coalesce duration can't be negative, got %s", d)
+		}
+		p.outBuf.SetCoalesce(d)
+		return nil
+	}
+}
+
+// WithChannelDepth sets how many chunks can be waiting between the output
+// buffer and the renderer. A deeper channel absorbs bursts better but
+// delays priority output that has to wait behind the queued chunks.
+func WithChannelDepth(n int) Option {
+	return func(p *Prompt) error {
+		if n < MinChannelDepth || n > MaxChannelDepth {
+			return fmt.Errorf("channel depth must be between %d and %d, got %d", MinChannelDepth, MaxChannelDepth, n)
+		}
+		p.chanDepth = n
+		return nil
+	}
+}
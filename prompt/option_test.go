@@ -0,0 +1,333 @@
+package prompt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// TestOptions applies every option with a valid value to a new prompt and
+// checks that it got to the prompt
+func TestOptions(t *testing.T) {
+	dir := t.TempDir()
+	nop := func() {}
+	w, _ := fakeWriter()
+	parser := newFakeParser()
+	maxSuggestion := goprompt.OptionMaxSuggestion(3)
+	style := StreamStyle{Prefix: "! ", Color: "\x1b[33m"}
+
+	tests := []struct {
+		name  string
+		opt   Option
+		check func(p *Prompt) bool
+	}{
+		{"WithActivityIndicator", WithActivityIndicator("⣾"), func(p *Prompt) bool {
+			return p.activityMark == "⣾"
+		}},
+		{"WithBusyPolicy", WithBusyPolicy(BusyQueue), func(p *Prompt) bool {
+			return p.busyPolicy == BusyQueue
+		}},
+		{"WithChannelDepth", WithChannelDepth(MaxChannelDepth), func(p *Prompt) bool {
+			return p.chanDepth == MaxChannelDepth
+		}},
+		{"WithChunkSize", WithChunkSize(MinChunkSize), func(p *Prompt) bool {
+			return p.outBuf.chunkSize == MinChunkSize
+		}},
+		{"WithColorLevel", WithColorLevel(Color256), func(p *Prompt) bool {
+			return p.colorLevel == Color256
+		}},
+		{"WithCommandNotFoundHandler", WithCommandNotFoundHandler(func(string, []string) error { return nil }), func(p *Prompt) bool {
+			return p.notFoundHandler != nil
+		}},
+		{"WithCompletionTimeout", WithCompletionTimeout(time.Minute), func(p *Prompt) bool {
+			return p.completionTimeout == time.Minute
+		}},
+		{"WithConsoleParser", WithConsoleParser(parser), func(p *Prompt) bool {
+			return p.parser == parser
+		}},
+		{"WithConsoleWriter", WithConsoleWriter(w), func(p *Prompt) bool {
+			rw, ok := p.writer.(*recordWriter)
+			return ok && rw.ConsoleWriter == w
+		}},
+		{"WithContinuationPrefix", WithContinuationPrefix("... "), func(p *Prompt) bool {
+			return p.contPrefix == "... "
+		}},
+		{"WithCtrlC", WithCtrlC(CtrlCCancel), func(p *Prompt) bool {
+			return p.ctrlCMode == CtrlCCancel
+		}},
+		{"WithEditMode", WithEditMode(EditVi), func(p *Prompt) bool {
+			return p.editMode == EditVi
+		}},
+		{"WithGoPromptOptions", WithGoPromptOptions(maxSuggestion), func(p *Prompt) bool {
+			return len(p.goPromptOpts) == 1
+		}},
+		{"WithHistoryFile", WithHistoryFile(filepath.Join(dir, "history")), func(p *Prompt) bool {
+			return p.history.path == filepath.Join(dir, "history")
+		}},
+		{"WithHistoryFlushInterval", WithHistoryFlushInterval(time.Minute), func(p *Prompt) bool {
+			return p.history.interval == time.Minute
+		}},
+		{"WithIO", WithIO(strings.NewReader(""), &syncBuffer{}, SizeFunc(func() (int, int) { return 24, 80 }, time.Second)), func(p *Prompt) bool {
+			return p.remote != nil && p.termOut == p.remote.out
+		}},
+		{"WithIdleTimeout", WithIdleTimeout(time.Minute, nop), func(p *Prompt) bool {
+			return p.idleTimeout == time.Minute && p.onIdle != nil
+		}},
+		{"WithInfoRows", WithInfoRows(3), func(p *Prompt) bool {
+			return p.infoRows == 3 && p.reservedRows() == 4
+		}},
+		{"WithInitScript", WithInitScript(filepath.Join(dir, "init")), func(p *Prompt) bool {
+			return p.initScript == filepath.Join(dir, "init")
+		}},
+		{"WithInputReader", WithInputReader(strings.NewReader("")), func(p *Prompt) bool {
+			_, ok := p.parser.(*readerParser)
+			return ok
+		}},
+		{"WithLayout", WithLayout(Layout{InfoRows: 2, ScrollMargin: 1, MaxWidth: 60, Header: " foundry "}), func(p *Prompt) bool {
+			return p.infoRows == 2 && p.scrollMargin == 1 && p.maxWidth == 60 && p.headerText == "foundry"
+		}},
+		{"WithLogger", WithLogger(&fakeLogger{}), func(p *Prompt) bool {
+			_, ok := p.log.(*fakeLogger)
+			return ok
+		}},
+		{"WithMaxWidth", WithMaxWidth(40), func(p *Prompt) bool {
+			return p.maxWidth == 40
+		}},
+		{"WithNormalizeNewlines", WithNormalizeNewlines(), func(p *Prompt) bool {
+			return p.normalizeNewlines
+		}},
+		{"WithOutputLimit", WithOutputLimit(1024, OverflowDrop), func(p *Prompt) bool {
+			return p.outBuf.limit == 1024 && p.outBuf.policy == OverflowDrop
+		}},
+		{"WithOutputLog", WithOutputLog(filepath.Join(dir, "output.log"), true), func(p *Prompt) bool {
+			return p.outBuf.outputLog != nil
+		}},
+		{"WithOutputSpool", WithOutputSpool(1024 * 1024), func(p *Prompt) bool {
+			return p.outBuf.spool != nil
+		}},
+		{"WithPagerThreshold", WithPagerThreshold(10), func(p *Prompt) bool {
+			return p.pagerThreshold == 10
+		}},
+		{"WithPasteMode", WithPasteMode(PasteQueue), func(p *Prompt) bool {
+			return p.pasteMode == PasteQueue
+		}},
+		{"WithPostRender", WithPostRender(nop), func(p *Prompt) bool {
+			return p.postRender != nil
+		}},
+		{"WithPreRender", WithPreRender(nop), func(p *Prompt) bool {
+			return p.preRender != nil
+		}},
+		{"WithPrefix", WithPrefix("foundry> "), func(p *Prompt) bool {
+			return p.promptPrefix == "foundry> "
+		}},
+		{"WithRepeatOnEmpty", WithRepeatOnEmpty(), func(p *Prompt) bool {
+			return p.repeatOnEmpty
+		}},
+		{"WithScreenSnapshot", WithScreenSnapshot(), func(p *Prompt) bool {
+			return p.screen != nil
+		}},
+		{"WithScrollMargin", WithScrollMargin(2), func(p *Prompt) bool {
+			return p.scrollMargin == 2
+		}},
+		{"WithSearchPromptFormat", WithSearchPromptFormat("find %s: ", "no %s: "), func(p *Prompt) bool {
+			return p.searchFormat == [2]string{"find %s: ", "no %s: "}
+		}},
+		{"WithShellFallback", WithShellFallback(), func(p *Prompt) bool {
+			return p.notFoundHandler != nil
+		}},
+		{"WithStreamStyle", WithStreamStyle(StreamDefault, style), func(p *Prompt) bool {
+			return p.streamStyles[StreamDefault] == style && p.streamStyles[StreamStderr] == defaultStreamStyles()[StreamStderr]
+		}},
+		{"WithTerminalSize", WithTerminalSize(30, 100), func(p *Prompt) bool {
+			return p.fixedSize != nil && p.fixedSize.Row == 30 && p.fixedSize.Col == 100
+		}},
+		{"WithTheme", WithTheme(Theme{Prefix: goprompt.Blue, Info: goprompt.Yellow, Streams: map[Stream]StreamStyle{StreamDefault: style}}), func(p *Prompt) bool {
+			return p.prefixColor == goprompt.Blue && p.infoColor == goprompt.Yellow &&
+				p.streamStyles[StreamDefault] == style && p.streamStyles[StreamStderr] == defaultStreamStyles()[StreamStderr]
+		}},
+		{"WithWordDelimiters", WithWordDelimiters(" /"), func(p *Prompt) bool {
+			return p.editDelimiters == " /" && p.completionDelimiters == " /"
+		}},
+		{"WithWriteCoalesce", WithWriteCoalesce(time.Millisecond), func(p *Prompt) bool {
+			return p.outBuf.coalesce == time.Millisecond
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "", tt.opt)
+			if !tt.check(p) {
+				t.Fatalf("%s didn't get to the prompt", tt.name)
+			}
+		})
+	}
+}
+
+// TestInvalidOptionsCloseFiles gives New options opening files and an
+// invalid one after them. The files are closed and the temporary spool
+// is removed.
+func TestInvalidOptionsCloseFiles(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	path := filepath.Join(t.TempDir(), "session.log")
+
+	_, err := New(nil, WithOutputSpool(0), WithOutputLog(path, false), WithChunkSize(0))
+	if err == nil {
+		t.Fatal("New accepted the invalid chunk size")
+	}
+	if left, _ := ioutil.ReadDir(tmp); len(left) != 0 {
+		t.Fatalf("the spool %s was left behind", left[0].Name())
+	}
+	// Linux shows the open files in /proc
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return
+	}
+	for _, fd := range fds {
+		if target, _ := os.Readlink(filepath.Join("/proc/self/fd", fd.Name())); target == path {
+			t.Fatalf("the output log is still open as fd %s", fd.Name())
+		}
+	}
+}
+
+// TestInvalidOptions checks that New returns the error of every option
+// given a value it can't use
+func TestInvalidOptions(t *testing.T) {
+	nop := func() {}
+	size := SizeFunc(func() (int, int) { return 24, 80 }, time.Second)
+	tests := []struct {
+		name string
+		opt  Option
+		err  string // Part of the error
+	}{
+		{"WithActivityIndicator empty", WithActivityIndicator(""), "the activity mark can't be empty"},
+		{"WithActivityIndicator wide", WithActivityIndicator("...."), "can take at most 3 columns"},
+		{"WithBusyPolicy", WithBusyPolicy(BusyQueue + 1), "unknown busy policy"},
+		{"WithChannelDepth", WithChannelDepth(MaxChannelDepth + 1), "channel depth must be between"},
+		{"WithChunkSize", WithChunkSize(MinChunkSize - 1), "chunk size must be between"},
+		{"WithColorLevel", WithColorLevel(ColorTrueColor + 1), "unknown color level"},
+		{"WithCompletionTimeout", WithCompletionTimeout(0), "completion timeout must be positive"},
+		{"WithConsoleParser", WithConsoleParser(nil), "the console parser can't be nil"},
+		{"WithConsoleWriter", WithConsoleWriter(nil), "the console writer can't be nil"},
+		{"WithContinuationPrefix", WithContinuationPrefix(".\n."), "can't span more lines"},
+		{"WithCtrlC", WithCtrlC(CtrlCCancel + 1), "unknown Ctrl+C mode"},
+		{"WithEditMode", WithEditMode(EditVi + 1), "unknown edit mode"},
+		{"WithGoPromptOptions nil", WithGoPromptOptions(nil), "go-prompt option 0 is nil"},
+		{"WithHistoryFile", WithHistoryFile(""), "the history file path can't be empty"},
+		{"WithHistoryFlushInterval", WithHistoryFlushInterval(-time.Second), "history flush interval must be positive"},
+		{"WithIO nil", WithIO(nil, &syncBuffer{}, size), "can't be nil"},
+		{"WithIO no size", WithIO(strings.NewReader(""), &syncBuffer{}, SizeSource{}), "must come from SizeFunc or SizeChannel"},
+		{"WithIO polling", WithIO(strings.NewReader(""), &syncBuffer{}, SizeFunc(func() (int, int) { return 24, 80 }, 0)), "isn't positive"},
+		{"WithIdleTimeout duration", WithIdleTimeout(0, nop), "idle timeout must be positive"},
+		{"WithIdleTimeout callback", WithIdleTimeout(time.Second, nil), "idle timeout callback can't be nil"},
+		{"WithInfoRows none", WithInfoRows(0), "info rows must be between 1 and 5"},
+		{"WithInfoRows many", WithInfoRows(MaxInfoRows + 1), "info rows must be between 1 and 5"},
+		{"WithLayout info rows", WithLayout(Layout{InfoRows: -1}), "info rows must be between"},
+		{"WithLayout scroll margin", WithLayout(Layout{ScrollMargin: -1}), "scroll margin can't be negative"},
+		{"WithLayout max width", WithLayout(Layout{MaxWidth: -1}), "max width must be at least 1 column"},
+		{"WithLayout header", WithLayout(Layout{Header: "a\nb"}), "the header can't span more lines"},
+		{"WithLogger", WithLogger(nil), "logger can't be nil"},
+		{"WithMaxWidth", WithMaxWidth(0), "max width must be at least 1 column"},
+		{"WithOutputLimit", WithOutputLimit(0, OverflowBlock), "output limit must be positive"},
+		{"WithOutputLog", WithOutputLog("", false), "the output log path can't be empty"},
+		{"WithPagerThreshold", WithPagerThreshold(0), "pager threshold must be at least 1 row"},
+		{"WithPasteMode", WithPasteMode(PasteQueue + 1), "unknown paste mode"},
+		{"WithPostRender", WithPostRender(nil), "the post-render callback can't be nil"},
+		{"WithPreRender", WithPreRender(nil), "the pre-render callback can't be nil"},
+		{"WithPrefix", WithPrefix("a\r\n"), "can't span more lines"},
+		{"WithScrollMargin", WithScrollMargin(-1), "scroll margin can't be negative"},
+		{"WithSearchPromptFormat verbs", WithSearchPromptFormat("%s %d", "%s"), "needs a single %s"},
+		{"WithSearchPromptFormat lines", WithSearchPromptFormat("%s", "\n%s"), "can't span more lines"},
+		{"WithTheme", WithTheme(Theme{Prefix: goprompt.White + 1}), "unknown color"},
+		{"WithWordDelimiters", WithWordDelimiters(" \t"), "can't contain line breaks or tabs"},
+		{"WithWriteCoalesce", WithWriteCoalesce(-time.Millisecond), "write coalesce duration can't be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(nil, WithInputReader(strings.NewReader("")), tt.opt)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("New returned %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestInfoRows lays out three info rows at the bottom of a 24 row
+// terminal. Every line of the info text gets a row, the last one the rest.
+func TestInfoRows(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "", WithInfoRows(3))
+	startPrompt(t, p)
+
+	if err := p.SetInfoln("one\ntwo\nthree\nfour", InfoLineSeverityNormal); err != nil {
+		t.Fatalf("SetInfoln: %s", err)
+	}
+	p.lockRender()
+	infoRow, promptRow, lines := p.infoRow, p.promptRow, p.infoLines()
+	p.unlockRender()
+
+	if infoRow != 21 || promptRow != 24 {
+		t.Fatalf("the info rows start at %d and the prompt row is %d, want 21 and 24", infoRow, promptRow)
+	}
+	want := []string{"one", "two", "three four"}
+	if strings.Join(lines, "|") != strings.Join(want, "|") {
+		t.Fatalf("the info rows %q, want %q", lines, want)
+	}
+	drawn := out.String()
+	for i, l := range want {
+		row := fmt.Sprintf("\x1b[%d;1H\x1b[2K", infoRow+i)
+		if at := strings.LastIndex(drawn, row); at < 0 || !strings.Contains(drawn[at:], l) {
+			t.Errorf("%q isn't drawn on row %d", l, infoRow+i)
+		}
+	}
+}
+
+// TestGoPromptOptionsHonored runs go-prompt's loop with a key bind and
+// a history given through WithGoPromptOptions. Both work next to the
+// prompt's own options.
+func TestGoPromptOptionsHonored(t *testing.T) {
+	insert := goprompt.OptionAddKeyBind(goprompt.KeyBind{
+		Key: goprompt.F3,
+		Fn:  func(buf *goprompt.Buffer) { buf.InsertText("deploy ", false, true) },
+	})
+	history := goprompt.OptionHistory([]string{"logs api"})
+	p, parser, _, _ := newTTYPrompt(t, nil, WithGoPromptOptions(insert, history))
+	go p.Run()
+	<-p.Ready()
+
+	// F3
+	parser.keys <- []byte("\x1bOR")
+	parser.keys <- []byte("api")
+	waitFor(t, "the text of the key bind", func() bool { return p.input() == "deploy api" })
+	// Ctrl+U clears the line and the up arrow brings the history back
+	parser.keys <- []byte{0x15}
+	parser.keys <- []byte("\x1b[A")
+	waitFor(t, "the line from the history", func() bool { return p.input() == "logs api" })
+}
+
+// TestGoPromptOptionsOverridden passes options the prompt sets itself. The
+// prompt's prefix, writer and parser are used anyway and it keeps running.
+func TestGoPromptOptionsOverridden(t *testing.T) {
+	other := newFakeParser()
+	otherOut := &syncBuffer{}
+	p, parser, _, term := newTTYPrompt(t, nil, WithGoPromptOptions(
+		goprompt.OptionPrefix("$ "),
+		goprompt.OptionLivePrefix(func() (string, bool) { return "$ ", true }),
+		goprompt.OptionParser(other),
+		goprompt.OptionWriter(&ioWriter{w: otherOut}),
+		goprompt.OptionSetExitCheckerOnInput(func(string, bool) bool { return true }),
+	))
+	go p.Run()
+	<-p.Ready()
+
+	parser.keys <- []byte("deploy")
+	waitFor(t, "the typed text", func() bool { return p.input() == "deploy" })
+	waitFor(t, "the text drawn", func() bool { return strings.Contains(term.String(), "deploy") })
+	if !strings.Contains(term.String(), p.promptPrefix) || strings.Contains(term.String(), "$ ") || otherOut.String() != "" || other.readCount() != 0 {
+		t.Fatalf("the options replaced the prompt's, the terminal got %q", term.String())
+	}
+}
@@ -0,0 +1,37 @@
+package prompt
+
+import "time"
+
+// outputIdleDelay is how long nothing is printed before the output is idle.
+// Shorter gaps, e.g. between the lines of a command's output, don't count.
+const outputIdleDelay = 50 * time.Millisecond
+
+// outputFlow is whether the output is being printed. Only used by the
+// renderer goroutine.
+type outputFlow struct {
+	busy bool
+	last time.Time // When the last chunk was printed
+}
+
+// trackOutput sends the busy event when the output starts and the idle
+// event once everything written was printed and flushed. printed is true
+// if the renderer printed a chunk just now.
+func (p *Prompt) trackOutput(printed bool) {
+	f := &p.outputFlow
+	now := time.Now()
+	if printed {
+		f.last = now
+		if !f.busy {
+			f.busy = true
+			p.emit(NewBusyEvent())
+		}
+		return
+	}
+	// The output held back by the pager isn't printed yet. A chunk moves
+	// from the buffer to bufCh, the buffer is looked into first.
+	if !f.busy || now.Sub(f.last) < outputIdleDelay || p.paging() != nil || !p.outBuf.empty() || len(p.bufCh) > 0 {
+		return
+	}
+	f.busy = false
+	p.emit(NewIdleEvent())
+}
@@ -0,0 +1,249 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// WithPagerThreshold makes Page show output longer than rows lines in
+// a pager instead of printing it. The pager takes the rows between the
+// header and the info row, space shows the next page, the arrows scroll
+// by a line, b shows the previous page and q or Ctrl+C closes it. The page
+// that's visible then stays on the screen. Other output waits until the
+// pager is closed.
+func WithPagerThreshold(rows int) Option {
+	return func(p *Prompt) error {
+		if rows < 1 {
+			return fmt.Errorf("pager threshold must be at least 1 row, got %d", rows)
+		}
+		p.pagerThreshold = rows
+		return nil
+	}
+}
+
+// pager is the state of the output being paged. It's guarded by renderMutex
+// like the pager threshold.
+type pager struct {
+	lines []string
+	top   int // Index of the first visible line
+	done  chan struct{}
+}
+
+// Page writes the whole output of a command. It's printed like with
+// Writeln unless it's longer than the pager threshold, then it's shown
+// in the pager. Page doesn't wait for the pager to be closed.
+func (p *Prompt) Page(s string) error {
+	p.assertNotRenderLocked()
+	lines := strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+	// The set command can change the threshold
+	p.lockRender()
+	threshold := p.pagerThreshold
+	p.unlockRender()
+	if threshold == 0 || len(lines) <= threshold {
+		_, err := p.Writeln(s)
+		return err
+	}
+
+	select {
+	case <-p.readyCh:
+	default:
+		// The pager needs the size of the terminal. Before the initial
+		// render the output waits in the buffer like any other.
+		_, err := p.Writeln(s)
+		return err
+	}
+
+	// Whatever was written before is printed above the pager
+	if err := p.Drain(context.Background()); err != nil {
+		return err
+	}
+
+	// print() isn't called while the pager is open, consumeOne checks
+	// for it with consumeMutex held
+	p.consumeMutex.Lock()
+	defer p.consumeMutex.Unlock()
+
+	p.lockRender()
+	defer p.unlockRender()
+	if p.pager != nil {
+		close(p.pager.done)
+	}
+	p.pager = &pager{lines: lines, done: make(chan struct{})}
+	// The paged output is a part of the transcript as if it was printed
+	p.transcript.write(s)
+	if !strings.HasSuffix(s, "\n") {
+		p.transcript.write("\n")
+	}
+	p.logWith("lines", len(lines)).Debugf("Opening the pager")
+	p.renderPage()
+	return p.flush()
+}
+
+// paging returns the channel closed once the pager is closed
+// or nil if the pager isn't open
+func (p *Prompt) paging() chan struct{} {
+	p.lockRender()
+	defer p.unlockRender()
+	if p.pager == nil {
+		return nil
+	}
+	return p.pager.done
+}
+
+// pagerHeight returns the number of rows the pager shows, the same
+// as the output can take. renderMutex must be held.
+func (p *Prompt) pagerHeight() int {
+	if h := p.totalRows - p.scrollThreshold() - p.outputStart().Row; h > 0 {
+		return h
+	}
+	return 1
+}
+
+// renderPage draws the visible lines of the pager and its status on
+// the info row. renderMutex must be held.
+func (p *Prompt) renderPage() {
+	pg := p.pager
+	h := p.pagerHeight()
+	start := p.outputStart().Row
+	for r := 0; r < h; r++ {
+		p.writer.CursorGoTo(start+r, 1)
+		p.writer.EraseLine()
+		if i := pg.top + r; i < len(pg.lines) {
+			// Long lines are cut so the page fits the screen
+			p.writer.WriteRawStr(clip(pg.lines[i], p.totalColumns))
+			p.writer.WriteRawStr("\x1b[0m")
+		}
+	}
+
+	last := pg.top + h
+	if last > len(pg.lines) {
+		last = len(pg.lines)
+	}
+	status := fmt.Sprintf("lines %d-%d of %d (space: next page, b: previous page, arrows: scroll, q: quit)", pg.top+1, last, len(pg.lines))
+	p.writer.CursorGoTo(p.infoRow, 1)
+	p.writer.EraseLine()
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, true)
+	p.writer.WriteRawStr(clip(status, p.totalColumns))
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+}
+
+// pagerKey handles the key if the pager is open. It returns false
+// if the key should go to the input instead.
+func (p *Prompt) pagerKey(b []byte) bool {
+	p.lockRender()
+	defer p.unlockRender()
+	pg := p.pager
+	if pg == nil {
+		return false
+	}
+
+	h := p.pagerHeight()
+	// The last page is full unless the output is shorter than a page
+	clamp := func(top int) int {
+		if max := len(pg.lines) - h; top > max {
+			top = max
+		}
+		if top < 0 {
+			top = 0
+		}
+		return top
+	}
+
+	top := pg.top
+	switch goprompt.GetKey(b) {
+	case goprompt.Down, goprompt.Enter, goprompt.ControlM, goprompt.ControlJ:
+		top++
+	case goprompt.Up:
+		top--
+	case goprompt.PageDown:
+		top += h
+	case goprompt.PageUp:
+		top -= h
+	case goprompt.ControlC:
+		p.closePager()
+		return true
+	case goprompt.NotDefined:
+		// Scripted input can bring more keys at once
+		for _, r := range string(b) {
+			switch r {
+			case ' ':
+				if top+h >= len(pg.lines) {
+					// Space on the last page is done with the output
+					p.showPage(top)
+					p.closePager()
+					return true
+				}
+				top += h
+			case 'b':
+				top -= h
+			case 'j':
+				top++
+			case 'k':
+				top--
+			case 'q':
+				p.showPage(top)
+				p.closePager()
+				return true
+			}
+			top = clamp(top)
+		}
+	}
+	p.showPage(clamp(top))
+	return true
+}
+
+// showPage scrolls the pager so that top is the first visible line.
+// renderMutex must be held.
+func (p *Prompt) showPage(top int) {
+	pg := p.pager
+	if top == pg.top {
+		return
+	}
+	pg.top = top
+	p.renderPage()
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
+
+// closePager leaves the visible page on the screen and continues the
+// output below it. renderMutex must be held.
+func (p *Prompt) closePager() {
+	pg := p.pager
+	p.pager = nil
+	close(pg.done)
+	p.log.Debugf("Closing the pager")
+
+	visible := len(pg.lines) - pg.top
+	if h := p.pagerHeight(); visible > h {
+		visible = h
+	}
+	p.currentPos = CursorPos{Row: p.outputStart().Row + visible, Col: 1}
+	p.savedPos = p.currentPos
+	p.freeRows = p.totalRows - (p.currentPos.Row - 1)
+
+	p.renderInfo()
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
+
+// pagerParser gives the keys to the pager while it's open
+type pagerParser struct {
+	goprompt.ConsoleParser
+	p *Prompt
+}
+
+func (pp *pagerParser) Read() ([]byte, error) {
+	b, err := pp.ConsoleParser.Read()
+	if err != nil || !pp.p.pagerKey(b) {
+		return b, err
+	}
+	// go-prompt skips a lone zero byte
+	return []byte{0}, nil
+}
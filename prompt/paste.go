@@ -0,0 +1,221 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// PasteMode decides what happens to text pasted with more lines
+type PasteMode int
+
+const (
+	// PasteInsert puts the pasted text into the input as a single line,
+	// the line breaks are shown as ⏎. It's the default.
+	PasteInsert PasteMode = iota
+	// PasteQueue keeps the pasted lines and runs them one by one once
+	// enter is pressed on the empty input. The info row says how many
+	// there are. Ctrl+C or typing another line drops them.
+	PasteQueue
+)
+
+// pasteLineBreak stands for a pasted line break in the input
+const pasteLineBreak = "⏎"
+
+// The terminal puts pasted text between these in the bracketed paste mode
+var (
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// WithPasteMode sets what happens to pasted text with more lines
+func WithPasteMode(mode PasteMode) Option {
+	return func(p *Prompt) error {
+		if mode < PasteInsert || mode > PasteQueue {
+			return fmt.Errorf("unknown paste mode %d", mode)
+		}
+		p.pasteMode = mode
+		return nil
+	}
+}
+
+// pasteState is the pasted lines PasteQueue keeps until enter
+type pasteState struct {
+	mut   sync.Mutex
+	lines []string
+}
+
+// bracketedPaste turns the terminal's bracketed paste mode on or off.
+// The terminal doesn't send the pasted line breaks as enter then.
+func (p *Prompt) bracketedPaste(on bool) {
+	if os.Getenv("TERM") == "dumb" {
+		return
+	}
+	if on {
+		fmt.Fprint(p.termOut, "\x1b[?2004h")
+	} else {
+		fmt.Fprint(p.termOut, "\x1b[?2004l")
+	}
+}
+
+// pasteParser turns the bracketed paste mode on while go-prompt reads the
+// input and gives go-prompt the pasted text as a single piece of input
+type pasteParser struct {
+	goprompt.ConsoleParser
+	p *Prompt
+
+	pasting bool
+	pasted  []byte
+	pending []byte // The end of the last read that can be the start of a marker
+}
+
+func (pp *pasteParser) Setup() error {
+	if err := pp.ConsoleParser.Setup(); err != nil {
+		return err
+	}
+	pp.p.bracketedPaste(true)
+	return nil
+}
+
+func (pp *pasteParser) TearDown() error {
+	pp.p.bracketedPaste(false)
+	return pp.ConsoleParser.TearDown()
+}
+
+func (pp *pasteParser) Read() ([]byte, error) {
+	b, err := pp.ConsoleParser.Read()
+	if err != nil || (len(b) == 0 && len(pp.pending) == 0) {
+		return b, err
+	}
+	data := append(pp.pending, b...)
+	pp.pending = nil
+
+	var out []byte
+	for len(data) > 0 {
+		if !pp.pasting {
+			i := bytes.Index(data, pasteStart)
+			if i < 0 {
+				// A part of the marker is at least "ESC [ 2", a lone
+				// escape is the escape key and can't wait for more
+				n := len(data) - markerPrefix(data, pasteStart, 3)
+				out = append(out, data[:n]...)
+				pp.pending = append(pp.pending, data[n:]...)
+				break
+			}
+			out = append(out, data[:i]...)
+			data = data[i+len(pasteStart):]
+			pp.pasting = true
+		}
+
+		i := bytes.Index(data, pasteEnd)
+		if i < 0 {
+			// The paste continues in the next read
+			n := len(data) - markerPrefix(data, pasteEnd, 1)
+			pp.pasted = append(pp.pasted, data[:n]...)
+			pp.pending = append(pp.pending, data[n:]...)
+			break
+		}
+		pp.pasted = append(pp.pasted, data[:i]...)
+		data = data[i+len(pasteEnd):]
+		pp.pasting = false
+		out = append(out, pp.p.paste(string(pp.pasted))...)
+		pp.pasted = nil
+	}
+
+	if len(out) == 0 {
+		// go-prompt skips a lone zero byte
+		return []byte{0}, nil
+	}
+	return out, nil
+}
+
+// markerPrefix returns the length of the longest end of data
+// that's the start of marker and at least min bytes long
+func markerPrefix(data, marker []byte, min int) int {
+	for n := len(marker) - 1; n >= min; n-- {
+		if len(data) >= n && bytes.Equal(data[len(data)-n:], marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// pastedLines returns the lines of pasted text without control characters
+func pastedLines(s string) []string {
+	s = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\t", " ").Replace(s)
+	s = strings.Map(func(r rune) rune {
+		if (r < ' ' && r != '\n') || r == 0x7f {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Split(strings.Trim(s, "\n"), "\n")
+}
+
+// paste returns the input for go-prompt that the pasted text becomes
+func (p *Prompt) paste(s string) []byte {
+	lines := pastedLines(s)
+	if len(lines) == 1 || p.pasteMode == PasteInsert {
+		return []byte(strings.Join(lines, pasteLineBreak))
+	}
+
+	var queue []string
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			queue = append(queue, l)
+		}
+	}
+	p.pasted.mut.Lock()
+	p.pasted.lines = queue
+	p.pasted.mut.Unlock()
+	p.logWith("lines", len(queue)).Debugf("Pasted lines wait for enter")
+	p.SetInfoln(fmt.Sprintf("Press enter to run the %d pasted commands, Ctrl+C drops them", len(queue)), InfoLineSeverityWarning)
+	return nil
+}
+
+// runPasted runs the lines waiting for enter if the user pressed it on
+// the empty input. Another line drops them. It returns false if there
+// were no lines to run.
+func (p *Prompt) runPasted(s string) bool {
+	p.pasted.mut.Lock()
+	lines := p.pasted.lines
+	p.pasted.lines = nil
+	p.pasted.mut.Unlock()
+	if len(lines) == 0 {
+		return false
+	}
+	if s != "" {
+		p.log.Debugf("Pasted lines dropped by another line")
+		return false
+	}
+
+	for i, l := range lines {
+		l = p.sanitize(l)
+		p.history.add(l)
+		if err := p.runLine(l); err != nil {
+			if left := len(lines) - i - 1; left > 0 {
+				p.SetInfoln(fmt.Sprintf("%s, %d more pasted commands skipped", err, left), InfoLineSeverityError)
+			}
+			break
+		}
+	}
+	return true
+}
+
+// dropPasted forgets the lines waiting for enter. It returns false if
+// there were none.
+func (p *Prompt) dropPasted() bool {
+	p.pasted.mut.Lock()
+	n := len(p.pasted.lines)
+	p.pasted.lines = nil
+	p.pasted.mut.Unlock()
+	if n == 0 {
+		return false
+	}
+	p.SetInfoln(fmt.Sprintf("Dropped %d pasted commands", n), InfoLineSeverityNormal)
+	return true
+}
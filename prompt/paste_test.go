@@ -0,0 +1,127 @@
+package prompt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestPasteParser feeds reads with the bracketed paste markers, some of
+// them split between the reads, and checks what go-prompt gets
+func TestPasteParser(t *testing.T) {
+	tests := []struct {
+		name  string
+		reads []string
+		want  []string
+	}{
+		{"typed", []string{"deploy"}, []string{"deploy"}},
+		{"one line", []string{"\x1b[200~deploy api\x1b[201~"}, []string{"deploy api"}},
+		{"more lines", []string{"\x1b[200~deploy api\r\nlogs api\n\x1b[201~"}, []string{"deploy api⏎logs api"}},
+		{"typed around", []string{"x\x1b[200~a\nb\x1b[201~y"}, []string{"xa⏎by"}},
+		{"control characters", []string{"\x1b[200~a\tb\x1b[Ac\x7f\x1b[201~"}, []string{"a b[Ac"}},
+		{"split start", []string{"x\x1b[2", "00~a\nb\x1b[201~"}, []string{"x", "a⏎b"}},
+		{"split end", []string{"\x1b[200~a\nb\x1b", "[201~y"}, []string{"\x00", "a⏎by"}},
+		{"in pieces", []string{"\x1b[200~a", "\n", "b", "\x1b[201~"}, []string{"\x00", "\x00", "\x00", "a⏎b"}},
+		// Not the start of a marker, the keys are sent right away
+		{"escape", []string{"\x1b"}, []string{"\x1b"}},
+		{"up", []string{"\x1b[A"}, []string{"\x1b[A"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "")
+			parser := newFakeParser()
+			pp := &pasteParser{ConsoleParser: parser, p: p}
+			var got []string
+			for _, r := range tt.reads {
+				parser.keys <- []byte(r)
+				b, err := pp.Read()
+				if err != nil {
+					t.Fatalf("Read: %s", err)
+				}
+				got = append(got, string(b))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("go-prompt read %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestPasteInGoPrompt pastes lines into go-prompt's loop. None of them
+// runs as it's pasted. PasteInsert puts them into the input, PasteQueue
+// runs them once enter is pressed.
+func TestPasteInGoPrompt(t *testing.T) {
+	const paste = "\x1b[200~deploy api\r\nlogs api\r\n\x1b[201~"
+	var mut sync.Mutex
+	var ran []string
+	record := func(name string) *fakeCmd {
+		return &fakeCmd{name: name, run: func(args cmd.Args) error {
+			mut.Lock()
+			defer mut.Unlock()
+			ran = append(ran, name+" "+strings.Join(args, " "))
+			return nil
+		}}
+	}
+	commands := func() string {
+		mut.Lock()
+		defer mut.Unlock()
+		return strings.Join(ran, ", ")
+	}
+
+	t.Run("insert", func(t *testing.T) {
+		ran = nil
+		p, parser, _, term := newTTYPrompt(t, []cmd.Cmd{record("deploy"), record("logs")})
+		errCh := make(chan error, 1)
+		go func() { errCh <- p.Run() }()
+		<-p.Ready()
+		waitFor(t, "the bracketed paste mode", func() bool { return strings.Contains(term.String(), "\x1b[?2004h") })
+
+		parser.keys <- []byte(paste)
+		waitFor(t, "the pasted text", func() bool { return p.input() == "deploy api⏎logs api" })
+		if c := commands(); c != "" {
+			t.Fatalf("the paste ran %q", c)
+		}
+
+		p.Stop()
+		if err := <-errCh; err != nil {
+			t.Fatalf("Run: %s", err)
+		}
+		if s := term.String(); !strings.HasSuffix(s[:strings.LastIndex(s, "\x1b[?2004l")], "\x1b[0m\x1b[?25h") {
+			t.Errorf("the bracketed paste mode wasn't turned off: %q", tail(s, len(s)-40))
+		}
+	})
+
+	t.Run("queue", func(t *testing.T) {
+		ran = nil
+		p, parser, _, _ := newTTYPrompt(t, []cmd.Cmd{record("deploy"), record("logs")}, WithPasteMode(PasteQueue))
+		go p.Run()
+		<-p.Ready()
+
+		parser.keys <- []byte(paste)
+		waitFor(t, "the confirmation", func() bool {
+			return strings.Contains(infoText(p), "Press enter to run the 2 pasted commands")
+		})
+		if c := commands(); c != "" || p.input() != "" {
+			t.Fatalf("the paste ran %q and typed %q", c, p.input())
+		}
+		parser.keys <- []byte("\r")
+		waitFor(t, "the pasted commands", func() bool { return commands() == "deploy api, logs api" })
+
+		// Typing another line drops them
+		parser.keys <- []byte(paste)
+		waitFor(t, "the confirmation", func() bool { return strings.Contains(infoText(p), "Press enter") })
+		parser.keys <- []byte("deploy web")
+		parser.keys <- []byte("\r")
+		waitFor(t, "the typed command", func() bool { return strings.HasSuffix(commands(), "deploy web") })
+		// Enter on the empty input doesn't run the dropped lines
+		parser.keys <- []byte("\r")
+		parser.keys <- []byte("logs web")
+		parser.keys <- []byte("\r")
+		waitFor(t, "the typed command", func() bool { return strings.HasSuffix(commands(), "logs web") })
+		if c := commands(); c != "deploy api, logs api, deploy web, logs web" {
+			t.Fatalf("the commands that ran are %q", c)
+		}
+	})
+}
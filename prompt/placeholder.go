@@ -0,0 +1,92 @@
+package prompt
+
+import (
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+)
+
+// SetPlaceholder shows s dimmed on the prompt row while the input is empty,
+// e.g. "type 'help' for commands". It's gone once the user types and comes
+// back when the input is empty again. It's never a part of the input.
+// An empty s removes the placeholder.
+func (p *Prompt) SetPlaceholder(s string) {
+	s = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+
+	p.lockRender()
+	defer p.unlockRender()
+	p.placeholder = s
+	p.repaint()
+}
+
+// placeholderLine returns the placeholder cut to the width left after
+// the prefix. renderMutex must be held.
+func (p *Prompt) placeholderLine() string {
+	if p.totalColumns < 1 {
+		return p.placeholder
+	}
+	cols := p.totalColumns - p.prefixWidth()
+	if cols < 1 {
+		return ""
+	}
+	return runewidth.Truncate(p.placeholder, cols, "")
+}
+
+// renderPlaceholder draws the placeholder after the prefix of an empty
+// input. The cursor stays where the input starts. renderMutex must be held.
+func (p *Prompt) renderPlaceholder() {
+	ghost := p.placeholderLine()
+	if ghost == "" {
+		return
+	}
+	p.writer.WriteRawStr("\x1b[2m" + ghost + "\x1b[22m")
+	p.writer.CursorGoTo(p.promptRow, p.prefixWidth()+1)
+}
+
+// renderState is how far go-prompt got in drawing the prompt row.
+// It draws the prefix, then the input and erases the rest.
+type renderState int
+
+const (
+	renderedNothing renderState = iota
+	renderedPrefixColor
+	renderedPrefix
+	renderedEmptyInput
+)
+
+func (w *goPromptWriter) WriteStr(data string) {
+	switch w.state {
+	case renderedPrefixColor:
+		w.state = renderedPrefix
+	case renderedPrefix:
+		if data == "" {
+			w.state = renderedEmptyInput
+		} else {
+			w.state = renderedNothing
+		}
+	default:
+		w.state = renderedNothing
+	}
+	w.ConsoleWriter.WriteStr(data)
+}
+
+// EraseDown is where go-prompt is done with the prompt row. The placeholder
+// is drawn then, the suggestions below the row come after it.
+func (w *goPromptWriter) EraseDown() {
+	w.ConsoleWriter.EraseDown()
+	if w.state != renderedEmptyInput {
+		w.state = renderedNothing
+		return
+	}
+	w.state = renderedNothing
+
+	w.p.lockRender()
+	ghost := w.p.placeholderLine()
+	w.p.unlockRender()
+	if ghost == "" {
+		return
+	}
+	// go-prompt moves the cursor relative to where it left it
+	w.ConsoleWriter.WriteRawStr("\x1b[2m" + ghost + "\x1b[22m")
+	w.ConsoleWriter.CursorBackward(runewidth.StringWidth(ghost))
+}
@@ -0,0 +1,80 @@
+package prompt
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+const ghost = "\x1b[2mtype 'help' for commands\x1b[22m"
+
+// TestPlaceholderInGoPrompt types into go-prompt's loop. Its writer draws
+// the placeholder after the prefix of the empty input only, the cursor
+// goes back to where the input starts.
+func TestPlaceholderInGoPrompt(t *testing.T) {
+	p, parser, _, term := newTTYPrompt(t, nil)
+	p.SetPlaceholder("type 'help' for commands")
+	go p.Run()
+	<-p.Ready()
+
+	// drawn returns what go-prompt drew since start
+	drawn := func(start int) string { return term.String()[start:] }
+	back := ghost + "\x1b[24D"
+	waitFor(t, "the placeholder", func() bool { return strings.Contains(drawn(0), back) })
+
+	start := len(term.String())
+	parser.keys <- []byte("d")
+	waitFor(t, "the typed text", func() bool { return p.input() == "d" })
+	waitFor(t, "the input drawn", func() bool { return strings.Contains(drawn(start), "d") })
+	if s := drawn(start); strings.Contains(s, "type 'help'") {
+		t.Fatalf("the placeholder was drawn after the input: %q", s)
+	}
+
+	start = len(term.String())
+	parser.keys <- []byte{0x7f}
+	waitFor(t, "the placeholder back", func() bool { return strings.Contains(drawn(start), back) })
+	if text := p.input(); text != "" {
+		t.Fatalf("the input is %q", text)
+	}
+}
+
+// TestPlaceholderRedrawn checks that the placeholder is drawn again with
+// the prompt row after output is printed and after the terminal is
+// resized, cut to the width left after the prefix
+func TestPlaceholderRedrawn(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "", WithScreenSnapshot())
+	p.SetPlaceholder("type 'help' for commands")
+	startPrompt(t, p)
+	promptRow := func() string {
+		return strings.Split(p.Snapshot(), "\n")[23]
+	}
+	if row := promptRow(); row != "> type 'help' for commands" {
+		t.Fatalf("after the initial render the prompt row is %q", row)
+	}
+
+	start := len(out.String())
+	p.Writeln("deployed api\n")
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	if s := out.String()[start:]; !strings.Contains(s, "deployed api") || !strings.HasSuffix(s, ghost+"\x1b[24;3H") {
+		t.Fatalf("printing didn't draw the placeholder again: %q", s)
+	}
+
+	p.lockRender()
+	p.fixedSize = &goprompt.WinSize{Row: 24, Col: 12}
+	p.unlockRender()
+	if err := p.rerender(false); err != nil {
+		t.Fatalf("the rerender failed: %s", err)
+	}
+	if row := promptRow(); row != "> type 'help" {
+		t.Fatalf("at 12 columns the prompt row is %q", row)
+	}
+
+	p.SetPlaceholder("")
+	if row := promptRow(); row != ">" {
+		t.Fatalf("without the placeholder the prompt row is %q", row)
+	}
+}
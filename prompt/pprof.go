@@ -0,0 +1,214 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The mutex profile records one of this many contentions once the pprof
+// command turned it on
+const mutexProfileRate = 5
+
+// pprofProfiles are the profiles the pprof command writes
+var pprofProfiles = []string{"goroutine", "heap", "mutex"}
+
+// pprofPath returns where a profile is written without a path,
+// pprof/ in the cache directory next to the log file
+func pprofPath(kind string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	name := fmt.Sprintf("%s-%s.pb.gz", kind, time.Now().Format("20060102-150405"))
+	return filepath.Join(dir, "foundrycli", "pprof", name), nil
+}
+
+// writeProfile writes the runtime/pprof profile kind to path as
+// a gzipped proto, the format of `go tool pprof`
+func writeProfile(kind, path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if err := pprof.Lookup(kind).WriteTo(f, 0); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// goroutineStack is a stack of the goroutine dump and how many
+// goroutines are in it
+type goroutineStack struct {
+	count int
+	funcs []string // The innermost first
+}
+
+// goroutineSummary returns the stacks of all goroutines, the goroutines in
+// the same functions are collapsed to one stack whatever their lines are.
+// The stacks with the most goroutines come first.
+func goroutineSummary() ([]goroutineStack, error) {
+	var dump bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&dump, 1); err != nil {
+		return nil, err
+	}
+
+	// The dump is
+	//	3 @ 0x47d82a 0x480925
+	//	#	0x480924	time.Sleep+0x164	/usr/local/go/src/runtime/time.go:368
+	// for every stack, the stacks are separated by empty lines
+	byFuncs := make(map[string]*goroutineStack)
+	var cur *goroutineStack
+	sc := bufio.NewScanner(&dump)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		switch {
+		case strings.HasPrefix(line, "#\t"):
+			if cur == nil {
+				continue
+			}
+			fields := strings.Split(line, "\t")
+			if len(fields) < 3 {
+				continue
+			}
+			fn := fields[2]
+			if i := strings.LastIndex(fn, "+0x"); i > 0 {
+				fn = fn[:i]
+			}
+			cur.funcs = append(cur.funcs, fn)
+		case strings.Contains(line, " @ "):
+			n, err := strconv.Atoi(line[:strings.Index(line, " @ ")])
+			if err != nil {
+				cur = nil
+				continue
+			}
+			cur = &goroutineStack{count: n}
+		case line == "" && cur != nil:
+			addStack(byFuncs, cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		addStack(byFuncs, cur)
+	}
+
+	stacks := make([]goroutineStack, 0, len(byFuncs))
+	for _, s := range byFuncs {
+		stacks = append(stacks, *s)
+	}
+	sort.Slice(stacks, func(i, j int) bool {
+		if stacks[i].count != stacks[j].count {
+			return stacks[i].count > stacks[j].count
+		}
+		return strings.Join(stacks[i].funcs, " ") < strings.Join(stacks[j].funcs, " ")
+	})
+	return stacks, sc.Err()
+}
+
+// plural returns word with an s unless n is 1
+func plural(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+	return word + "s"
+}
+
+func addStack(byFuncs map[string]*goroutineStack, s *goroutineStack) {
+	key := strings.Join(s.funcs, "\n")
+	if same, ok := byFuncs[key]; ok {
+		same.count += s.count
+		return
+	}
+	byFuncs[key] = s
+}
+
+// dumpGoroutines writes the stacks of all goroutines to the log, for the
+// wedged prompt that got SIGQUIT or Ctrl+\. Unlike Go's own SIGQUIT
+// handling the prompt keeps running.
+func (p *Prompt) dumpGoroutines() {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	p.log.Errorf("Goroutine dump requested:\n%s", buf)
+	p.SetInfoln("The goroutines were dumped to the log", InfoLineSeverityNormal)
+}
+
+func runPprof(p *Prompt, args []string) error {
+	usage := errors.New("usage: pprof <goroutine|heap|mutex> [path] | pprof goroutines")
+	if len(args) == 1 && args[0] == "goroutines" {
+		stacks, err := goroutineSummary()
+		if err != nil {
+			return fmt.Errorf("dumping the goroutines failed: %w", err)
+		}
+		total := 0
+		var lines []string
+		for _, s := range stacks {
+			total += s.count
+			lines = append(lines, fmt.Sprintf("%d %s", s.count, plural(s.count, "goroutine")))
+			for _, fn := range s.funcs {
+				lines = append(lines, "    "+fn)
+			}
+			lines = append(lines, "")
+		}
+		lines = append(lines, fmt.Sprintf("%d goroutines in %d stacks", total, len(stacks)))
+		_, err = p.WriteLines(lines)
+		return err
+	}
+	if len(args) < 1 || len(args) > 2 {
+		return usage
+	}
+	kind := args[0]
+	known := false
+	for _, k := range pprofProfiles {
+		known = known || k == kind
+	}
+	if !known {
+		return usage
+	}
+
+	var path string
+	if len(args) == 2 {
+		path = args[1]
+	} else {
+		var err error
+		if path, err = pprofPath(kind); err != nil {
+			return fmt.Errorf("there's no directory for the profile: %w", err)
+		}
+	}
+	// Off by default, the profile would be empty
+	mutexOff := kind == "mutex" && runtime.SetMutexProfileFraction(-1) == 0
+	if mutexOff {
+		runtime.SetMutexProfileFraction(mutexProfileRate)
+	}
+	if err := writeProfile(kind, path); err != nil {
+		return fmt.Errorf("writing the %s profile failed: %w", kind, err)
+	}
+	p.logWith("profile", kind, "path", path).Debugf("Profile written")
+
+	msg := fmt.Sprintf("The %s profile was written to %s", kind, path)
+	if mutexOff {
+		msg += ", it's empty until now, the next one has the contentions"
+	}
+	p.SetInfoln(msg, InfoLineSeverityNormal)
+	return nil
+}
@@ -0,0 +1,142 @@
+package prompt
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+
+	"github.com/google/pprof/profile"
+)
+
+func TestPprofWritesValidProfiles(t *testing.T) {
+	tests := []struct {
+		kind   string
+		sample string // The first sample type of the profile
+	}{
+		{"goroutine", "goroutine"},
+		{"heap", "alloc_objects"},
+		{"mutex", "contentions"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "")
+			path := filepath.Join(dir, tt.kind+".pb.gz")
+			if err := p.Execute("pprof " + tt.kind + " " + path); err != nil {
+				t.Fatalf("Execute: %s", err)
+			}
+
+			f, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			prof, err := profile.Parse(f)
+			if err != nil {
+				t.Fatalf("the %s profile isn't a valid pprof proto: %s", tt.kind, err)
+			}
+			if err := prof.CheckValid(); err != nil {
+				t.Fatalf("the %s profile is invalid: %s", tt.kind, err)
+			}
+			if len(prof.SampleType) == 0 || prof.SampleType[0].Type != tt.sample {
+				t.Fatalf("the sample types %v, want %s first", prof.SampleType, tt.sample)
+			}
+			if !strings.Contains(p.infoText, path) {
+				t.Fatalf("the info row %q doesn't show the path", p.infoText)
+			}
+		})
+	}
+}
+
+func TestPprofGoroutinesSummary(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	if err := p.Execute("pprof goroutines"); err != nil {
+		t.Fatalf("Execute: %s", err)
+	}
+	var out strings.Builder
+	for _, c := range readChunks(p.outBuf) {
+		out.Write(c.Data)
+	}
+	summary := out.String()
+	for _, want := range []string{"testing.tRunner", " goroutines in ", " stacks"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("the summary doesn't contain %q:\n%s", want, summary)
+		}
+	}
+}
+
+func TestPprofUsage(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	for _, line := range []string{"pprof", "pprof cpu", "pprof heap a b"} {
+		if err := p.Execute(line); err == nil || !strings.HasPrefix(err.Error(), "usage: pprof") {
+			t.Errorf("Execute(%q) = %v, want the usage", line, err)
+		}
+	}
+}
+
+// TestSigquitDumpsGoroutines sends SIGQUIT to a prompt run by go-prompt's
+// loop. The goroutines go to the log and the prompt keeps running with
+// the text that was typed.
+func TestSigquitDumpsGoroutines(t *testing.T) {
+	// Go's own SIGQUIT handling would end the test binary
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGQUIT)
+	defer signal.Stop(sigCh)
+
+	log := &fakeLogger{}
+	ran := make(chan string, 1)
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		ran <- strings.Join(args, " ")
+		return nil
+	}}
+	p, parser, _, _ := newTTYPrompt(t, []cmd.Cmd{deploy}, WithLogger(log))
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	<-p.Ready()
+	parser.keys <- []byte("deploy api")
+	waitFor(t, "the typed text", func() bool { return p.input() == "deploy api" })
+
+	dumped := func() bool {
+		_, ok := log.errorContaining("Goroutine dump requested")
+		return ok
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !dumped() {
+		if time.Now().After(deadline) {
+			t.Fatal("SIGQUIT didn't dump the goroutines")
+		}
+		syscall.Kill(os.Getpid(), syscall.SIGQUIT)
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	absorbSignals(t, sigCh, syscall.SIGQUIT)
+	select {
+	case err := <-errCh:
+		t.Fatalf("Run returned %v after SIGQUIT", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if msg, _ := log.errorContaining("Goroutine dump requested"); !strings.Contains(msg, "goroutine ") {
+		t.Fatalf("the dump has no stacks: %q", msg)
+	}
+
+	waitFor(t, "the typed text after SIGQUIT", func() bool { return p.input() == "deploy api" })
+	parser.keys <- []byte("\r")
+	select {
+	case args := <-ran:
+		if args != "api" {
+			t.Fatalf("deploy ran with %q", args)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("enter didn't run the line after SIGQUIT")
+	}
+	p.Stop()
+	if err := <-errCh; err != nil {
+		t.Fatalf("Run returned %v after Stop", err)
+	}
+}
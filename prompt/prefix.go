@@ -0,0 +1,58 @@
+package prompt
+
+import (
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// livePrefixColor is the prefix color go-prompt is given. It isn't a real
+// color, prefixColorWriter replaces it by the prompt's current one.
+const livePrefixColor goprompt.Color = -1
+
+// SetPrefix replaces the text in front of the user's input, e.g. "staging> "
+// after a command switches the environment. The prompt row is drawn again
+// right away, the input stays. A line break in s is replaced by a space.
+func (p *Prompt) SetPrefix(s string) {
+	s = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(s)
+
+	p.lockRender()
+	defer p.unlockRender()
+	p.promptPrefix = s
+	p.log.Debugf("Prompt prefix changed to %q", s)
+	p.repaint()
+}
+
+// SetPrefixColor changes the color of the prefix, e.g. to goprompt.Red
+// while the connection is down. The prompt row is drawn again right away.
+func (p *Prompt) SetPrefixColor(c goprompt.Color) {
+	p.lockRender()
+	defer p.unlockRender()
+	p.prefixColor = c
+	p.repaint()
+}
+
+// prefixWidth returns the number of columns the prefix takes, the
+// continuation prefix while a line continues. renderMutex must be held.
+func (p *Prompt) prefixWidth() int {
+	return stringWidth(p.currentPrefix())
+}
+
+// goPromptWriter is go-prompt's writer. go-prompt takes the prefix color
+// once when it's created, the writer colors the prefix with the prompt's
+// current color instead. It draws the placeholder after an empty input too.
+type goPromptWriter struct {
+	goprompt.ConsoleWriter
+	p     *Prompt
+	state renderState // Where go-prompt is in drawing the prompt row
+}
+
+func (w *goPromptWriter) SetColor(fg, bg goprompt.Color, bold bool) {
+	if fg == livePrefixColor {
+		w.state = renderedPrefixColor
+		w.p.lockRender()
+		fg = w.p.prefixColor
+		w.p.unlockRender()
+	}
+	w.ConsoleWriter.SetColor(fg, bg, bold)
+}
@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// TestSetPrefixWhileStreaming changes the prefix and its color while
+// another goroutine writes output and a command is typed. The output ends
+// up on the screen in order, the prompt row has the last prefix in its
+// color followed by the input. Run it with -race.
+func TestSetPrefixWhileStreaming(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "logs api", WithScreenSnapshot())
+	startPrompt(t, p)
+	waitFor(t, "the typed text", func() bool { return p.input() == "logs api" })
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 1; i <= 200; i++ {
+			p.Writeln(fmt.Sprintf("line %d\n", i))
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		prefixes := []string{"staging> ", "prod> ", "!> "}
+		colors := []goprompt.Color{goprompt.Green, goprompt.Yellow, goprompt.Red}
+		for i := 0; i < 100; i++ {
+			p.SetPrefix(prefixes[i%len(prefixes)])
+			p.SetPrefixColor(colors[i%len(colors)])
+		}
+	}()
+	wg.Wait()
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	// Nothing is printed anymore, the prompt row is drawn by the setters
+	p.SetPrefix("prod> ")
+	p.SetPrefixColor(goprompt.Red)
+
+	rows := strings.Split(p.Snapshot(), "\n")
+	for i, row := range rows[:21] {
+		if want := fmt.Sprintf("line %d", 180+i); row != want {
+			t.Errorf("row %d is %q, want %q", i+1, row, want)
+		}
+	}
+	if rows[23] != "prod> logs api" {
+		t.Errorf("the prompt row is %q", rows[23])
+	}
+
+	want := &syncBuffer{}
+	w := &ioWriter{w: want}
+	w.SetColor(goprompt.Red, goprompt.DefaultColor, false)
+	w.WriteRawStr("prod> ")
+	w.Flush()
+	// The last time the prompt row was drawn
+	got := out.String()
+	got = got[strings.LastIndex(got, "\x1b[24;1H"):]
+	if !strings.Contains(got, want.String()+"\x1b[0;39;49mlogs api") {
+		t.Errorf("the prompt row was drawn with %q, want the red %q", got, want.String())
+	}
+}
@@ -0,0 +1,87 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// preloadCode is fed to go-prompt in place of a key once there's text to
+// preload, its binding puts the text into the input. No terminal sends it.
+var preloadCode = []byte("\x1b]foundry-preload\x07")
+
+// preloadState is the text Preload staged for the input goroutine
+type preloadState struct {
+	mut     sync.Mutex
+	text    string
+	pending bool
+}
+
+// Preload replaces the input by s with the cursor at its end, e.g.
+// "deploy " for the user to complete, or a template. Unlike Execute
+// nothing runs, the user edits the line and submits it. Text staged
+// before the input got the last one is replaced. With scripted input
+// it's put in at the next key.
+func (p *Prompt) Preload(s string) error {
+	if strings.ContainsAny(s, "\r\n") {
+		return fmt.Errorf("the preloaded text %q can't span more lines", s)
+	}
+	p.preload.mut.Lock()
+	p.preload.text, p.preload.pending = s, true
+	p.preload.mut.Unlock()
+	p.logWith("text", s).Debugf("Input preloaded")
+	return nil
+}
+
+// takePreload returns the staged text. It returns false if there's none.
+func (p *Prompt) takePreload() (string, bool) {
+	p.preload.mut.Lock()
+	defer p.preload.mut.Unlock()
+	s, ok := p.preload.text, p.preload.pending
+	p.preload.text, p.preload.pending = "", false
+	return s, ok
+}
+
+// preloadOptions returns the go-prompt binding of preloadCode
+func (p *Prompt) preloadOptions() []goprompt.Option {
+	return []goprompt.Option{goprompt.OptionAddASCIICodeBind(goprompt.ASCIICodeBind{
+		ASCIICode: preloadCode,
+		Fn: func(buf *goprompt.Buffer) {
+			if s, ok := p.takePreload(); ok {
+				setInput(buf, s)
+			}
+		},
+	})}
+}
+
+// preloadParser gives go-prompt preloadCode when there's text to preload.
+// go-prompt reads the parser every few milliseconds, the text doesn't
+// wait for a key.
+type preloadParser struct {
+	goprompt.ConsoleParser
+	p *Prompt
+}
+
+func (pp *preloadParser) Read() ([]byte, error) {
+	pp.p.preload.mut.Lock()
+	pending := pp.p.preload.pending
+	pp.p.preload.mut.Unlock()
+	if pending {
+		pp.p.lockRender()
+		paging := pp.p.pager != nil
+		pp.p.unlockRender()
+		// The pager would take it for a key
+		if !paging {
+			return append([]byte(nil), preloadCode...), nil
+		}
+	}
+	b, err := pp.ConsoleParser.Read()
+	if err == nil && bytes.Equal(b, preloadCode) {
+		// Typed or pasted, it's not staged text
+		return []byte{0}, nil
+	}
+	return b, err
+}
@@ -0,0 +1,50 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestPreloadInGoPrompt stages text in go-prompt's input, over what was
+// typed, and edits it before enter runs it
+func TestPreloadInGoPrompt(t *testing.T) {
+	ran := make(chan string, 1)
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		ran <- strings.Join(args, " ")
+		return nil
+	}}
+	p, parser, _, term := newTTYPrompt(t, []cmd.Cmd{deploy})
+	go p.Run()
+	<-p.Ready()
+
+	parser.keys <- []byte("logs")
+	waitFor(t, "the typed text", func() bool { return p.input() == "logs" })
+	if err := p.Preload("deploy api --region eu"); err != nil {
+		t.Fatalf("Preload: %s", err)
+	}
+	waitFor(t, "the staged text", func() bool { return p.input() == "deploy api --region eu" })
+	waitFor(t, "the staged text drawn", func() bool { return strings.Contains(term.String(), "deploy api --region eu") })
+
+	// The cursor is at the end of the staged text
+	parser.keys <- []byte{0x7f}
+	parser.keys <- []byte{0x7f}
+	parser.keys <- []byte("us")
+	waitFor(t, "the edited text", func() bool { return p.input() == "deploy api --region us" })
+	parser.keys <- []byte("\r")
+	if args := <-ran; args != "api --region us" {
+		t.Fatalf("deploy ran with %q", args)
+	}
+	waitFor(t, "the empty input", func() bool { return p.input() == "" })
+}
+
+func TestPreloadRejectsLines(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	if err := p.Preload("deploy api\nlogs api"); err == nil || !strings.Contains(err.Error(), "more lines") {
+		t.Fatalf("Preload of two lines returned %v", err)
+	}
+	if _, ok := p.takePreload(); ok {
+		t.Fatal("the rejected text was staged")
+	}
+}
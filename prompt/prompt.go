@@ -1,14 +1,21 @@
 package prompt
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"foundry/cli/logger"
+	"io"
 	"os"
 	"os/signal"
+	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
+	"unicode/utf8"
 
 	"foundry/cli/prompt/cmd"
 
@@ -20,42 +27,174 @@ type CursorPos struct {
 	Col int
 }
 
+// CursorOutputStart is where the output starts when there's no header
 func CursorOutputStart() CursorPos {
 	return CursorPos{1, 1}
 }
 
 type PromptEventType string
 
+// PromptEvent tells the listeners on Prompt.Events what happened. Data is
+// the payload of the event's type, e.g. a CommandEvent for PromptEventTypeCommand.
 type PromptEvent struct {
 	Type PromptEventType
+	Data interface{}
 }
 type Prompt struct {
-	cmds []cmd.Cmd
+	cmds     []cmd.Cmd       // Guarded by renderMutex. The commands of the current context.
+	contexts []promptContext // Guarded by renderMutex. Pushed by PushContext.
 
-	outBuf *Buffer
+	notFoundHandler CommandNotFoundHandler
+	initScript      string // Path to a file with lines that are executed on start and by the reload command
+
+	outBuf    *Buffer
+	bufCh     chan Chunk
+	chanDepth int // Capacity of the channel between outBuf and print()
+
+	completionTimeout time.Duration // How long the input waits for an ArgCompleter
+
+	completionDelimiters string // Set by WithWordDelimiters, besides the spaces
+	editDelimiters       string // Set by WithWordDelimiters, besides the spaces
+	// Held while a chunk is taken from bufCh and printed so
+	// Drain() and the Run() goroutine keep the output in order
+	consumeMutex sync.Mutex
 	// outBufMutex sync.Mutex
 
+	// Output must never be written to outBuf while holding renderMutex.
+	// When the buffer blocks on overflow it would wait for the renderer
+	// that waits for the mutex. Use lockRender() and unlockRender().
+	// It guards the fields that say so and everything drawn by the writer.
+	// The fields set by options aren't changed once Run started. The input
+	// has inputMutex. The state kept by other goroutines has its own small
+	// mutex, e.g. ctrlC, pasted, preload and idleMutex, or is atomic.
 	renderMutex sync.Mutex
+	// Guards promptText only. The completer runs on every key and doesn't
+	// wait for renderMutex unless the search runs. Taken with renderMutex
+	// held, never the other way around.
+	inputMutex sync.Mutex
+	inputDoc   goprompt.Document // Guarded by inputMutex, the input with the cursor
+
+	promptPrefix string         // Guarded by renderMutex. Changed by SetPrefix and the set command.
+	prefixColor  goprompt.Color // Guarded by renderMutex
+	promptText   string         // Guarded by inputMutex. Written by completer() on every input change.
+	promptRow    int            // Guarded by renderMutex. Will be recalculated once the terminal is ready.
+	placeholder  string         // Guarded by renderMutex. Shown while the input is empty.
+	continued    []string       // Guarded by renderMutex. Lines that ended with a backslash.
+	contPrefix   string         // Replaces the prefix while a line continues, set by WithContinuationPrefix
+
+	infoText   string         // Guarded by renderMutex
+	infoColor  goprompt.Color // Guarded by renderMutex
+	headerText string         // Guarded by renderMutex
+	infoRow    int            // Guarded by renderMutex. The first info row, will be recalculated once the terminal is ready.
+	infoRows   int            // Set by WithInfoRows
+
+	totalColumns int // Guarded by renderMutex. Will be recalculated once the terminal is ready. Capped by maxWidth.
+	maxWidth     int // Set by WithMaxWidth, 0 is the whole terminal
+	totalRows    int // Guarded by renderMutex. Will be recalculated once the terminal is ready.
+	freeRows     int // Guarded by renderMutex. Will be recalculated once the terminal is ready.
+	scrollMargin int // Blank rows kept above the info row, see WithScrollMargin
+
+	pagerThreshold int    // Guarded by renderMutex. Output of Page longer than this is paged, 0 turns the pager off.
+	pager          *pager // The open pager, guarded by renderMutex
+
+	parser    goprompt.ConsoleParser // Created by Run unless WithInputReader is used
+	fixedSize *goprompt.WinSize      // Set by WithTerminalSize
+	sizeCheck sizeCheck              // Where the terminal's size came from last
+	writer    goprompt.ConsoleWriter // Only used with renderMutex held
+	screen    *screen                // Model of the visible grid, nil unless WithScreenSnapshot is used
+
+	// The state of print(), guarded by renderMutex like the rest of the drawing
+	savedPos   CursorPos
+	currentPos CursorPos // Current position of the cursor when printing output
 
-	promptPrefix string
-	promptText   string
-	promptRow    int // Will be recalculated once the terminal is ready
+	lastEscapeCode string      // Last VT100 terminal escape code that should be applied next time the print() method is called
+	pendingEscape  string      // Beginning of an escape code that continues in the next chunk
+	escState       escapeState // Where in pendingEscape the previous chunk ended
+	segBuf         []byte      // Reused by print() to collect the text between scrolls
 
-	infoText string
-	infoRow  int // Will be recalculated once the terminal is ready
+	normalizeNewlines bool // Treat '\r' as a return to the start of the line instead of a printed character
 
-	totalColumns int // Will be recalculated once the terminal is ready
-	totalRows    int // Will be recalculated once the terminal is ready
-	freeRows     int // Will be recalculated once the terminal is ready
+	streamStyles map[Stream]StreamStyle
+	colorLevel   ColorLevel
 
-	parser *goprompt.PosixParser
-	writer goprompt.ConsoleWriter
+	stats *renderStats
 
-	savedPos   CursorPos
-	currentPos CursorPos // Current position of the cursor when printing output
+	idleTimeout time.Duration
+	onIdle      func()
+	idleTimer   *time.Timer
+	idleMutex   sync.Mutex
+
+	ctrlCMode CtrlCMode
+	ctrlC     ctrlCState
+
+	pasteMode PasteMode
+	pasted    pasteState
+
+	editMode EditMode // Guarded by renderMutex, the set command changes it
+	vi       viState  // Guarded by renderMutex
+
+	search       searchState  // Guarded by renderMutex
+	searching    int32        // Set to 1 while the search runs, atomic
+	searchFormat [2]string    // The prefixes of the search and of the failed one, set by WithSearchPromptFormat
+	kills        killRing     // Only used on the input goroutine
+	preload      preloadState // Has its own mutex, Preload is called from any goroutine
+
+	repeatOnEmpty bool   // Guarded by renderMutex, the set command changes it
+	lastLine      string // The line repeat-on-empty runs, only used by the executor
+
+	busy       chan struct{} // Holds a value while a submitted line runs
+	busyPolicy BusyPolicy    // Set by WithBusyPolicy
+
+	preRender  func() // Set by WithPreRender
+	postRender func() // Set by WithPostRender
+
+	transcript transcript // Guarded by renderMutex
+	outputFlow outputFlow // Only used by the renderer goroutine
+	rec        recorder   // Started by the record command
+	debug      debugMode  // Turned on by the debug command
+
+	remote   *remoteIO    // Set by WithIO
+	termOut  io.Writer    // Gets what isn't drawn by the writer, os.Stdout, os.Stderr or WithIO's writer
+	term     *termQueue   // Writes what the terminal writers flush to termOut
+	termMode terminalMode // Which of stdout and stderr is the terminal
+	plain    int32        // Set to 1 if Run runs without a terminal, atomic
 
-	lastEscapeCode string // Last VT100 terminal escape code that should be applied next time the print() method is called
+	goPromptOpts []goprompt.Option // Set by WithGoPromptOptions
 
+	history history
+
+	activityMark string    // Set by WithActivityIndicator
+	activityPos  CursorPos // Guarded by renderMutex. Where the mark is, zero if it isn't shown.
+	activityAt   time.Time // Guarded by renderMutex. When the mark was drawn last.
+
+	keyBinds  map[goprompt.Key]func(p *Prompt) // Set by Bind before Run
+	started   int32                            // Set to 1 by Run, atomic
+	termLost  int32                            // Set to 1 once the terminal is gone, atomic
+	cooked    int32                            // Set to 1 while go-prompt runs a line, atomic
+	suspended int32                            // Set to 1 while Suspend runs its function, atomic
+
+	suspendCh chan chan struct{} // Ctrl+Z asks handleSuspend to stop the process
+	keys      keyReader
+
+	stopCh       chan struct{} // Closed once the prompt is stopped
+	stopOnce     sync.Once
+	teardownOnce sync.Once
+	exitCh       chan exitRequest // Makes Run return
+	doneCh       chan struct{}    // Closed once Run returns
+	inputDone    chan struct{}    // Closed once go-prompt's loop ended, nil with the scripted input
+	inputLost    chan struct{}    // Gets a value when a signal ended go-prompt's loop
+	readyCh      chan struct{}    // Closed once the initial render is done
+	initFailedCh chan struct{}    // Closed if the initial render fails
+	runErr       error            // What Run returned, read after doneCh is closed
+	optErr       error            // The invalid options given to NewPrompt, Run returns it
+
+	log      Logger
+	chunkLog *logLimiter // Guarded by renderMutex
+
+	subs subscribers
+
+	// Events gets all the events. Its listener must keep receiving or the
+	// events are dropped, Subscribe allows more listeners and filtering.
 	Events chan PromptEvent
 }
 
@@ -69,60 +208,230 @@ const (
 	InfoLineSeverityError
 )
 
+const (
+	// maxSegBuf is the biggest buffer print() keeps for the next chunk
+	maxSegBuf = 64 * 1024
+	// flushEvery is how many bytes of a chunk print() writes to the terminal at once
+	flushEvery = 32 * 1024
+)
+
 //////////////////////
 
 func (p *Prompt) completer(d goprompt.Document) []goprompt.Suggest {
-	p.renderMutex.Lock()
+	// Completer is called on every input change
+	p.resetIdleTimer()
+
+	p.inputMutex.Lock()
+	wasEmpty := p.promptText == ""
 	p.promptText = d.CurrentLine()
-	p.renderMutex.Unlock()
+	p.inputDoc = d
+	p.inputMutex.Unlock()
+
+	searching := atomic.LoadInt32(&p.searching) == 1
+	_, scripted := p.parser.(*readerParser)
+	if searching || (scripted && wasEmpty != (d.CurrentLine() == "")) {
+		p.lockRender()
+		// go-prompt resets the completion before every key and the reset
+		// gives an empty document, the live prefix checks the search
+		// with the input after the key instead
+		if d != (goprompt.Document{}) {
+			p.checkSearch(d)
+		}
+		searching = p.search.active
+		if scripted && p.placeholder != "" && wasEmpty != (d.CurrentLine() == "") {
+			// go-prompt draws the placeholder through its writer,
+			// the scripted input has nobody else to do it
+			p.repaint()
+		}
+		p.unlockRender()
+	}
 
-	return []goprompt.Suggest{}
+	if searching {
+		return nil
+	}
+	return p.suggest(d)
 }
 
+// inputDocument returns the input with the position of the cursor
+func (p *Prompt) inputDocument() goprompt.Document {
+	p.inputMutex.Lock()
+	defer p.inputMutex.Unlock()
+	return p.inputDoc
+}
+
+// input returns what the user typed so far
+func (p *Prompt) input() string {
+	p.inputMutex.Lock()
+	defer p.inputMutex.Unlock()
+	return p.promptText
+}
+
+// executor is called by go-prompt when the user submits a line
 func (p *Prompt) executor(s string) {
-	if s == "" {
+	// go-prompt gives the terminal back to the cooked mode while the line runs
+	atomic.StoreInt32(&p.cooked, 1)
+	defer atomic.StoreInt32(&p.cooked, 0)
+	if !p.acquireBusy() {
+		return
+	}
+	defer p.releaseBusy()
+	// Every line starts in vi's insert mode, enter ends the search
+	p.lockRender()
+	p.vi = viState{}
+	p.endSearch()
+	p.unlockRender()
+	if p.runPasted(s) {
+		return
+	}
+	// Invisible characters would make the command unknown
+	s = p.sanitize(s)
+	if p.continueLine(s) {
 		return
 	}
-	logger.Fdebugln("Executor:", s)
+	// The continued lines are a single line of the history
+	s = p.joinContinued(s)
+	if p.repeatLast(s) {
+		return
+	}
+	p.history.add(s)
+	p.runLine(strings.Replace(s, pasteLineBreak, "\n", -1))
+}
+
+// Execute parses a line of input and dispatches it to a built-in command,
+// a registered command or the command not found handler, the same way as
+// if the user typed it. Errors are shown on the info row and returned so
+// callers (scripts, tests) know the line failed. It doesn't need Run
+// to be called first.
+func (p *Prompt) Execute(s string) error {
+	if s == "" {
+		return nil
+	}
+	ctx, id := newExecContext()
+	log := p.logWith("exec", id)
+	withFields(log, "input", s).Debugf("Executing")
 
 	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return nil
+	}
 
-	if cmd := p.getCommand(fields[0]); cmd != nil {
-		logger.Fdebugln("cmd:", cmd)
+	start := time.Now()
+	if b := p.getBuiltin(fields[0]); b != nil {
+		err := p.safeRun(b.name, func() error { return b.run(p, fields[1:]) })
+		p.emit(NewCommandEvent(b.name, fields[1:], time.Since(start), err))
+		if err != nil {
+			p.SetInfoln(err.Error(), InfoLineSeverityError)
+			return err
+		}
+	} else if c := p.getCommand(fields[0]); c != nil {
 		args := fields[1:]
-		logger.Fdebugln("args:", args)
-		cmd.RunRequest(args)
+		ctx, cancel := context.WithCancel(ctx)
+		p.startExec(c.Name(), cancel)
+		err := p.safeRun(c.Name(), func() error { return runRequest(ctx, c, args) })
+		p.endExec()
+		cancelled := ctx.Err() != nil
+		cancel()
+		d := time.Since(start)
+		withFields(log, "cmd", c.Name(), "args", args, "duration", d, "err", err).Debugf("Command finished")
+		p.emit(NewCommandEvent(c.Name(), args, d, err))
+		if err != nil {
+			// The info row already says the command was cancelled by Ctrl+C
+			if !(cancelled && errors.Is(err, context.Canceled)) {
+				p.showCmdError(c, err, id)
+			}
+			return err
+		}
+	} else if p.notFoundHandler != nil {
+		// The handler may run the line as a program, the quotes keep its arguments together
+		words, err := splitWords(s)
+		if err != nil {
+			p.SetInfoln(err.Error(), InfoLineSeverityError)
+			return err
+		}
+		err = p.safeRun(words[0], func() error { return p.notFoundHandler(words[0], words[1:]) })
+		p.emit(NewCommandEvent(words[0], words[1:], time.Since(start), err))
+		if err != nil {
+			p.SetInfoln(err.Error(), InfoLineSeverityError)
+			return err
+		}
 	} else {
-		// Delete an old info message and show the new one
+		withFields(log, "cmd", fields[0], "args", fields[1:]).Debugf("Unknown command")
 
-		p.renderMutex.Lock()
+		// Delete an old info message and show the new one
 
-		// Delete an old info message
-		p.writer.CursorGoTo(p.infoRow, 1)
-		p.writer.EraseLine()
+		p.lockRender()
+		msg := fmt.Sprintf("Unknown command '%s'", fields[0])
+		p.infoText = msg
 
-		// Print the new info message
-		p.writer.SetColor(goprompt.Red, goprompt.DefaultColor, true)
-		p.infoText = fmt.Sprintf("Unknown command '%s'", fields[0])
-		p.writer.WriteRawStr(p.infoText)
-		p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+		// Without the initial render, e.g. in ExecuteOnce, there's no info row yet
+		if p.totalRows > 0 {
+			// Replace the old info message with the new one
+			p.renderInfo()
 
-		// Move cursor back to the prompt
-		p.writer.CursorGoTo(p.promptRow, len(p.promptPrefix)+len(p.promptText)+1)
+			// Move cursor back to the prompt
+			p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
 
-		if err := p.writer.Flush(); err != nil {
-			logger.FdebuglnFatal("Error flushing prompt buffer", err)
-			logger.FatalLogln("Error flushing prompt buffer", err)
+			if err := p.flush(); err != nil {
+				p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+			}
 		}
 
-		p.renderMutex.Unlock()
+		p.unlockRender()
+		return &notFoundError{msg: msg}
 	}
+	return nil
+}
+
+// Exec is Execute by the name of Interface
+func (p *Prompt) Exec(s string) error {
+	return p.Execute(s)
+}
+
+// showCmdError reports an error returned from a command's RunRequest.
+// The error goes to the stderr stream of the output, which skips the
+// output the command queued before failing, and to the info row.
+// Commands that were used wrong get their usage printed after it.
+// The execution id makes the run easy to find in the log.
+func (p *Prompt) showCmdError(c cmd.Cmd, err error, execID string) {
+	msg := fmt.Sprintf("%s (exec %s)", err, execID)
+	out := msg + "\n"
+	if errors.Is(err, cmd.ErrUsage) {
+		if u, ok := c.(cmd.Usager); ok && u.Usage() != "" {
+			out += u.Usage() + "\n"
+		}
+	}
+	p.ErrWriteln(out)
+	p.SetInfoln(msg, InfoLineSeverityError)
 }
 
 func (p *Prompt) getCommand(s string) cmd.Cmd {
-	for _, c := range p.cmds {
-		if c.Name() == s {
-			return c
+	for _, c := range p.commands() {
+		for _, name := range cmd.Names(c) {
+			if name == s {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// Validate checks that every command name and alias is used only once
+// and doesn't shadow any of the prompt's built-in commands
+func (p *Prompt) Validate() error {
+	return p.validateCmds(p.commands())
+}
+
+func (p *Prompt) validateCmds(cmds []cmd.Cmd) error {
+	seen := map[string]cmd.Cmd{}
+	for _, c := range cmds {
+		for _, name := range cmd.Names(c) {
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("command name '%s' is used by both '%s' and '%s'", name, other.Name(), c.Name())
+			}
+			if p.getBuiltin(name) != nil {
+				return fmt.Errorf("command name '%s' of '%s' collides with a built-in command", name, c.Name())
+			}
+			seen[name] = c
 		}
 	}
 	return nil
@@ -130,73 +439,464 @@ func (p *Prompt) getCommand(s string) cmd.Cmd {
 
 /////////////
 
-func NewPrompt(cmds []cmd.Cmd) *Prompt {
+// NewPrompt is like New with the old signature. If an option is invalid
+// the prompt is created without the options and Run returns their error.
+//
+// Deprecated: use New, which returns the error of an invalid option.
+func NewPrompt(cmds []cmd.Cmd, opts ...Option) *Prompt {
+	p, err := New(cmds, opts...)
+	if err != nil {
+		// Without options New can't fail
+		p, _ = New(cmds)
+		p.optErr = err
+	}
+	return p
+}
+
+// New creates a prompt for cmds configured by opts. It returns the errors
+// of all the invalid options together.
+func New(cmds []cmd.Cmd, opts ...Option) (*Prompt, error) {
 	prefix := "> "
-	return &Prompt{
+	p := &Prompt{
 		cmds: cmds,
 
-		outBuf: NewBuffer(),
+		outBuf:    NewBuffer(),
+		chanDepth: DefaultChannelDepth,
+
+		completionTimeout: DefaultCompletionTimeout,
+		editDelimiters:    defaultEditDelimiters,
 
 		promptPrefix: prefix,
+		prefixColor:  goprompt.Green,
+		infoColor:    goprompt.Red,
+		infoRows:     1,
+		contPrefix:   defaultContinuationPrefix,
+		searchFormat: [2]string{defaultSearchFormat, defaultFailedSearchFormat},
 
-		parser: goprompt.NewStandardInputParser(),
-		writer: goprompt.NewStandardOutputWriter(),
+		termOut:  os.Stdout,
+		termMode: detectTerminalMode(),
 
 		// Terminal is indexed from 1
 		savedPos:   CursorOutputStart(),
 		currentPos: CursorPos{1, len(prefix) + 1},
 
 		Events: make(chan PromptEvent),
+
+		streamStyles: defaultStreamStyles(),
+		colorLevel:   DetectColorLevel(),
+
+		stats: &renderStats{},
+
+		busy: make(chan struct{}, 1),
+
+		stopCh: make(chan struct{}),
+		exitCh: make(chan exitRequest, 1),
+		doneCh: make(chan struct{}),
+
+		suspendCh: make(chan chan struct{}),
+
+		readyCh:      make(chan struct{}),
+		initFailedCh: make(chan struct{}),
+
+		log:      newFoundryLogger(),
+		chunkLog: &logLimiter{every: time.Second},
+	}
+
+	if p.termMode == termStderr {
+		// The results of the commands go to the redirected stdout
+		p.termOut = os.Stderr
+	}
+
+	var errs []string
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		// Nobody can stop a prompt that isn't returned, the files opened by
+		// the valid options are closed here. The spool was never written.
+		spool := p.outBuf.SpoolPath()
+		p.outBuf.closeSpools(DefaultDrainTimeout)
+		if spool != "" {
+			os.Remove(spool)
+		}
+		return nil, fmt.Errorf("invalid options: %s", strings.Join(errs, "; "))
+	}
+	p.term = newTermQueue(p.termOut)
+	if p.writer == nil {
+		p.writer = p.terminalWriter()
+	}
+	// The recording gets what the real writer sends to the terminal
+	p.writer = &recordWriter{ConsoleWriter: p.writer, rec: &p.rec}
+	if p.screen != nil {
+		p.writer = &screenWriter{ConsoleWriter: p.writer, s: p.screen}
+	}
+	p.outBuf.setLogger(p.log)
+	p.history.log = p.log
+
+	// Subscribed right away so no event is missed before the goroutine starts
+	ch, cancel := p.Subscribe()
+	go p.forwardEvents(ch, cancel)
+	return p, nil
+}
+
+// Run runs the prompt until it ends. The package never exits the process,
+// the terminal is restored and Run returns instead. The error is nil after
+// Ctrl+C, Ctrl+D or Stop, the same when the terminal goes away, and a
+// *SignalError after SIGINT or SIGTERM. Any other error means the prompt
+// couldn't continue, e.g. the writer failed or one of the prompt's goroutines
+// panicked, or the options given to NewPrompt were invalid. It's up to
+// the caller to exit.
+func (p *Prompt) Run() error {
+	return p.RunContext(context.Background())
+}
+
+// RunContext is like Run but it also ends when ctx is done
+// and then returns ctx.Err(). A prompt runs only once, another Run
+// needs a new Prompt. The old one doesn't get in the new one's way,
+// go-prompt's loop has ended and stopped reading the terminal by the
+// time RunContext returns.
+func (p *Prompt) RunContext(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&p.started, 0, 1) {
+		return errors.New("the prompt already ran, create a new one")
 	}
+	err := p.optErr
+	if err == nil {
+		err = p.run(ctx)
+	}
+	p.runErr = err
+	close(p.doneCh)
+	return err
+}
+
+// Wait blocks until Run or RunContext returns and returns the same error.
+// It lets other goroutines wait for the prompt. It blocks forever if the
+// prompt is never run.
+func (p *Prompt) Wait() error {
+	<-p.doneCh
+	return p.runErr
 }
 
-func (p *Prompt) Run() {
+func (p *Prompt) run(ctx context.Context) error {
+	if p.termMode == termNone && p.parser == nil {
+		return p.runPlain(ctx)
+	}
+	// The parser opens the terminal so it's only created once the prompt runs
+	if p.parser == nil {
+		p.parser = &ttyParser{ConsoleParser: goprompt.NewStandardInputParser(), p: p}
+	}
+	p.setFatalHandler()
+
 	// Read buffer and print anything that gets send to the channel
-	bufCh := make(chan []byte, 128)
-	stopReadCh := make(chan struct{})
-	go p.outBuf.Read(bufCh, stopReadCh)
+	p.bufCh = make(chan Chunk, p.chanDepth)
+	go func() {
+		defer p.recoverGoroutine("output reader")
+		p.outBuf.Read(p.bufCh, p.stopCh)
+	}()
 	go func() {
+		defer p.recoverGoroutine("output renderer")
+		// The output waits for the geometry of the initial render
+		select {
+		case <-p.readyCh:
+		case <-p.stopCh:
+			return
+		}
 		for {
 			select {
-			case b := <-bufCh:
-				p.print(b)
+			case <-p.stopCh:
+				return
 			default:
-				time.Sleep(time.Millisecond * 10)
+			}
+
+			printed := p.consumeOne(renderIdleWait)
+			p.trackOutput(printed)
+			if !printed && p.activityMark != "" {
+				p.idleActivity()
 			}
 		}
 	}()
 
+	if _, ok := p.parser.(*readerParser); !ok {
+		p.inputLost = make(chan struct{}, 1)
+		if err := p.startInput(p.history.load()); err == nil {
+			if p.remote == nil {
+				go p.handleSuspend()
+			}
+		} else if p.remote != nil {
+			// WithIO in a process without a terminal, e.g. a daemon
+			p.log.Debugf("%s, the input only gets the basic line editing", err)
+			p.parser = &readerParser{r: bufio.NewReader(p.remote.in)}
+		} else {
+			close(p.initFailedCh)
+			p.teardown("error")
+			return err
+		}
+	}
+	if rp, ok := p.parser.(*readerParser); ok {
+		// go-prompt always opens the terminal, scripted input is handled without it
+		go func() {
+			p.runScripted(rp)
+			if p.remote != nil {
+				// The other side of WithIO is gone
+				p.lostTerminal(nil)
+			}
+		}()
+	}
+
+	// The initial rerender for the current terminal size
+	if err := p.rerender(true); err != nil {
+		close(p.initFailedCh)
+		p.teardown("error")
+		return fmt.Errorf("the initial rerender failed: %w", err)
+	}
+	close(p.readyCh)
+
+	// Rerender a terminal for every size change
+	if p.remote != nil {
+		go p.rerenderOnSizeSource()
+	} else {
+		go p.rerenderOnTermSizeChange()
+	}
+
+	p.runInitScript()
+
+	p.startIdleTimer()
+
+	return p.wait(ctx)
+}
+
+// startInput starts go-prompt's loop with the lines of history to go
+// through with the up arrow. go-prompt's New opens the process's terminal
+// even when the loop reads another one and never closes it. Without a
+// terminal startInput returns an error.
+//
+// go-prompt's loop exits the process on SIGINT, SIGTERM and SIGQUIT, its
+// BreakLine callback is the last thing to run before. The callback ends
+// the loop's goroutine instead and wait gets the signal, inputLost tells
+// it to start another loop if the prompt goes on.
+func (p *Prompt) startInput(history []string) error {
 	interupOpt := goprompt.OptionAddKeyBind(goprompt.KeyBind{
 		Key: goprompt.ControlC,
 		Fn: func(buf *goprompt.Buffer) {
-			os.Exit(0)
+			p.onCtrlC()
+		},
+	})
+	suspendOpt := goprompt.OptionAddKeyBind(goprompt.KeyBind{
+		Key: goprompt.ControlZ,
+		Fn: func(buf *goprompt.Buffer) {
+			p.onCtrlZ()
+		},
+	})
+	// The raw mode gets Ctrl+\ as a key instead of SIGQUIT
+	dumpOpt := goprompt.OptionAddKeyBind(goprompt.KeyBind{
+		Key: goprompt.ControlBackslash,
+		Fn: func(buf *goprompt.Buffer) {
+			p.dumpGoroutines()
 		},
 	})
 	prefixOpt := goprompt.OptionPrefix(p.promptPrefix)
-	prefixColOpt := goprompt.OptionPrefixTextColor(goprompt.Green)
-	prompt := goprompt.New(p.executor, p.completer, interupOpt, prefixOpt, prefixColOpt)
-	go prompt.Run()
-
-	// The initial rerender for the current terminal size
-	if err := p.rerender(true); err != nil {
-		logger.Fdebugln("Error during the initial rerender", err)
-		logger.FatalLogln("Error during the initial rerender", err)
+	// SetPrefix and the set command change the prefix while go-prompt runs
+	livePrefixOpt := goprompt.OptionLivePrefix(func() (string, bool) {
+		p.lockRender()
+		defer p.unlockRender()
+		if p.search.active {
+			p.checkSearch(p.inputDocument())
+		}
+		return p.currentPrefix(), true
+	})
+	// SetPrefixColor changes the color while go-prompt runs,
+	// the writer also draws the placeholder
+	prefixColOpt := goprompt.OptionPrefixTextColor(livePrefixColor)
+	writerOpt := goprompt.OptionWriter(&goPromptWriter{
+		ConsoleWriter: &recordWriter{ConsoleWriter: p.terminalWriter(), rec: &p.rec},
+		p:             p,
+	})
+	// go-prompt's loop only checks it after a key, stopParser
+	// gives it one once the prompt is stopped
+	stopped := false
+	exitOpt := goprompt.OptionSetExitCheckerOnInput(func(string, bool) bool {
+		select {
+		case <-p.stopCh:
+			stopped = true
+			return true
+		default:
+			return false
+		}
+	})
+	stop := &stopParser{p: p, lost: make(chan struct{})}
+	breakLineOpt := goprompt.OptionBreakLineCallback(func(d *goprompt.Document) {
+		if stopped {
+			return
+		}
+		// It's a signal, Ctrl+D reaches go-prompt as ctrlDCode. The
+		// next loop gets the text that was typed.
+		close(stop.lost)
+		if d.Text != "" {
+			p.Preload(d.Text)
+		}
+		runtime.Goexit()
+	})
+	var in goprompt.ConsoleParser = &pasteParser{ConsoleParser: p.parser, p: p}
+	if p.maxWidth > 0 {
+		in = &widthParser{ConsoleParser: in, p: p}
+	}
+	in = &pagerParser{ConsoleParser: in, p: p}
+	in = &preloadParser{ConsoleParser: in, p: p}
+	stop.ConsoleParser = in
+	// The prompt's options come after the ones of WithGoPromptOptions and override them
+	opts := append([]goprompt.Option(nil), p.goPromptOpts...)
+	opts = append(opts, interupOpt, dumpOpt, prefixOpt, livePrefixOpt, prefixColOpt, writerOpt, exitOpt, breakLineOpt, goprompt.OptionParser(stop))
+	if p.remote == nil {
+		// Over WithIO the whole process would stop, not only the prompt
+		opts = append(opts, suspendOpt)
+	}
+	if len(history) > 0 {
+		opts = append(opts, goprompt.OptionHistory(history))
+	}
+	if p.completionDelimiters != "" {
+		// go-prompt replaces the part of the word after the last delimiter
+		opts = append(opts, goprompt.OptionCompletionWordSeparator(" "+p.completionDelimiters))
+	}
+	opts = append(opts, p.readlineOptions()...)
+	opts = append(opts, p.editModeOptions()...)
+	opts = append(opts, p.searchOptions()...)
+	opts = append(opts, p.preloadOptions()...)
+	opts = append(opts, p.keyBindOptions()...)
+	prompt, err := newGoPrompt(p.completer, p.executor, opts)
+	if err != nil {
+		return err
 	}
 
-	// Rerender a terminal for every size change
-	go p.rerenderOnTermSizeChange()
+	done := make(chan struct{})
+	p.inputDone = done
+	go func() {
+		defer close(done)
+		ended := false
+		defer func() {
+			if !ended {
+				select {
+				case p.inputLost <- struct{}{}:
+				default:
+				}
+			}
+		}()
+		// The loop ends after Stop, Ctrl+D ends the prompt with
+		// requestExit. The loop's teardown restores the terminal
+		// before the deferred calls run.
+		prompt.Run()
+		ended = true
+	}()
+	return nil
+}
+
+// newGoPrompt is goprompt.New returning the panic of a process without a
+// terminal as an error
+func newGoPrompt(completer goprompt.Completer, executor goprompt.Executor, opts []goprompt.Option) (prompt *goprompt.Prompt, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("go-prompt can't open the terminal: %v", r)
+		}
+	}()
+	return goprompt.New(executor, completer, opts...), nil
+}
+
+// Ready returns a channel that's closed once the initial render is done
+// and the prompt knows the size of the terminal. Output can be written
+// before, it waits in the buffer and is printed after the initial render
+// in the order it was written. The info row and the header set before
+// are drawn by the initial render. The channel is never closed if the
+// initial render fails, Wait tells then why the prompt ended.
+func (p *Prompt) Ready() <-chan struct{} {
+	return p.readyCh
+}
+
+// Stop prints all pending output and stops the goroutines
+// started by Run. The history waiting for its flush and the output
+// waiting for the output log are written, the log file is closed too. It's safe to call it more than once.
+func (p *Prompt) Stop() {
+	p.stopOnce.Do(func() {
+		// The output would wait for the pager until the timeout
+		p.lockRender()
+		if p.pager != nil {
+			p.closePager()
+		}
+		p.unlockRender()
+
+		// Without a terminal the output is printed by runPlain or ExecuteOnce. Nothing
+		// prints the output of a prompt that never ran.
+		if atomic.LoadInt32(&p.plain) == 0 && atomic.LoadInt32(&p.started) == 1 && p.optErr == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), DefaultDrainTimeout)
+			defer cancel()
+			if err := p.Drain(ctx); err != nil {
+				p.log.Errorf("Output wasn't fully drained before stopping the prompt: %s", err)
+			}
+		}
+
+		close(p.stopCh)
+		p.stopIdleTimer()
+		p.history.flush()
+		p.outBuf.closeSpools(DefaultDrainTimeout)
+		p.clearFatalHandler()
+		logger.Close()
+	})
 }
 
 func (p *Prompt) Writeln(s string) (n int, err error) {
-	return p.outBuf.Write([]byte(s))
+	p.assertNotRenderLocked()
+	return p.outBuf.WriteString(s)
 }
 
-func (p *Prompt) SetInfoln(s string, severity InfoLineSeverity) error {
-	p.renderMutex.Lock()
-	defer p.renderMutex.Unlock()
+// WritelnCtx is like Writeln but when the output buffer blocks
+// because it's full, the caller can give up by cancelling ctx
+func (p *Prompt) WritelnCtx(ctx context.Context, s string) (n int, err error) {
+	p.assertNotRenderLocked()
+	return p.outBuf.WriteStringCtx(ctx, s)
+}
+
+// WriteBytes is like Writeln for output that already is a []byte
+func (p *Prompt) WriteBytes(b []byte) (n int, err error) {
+	p.assertNotRenderLocked()
+	return p.outBuf.Write(b)
+}
 
-	p.writer.CursorGoTo(p.infoRow, 1)
-	p.writer.EraseLine()
+// WriteFrom copies everything from r to the output until EOF, such as
+// a file or a pipe of a subprocess. The data is streamed in pieces of
+// at most the chunk size, it's never read into memory whole.
+func (p *Prompt) WriteFrom(r io.Reader) (n int64, err error) {
+	p.assertNotRenderLocked()
+
+	p.outBuf.mut.Lock()
+	size := p.outBuf.chunkSize
+	p.outBuf.mut.Unlock()
+
+	// Hide a WriterTo of r, it would write in pieces of its own size
+	return io.CopyBuffer(p.outBuf, struct{ io.Reader }{r}, make([]byte, size))
+}
+
+// WriteLines writes lines so that they are rendered together, without
+// lines from other goroutines writing at the same time between them.
+// Every single Writeln is rendered in one piece too.
+func (p *Prompt) WriteLines(lines []string) (n int, err error) {
+	p.assertNotRenderLocked()
+	return p.outBuf.WriteLines(lines)
+}
+
+// Writef formats according to format and writes the text like Writeln
+func (p *Prompt) Writef(format string, args ...interface{}) (n int, err error) {
+	return p.Writeln(fmt.Sprintf(format, args...))
+}
+
+// ErrWriteln writes s to the stderr stream of the output. The text skips any
+// queued normal output so errors don't wait behind a large burst of logs.
+func (p *Prompt) ErrWriteln(s string) (n int, err error) {
+	p.assertNotRenderLocked()
+	return p.outBuf.WritePriority([]byte(s))
+}
+
+func (p *Prompt) SetInfoln(s string, severity InfoLineSeverity) error {
+	p.lockRender()
+	defer p.unlockRender()
 
 	red := "\x1b[31m"
 	yellow := "\x1b[33m"
@@ -219,66 +919,136 @@ func (p *Prompt) SetInfoln(s string, severity InfoLineSeverity) error {
 	// p.writer.SetColor(goprompt.Green, goprompt.DefaultColor, true)
 	t := strings.TrimSpace(s)
 	info := fmt.Sprintf("%s%s", prefix, t)
-	logger.Fdebugln("Info line text:", info)
+	p.log.Debugf("Info line text: %s", info)
 	p.infoText = info
+	if p.totalRows == 0 {
+		// The initial render shows it
+		return nil
+	}
 
-	p.writer.WriteRawStr(info)
-	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, true)
+	p.renderInfo()
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
 
-	p.writer.CursorGoTo(p.promptRow, len(p.promptPrefix)+len(p.promptText)+1)
+	return p.flush()
+}
 
-	return p.writer.Flush()
+// SetErrorln shows s on the info row as an error
+func (p *Prompt) SetErrorln(s string) error {
+	return p.SetInfoln(s, InfoLineSeverityError)
 }
 
 func (p *Prompt) ShowLoading() error {
-	p.renderMutex.Lock()
-	defer p.renderMutex.Unlock()
+	p.lockRender()
+	defer p.unlockRender()
 
-	p.writer.CursorGoTo(p.infoRow, 1)
-	p.writer.EraseLine()
+	p.infoText = "Loading..."
+	if p.totalRows == 0 {
+		return nil
+	}
 
-	msg := "Loading..."
-	p.writer.WriteRawStr(msg)
-	p.infoText = msg
+	p.renderInfo()
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
 
-	p.writer.CursorGoTo(p.promptRow, len(p.promptPrefix)+len(p.promptText)+1)
+	return p.flush()
+}
 
-	return p.writer.Flush()
+// reservedRows returns the number of rows at the bottom of the terminal
+// taken by the prompt's UI. Output can never be printed there.
+func (p *Prompt) reservedRows() int {
+	// The info rows and the prompt row
+	return p.infoRows + 1
+}
+
+// layout places the UI rows at the bottom of the terminal. It has
+// to be called whenever the terminal size or the UI rows change.
+// renderMutex must be held.
+func (p *Prompt) layout() {
+	p.promptRow = p.totalRows
+	p.infoRow = p.promptRow - p.infoRows
+	// The rows above the output start are taken by the header
+	p.freeRows = p.totalRows - (p.outputStart().Row - 1)
+}
+
+// promptCursorCol returns the column right after the user's input. renderMutex must be held.
+func (p *Prompt) promptCursorCol() int {
+	return p.prefixWidth() + utf8.RuneCountInString(p.promptLine()) + 1
+}
+
+// renderPromptRow draws the prefix and the user's input or the placeholder
+// on the prompt row, the cursor ends up right after the input. renderMutex
+// must be held.
+func (p *Prompt) renderPromptRow() {
+	p.writer.CursorGoTo(p.promptRow, 1)
+	p.writer.SetColor(p.prefixColor, goprompt.DefaultColor, false)
+	p.writer.WriteRawStr(p.currentPrefix())
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+	p.writer.WriteRawStr(p.promptLine())
+	if p.input() == "" {
+		p.renderPlaceholder()
+	}
 }
 
 func (p *Prompt) rerender(initialRun bool) error {
-	p.renderMutex.Lock()
-	defer p.renderMutex.Unlock()
+	rows, cols, err := p.rerenderLocked(initialRun)
+	if err != nil {
+		return err
+	}
+
+	// Send the event only once renderMutex is released. Whoever
+	// listens may want to update the info row in a response.
+	p.emit(NewRerenderEvent(rows, cols, initialRun))
+	if !initialRun {
+		p.emit(NewResizeEvent(rows, cols))
+	}
+	return nil
+}
 
-	size := p.parser.GetWinSize()
+// rerenderLocked returns the rows and columns the prompt uses after the rerender
+func (p *Prompt) rerenderLocked(initialRun bool) (rows, cols int, err error) {
+	p.lockRender()
+	defer p.unlockRender()
+	defer p.stats.observeRender(time.Now())
+	p.runPreRender()
+
+	size := p.winSize()
+	if p.screen != nil {
+		p.screen.resize(int(size.Row), int(size.Col))
+	}
+	p.rec.resize(int(size.Col), int(size.Row))
+	p.logWith("rows", size.Row, "cols", size.Col, "initial", initialRun).Debugf("Rerendering")
 	if initialRun {
 		p.moveWindowDown(int(size.Row))
 	}
 
 	p.writer.EraseScreen()
 
-	p.currentPos = CursorOutputStart()
-	p.savedPos = CursorOutputStart()
+	p.currentPos = p.outputStart()
+	p.savedPos = p.outputStart()
+	// The erased screen took the activity mark with it
+	p.activityPos = CursorPos{}
 
 	p.totalRows = int(size.Row)
-	p.totalColumns = int(size.Col)
-	p.promptRow = p.totalRows
-	p.infoRow = p.totalRows - 1
-	p.freeRows = p.totalRows
+	p.totalColumns = p.columns(int(size.Col))
+	p.layout()
+	p.renderHeader()
 
-	// Move to the info row and restore the text
-	p.writer.CursorGoTo(p.infoRow, 1)
-	p.writer.SetColor(goprompt.Red, goprompt.DefaultColor, true)
-	p.writer.WriteRawStr(p.infoText)
+	// Restore the info text
+	p.renderInfo()
 
-	p.writer.CursorGoTo(p.promptRow, 1)
+	// The input is still in go-prompt's buffer, it's drawn
+	// again once the user types. Until then it'd be gone.
+	p.renderPromptRow()
 
-	if err := p.writer.Flush(); err != nil {
-		return err
+	// The pager is drawn again for the new size
+	if p.pager != nil {
+		p.renderPage()
 	}
 
-	p.Events <- PromptEvent{PromptEventTypeRerender}
-	return nil
+	if err := p.flush(); err != nil {
+		return 0, 0, err
+	}
+	p.runPostRender()
+	return p.totalRows, p.totalColumns, nil
 }
 
 // Prints # of rows of "\n" - this way the visible terminal window
@@ -287,59 +1057,148 @@ func (p *Prompt) rerender(initialRun bool) error {
 func (p *Prompt) moveWindowDown(rows int) error {
 	p.writer.CursorGoTo(rows, 0)
 	p.writer.WriteRawStr(strings.Repeat("\n", rows))
-	return p.writer.Flush()
+	return p.flush()
+}
+
+// plainRun returns how many of the first max bytes of s are ASCII characters
+// that aren't a part of an escape code or a new line
+func plainRun(s string, max int) int {
+	n := 0
+	for n < max {
+		if b := s[n]; b >= utf8.RuneSelf || b == '\u001b' || b == '\n' || b == '\r' {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// flush writes everything buffered in the writer to the terminal
+func (p *Prompt) flush() error {
+	if atomic.LoadInt32(&p.termLost) != 0 {
+		return nil
+	}
+	atomic.AddInt64(&p.stats.flushCalls, 1)
+	err := p.writer.Flush()
+	if err != nil && terminalGone(err) {
+		// Not an error of the prompt, the shutdown it starts is clean
+		p.lostTerminal(fmt.Errorf("writing to the terminal failed: %w", err))
+		return nil
+	}
+	return err
 }
 
 func (p *Prompt) rerenderOnTermSizeChange() {
+	defer p.recoverGoroutine("terminal resize handler")
 	sigwinchCh := make(chan os.Signal, 1)
-	defer close(sigwinchCh)
 	signal.Notify(sigwinchCh, syscall.SIGWINCH)
+	defer signal.Stop(sigwinchCh)
 	for {
-		if _, ok := <-sigwinchCh; !ok {
+		select {
+		case <-sigwinchCh:
+		case <-p.stopCh:
 			return
 		}
+		select {
+		case <-p.stopCh:
+			// The resize came together with the stop
+			return
+		default:
+		}
+		if atomic.LoadInt32(&p.suspended) != 0 {
+			// Suspend draws the prompt for the new size once it's done
+			continue
+		}
 		if err := p.rerender(false); err != nil {
-			logger.FdebuglnFatal("Error during the rerender", err)
-			logger.FatalLogln("Error during the rerender", err)
+			p.fail(fmt.Errorf("the rerender failed: %w", err))
+			return
 		}
 	}
 }
 
-func (p *Prompt) print(b []byte) {
-	p.renderMutex.Lock()
-	defer p.renderMutex.Unlock()
+func (p *Prompt) print(c Chunk) {
+	p.lockRender()
+	defer p.unlockRender()
+	defer p.stats.observeRender(time.Now())
 
 	// The invariant is that the the p.savedPos always holds
 	// a position where we stopped printing the text = where
 	// we should start printing text again.
+	p.runPreRender()
+	p.clearActivity()
 	p.writer.CursorGoTo(p.savedPos.Row, p.savedPos.Col)
 
-	s := string(b)
+	s := p.decorate(c)
+	p.outBuf.Release(c)
+	p.transcript.write(s)
 	// s = "\n====================\nLorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor incididunt ut labore et dolore magna aliqua. Ut enim ad minim veniam, quis nostrud exercitation ullamco laboris nisi ut aliquip ex ea commodo consequat. Duis aute irure dolor in reprehenderit in voluptate velit esse cillum dolore eu fugiat nulla pariatur. Excepteur \nsint occaecat cupidatat non proident, sunt in culpa qui officia deserunt mollit anim id est laborum."
-	logger.Fdebugln(s)
+	// Logging every chunk would make the log file bigger than the output itself
+	if n, ok := p.chunkLog.allow(); ok {
+		p.logWith("stream", c.Stream, "bytes", len(s), "pos", p.savedPos, "text", s, "suppressed", n).
+			Debugf("Printing chunk")
+	}
+
+	// Runes are written in segments rather than one by one. segStart is
+	// where the text that hasn't been collected in seg yet starts. seg is
+	// written to the writer at once whenever the terminal has to scroll.
+	segStart := 0
+	seg := p.segBuf[:0]
+	esc := p.escState
+	escStart := 0
+	// Beginning of an escape code from the previous chunk
+	escPrefix := p.pendingEscape
+
+	// The writer's colors might have changed since the last print
+	seg = append(seg, p.lastEscapeCode...)
+	seg = append(seg, p.pendingEscape...)
+
+	cols := p.totalColumns
+	// The output scrolls once only this many rows are free
+	threshold := p.scrollThreshold()
+	unflushed := 0
+	var end int
+	for i := 0; i < len(s); i = end {
+		if esc == escNone && p.freeRows != threshold {
+			// Plain characters only move the cursor. They are skipped at once
+			// up to the one that reaches the end of the line.
+			limit := len(s) - i
+			if room := cols - p.currentPos.Col - 1; room >= 0 && room < limit {
+				limit = room
+			}
+			if n := plainRun(s[i:], limit); n > 0 {
+				p.currentPos.Col += n
+				end = i + n
+				continue
+			}
+		}
 
-	escapeStart := false
-	for _, r := range s {
-		p.writer.WriteRawStr(p.lastEscapeCode)
-		p.writer.WriteRawStr(string(r))
+		// Most of the output is ASCII and doesn't have to be decoded
+		r, size := rune(s[i]), 1
+		if r >= utf8.RuneSelf {
+			r, size = utf8.DecodeRuneInString(s[i:])
+		}
+		end = i + size
 
 		// Don't increase p.currentPos.Col while we are processing a terminal VT100 escape code
-		if r == '\u001b' {
-			// Reset the the last escape code
-			p.lastEscapeCode = string('\u001b')
-			escapeStart = true
+		if esc != escNone || r == '\u001b' {
+			prev := esc
+			esc = esc.next(r)
+			if esc == escIntro {
+				// An unfinished escape code before this one is abandoned
+				escStart = i
+				escPrefix = ""
+			} else if esc == escNone && prev == escCSI && r == 'm' {
+				// Colors and text attributes are applied again in the next print()
+				p.lastEscapeCode = escPrefix + s[escStart:end]
+			}
 			continue
 		}
 
-		if escapeStart {
-			p.lastEscapeCode += string(r)
-			// 'm' character signals that the escaped code is ending
-			if r == 'm' {
-				escapeStart = false
-				continue
-			} else {
-				continue
-			}
+		if r == '\r' && p.normalizeNewlines {
+			// The terminal moves the cursor to the start of the line. In "\r\n"
+			// the '\n' that follows then only adds the one row.
+			p.currentPos.Col = 1
+			continue
 		}
 
 		p.currentPos.Col++
@@ -355,33 +1214,49 @@ func (p *Prompt) print(b []byte) {
 		// TODO: Is this required?
 		// This hardcoded solution makes it impossible to have resizable text
 		// as you resize your terminal
-		if p.currentPos.Col == p.totalColumns {
+		if p.currentPos.Col == cols {
 			// Make a new line
-			p.writer.WriteRawStr("\n")
+			seg = append(seg, s[segStart:end]...)
+			seg = append(seg, '\n')
+			segStart = end
 			p.currentPos.Col = 1
 			p.currentPos.Row++
 			p.freeRows--
 		}
 
-		if p.freeRows == 2 {
+		if p.freeRows == threshold {
+			seg = append(seg, s[segStart:end]...)
+			p.writer.WriteRaw(seg)
+			unflushed += len(seg)
+			seg = seg[:0]
+			segStart = end
+
 			p.savedPos = p.currentPos
 			// Go to a prompt row and create a new line so that we
-			// once again have 3 free rows.
+			// once again have one more free row than the UI needs.
 			// The reason we have to go to the prompt row is becauase
 			// if we had printed a new line anywhere before the prompt
 			// row, the cursor would simply move down without actually
 			// creating a new line in the terminal.
 
-			// Erase the info row and prompt row so that a text doesn't stay there
+			// Erase the info rows and prompt row so that a text doesn't stay there
 			// when the everything is moved up by 1 row
-			p.writer.CursorGoTo(p.infoRow, 1)
-			p.writer.EraseLine()
-			p.writer.CursorGoTo(p.promptRow, 1)
-			p.writer.EraseLine()
+			for r := p.infoRow; r <= p.promptRow; r++ {
+				p.writer.CursorGoTo(r, 1)
+				p.writer.EraseLine()
+			}
 
 			// Create a new line
 			p.writer.WriteRawStr("\n")
 
+			// The header moved up with the rest of the text. It replaces
+			// the output line that's now on its row, the output's colors
+			// are applied again after it.
+			if p.headerText != "" {
+				p.renderHeader()
+				p.writer.WriteRawStr(p.lastEscapeCode)
+			}
+
 			// Move cursor back to a position where we stopped outputting
 			// text. This will be next available new line after the last
 			// line of printed text
@@ -392,25 +1267,47 @@ func (p *Prompt) print(b []byte) {
 
 			p.currentPos.Row--
 			p.currentPos.Col = 1
-			p.freeRows = 3
+			p.freeRows = threshold + 1
+
+			// The writer's buffer would otherwise keep growing with a big chunk
+			if unflushed >= flushEvery {
+				if err := p.flush(); err != nil {
+					p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+					return
+				}
+				unflushed = 0
+			}
 		}
 	}
+
+	if esc != escNone {
+		// The escape code continues in the next chunk. Keep its start
+		// and write it whole once the rest of it arrives.
+		seg = append(seg, s[segStart:escStart]...)
+		p.pendingEscape = escPrefix + s[escStart:]
+	} else {
+		seg = append(seg, s[segStart:]...)
+		p.pendingEscape = ""
+	}
+	p.writer.WriteRaw(seg)
+	// The writer copies seg. A huge chunk's buffer isn't kept around.
+	if cap(seg) <= maxSegBuf {
+		p.segBuf = seg[:0]
+	}
+	p.escState = esc
 	p.savedPos = p.currentPos
+	p.renderActivity()
 
-	// Move to the info row and restore the info text
-	p.writer.CursorGoTo(p.infoRow, 1)
-	p.writer.SetColor(goprompt.Red, goprompt.DefaultColor, true)
-	p.writer.WriteRawStr(p.infoText)
+	// Restore the info text
+	p.renderInfo()
 
 	// Move to the prompt row and restore the text
-	p.writer.CursorGoTo(p.promptRow, 1)
-	p.writer.SetColor(goprompt.Green, goprompt.DefaultColor, false)
-	p.writer.WriteRawStr(p.promptPrefix)
-	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
-	p.writer.WriteRawStr(p.promptText)
+	p.renderPromptRow()
 
-	if err := p.writer.Flush(); err != nil {
-		logger.FdebuglnFatal("Error flushing prompt buffer (2)", err)
-		logger.FatalLogln("Error flushing prompt buffer", err)
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	} else {
+		p.runPostRender()
 	}
+	p.emit(NewOutputEvent(len(s)))
 }
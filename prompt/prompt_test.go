@@ -0,0 +1,851 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// TestTypingWhileSettingTheInfoRow types into the prompt while another
+// goroutine keeps updating the info row, which moves the cursor back to
+// the typed text. Run it with -race.
+func TestTypingWhileSettingTheInfoRow(t *testing.T) {
+	r, w := io.Pipe()
+	p, _ := newTestPrompt(t, nil, "", WithInputReader(r))
+	startPrompt(t, p)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			io.WriteString(w, "abc\x7f\x7f")
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			p.SetInfoln(fmt.Sprintf("status %d", i), InfoLineSeverityNormal)
+			p.input()
+		}
+	}()
+	wg.Wait()
+	w.Close()
+
+	waitFor(t, "the typed text", func() bool {
+		return len(p.input()) == 200
+	})
+}
+
+// TestConcurrentUse calls the exported methods from several goroutines
+// while keys are typed and commands run. Run it with -race, the race
+// detector must stay quiet.
+func TestConcurrentUse(t *testing.T) {
+	r, w := io.Pipe()
+	var p *Prompt
+	logs := &fakeCmd{name: "logs", run: func(cmd.Args) error {
+		_, err := p.Writeln("logs of the command\n")
+		return err
+	}}
+	p, _ = newTestPrompt(t, []cmd.Cmd{&fakeCmd{name: "deploy"}, logs}, "", WithInputReader(r))
+	startPrompt(t, p)
+
+	const n = 200
+	var wg sync.WaitGroup
+	run := func(fn func(i int)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < n; i++ {
+				fn(i)
+			}
+		}()
+	}
+	run(func(i int) { p.Writeln(fmt.Sprintf("line %d\n", i)) })
+	run(func(i int) { p.ErrWriteln(fmt.Sprintf("error %d\n", i)) })
+	run(func(i int) { p.SetPrefix(fmt.Sprintf("%d> ", i)) })
+	run(func(i int) { p.SetInfoln(fmt.Sprintf("status %d", i), InfoLineSeverityNormal) })
+	run(func(i int) {
+		if err := p.Validate(); err != nil {
+			t.Errorf("Validate: %s", err)
+		}
+	})
+	run(func(i int) { p.SetHeaderln(fmt.Sprintf("header %d", i)) })
+	run(func(i int) { p.input() })
+	run(func(i int) {
+		if i%20 == 0 {
+			io.WriteString(w, "logs\r")
+		} else {
+			io.WriteString(w, "de\x7f")
+		}
+	})
+	wg.Wait()
+	w.Close()
+
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+}
+
+// TestConcurrentMultiLineWrites has several goroutines write three lines
+// at a time, with Writeln and WriteLines. The writes are bigger than a
+// chunk so they are printed in parts, but the lines of one write must end
+// up together and no line may be cut by another writer's output.
+func TestConcurrentMultiLineWrites(t *testing.T) {
+	const writers, writes = 4, 40
+	p, _ := newTestPrompt(t, nil, "", WithChunkSize(MinChunkSize))
+	startPrompt(t, p)
+
+	pad := strings.Repeat(".", 100)
+	lines := func(w, i int) []string {
+		return []string{
+			fmt.Sprintf("writer %d write %02d line 1 %s", w, i, pad),
+			fmt.Sprintf("writer %d write %02d line 2 %s", w, i, pad),
+			fmt.Sprintf("writer %d write %02d line 3 %s", w, i, pad),
+		}
+	}
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < writes; i++ {
+				if i%2 == 0 {
+					p.Writeln(strings.Join(lines(w, i), "\n") + "\n")
+				} else {
+					p.WriteLines(lines(w, i))
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+
+	p.lockRender()
+	printed := p.transcript.recent(transcriptSize)
+	p.unlockRender()
+	if len(printed) != writers*writes*3 {
+		t.Fatalf("%d lines were printed, want %d: %q", len(printed), writers*writes*3, printed)
+	}
+	next := make([]int, writers)
+	for i := 0; i < len(printed); i += 3 {
+		var w, n int
+		if _, err := fmt.Sscanf(printed[i], "writer %d write %d", &w, &n); err != nil || w < 0 || w >= writers {
+			t.Fatalf("line %d is torn: %q", i, printed[i])
+		}
+		if n != next[w] {
+			t.Fatalf("line %d is write %d of writer %d, want write %d", i, n, w, next[w])
+		}
+		next[w]++
+		for j, want := range lines(w, n) {
+			if printed[i+j] != want {
+				t.Fatalf("line %d is %q, want %q", i+j, printed[i+j], want)
+			}
+		}
+	}
+}
+
+// TestWritesBeforeReady writes 100 lines before the initial render is
+// done, half of them even before Run. They must be printed after it, in
+// order and in the rows above the prompt.
+func TestWritesBeforeReady(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "", WithScreenSnapshot())
+	var want []string
+	write := func(from, to int) {
+		for i := from; i <= to; i++ {
+			line := fmt.Sprintf("line %d", i)
+			want = append(want, line)
+			p.Writeln(line + "\n")
+		}
+	}
+	write(1, 50)
+	go p.Run()
+	write(51, 100)
+	<-p.Ready()
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+
+	// The initial render clears the screen, no line may come before it
+	screen := out.String()
+	if clear, first := strings.Index(screen, "\x1b[2J"), strings.Index(screen, "line 1\n"); clear < 0 || first < clear {
+		t.Fatalf("the output was printed before the initial render: %q", screen)
+	}
+	p.lockRender()
+	printed := p.transcript.recent(transcriptSize)
+	p.unlockRender()
+	if strings.Join(printed, "|") != strings.Join(want, "|") {
+		t.Fatalf("the printed lines are %q", printed)
+	}
+
+	// 21 output rows, the free row, the info row and the prompt
+	rows := strings.Split(p.Snapshot(), "\n")
+	for i, row := range rows[:21] {
+		if want := fmt.Sprintf("line %d", 80+i); row != want {
+			t.Errorf("row %d is %q, want %q", i+1, row, want)
+		}
+	}
+	if rows[21] != "" || rows[22] != "" || rows[23] != ">" {
+		t.Errorf("the last rows are %q", rows[21:])
+	}
+}
+
+// TestUIBeforeRun uses the prompt before Run: it writes output longer
+// than a row, pages text, sets the info row and clears the output.
+// Nothing may reach the terminal before the initial render knows its
+// size. The initial render then shows the info row and prints the output.
+func TestUIBeforeRun(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "", WithScreenSnapshot())
+	long := strings.Repeat("0123456789", 10)
+	p.Writeln("first\n")
+	p.Writeln(long + "\n")
+	if err := p.ClearOutput(); err != nil {
+		t.Fatalf("ClearOutput: %s", err)
+	}
+	if err := p.Page("paged 1\npaged 2\n"); err != nil {
+		t.Fatalf("Page: %s", err)
+	}
+	p.SetInfoln("connecting", InfoLineSeverityNormal)
+	if s := out.String(); s != "" {
+		t.Fatalf("the terminal got %q before Run", s)
+	}
+
+	startPrompt(t, p)
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	rows := strings.Split(p.Snapshot(), "\n")
+	want := []string{"first", long[:79], long[79:], "paged 1", "paged 2", ""}
+	if got := rows[:len(want)]; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("the output rows are %q, want %q", got, want)
+	}
+	if rows[22] != "connecting" || rows[23] != ">" {
+		t.Errorf("the info row is %q and the prompt row %q", rows[22], rows[23])
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		cmds []cmd.Cmd
+		err  string // Part of the error, empty when the commands are fine
+	}{
+		{"distinct", []cmd.Cmd{
+			&fakeCmd{name: "deploy", aliases: []string{"d"}},
+			&fakeCmd{name: "logs", aliases: []string{"l"}},
+		}, ""},
+		{"same name", []cmd.Cmd{
+			&fakeCmd{name: "deploy"},
+			&fakeCmd{name: "deploy"},
+		}, "command name 'deploy' is used by both 'deploy' and 'deploy'"},
+		{"alias of another's name", []cmd.Cmd{
+			&fakeCmd{name: "deploy"},
+			&fakeCmd{name: "logs", aliases: []string{"deploy"}},
+		}, "command name 'deploy' is used by both 'deploy' and 'logs'"},
+		{"name of another's alias", []cmd.Cmd{
+			&fakeCmd{name: "deploy", aliases: []string{"d"}},
+			&fakeCmd{name: "d"},
+		}, "command name 'd' is used by both 'deploy' and 'd'"},
+		{"same alias", []cmd.Cmd{
+			&fakeCmd{name: "deploy", aliases: []string{"x"}},
+			&fakeCmd{name: "logs", aliases: []string{"x"}},
+		}, "command name 'x' is used by both 'deploy' and 'logs'"},
+		{"alias repeated by one command", []cmd.Cmd{
+			&fakeCmd{name: "deploy", aliases: []string{"d", "d"}},
+		}, "command name 'd' is used by both 'deploy' and 'deploy'"},
+		{"built-in name", []cmd.Cmd{
+			&fakeCmd{name: "stats"},
+		}, "command name 'stats' of 'stats' collides with a built-in command"},
+		{"built-in alias", []cmd.Cmd{
+			&fakeCmd{name: "deploy", aliases: []string{"debug"}},
+		}, "command name 'debug' of 'deploy' collides with a built-in command"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, tt.cmds, "")
+			err := p.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Fatalf("Validate: %s", err)
+			case tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)):
+				t.Fatalf("Validate returned %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// TestUnknownCommandLogging runs a line naming no command. The executor
+// logs the command and its arguments to foundry/cli/logger.
+func TestUnknownCommandLogging(t *testing.T) {
+	p, _ := newTestPrompt(t, []cmd.Cmd{&fakeCmd{name: "deploy"}}, "")
+	c := logger.CaptureForTest(t)
+
+	if err := p.Execute("deploi api --force"); err == nil {
+		t.Fatal("Execute of an unknown command didn't fail")
+	}
+	unknown := func() []logger.Record {
+		var records []logger.Record
+		for _, r := range c.AtLevel(logger.LevelDebug) {
+			if strings.HasPrefix(r.Message, "Unknown command") {
+				records = append(records, r)
+			}
+		}
+		return records
+	}
+	records := unknown()
+	if len(records) != 1 {
+		t.Fatalf("the unknown command was logged %d times: %+v", len(records), c.Entries())
+	}
+	for _, field := range []string{"cmd=deploi", "args=\"[api --force]\""} {
+		if !strings.Contains(records[0].Message, field) {
+			t.Errorf("the message %q doesn't have %s", records[0].Message, field)
+		}
+	}
+
+	if err := p.Execute("deploy api"); err != nil {
+		t.Fatalf("Execute: %s", err)
+	}
+	if len(unknown()) != 1 {
+		t.Fatal("a known command was logged as unknown")
+	}
+}
+
+// TestNewPromptInvalidOptions checks that the deprecated NewPrompt doesn't
+// panic on an invalid option, Run returns the error instead
+func TestNewPromptInvalidOptions(t *testing.T) {
+	p := NewPrompt(nil, WithChannelDepth(0), WithInputReader(strings.NewReader("")))
+	defer p.Stop()
+	err := p.Run()
+	if err == nil || !strings.Contains(err.Error(), "channel depth must be between") {
+		t.Fatalf("Run returned %v, want the error of the option", err)
+	}
+	if err := p.Wait(); err == nil {
+		t.Fatal("Wait didn't return the error of the option")
+	}
+}
+
+// slowWriter takes a while for every write like a terminal over SSH
+type slowWriter struct {
+	w     io.Writer
+	delay time.Duration
+}
+
+func (s slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.delay)
+	return s.w.Write(p)
+}
+
+// TestStopPrintsAllOutput writes 1,000 lines and stops the prompt right
+// away. Stop must print all of them before its timeout, also when the
+// channel to the renderer is full or the terminal is slow.
+func TestStopPrintsAllOutput(t *testing.T) {
+	tests := []struct {
+		name  string
+		depth int
+		delay time.Duration
+	}{
+		{"default", DefaultChannelDepth, 0},
+		{"channel depth 1", 1, 0},
+		{"slow terminal", 1, 20 * time.Millisecond},
+	}
+	pad := strings.Repeat(".", 60)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _ := io.Pipe()
+			out := &syncBuffer{}
+			p, _ := newTestPrompt(t, nil, "",
+				WithInputReader(r),
+				WithChannelDepth(tt.depth),
+				WithConsoleWriter(&ioWriter{w: slowWriter{out, tt.delay}}),
+			)
+			startPrompt(t, p)
+
+			for i := 0; i < 1000; i++ {
+				p.Writeln(fmt.Sprintf("line %d %s\n", i, pad))
+			}
+			stopped := make(chan struct{})
+			go func() {
+				p.Stop()
+				close(stopped)
+			}()
+			select {
+			case <-stopped:
+			case <-time.After(DefaultDrainTimeout + time.Second):
+				t.Fatal("Stop didn't return")
+			}
+
+			printed := out.String()
+			for i := 0; i < 1000; i++ {
+				line := fmt.Sprintf("line %d %s\n", i, pad)
+				at := strings.Index(printed, line)
+				if at < 0 {
+					t.Fatalf("%q wasn't printed", line)
+				}
+				printed = printed[at+len(line):]
+			}
+		})
+	}
+}
+
+// markWriter is a terminal that signals seen every time mark is written
+// to it. Every write takes delay like a syscall over a slow link, and
+// perKB more for every KB written.
+type markWriter struct {
+	mark  []byte
+	seen  chan struct{}
+	delay time.Duration
+	perKB time.Duration
+
+	mut  sync.Mutex
+	tail []byte // The end of the last write, it can be the start of mark
+}
+
+func newMarkWriter(mark string, delay time.Duration) *markWriter {
+	return &markWriter{mark: []byte(mark), seen: make(chan struct{}, 1024), delay: delay}
+}
+
+func (w *markWriter) Write(p []byte) (int, error) {
+	time.Sleep(w.delay + time.Duration(len(p))*w.perKB/1024)
+	w.mut.Lock()
+	data := append(w.tail, p...)
+	n := bytes.Count(data, w.mark)
+	if keep := len(w.mark) - 1; len(data) > keep {
+		data = data[len(data)-keep:]
+	}
+	w.tail = append(w.tail[:0], data...)
+	w.mut.Unlock()
+
+	for ; n > 0; n-- {
+		w.seen <- struct{}{}
+	}
+	return len(p), nil
+}
+
+// BenchmarkChunkSize measures how long a line takes from Writeln to the
+// terminal when nothing else is printed, and how fast 1MB of lines gets
+// there. The remote terminal takes 200µs for every write.
+func BenchmarkChunkSize(b *testing.B) {
+	const mark = "--mark--\n"
+	line := strings.Repeat("x", 99) + "\n"
+	terminals := []struct {
+		name  string
+		delay time.Duration
+	}{
+		{"local", 0},
+		{"remote", 200 * time.Microsecond},
+	}
+	for _, size := range []int{1024, 4 * 1024, 16 * 1024, 64 * 1024} {
+		for _, term := range terminals {
+			run := func(name string, bench func(b *testing.B, p *Prompt, w *markWriter)) {
+				b.Run(fmt.Sprintf("%dKB/%s/%s", size/1024, term.name, name), func(b *testing.B) {
+					r, _ := io.Pipe()
+					w := newMarkWriter(mark, term.delay)
+					p, _ := newTestPrompt(b, nil, "",
+						WithInputReader(r),
+						WithChunkSize(size),
+						WithConsoleWriter(&ioWriter{w: w}),
+					)
+					startPrompt(b, p)
+					b.ResetTimer()
+					bench(b, p, w)
+				})
+			}
+			run("latency", func(b *testing.B, p *Prompt, w *markWriter) {
+				for i := 0; i < b.N; i++ {
+					p.Writeln(mark)
+					<-w.seen
+				}
+			})
+			run("throughput", func(b *testing.B, p *Prompt, w *markWriter) {
+				const total = 1024 * 1024
+				b.SetBytes(total)
+				for i := 0; i < b.N; i++ {
+					for n := 0; n < total; n += len(line) {
+						p.Writeln(line)
+					}
+					p.Writeln(mark)
+					<-w.seen
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkChannelDepth measures how fast 1MB of lines gets to a remote
+// terminal depending on how many chunks wait for the renderer
+func BenchmarkChannelDepth(b *testing.B) {
+	const mark = "--mark--\n"
+	line := strings.Repeat("x", 99) + "\n"
+	for _, depth := range []int{1, 16, DefaultChannelDepth, 1024} {
+		b.Run(fmt.Sprintf("%d", depth), func(b *testing.B) {
+			r, _ := io.Pipe()
+			w := newMarkWriter(mark, 200*time.Microsecond)
+			p, _ := newTestPrompt(b, nil, "",
+				WithInputReader(r),
+				WithChannelDepth(depth),
+				WithConsoleWriter(&ioWriter{w: w}),
+			)
+			startPrompt(b, p)
+			b.ResetTimer()
+
+			const total = 1024 * 1024
+			b.SetBytes(total)
+			for i := 0; i < b.N; i++ {
+				for n := 0; n < total; n += len(line) {
+					p.Writeln(line)
+				}
+				p.Writeln(mark)
+				<-w.seen
+			}
+		})
+	}
+}
+
+// BenchmarkKeystrokeLatency streams 10MB of lines to a terminal that takes
+// 100µs per KB and presses a key every millisecond until the output is on
+// the terminal. A key is what go-prompt does for it before drawing: the
+// completer, the live prefix and its color. Every op is one such stream.
+func BenchmarkKeystrokeLatency(b *testing.B) {
+	const mark = "--mark--\n"
+	line := strings.Repeat("x", 99) + "\n"
+	r, _ := io.Pipe()
+	w := newMarkWriter(mark, 0)
+	w.perKB = 100 * time.Microsecond
+	p, err := New(nil, WithInputReader(r), WithTerminalSize(24, 80), withTermOut(w))
+	if err != nil {
+		b.Fatalf("New: %s", err)
+	}
+	b.Cleanup(p.Stop)
+	startPrompt(b, p)
+
+	key := func(text string) {
+		p.completer(goprompt.Document{Text: text})
+		p.lockRender()
+		_ = p.currentPrefix()
+		_ = p.prefixColor
+		p.unlockRender()
+	}
+	var latencies []time.Duration
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		go func() {
+			for n := 0; n < 10*1024*1024; n += len(line) {
+				p.Writeln(line)
+			}
+			p.Writeln(mark)
+		}()
+		tick := time.NewTicker(time.Millisecond)
+		for done := false; !done; {
+			select {
+			case <-w.seen:
+				done = true
+			case <-tick.C:
+				start := time.Now()
+				key(strings.Repeat("a", len(latencies)%8))
+				latencies = append(latencies, time.Since(start))
+			}
+		}
+		tick.Stop()
+	}
+	b.StopTimer()
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(q float64) float64 {
+		return float64(latencies[int(q*float64(len(latencies)-1))])
+	}
+	b.ReportMetric(at(0.5), "p50-ns")
+	b.ReportMetric(at(0.99), "p99-ns")
+	b.ReportMetric(at(1), "max-ns")
+}
+
+// countWriter is a terminal that only counts the bytes written to it
+type countWriter struct{ n int64 }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// BenchmarkWriteStream writes 10MB of colored log lines and prints them
+// a chunk at a time like the renderer. out-B/op is what the terminal gets.
+func BenchmarkWriteStream(b *testing.B) {
+	const total = 10 * 1024 * 1024
+	line := "\x1b[32mINFO\x1b[0m 2020/04/10 12:00:00 function deployed, 42 ms\n"
+	w := &countWriter{}
+	p, _ := newTestPrompt(b, nil, "", WithConsoleWriter(&ioWriter{w: w}))
+	if _, _, err := p.rerenderLocked(true); err != nil {
+		b.Fatalf("the initial render failed: %s", err)
+	}
+	printAll := func() {
+		for _, c := range readChunks(p.outBuf) {
+			p.print(c)
+		}
+	}
+
+	w.n = 0
+	b.SetBytes(total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for n := 0; n < total; n += len(line) {
+			p.Writeln(line)
+			if n%DefaultChunkSize < len(line) {
+				printAll()
+			}
+		}
+		printAll()
+	}
+	b.ReportMetric(float64(w.n)/float64(b.N), "out-B/op")
+}
+
+// printChunks cover what print has to handle: wrapping, scrolling, escape
+// codes split between chunks, wide characters and stderr
+var printChunks = []Chunk{
+	{Data: []byte("plain line\n")},
+	{Data: []byte(strings.Repeat("0123456789", 20) + "\n")},
+	{Data: []byte("\x1b[31mred\x1b[0m and \x1b[1;32mbold green\x1b[0m\n")},
+	{Data: []byte("split \x1b[3")},
+	{Data: []byte("4mblue\x1b[0m escape\n")},
+	{Data: []byte(strings.Repeat("日本語のテキスト ", 12) + "\n")},
+	{Data: []byte("emoji 👩‍💻 and é\n")},
+	{Data: []byte("no new line, ")},
+	{Data: []byte("continued\n")},
+	{Data: []byte("to stderr\n"), Stream: StreamStderr},
+	{Data: []byte(strings.Repeat("scrolling line\n", 40))},
+	{Data: []byte("\x1b[33m" + strings.Repeat("yellow ", 30) + "\x1b[0m\n")},
+}
+
+// TestPrintGolden compares what print sends to the terminal for
+// printChunks with testdata/print.golden. Collecting the text in segments
+// mustn't change the bytes the terminal gets. Run with -update to rewrite
+// the golden file after an intended change.
+func TestPrintGolden(t *testing.T) {
+	p, out := newTestPrompt(t, nil, "")
+	if _, _, err := p.rerenderLocked(true); err != nil {
+		t.Fatalf("the initial render failed: %s", err)
+	}
+	start := len(out.String())
+	for _, c := range printChunks {
+		p.print(c)
+	}
+	checkGolden(t, "print.golden", out.String()[start:])
+}
+
+// TestNormalizeNewlines prints output with "\r\n" line endings and
+// progress updated with a lone '\r'. The lines end one character before
+// the renderer wraps them. With WithNormalizeNewlines each takes one row and
+// no line break is added. Without it the '\r' is counted as a character
+// that fills the row and the renderer breaks the line after it.
+func TestNormalizeNewlines(t *testing.T) {
+	line := strings.Repeat("x", 78) + "\r\n"
+	text := strings.Repeat(line, 5) + "progress 50%\rprogress 100%\r\n" + "done"
+
+	tests := []struct {
+		name   string
+		opts   []Option
+		rows   int
+		breaks int
+	}{
+		{"normalized", []Option{WithNormalizeNewlines()}, 6, 0},
+		{"raw", nil, 11, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, out := newTestPrompt(t, nil, "", tt.opts...)
+			if _, _, err := p.rerenderLocked(true); err != nil {
+				t.Fatalf("the initial render failed: %s", err)
+			}
+			start, row := len(out.String()), p.currentPos.Row
+			// The same bytes in pieces ending in the middle of "\r\n"
+			for i := 0; i < len(text); i += 81 {
+				end := i + 81
+				if end > len(text) {
+					end = len(text)
+				}
+				p.print(Chunk{Data: []byte(text[i:end])})
+			}
+
+			if rows := p.currentPos.Row - row; rows != tt.rows || p.currentPos.Col != len("done")+1 {
+				t.Errorf("the cursor moved %d rows to column %d, want %d rows to column %d", rows, p.currentPos.Col, tt.rows, len("done")+1)
+			}
+			added := strings.Count(out.String()[start:], "\n") - strings.Count(text, "\n")
+			if added != tt.breaks {
+				t.Errorf("%d line breaks were added, want %d: %q", added, tt.breaks, out.String()[start:])
+			}
+		})
+	}
+}
+
+// TestResizeKeepsInput resizes the terminal while a command is typed.
+// The prompt row shows the input again right after the rerender, cut from
+// the left once it doesn't fit the narrower terminal.
+func TestResizeKeepsInput(t *testing.T) {
+	const input = "deploy api --region eu-west"
+	p, _ := newTestPrompt(t, nil, input, WithScreenSnapshot())
+	startPrompt(t, p)
+	waitFor(t, "the typed text", func() bool { return p.input() == input })
+
+	tests := []struct {
+		cols int
+		want string
+	}{
+		{40, "> " + input},
+		{16, "> …egion eu-west"},
+		{80, "> " + input},
+	}
+	for _, tt := range tests {
+		p.lockRender()
+		p.fixedSize = &goprompt.WinSize{Row: 24, Col: uint16(tt.cols)}
+		p.unlockRender()
+		if err := p.rerender(false); err != nil {
+			t.Fatalf("the rerender failed: %s", err)
+		}
+
+		rows := strings.Split(p.Snapshot(), "\n")
+		if row := rows[23]; row != tt.want {
+			t.Errorf("at %d columns the prompt row is %q, want %q", tt.cols, row, tt.want)
+		}
+		p.screen.mut.Lock()
+		pos := CursorPos{p.screen.row, p.screen.col}
+		p.screen.mut.Unlock()
+		if want := (CursorPos{24, utf8.RuneCountInString(tt.want) + 1}); pos != want {
+			t.Errorf("at %d columns the cursor is at %v, want %v", tt.cols, pos, want)
+		}
+	}
+}
+
+// BenchmarkPrintLargeOutput prints 1MB of log lines, some of them colored
+// and some longer than the terminal, in chunks of about 4KB
+func BenchmarkPrintLargeOutput(b *testing.B) {
+	const total = 1024 * 1024
+	lines := []string{
+		"2020/04/10 12:00:00 function deployed, 42 ms\n",
+		"\x1b[32mINFO\x1b[0m watching 3 functions\n",
+		strings.Repeat("a long stack trace line ", 6) + "\n",
+	}
+	var text []byte
+	for i := 0; len(text) < total; i++ {
+		text = append(text, lines[i%len(lines)]...)
+	}
+	var chunks []Chunk
+	for len(text) > 0 {
+		n := 4000
+		if n > len(text) {
+			n = len(text)
+		}
+		// The capacity isn't the chunk size, print doesn't reuse the data
+		chunks = append(chunks, Chunk{Data: text[:n:n]})
+		text = text[n:]
+	}
+
+	p, _ := newTestPrompt(b, nil, "", WithConsoleWriter(&ioWriter{w: &countWriter{}}))
+	if _, _, err := p.rerenderLocked(true); err != nil {
+		b.Fatalf("the initial render failed: %s", err)
+	}
+	b.SetBytes(total)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, c := range chunks {
+			p.print(c)
+		}
+	}
+}
+
+// BenchmarkWriteCoalesce writes 1000 pieces of a line a millisecond apart,
+// like a command printing its progress, and reports how many times the
+// renderer flushed the terminal for them
+func BenchmarkWriteCoalesce(b *testing.B) {
+	const mark = "--mark--\n"
+	for _, d := range []time.Duration{0, 2 * time.Millisecond, 5 * time.Millisecond} {
+		b.Run(d.String(), func(b *testing.B) {
+			r, _ := io.Pipe()
+			w := newMarkWriter(mark, 0)
+			p, _ := newTestPrompt(b, nil, "",
+				WithInputReader(r),
+				WithWriteCoalesce(d),
+				WithConsoleWriter(&ioWriter{w: w}),
+			)
+			startPrompt(b, p)
+			flushes := p.Metrics().FlushCalls
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				for n := 0; n < 1000; n++ {
+					p.Writeln(".")
+					time.Sleep(time.Millisecond)
+				}
+				p.Writeln(mark)
+				<-w.seen
+			}
+			b.ReportMetric(float64(p.Metrics().FlushCalls-flushes)/float64(b.N), "flushes/op")
+		})
+	}
+}
+
+// TestScrollReserve prints more lines than fit with more info rows and a
+// header. The output scrolls above the rows the UI reserves, the last line
+// is right above the info rows and never covers them.
+func TestScrollReserve(t *testing.T) {
+	for _, infoRows := range []int{1, 2, 3} {
+		for _, header := range []string{"", "connected to prod"} {
+			t.Run(fmt.Sprintf("%d info rows %q", infoRows, header), func(t *testing.T) {
+				p, _ := newTestPrompt(t, nil, "", WithScreenSnapshot(), WithInfoRows(infoRows))
+				startPrompt(t, p)
+				p.SetHeaderln(header)
+				info := strings.TrimSuffix(strings.Repeat("info\n", infoRows), "\n")
+				p.SetInfoln(info, InfoLineSeverityNormal)
+				p.lockRender()
+				reserved := p.reservedRows()
+				p.unlockRender()
+				if reserved != infoRows+1 {
+					t.Fatalf("%d rows are reserved, want %d", reserved, infoRows+1)
+				}
+
+				for i := 1; i <= 40; i++ {
+					p.Writeln(fmt.Sprintf("line %d\n", i))
+				}
+				if err := p.Drain(context.Background()); err != nil {
+					t.Fatalf("Drain: %s", err)
+				}
+
+				rows := strings.Split(p.Snapshot(), "\n")
+				if got := strings.TrimSpace(rows[0]); header != "" && got != header {
+					t.Errorf("the header row is %q", got)
+				}
+				// The row above the info rows is empty, the next line goes there
+				last := 24 - reserved - 2
+				for i := 0; i < 5; i++ {
+					want := fmt.Sprintf("line %d", 40-i)
+					if got := strings.TrimSpace(rows[last-i]); got != want {
+						t.Errorf("row %d is %q, want %q", last-i+1, got, want)
+					}
+				}
+				if got := strings.TrimSpace(rows[last+1]); got != "" {
+					t.Errorf("the row above the info rows is %q", got)
+				}
+				for i := 24 - reserved; i < 23; i++ {
+					if got := strings.TrimSpace(rows[i]); got != "info" {
+						t.Errorf("info row %d is %q", i+1, got)
+					}
+				}
+				if got := strings.TrimSpace(rows[23]); got != ">" {
+					t.Errorf("the prompt row is %q", got)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,338 @@
+//go:build linux
+// +build linux
+
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"foundry/cli/logger"
+)
+
+// The tests in this file run the prompt in a child process on a real
+// pseudo terminal. The variable tells the child which part it plays.
+const ptyChildEnv = "PROMPT_TEST_PTY_CHILD"
+
+func ioctl(fd, req, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, req, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// openPTY opens a new pseudo terminal of 24x80. Both ends are closed
+// when the test finishes.
+func openPTY(t *testing.T) (master, slave *os.File) {
+	t.Helper()
+	master, slave, err := newPTY()
+	if err != nil {
+		t.Skipf("no pseudo terminals: %v", err)
+	}
+	t.Cleanup(func() {
+		master.Close()
+		slave.Close()
+	})
+	return master, slave
+}
+
+// newPTY opens a new pseudo terminal of 24x80
+func newPTY() (master, slave *os.File, err error) {
+	master, err = os.OpenFile("/dev/ptmx", os.O_RDWR, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		if err != nil {
+			master.Close()
+		}
+	}()
+
+	var unlock int32
+	if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+		return nil, nil, fmt.Errorf("unlocking the pty failed: %w", err)
+	}
+	var n uint32
+	if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&n))); err != nil {
+		return nil, nil, fmt.Errorf("getting the pty number failed: %w", err)
+	}
+	slave, err = os.OpenFile(fmt.Sprintf("/dev/pts/%d", n), os.O_RDWR|syscall.O_NOCTTY, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ws := struct{ row, col, x, y uint16 }{24, 80, 0, 0}
+	if err := ioctl(slave.Fd(), syscall.TIOCSWINSZ, uintptr(unsafe.Pointer(&ws))); err != nil {
+		slave.Close()
+		return nil, nil, fmt.Errorf("setting the pty size failed: %w", err)
+	}
+	return master, slave, nil
+}
+
+// ptyTestsEnv is set for the test binary run by TestMain on a pseudo terminal
+const ptyTestsEnv = "PROMPT_TEST_ON_PTY"
+
+// TestMain runs the tests again in a child process with a pseudo terminal
+// as its controlling terminal if the test binary has none, e.g. in CI.
+// go-prompt opens the process's terminal even when it reads a fake one.
+func TestMain(m *testing.M) {
+	if !hasTerminal() && os.Getenv(ptyChildEnv) == "" && os.Getenv(ptyTestsEnv) == "" {
+		code, err := runOnPTY()
+		if err == nil {
+			os.Exit(code)
+		}
+		// The tests of go-prompt's loop fail without a terminal
+		fmt.Fprintf(os.Stderr, "no pseudo terminal for the tests: %s\n", err)
+	}
+	os.Exit(m.Run())
+}
+
+// hasTerminal reports whether the process has a controlling terminal
+func hasTerminal() bool {
+	tty, err := os.Open("/dev/tty")
+	if err != nil {
+		return false
+	}
+	tty.Close()
+	return true
+}
+
+// runOnPTY runs the test binary with the same arguments on a new pseudo
+// terminal and returns its exit code
+func runOnPTY() (int, error) {
+	master, slave, err := newPTY()
+	if err != nil {
+		return 0, err
+	}
+	defer master.Close()
+	// Nothing should be written to it, it mustn't block if it is
+	go io.Copy(ioutil.Discard, master)
+
+	c := exec.Command(os.Args[0], os.Args[1:]...)
+	c.Env = append(os.Environ(), ptyTestsEnv+"=1")
+	c.Stdin, c.Stdout, c.Stderr = slave, os.Stdout, os.Stderr
+	c.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	err = c.Run()
+	slave.Close()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	return 0, err
+}
+
+func getTermios(fd uintptr) (syscall.Termios, error) {
+	var tio syscall.Termios
+	err := ioctl(fd, syscall.TCGETS, uintptr(unsafe.Pointer(&tio)))
+	return tio, err
+}
+
+// isCooked reports whether the terminal reads lines and echoes them
+// like the shell expects
+func isCooked(fd uintptr) bool {
+	tio, err := getTermios(fd)
+	return err == nil && tio.Lflag&syscall.ICANON != 0 && tio.Lflag&syscall.ECHO != 0
+}
+
+// ptyChild is the test binary running one test in a child process
+// that has the slave of a pseudo terminal as its controlling terminal
+type ptyChild struct {
+	cmd    *exec.Cmd
+	master *os.File
+	slave  *os.File
+	out    *syncBuffer
+	done   chan struct{} // Closed once the child exited
+	err    error         // Returned by cmd.Wait
+}
+
+// startPTYChild runs test in a child process with role in ptyChildEnv.
+// Everything the child writes to the terminal ends up in out.
+func startPTYChild(t *testing.T, test, role string) *ptyChild {
+	t.Helper()
+	master, slave := openPTY(t)
+	c := &ptyChild{master: master, slave: slave, out: &syncBuffer{}, done: make(chan struct{})}
+
+	c.cmd = exec.Command(os.Args[0], "-test.run=^"+test+"$")
+	// The race detector waits a second before a process exits otherwise
+	c.cmd.Env = append(os.Environ(), ptyChildEnv+"="+role, "GORACE=atexit_sleep_ms=0")
+	c.cmd.Stdin, c.cmd.Stdout, c.cmd.Stderr = slave, slave, slave
+	c.cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+	if err := c.cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		c.err = c.cmd.Wait()
+		close(c.done)
+	}()
+	t.Cleanup(func() {
+		c.cmd.Process.Kill()
+		<-c.done
+	})
+	go io.Copy(c.out, master)
+	return c
+}
+
+// wait waits for the child to exit and returns its exit code
+func (c *ptyChild) wait(t *testing.T) int {
+	t.Helper()
+	select {
+	case <-c.done:
+		var exitErr *exec.ExitError
+		if errors.As(c.err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		if c.err != nil {
+			t.Fatal(c.err)
+		}
+		return 0
+	case <-time.After(10 * time.Second):
+		t.Fatalf("the child didn't exit, the terminal got %q", c.out.String())
+		return 0
+	}
+}
+
+// runPTYPrompt is the child's part: it runs a prompt on its terminal and
+// calls fn once the prompt is ready and go-prompt put the terminal into
+// the raw mode
+func runPTYPrompt(t *testing.T, fn func(p *Prompt)) {
+	logger.SetOutput(ioutil.Discard)
+	p, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	<-p.Ready()
+	waitFor(t, "the raw mode", func() bool { return !isCooked(os.Stdin.Fd()) })
+	fn(p)
+	if err := <-errCh; err != nil {
+		fmt.Println("Run:", err)
+		os.Exit(3)
+	}
+}
+
+// TestFatalRestoresPTY calls logger.FatalLogln while the prompt has the
+// terminal in the raw mode. The process exits but the terminal must be
+// usable afterwards: cooked, echoing and with the cursor shown.
+func TestFatalRestoresPTY(t *testing.T) {
+	if os.Getenv(ptyChildEnv) == "fatal" {
+		runPTYPrompt(t, func(*Prompt) { logger.FatalLogln("boom") })
+		return
+	}
+
+	c := startPTYChild(t, "TestFatalRestoresPTY", "fatal")
+	if code := c.wait(t); code != 1 {
+		t.Fatalf("the child exited with %d, want 1: %q", code, c.out.String())
+	}
+	if !isCooked(c.slave.Fd()) {
+		t.Fatal("the terminal was left in the raw mode")
+	}
+
+	// The line discipline echoes and hands over whole lines again
+	if _, err := c.master.Write([]byte("echo ok\n")); err != nil {
+		t.Fatal(err)
+	}
+	line := make([]byte, 64)
+	n, err := c.slave.Read(line)
+	if err != nil || string(line[:n]) != "echo ok\n" {
+		t.Fatalf("reading the terminal got %q, %v", line[:n], err)
+	}
+	waitFor(t, "the echo", func() bool { return strings.Contains(c.out.String(), "echo ok") })
+
+	out := c.out.String()
+	for _, want := range []string{"\x1b[0m\x1b[?25h", "boom"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("the terminal didn't get %q: %q", want, out)
+		}
+	}
+	if strings.LastIndex(out, "boom") < strings.LastIndex(out, "\x1b[?25h") {
+		t.Errorf("the message was written before the terminal was restored: %q", out)
+	}
+}
+
+// TestHangupEndsPTYSession closes the master of the pseudo terminal while
+// the prompt runs and prints output, like when the terminal window is
+// closed or the ssh connection drops. Run must return nil instead of the
+// process dying of SIGHUP or spinning on the dead terminal.
+func TestHangupEndsPTYSession(t *testing.T) {
+	if os.Getenv(ptyChildEnv) == "hangup" {
+		runPTYPrompt(t, func(p *Prompt) {
+			go func() {
+				for i := 0; ; i++ {
+					select {
+					case <-p.doneCh:
+						return
+					default:
+					}
+					p.Writef("line %d\n", i)
+					time.Sleep(time.Millisecond)
+				}
+			}()
+		})
+		return
+	}
+
+	c := startPTYChild(t, "TestHangupEndsPTYSession", "hangup")
+	waitFor(t, "the raw mode", func() bool { return !isCooked(c.slave.Fd()) })
+	waitFor(t, "the output", func() bool { return strings.Contains(c.out.String(), "line 10") })
+	c.master.Close()
+	c.slave.Close()
+
+	if code := c.wait(t); code != 0 {
+		t.Fatalf("the child exited with %d, the terminal got %q", code, c.out.String())
+	}
+}
+
+// TestSuspendRepaintsPTY presses Ctrl+Z in a prompt running on a pseudo
+// terminal. The process must stop with the terminal given back cooked,
+// and once it gets SIGCONT it must take the raw mode again and draw the
+// info row and the prompt over whatever the shell printed meanwhile.
+func TestSuspendRepaintsPTY(t *testing.T) {
+	if os.Getenv(ptyChildEnv) == "suspend" {
+		runPTYPrompt(t, func(p *Prompt) {
+			p.SetInfoln("connected to prod", InfoLineSeverityNormal)
+		})
+		return
+	}
+
+	c := startPTYChild(t, "TestSuspendRepaintsPTY", "suspend")
+	waitFor(t, "the info row", func() bool { return strings.Contains(c.out.String(), "connected to prod") })
+
+	c.master.Write([]byte{0x1a})
+	var ws syscall.WaitStatus
+	if _, err := syscall.Wait4(c.cmd.Process.Pid, &ws, syscall.WUNTRACED, nil); err != nil || !ws.Stopped() {
+		t.Fatalf("the child didn't stop: %v, %v", ws, err)
+	}
+	if !isCooked(c.slave.Fd()) {
+		t.Fatal("the terminal was left in the raw mode while the process is stopped")
+	}
+	// What the child wrote before it stopped may still be on its way
+	waitFor(t, "the restored terminal", func() bool {
+		return strings.HasSuffix(c.out.String(), "\x1b[0m\x1b[?25h\x1b[?2004l\x1b[24;1H\r\n")
+	})
+
+	// The shell prints its job control message
+	c.slave.Write([]byte("[1]+  Stopped\n"))
+	mark := len(c.out.String())
+	syscall.Kill(c.cmd.Process.Pid, syscall.SIGCONT)
+	waitFor(t, "the raw mode", func() bool { return !isCooked(c.slave.Fd()) })
+	waitFor(t, "the repaint", func() bool {
+		repaint := c.out.String()[mark:]
+		return strings.Contains(repaint, "connected to prod") && strings.Contains(repaint, "\x1b[24;1H\x1b[2K")
+	})
+
+	// Ctrl+D ends the prompt that runs as before
+	c.master.Write([]byte{0x04})
+	if code := c.wait(t); code != 0 {
+		t.Fatalf("the child exited with %d: %q", code, c.out.String())
+	}
+}
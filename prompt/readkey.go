@@ -0,0 +1,187 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// Key is a key read by ReadKey. Enter is always goprompt.Enter,
+// not the control key the terminal sends for it.
+type Key struct {
+	Key  goprompt.Key // goprompt.NotDefined for a printable character
+	Rune rune         // The character if Key is goprompt.NotDefined
+}
+
+// keyReader is the state of ReadKey. Keys typed faster than they are read
+// wait in r until the next ReadKey.
+type keyReader struct {
+	mut sync.Mutex
+	r   *bufio.Reader
+	tty *ttyKeys
+}
+
+// errNoKey is returned by ttyKeys once its deadline passes
+var errNoKey = errors.New("no key was pressed")
+
+// ReadKey waits for the next key the user presses, without enter, e.g.
+// for a menu chosen with the arrows. It can only be called from a command's
+// RunRequest while it runs, go-prompt doesn't read the input then. Ctrl+C
+// comes as a key too, the command decides what it does.
+func (p *Prompt) ReadKey() (Key, error) {
+	var k Key
+	err := p.readKeys(func(read func() (Key, error)) (err error) {
+		k, err = read()
+		return err
+	})
+	return k, err
+}
+
+// readKeys calls fn that reads the keys with read. The terminal stays
+// in the raw mode until fn returns so no key is echoed in between.
+func (p *Prompt) readKeys(fn func(read func() (Key, error)) error) error {
+	return p.keyInput(func(r *bufio.Reader, _ *ttyKeys) error {
+		return fn(func() (Key, error) { return readKey(r) })
+	})
+}
+
+// pollKeys is like readKeys but poll waits at most d for a key. It returns
+// false if none came. The scripted input is only checked for the keys
+// already read.
+func (p *Prompt) pollKeys(fn func(poll func(d time.Duration) (Key, bool, error)) error) error {
+	return p.keyInput(func(r *bufio.Reader, t *ttyKeys) error {
+		return fn(func(d time.Duration) (Key, bool, error) {
+			if r.Buffered() > 0 {
+				k, err := readKey(r)
+				return k, err == nil, err
+			}
+			if t == nil {
+				time.Sleep(d)
+				return Key{}, false, nil
+			}
+			t.deadline = time.Now().Add(d)
+			defer func() { t.deadline = time.Time{} }()
+			k, err := readKey(r)
+			if err == errNoKey {
+				return Key{}, false, nil
+			}
+			return k, err == nil, err
+		})
+	})
+}
+
+// keyInput calls fn with the reader of the keys, t is nil for the
+// scripted input
+func (p *Prompt) keyInput(fn func(r *bufio.Reader, t *ttyKeys) error) error {
+	if p.parser == nil || atomic.LoadInt32(&p.cooked) == 0 {
+		return errors.New("keys can only be read while a command runs")
+	}
+	p.keys.mut.Lock()
+	defer p.keys.mut.Unlock()
+
+	if rp, ok := p.parser.(*readerParser); ok {
+		// Scripted input is read by nobody else while the command runs
+		return fn(rp.r, nil)
+	}
+
+	// go-prompt gives the command the cooked terminal, the key
+	// shouldn't wait for enter. It's cooked again for the command.
+	if err := p.parser.Setup(); err != nil {
+		return err
+	}
+	defer p.parser.TearDown()
+	if p.keys.r == nil {
+		p.keys.tty = &ttyKeys{p: p}
+		p.keys.r = bufio.NewReader(p.keys.tty)
+	}
+	return fn(p.keys.r, p.keys.tty)
+}
+
+// readKey reads a single key from r
+func readKey(r *bufio.Reader) (Key, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return Key{}, err
+	}
+
+	switch {
+	case b == 0x1b:
+		// The terminal sends an escape sequence at once,
+		// the escape key alone has nothing after it
+		if r.Buffered() == 0 {
+			return Key{Key: goprompt.Escape}, nil
+		}
+		seq := []byte{b}
+		next, _ := r.ReadByte()
+		seq = append(seq, next)
+		if next == '[' || next == 'O' {
+			for r.Buffered() > 0 {
+				c, _ := r.ReadByte()
+				seq = append(seq, c)
+				if c >= 0x40 && c <= 0x7e {
+					break
+				}
+			}
+		}
+		return Key{Key: goprompt.GetKey(seq)}, nil
+	case b == '\r' || b == '\n':
+		return Key{Key: goprompt.Enter}, nil
+	case b < ' ' || b == 0x7f:
+		return Key{Key: goprompt.GetKey([]byte{b})}, nil
+	}
+
+	if err := r.UnreadByte(); err != nil {
+		return Key{}, err
+	}
+	c, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	if c == utf8.RuneError {
+		// Not UTF-8, there's no character to return
+		return Key{Key: goprompt.NotDefined}, nil
+	}
+	return Key{Key: goprompt.NotDefined, Rune: c}, nil
+}
+
+// ttyKeys is an io.Reader of the terminal for ReadKey. The terminal is
+// read in the non-blocking mode so ReadKey ends when the prompt stops.
+type ttyKeys struct {
+	p        *Prompt
+	pending  []byte    // Read from the terminal but not returned yet
+	deadline time.Time // Read gives up with errNoKey after it, zero waits for a key
+}
+
+func (t *ttyKeys) Read(b []byte) (int, error) {
+	for {
+		if len(t.pending) > 0 {
+			n := copy(b, t.pending)
+			t.pending = t.pending[n:]
+			return n, nil
+		}
+		in, err := t.p.parser.Read()
+		if err == nil && len(in) > 0 {
+			t.pending = in
+			continue
+		}
+		if err != nil && err != syscall.EAGAIN {
+			return 0, err
+		}
+		if !t.deadline.IsZero() && time.Now().After(t.deadline) {
+			return 0, errNoKey
+		}
+
+		select {
+		case <-t.p.stopCh:
+			return 0, io.EOF
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// killRingSize is how many of the last kills Ctrl+Y and Alt+Y can yank back
+const killRingSize = 10
+
+// killRing keeps the text deleted by the kill keys. Only used on the
+// input goroutine.
+type killRing struct {
+	kills  []string // Oldest first
+	yanked int      // Index of the kill yanked last
+	last   string   // The text yanked last, Alt+Y replaces it by an older kill
+}
+
+// add keeps a kill, at most killRingSize of them
+func (k *killRing) add(s string) {
+	if s == "" {
+		return
+	}
+	k.kills = append(k.kills, s)
+	if len(k.kills) > killRingSize {
+		k.kills = k.kills[len(k.kills)-killRingSize:]
+	}
+	k.last = ""
+}
+
+// defaultEditDelimiters separate the words of the word keys besides the
+// spaces. The arguments are mostly paths and flags.
+const defaultEditDelimiters = "-/."
+
+// WithWordDelimiters sets the characters that separate words besides the
+// spaces, e.g. ":" for arguments like service:restart. The word keys like
+// Alt+B and Ctrl+W stop at them instead of at "-/.". Completion completes
+// the part of the argument after the last one, a suggestion of the whole
+// argument shows only that part.
+func WithWordDelimiters(set string) Option {
+	return func(p *Prompt) error {
+		if strings.ContainsAny(set, "\n\r\t") {
+			return fmt.Errorf("word delimiters %q can't contain line breaks or tabs", set)
+		}
+		p.editDelimiters = set
+		p.completionDelimiters = set
+		return nil
+	}
+}
+
+// isWordSeparator returns true if r is between words
+func isWordSeparator(r rune, delims string) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(delims, r)
+}
+
+// wordStart returns the index of the start of the word before i
+func wordStart(text []rune, i int, delims string) int {
+	for i > 0 && isWordSeparator(text[i-1], delims) {
+		i--
+	}
+	for i > 0 && !isWordSeparator(text[i-1], delims) {
+		i--
+	}
+	return i
+}
+
+// wordEndAfter returns the index right after the end of the word after i
+func wordEndAfter(text []rune, i int, delims string) int {
+	for i < len(text) && isWordSeparator(text[i], delims) {
+		i++
+	}
+	for i < len(text) && !isWordSeparator(text[i], delims) {
+		i++
+	}
+	return i
+}
+
+// readlineOptions returns go-prompt options with the readline keys instead
+// of go-prompt's emacs ones. The kill keys keep what they deleted for
+// Ctrl+Y, the word keys stop at the delimiters set by WithWordDelimiters.
+//
+//	Ctrl+A, Ctrl+E  beginning, end of the line
+//	Ctrl+B, Ctrl+F  a character back, forward
+//	Alt+B, Alt+F    a word back, forward
+//	Ctrl+D, Ctrl+H  delete the character under, before the cursor
+//	Ctrl+W, Alt+⌫   kill the word before the cursor
+//	Alt+D           kill the word after the cursor
+//	Ctrl+U, Ctrl+K  kill to the beginning, the end of the line
+//	Ctrl+Y          yank the last kill
+//	Alt+Y           replace the yanked text by the kill before it
+//	Ctrl+L          clear the output
+func (p *Prompt) readlineOptions() []goprompt.Option {
+	keys, alt := p.readlineBinds()
+	return []goprompt.Option{
+		goprompt.OptionSwitchKeyBindMode(goprompt.CommonKeyBind),
+		goprompt.OptionAddKeyBind(keys...),
+		goprompt.OptionAddASCIICodeBind(alt...),
+	}
+}
+
+// readlineBinds returns the binds of the readline keys, the Alt ones and
+// Ctrl+D read as ctrlDCode are sequences go-prompt doesn't know as keys
+func (p *Prompt) readlineBinds() ([]goprompt.KeyBind, []goprompt.ASCIICodeBind) {
+	delims := p.editDelimiters
+	keys := []goprompt.KeyBind{
+		{Key: goprompt.ControlA, Fn: func(buf *goprompt.Buffer) {
+			buf.CursorLeft(len([]rune(buf.Document().TextBeforeCursor())))
+		}},
+		{Key: goprompt.ControlE, Fn: func(buf *goprompt.Buffer) {
+			buf.CursorRight(len([]rune(buf.Document().TextAfterCursor())))
+		}},
+		{Key: goprompt.ControlB, Fn: func(buf *goprompt.Buffer) {
+			buf.CursorLeft(1)
+		}},
+		{Key: goprompt.ControlF, Fn: func(buf *goprompt.Buffer) {
+			buf.CursorRight(1)
+		}},
+		{Key: goprompt.ControlH, Fn: func(buf *goprompt.Buffer) {
+			buf.DeleteBeforeCursor(1)
+		}},
+		{Key: goprompt.ControlW, Fn: p.killWordBefore},
+		{Key: goprompt.ControlU, Fn: func(buf *goprompt.Buffer) {
+			p.kills.add(buf.DeleteBeforeCursor(cursorIndex(buf)))
+		}},
+		{Key: goprompt.ControlK, Fn: func(buf *goprompt.Buffer) {
+			p.kills.add(buf.Delete(len([]rune(buf.Document().TextAfterCursor()))))
+		}},
+		{Key: goprompt.ControlY, Fn: p.yank},
+		{Key: goprompt.ControlL, Fn: func(*goprompt.Buffer) {
+			// go-prompt would erase the whole screen, the rows of the prompt too
+			if err := p.ClearOutput(); err != nil {
+				p.SetInfoln(err.Error(), InfoLineSeverityError)
+			}
+		}},
+	}
+	alt := []goprompt.ASCIICodeBind{
+		{ASCIICode: ctrlDCode, Fn: func(buf *goprompt.Buffer) {
+			// Ctrl+D on an empty input ends the prompt like in a shell
+			if buf.Text() == "" {
+				p.requestExit("ctrl+d", nil)
+				return
+			}
+			buf.Delete(1)
+		}},
+		{ASCIICode: []byte("\x1bb"), Fn: func(buf *goprompt.Buffer) {
+			buf.CursorLeft(cursorIndex(buf) - wordStart([]rune(buf.Text()), cursorIndex(buf), delims))
+		}},
+		{ASCIICode: []byte("\x1bf"), Fn: func(buf *goprompt.Buffer) {
+			buf.CursorRight(wordEndAfter([]rune(buf.Text()), cursorIndex(buf), delims) - cursorIndex(buf))
+		}},
+		{ASCIICode: []byte("\x1bd"), Fn: func(buf *goprompt.Buffer) {
+			p.kills.add(buf.Delete(wordEndAfter([]rune(buf.Text()), cursorIndex(buf), delims) - cursorIndex(buf)))
+		}},
+		{ASCIICode: []byte("\x1b\x7f"), Fn: p.killWordBefore},
+		{ASCIICode: []byte("\x1by"), Fn: p.yankOlder},
+	}
+	return keys, alt
+}
+
+// killWordBefore kills the word before the cursor
+func (p *Prompt) killWordBefore(buf *goprompt.Buffer) {
+	i := cursorIndex(buf)
+	p.kills.add(buf.DeleteBeforeCursor(i - wordStart([]rune(buf.Text()), i, p.editDelimiters)))
+}
+
+// yank inserts the last kill
+func (p *Prompt) yank(buf *goprompt.Buffer) {
+	k := &p.kills
+	if len(k.kills) == 0 {
+		return
+	}
+	k.yanked = len(k.kills) - 1
+	k.last = k.kills[k.yanked]
+	buf.InsertText(k.last, false, true)
+}
+
+// yankOlder replaces the text yanked right before the cursor by the kill
+// before it, the newest one comes again after the oldest
+func (p *Prompt) yankOlder(buf *goprompt.Buffer) {
+	k := &p.kills
+	if k.last == "" || !strings.HasSuffix(buf.Document().TextBeforeCursor(), k.last) {
+		return
+	}
+	buf.DeleteBeforeCursor(len([]rune(k.last)))
+	k.yanked = (k.yanked - 1 + len(k.kills)) % len(k.kills)
+	k.last = k.kills[k.yanked]
+	buf.InsertText(k.last, false, true)
+}
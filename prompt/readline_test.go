@@ -0,0 +1,148 @@
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// The bytes of the readline keys
+const (
+	ctrlA    = "\x01"
+	ctrlB    = "\x02"
+	ctrlD    = "\x1b]foundry-ctrl-d\x07" // What stopParser reads for Ctrl+D
+	ctrlE    = "\x05"
+	ctrlF    = "\x06"
+	ctrlK    = "\x0b"
+	ctrlU    = "\x15"
+	ctrlW    = "\x17"
+	ctrlY    = "\x19"
+	altB     = "\x1bb"
+	altD     = "\x1bd"
+	altF     = "\x1bf"
+	altY     = "\x1by"
+	altBkspc = "\x1b\x7f"
+)
+
+// applyBinds types text and presses keys like go-prompt does: a key with
+// a bind runs it, a sequence of an Alt bind runs that one, the rest is
+// inserted
+func applyBinds(p *Prompt, text string, keys ...string) *goprompt.Buffer {
+	binds, alt := p.readlineBinds()
+	buf := goprompt.NewBuffer()
+	buf.InsertText(text, false, true)
+	for _, k := range keys {
+		b := []byte(k)
+		ran := false
+		for _, kb := range binds {
+			if kb.Key == goprompt.GetKey(b) {
+				kb.Fn(buf)
+				ran = true
+			}
+		}
+		for _, kb := range alt {
+			if bytes.Equal(kb.ASCIICode, b) {
+				kb.Fn(buf)
+				ran = true
+			}
+		}
+		if !ran {
+			buf.InsertText(k, false, true)
+		}
+	}
+	return buf
+}
+
+func TestReadlineBinds(t *testing.T) {
+	const path = "deploy ./functions/api"
+	tests := []struct {
+		name   string
+		text   string
+		keys   []string
+		want   string
+		cursor int
+	}{
+		{"word back", path, []string{altB}, path, 19},
+		{"two words back", path, []string{altB, altB}, path, 9},
+		{"back over the separators", path, []string{altB, altB, altB}, path, 0},
+		{"back at the start", path, []string{ctrlA, altB}, path, 0},
+		{"word forward", path, []string{ctrlA, altF}, path, 6},
+		{"forward over the separators", path, []string{ctrlA, altF, altF}, path, 18},
+		{"forward at the end", path, []string{altF}, path, 22},
+		{"character back and forward", path, []string{ctrlB, ctrlB, ctrlB, ctrlF}, path, 20},
+		{"delete under the cursor", path, []string{ctrlA, ctrlD}, "eploy ./functions/api", 0},
+		{"kill the word before", path, []string{ctrlW}, "deploy ./functions/", 19},
+		{"kill two words before", path, []string{ctrlW, ctrlW}, "deploy ./", 9},
+		{"kill with Alt+Backspace", path, []string{altBkspc}, "deploy ./functions/", 19},
+		{"kill in the middle of a word", path, []string{altB, ctrlF, ctrlW}, "deploy ./functions/pi", 19},
+		{"kill the word after", path, []string{ctrlA, altD}, " ./functions/api", 0},
+		{"kill the word after the separators", path, []string{ctrlA, altF, altD}, "deploy/api", 6},
+		{"kill to the end", path, []string{ctrlA, altF, ctrlK}, "deploy", 6},
+		{"kill to the start", path, []string{ctrlA, altF, ctrlU}, " ./functions/api", 0},
+		{"yank", path, []string{ctrlW, ctrlY}, path, 22},
+		{"yank the last kill", path, []string{ctrlW, ctrlW, ctrlY}, "deploy ./functions/", 19},
+		{"yank elsewhere", path, []string{ctrlA, altF, ctrlU, ctrlE, ctrlY}, " ./functions/apideploy", 22},
+		{"yank twice", "logs ", []string{ctrlU, ctrlY, ctrlY}, "logs logs ", 10},
+		{"yank nothing", path, []string{ctrlY}, path, 22},
+		{"older kills", "a b c", []string{ctrlW, ctrlW, ctrlW, ctrlY, altY}, "b ", 2},
+		{"older kills wrap", "a b c", []string{ctrlW, ctrlW, ctrlW, ctrlY, altY, altY, altY}, "a ", 2},
+		{"Alt+Y without a yank", "a b c", []string{ctrlW, altY}, "a b ", 4},
+		{"Alt+Y after typing", "a b c", []string{ctrlW, ctrlY, "x", altY}, "a b cx", 6},
+		{"flags", "deploy --env-file .env", []string{altB, altB, altB}, "deploy --env-file .env", 9},
+		{"kill a flag's word", "deploy --env-file", []string{ctrlW, ctrlW}, "deploy --", 9},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "")
+			buf := applyBinds(p, tt.text, tt.keys...)
+			if text, cursor := buf.Text(), cursorIndex(buf); text != tt.want || cursor != tt.cursor {
+				t.Errorf("the input is %q with the cursor at %d, want %q at %d", text, cursor, tt.want, tt.cursor)
+			}
+		})
+	}
+}
+
+// TestReadlineDelimiters moves by words separated by ':' instead of "-/."
+func TestReadlineDelimiters(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithWordDelimiters(":"))
+	buf := applyBinds(p, "svc:restart --force-all", altB, altB)
+	if text, cursor := buf.Text(), cursorIndex(buf); cursor != 4 {
+		t.Fatalf("in %q the cursor is at %d, want 4", text, cursor)
+	}
+	buf = applyBinds(p, "svc:restart --force-all", ctrlW, ctrlW)
+	if text := buf.Text(); text != "svc:" {
+		t.Fatalf("the input is %q", text)
+	}
+}
+
+// TestKillRingSize kills more words than the ring keeps. Alt+Y goes
+// through the last killRingSize ones and starts over.
+func TestKillRingSize(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	var text string
+	var keys []string
+	for i := 1; i <= killRingSize+2; i++ {
+		text += fmt.Sprintf(" w%d", i)
+		keys = append(keys, ctrlW)
+	}
+	keys = append(keys, ctrlY)
+	for i := 0; i < killRingSize; i++ {
+		keys = append(keys, altY)
+	}
+
+	buf := applyBinds(p, text, keys...)
+	if len(p.kills.kills) != killRingSize {
+		t.Fatalf("the ring has %d kills", len(p.kills.kills))
+	}
+	// Ctrl+W killed from the end, after the oldest kill comes the newest again
+	if text := buf.Text(); text != " w1 " {
+		t.Fatalf("after going through the ring the input is %q", text)
+	}
+	buf = applyBinds(p, "", ctrlY, altY, altY, altY, altY, altY, altY, altY, altY, altY)
+	// w12 and w11 were killed first and are gone
+	if text := buf.Text(); text != "w10 " {
+		t.Fatalf("the oldest kill is %q", text)
+	}
+}
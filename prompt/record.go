@@ -0,0 +1,352 @@
+package prompt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// recorder writes what the prompt draws to an asciinema v2 cast file,
+// started and stopped by the record command. Only the output is recorded,
+// the keys are never. The file is written by a spool so the rendering
+// never waits for the disk.
+type recorder struct {
+	on int32 // Set to 1 while recording, atomic
+
+	mut   sync.Mutex
+	cast  *spool
+	path  string
+	start time.Time
+	cols  int // The size of the last header or resize event
+	rows  int
+}
+
+// castHeader is the first line of a cast file
+type castHeader struct {
+	Version   int   `json:"version"`
+	Width     int   `json:"width"`
+	Height    int   `json:"height"`
+	Timestamp int64 `json:"timestamp"`
+	// The longest pause a player keeps, in seconds. Not in our recordings.
+	IdleTimeLimit float64 `json:"idle_time_limit,omitempty"`
+}
+
+func (r *recorder) recording() bool {
+	return atomic.LoadInt32(&r.on) == 1
+}
+
+// begin truncates the file at path and writes the header with the
+// terminal's size
+func (r *recorder) begin(path string, cols, rows int, onError func(err error)) error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.cast != nil {
+		return fmt.Errorf("already recording to %s", r.path)
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	header, _ := json.Marshal(castHeader{Version: 2, Width: cols, Height: rows, Timestamp: now.Unix()})
+	if _, err := f.Write(append(header, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+
+	s := startSpool(path, f, false, 0, onError)
+	r.cast, r.path, r.start = s, path, now
+	r.cols, r.rows = cols, rows
+	atomic.StoreInt32(&r.on, 1)
+	return nil
+}
+
+// end stops the recording and closes the file. It returns the path and
+// how many bytes of the events the spool dropped.
+func (r *recorder) end() (path string, dropped int64, err error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.cast == nil {
+		return "", 0, errors.New("not recording")
+	}
+	atomic.StoreInt32(&r.on, 0)
+	path, dropped = r.path, r.cast.close(time.Second)
+	r.cast = nil
+	return path, dropped, nil
+}
+
+// event adds an event of type typ, "o" for output or "r" for a resize
+func (r *recorder) event(typ, data string) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	if r.cast == nil {
+		return
+	}
+	elapsed := time.Since(r.start).Seconds()
+	line, _ := json.Marshal([]interface{}{elapsed, typ, data})
+	r.cast.write(append(line, '\n'))
+}
+
+// resize adds a resize event if the prompt is recording and the size changed
+func (r *recorder) resize(cols, rows int) {
+	if !r.recording() {
+		return
+	}
+	r.mut.Lock()
+	changed := cols != r.cols || rows != r.rows
+	r.cols, r.rows = cols, rows
+	r.mut.Unlock()
+	if changed {
+		r.event("r", fmt.Sprintf("%dx%d", cols, rows))
+	}
+}
+
+// recordWriter passes everything to the real writer. While recording it
+// keeps a copy of what the real writer sends to the terminal and adds it
+// to the recording once flushed. The title and the cursor position
+// requests aren't recorded, a player has no title and can't answer.
+type recordWriter struct {
+	goprompt.ConsoleWriter
+	rec  *recorder
+	copy vt100
+}
+
+func (w *recordWriter) Flush() error {
+	err := w.ConsoleWriter.Flush()
+	pending := w.copy.buf
+	if len(pending) == 0 {
+		return err
+	}
+	if err != nil || !w.rec.recording() {
+		w.copy.buf = pending[:0]
+		return err
+	}
+	// A character cut by the flush is kept for the next event,
+	// a cast event must be valid UTF-8
+	n := len(pending)
+	for i := 1; i <= utf8.UTFMax && i <= n; i++ {
+		if utf8.RuneStart(pending[n-i]) {
+			if !utf8.FullRune(pending[n-i:]) {
+				n -= i
+			}
+			break
+		}
+	}
+	if n > 0 {
+		w.rec.event("o", string(pending[:n]))
+	}
+	w.copy.buf = append(pending[:0], pending[n:]...)
+	return nil
+}
+
+func (w *recordWriter) WriteRaw(data []byte) {
+	w.ConsoleWriter.WriteRaw(data)
+	if w.rec.recording() {
+		w.copy.WriteRaw(data)
+	}
+}
+
+func (w *recordWriter) Write(data []byte) {
+	w.ConsoleWriter.Write(data)
+	if w.rec.recording() {
+		w.copy.Write(data)
+	}
+}
+
+func (w *recordWriter) WriteRawStr(data string) {
+	w.ConsoleWriter.WriteRawStr(data)
+	if w.rec.recording() {
+		w.copy.WriteRawStr(data)
+	}
+}
+
+func (w *recordWriter) WriteStr(data string) {
+	w.ConsoleWriter.WriteStr(data)
+	if w.rec.recording() {
+		w.copy.WriteStr(data)
+	}
+}
+
+func (w *recordWriter) EraseScreen() {
+	w.ConsoleWriter.EraseScreen()
+	if w.rec.recording() {
+		w.copy.EraseScreen()
+	}
+}
+
+func (w *recordWriter) EraseUp() {
+	w.ConsoleWriter.EraseUp()
+	if w.rec.recording() {
+		w.copy.EraseUp()
+	}
+}
+
+func (w *recordWriter) EraseDown() {
+	w.ConsoleWriter.EraseDown()
+	if w.rec.recording() {
+		w.copy.EraseDown()
+	}
+}
+
+func (w *recordWriter) EraseStartOfLine() {
+	w.ConsoleWriter.EraseStartOfLine()
+	if w.rec.recording() {
+		w.copy.EraseStartOfLine()
+	}
+}
+
+func (w *recordWriter) EraseEndOfLine() {
+	w.ConsoleWriter.EraseEndOfLine()
+	if w.rec.recording() {
+		w.copy.EraseEndOfLine()
+	}
+}
+
+func (w *recordWriter) EraseLine() {
+	w.ConsoleWriter.EraseLine()
+	if w.rec.recording() {
+		w.copy.EraseLine()
+	}
+}
+
+func (w *recordWriter) ShowCursor() {
+	w.ConsoleWriter.ShowCursor()
+	if w.rec.recording() {
+		w.copy.ShowCursor()
+	}
+}
+
+func (w *recordWriter) HideCursor() {
+	w.ConsoleWriter.HideCursor()
+	if w.rec.recording() {
+		w.copy.HideCursor()
+	}
+}
+
+func (w *recordWriter) CursorGoTo(row, col int) {
+	w.ConsoleWriter.CursorGoTo(row, col)
+	if w.rec.recording() {
+		w.copy.CursorGoTo(row, col)
+	}
+}
+
+func (w *recordWriter) CursorUp(n int) {
+	w.ConsoleWriter.CursorUp(n)
+	if w.rec.recording() {
+		w.copy.CursorUp(n)
+	}
+}
+
+func (w *recordWriter) CursorDown(n int) {
+	w.ConsoleWriter.CursorDown(n)
+	if w.rec.recording() {
+		w.copy.CursorDown(n)
+	}
+}
+
+func (w *recordWriter) CursorForward(n int) {
+	w.ConsoleWriter.CursorForward(n)
+	if w.rec.recording() {
+		w.copy.CursorForward(n)
+	}
+}
+
+func (w *recordWriter) CursorBackward(n int) {
+	w.ConsoleWriter.CursorBackward(n)
+	if w.rec.recording() {
+		w.copy.CursorBackward(n)
+	}
+}
+
+func (w *recordWriter) SaveCursor() {
+	w.ConsoleWriter.SaveCursor()
+	if w.rec.recording() {
+		w.copy.SaveCursor()
+	}
+}
+
+func (w *recordWriter) UnSaveCursor() {
+	w.ConsoleWriter.UnSaveCursor()
+	if w.rec.recording() {
+		w.copy.UnSaveCursor()
+	}
+}
+
+func (w *recordWriter) ScrollDown() {
+	w.ConsoleWriter.ScrollDown()
+	if w.rec.recording() {
+		w.copy.ScrollDown()
+	}
+}
+
+func (w *recordWriter) ScrollUp() {
+	w.ConsoleWriter.ScrollUp()
+	if w.rec.recording() {
+		w.copy.ScrollUp()
+	}
+}
+
+func (w *recordWriter) SetColor(fg, bg goprompt.Color, bold bool) {
+	w.ConsoleWriter.SetColor(fg, bg, bold)
+	if w.rec.recording() {
+		w.copy.SetColor(fg, bg, bold)
+	}
+}
+
+func runRecord(p *Prompt, args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "stop":
+		path, dropped, err := p.rec.end()
+		if err != nil {
+			return err
+		}
+		p.log.Debugf("Recording to %s stopped", path)
+		msg := fmt.Sprintf("Recording saved to %s", path)
+		if dropped > 0 {
+			msg += fmt.Sprintf(", %d bytes were too slow to write and are missing", dropped)
+		}
+		p.SetInfoln(msg, InfoLineSeverityNormal)
+		return nil
+	case len(args) >= 1 && len(args) <= 2 && args[0] == "start":
+	default:
+		return errors.New("usage: record start [path] | record stop")
+	}
+
+	path := fmt.Sprintf("foundry-%s.cast", time.Now().Format("20060102-150405"))
+	if len(args) == 2 {
+		path = args[1]
+	}
+	p.lockRender()
+	rendered := p.totalRows > 0
+	var size *goprompt.WinSize
+	if rendered {
+		size = p.winSize()
+	}
+	p.unlockRender()
+	if !rendered {
+		return errors.New("there's nothing to record before the prompt runs")
+	}
+
+	err := p.rec.begin(path, int(size.Col), int(size.Row), func(err error) {
+		atomic.StoreInt32(&p.rec.on, 0)
+		p.log.Errorf("Recording to %s failed: %s", path, err)
+	})
+	if err != nil {
+		return fmt.Errorf("recording failed: %w", err)
+	}
+	p.log.Debugf("Recording to %s", path)
+	// The recording starts with the whole screen
+	if _, _, err := p.rerenderLocked(false); err != nil {
+		return err
+	}
+	p.SetInfoln(fmt.Sprintf("Recording to %s, 'record stop' ends it", path), InfoLineSeverityNormal)
+	return nil
+}
@@ -0,0 +1,105 @@
+package prompt
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// TestRecordCastFormat records output, typing and a resize and checks the
+// file against the asciinema v2 format a player reads: a header object,
+// then [time, type, data] events with times that never go back, only
+// output and resize events and valid UTF-8 data.
+func TestRecordCastFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.cast")
+	p, parser, _, _ := newTTYPrompt(t, nil)
+	go p.Run()
+	<-p.Ready()
+
+	if err := p.Execute("record start " + path); err != nil {
+		t.Fatalf("record start: %s", err)
+	}
+	p.Writeln("✓ deployed api\n")
+	parser.keys <- []byte("logs api")
+	waitFor(t, "the typed text", func() bool { return p.input() == "logs api" })
+	p.lockRender()
+	p.fixedSize = &goprompt.WinSize{Row: 30, Col: 100}
+	p.unlockRender()
+	if err := p.rerender(false); err != nil {
+		t.Fatalf("the rerender failed: %s", err)
+	}
+	if err := p.Execute("record stop"); err != nil {
+		t.Fatalf("record stop: %s", err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, maxCastLine)
+	if !sc.Scan() {
+		t.Fatal("the file is empty")
+	}
+	var header map[string]interface{}
+	if err := json.Unmarshal(sc.Bytes(), &header); err != nil {
+		t.Fatalf("the header %q isn't a JSON object: %s", sc.Text(), err)
+	}
+	if header["version"] != 2.0 || header["width"] != 80.0 || header["height"] != 24.0 {
+		t.Errorf("the header is %q", sc.Text())
+	}
+	if ts, ok := header["timestamp"].(float64); !ok || ts <= 0 {
+		t.Errorf("the header has no timestamp: %q", sc.Text())
+	}
+
+	size := regexp.MustCompile(`^[0-9]+x[0-9]+$`)
+	var output strings.Builder
+	var resizes []string
+	last := 0.0
+	for n := 2; sc.Scan(); n++ {
+		var event []interface{}
+		if err := json.Unmarshal(sc.Bytes(), &event); err != nil || len(event) != 3 {
+			t.Fatalf("line %d isn't an event: %q", n, sc.Text())
+		}
+		time, ok := event[0].(float64)
+		if !ok || time < last {
+			t.Fatalf("line %d has the time %v after %v", n, event[0], last)
+		}
+		last = time
+		data, ok := event[2].(string)
+		if !ok || !utf8.ValidString(data) {
+			t.Fatalf("line %d has the data %v", n, event[2])
+		}
+		switch event[1] {
+		case "o":
+			output.WriteString(data)
+		case "r":
+			if !size.MatchString(data) {
+				t.Fatalf("line %d resizes to %q", n, data)
+			}
+			resizes = append(resizes, data)
+		default:
+			t.Fatalf("line %d has the type %v", n, event[1])
+		}
+	}
+	if err := sc.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(resizes) != 1 || resizes[0] != "100x30" {
+		t.Errorf("the resizes are %q", resizes)
+	}
+	for _, want := range []string{"✓ deployed api", "logs api", "\x1b[30;1H"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("the output has no %q: %q", want, output.String())
+		}
+	}
+}
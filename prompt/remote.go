@@ -0,0 +1,230 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"syscall"
+	"time"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// SizeSource is where a prompt run with WithIO gets the size of its
+// terminal instead of the process's terminal and SIGWINCH. Use SizeFunc
+// or SizeChannel to make one.
+type SizeSource struct {
+	size    func() (rows, cols int)
+	every   time.Duration
+	changes <-chan ResizeEvent
+	initial ResizeEvent
+}
+
+// SizeFunc returns a SizeSource that calls size for the current size.
+// It's called every interval to notice a change.
+func SizeFunc(size func() (rows, cols int), every time.Duration) SizeSource {
+	return SizeSource{size: size, every: every}
+}
+
+// SizeChannel returns a SizeSource of a terminal of rows and cols that
+// changes gets the new size of, e.g. from an SSH session's window-change
+// requests. The size stays once changes is closed.
+func SizeChannel(rows, cols int, changes <-chan ResizeEvent) SizeSource {
+	return SizeSource{changes: changes, initial: ResizeEvent{Rows: rows, Cols: cols}}
+}
+
+// remoteIO is the terminal of a prompt run with WithIO
+type remoteIO struct {
+	in  io.Reader
+	out io.Writer
+	src SizeSource
+
+	mut  sync.Mutex
+	size ResizeEvent // The last size from the channel of the source
+}
+
+// winSize returns the current size of the terminal
+func (r *remoteIO) winSize() *goprompt.WinSize {
+	if r.src.size != nil {
+		rows, cols := r.src.size()
+		return &goprompt.WinSize{Row: uint16(rows), Col: uint16(cols)}
+	}
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return &goprompt.WinSize{Row: uint16(r.size.Rows), Col: uint16(r.size.Cols)}
+}
+
+// WithIO runs the prompt over in, out and size instead of the process's
+// terminal, e.g. over an SSH session. in gets the keys, out everything
+// the prompt draws. The other side puts its terminal in the raw mode.
+// Ctrl+Z doesn't suspend, it would stop the whole process.
+func WithIO(in io.Reader, out io.Writer, size SizeSource) Option {
+	return func(p *Prompt) error {
+		switch {
+		case in == nil || out == nil:
+			return errors.New("the reader and the writer of WithIO can't be nil")
+		case size.size == nil && size.changes == nil:
+			return errors.New("the size source of WithIO must come from SizeFunc or SizeChannel")
+		case size.size != nil && size.every <= 0:
+			return fmt.Errorf("the size polling interval %s isn't positive", size.every)
+		}
+		r := &remoteIO{in: in, out: &lockedWriter{w: out}, src: size, size: size.initial}
+		p.remote = r
+		p.termOut = r.out
+		p.parser = &ttyParser{ConsoleParser: &ioParser{r: in, remote: r}, p: p}
+		return nil
+	}
+}
+
+// lockedWriter lets the terminal queue and restoreTerminal write at once
+type lockedWriter struct {
+	mut sync.Mutex
+	w   io.Writer
+}
+
+func (l *lockedWriter) Write(b []byte) (int, error) {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+	return l.w.Write(b)
+}
+
+// ioWriter is a goprompt.ConsoleWriter that flushes to an io.Writer
+type ioWriter struct {
+	vt100
+	w io.Writer
+}
+
+func (w *ioWriter) Flush() error {
+	_, err := w.w.Write(w.buf)
+	w.buf = w.buf[:0]
+	return err
+}
+
+// ioParser is a goprompt.ConsoleParser of an io.Reader. Like go-prompt's
+// parser of the terminal it doesn't block, no input is EAGAIN. A goroutine
+// reads r until it fails. A connection often brings more keys in one read,
+// e.g. a whole line with its enter, they're split like by readerParser.
+type ioParser struct {
+	r      io.Reader
+	remote *remoteIO
+
+	once sync.Once
+	ch   chan []byte
+	err  error // Why reading r ended, set before ch is closed
+}
+
+func (ip *ioParser) start() {
+	ip.ch = make(chan []byte, 16)
+	go func() {
+		for {
+			b := make([]byte, 1024)
+			n, err := ip.r.Read(b)
+			for _, key := range splitKeys(b[:n]) {
+				ip.ch <- key
+			}
+			if err != nil {
+				ip.err = err
+				close(ip.ch)
+				return
+			}
+		}
+	}()
+}
+
+// splitKeys splits what a read got into the keys go-prompt recognizes
+// only on their own, the text between them stays in one piece. Unlike
+// readerParser it never waits for more, a key cut by the read stays cut.
+func splitKeys(b []byte) [][]byte {
+	var keys [][]byte
+	for len(b) > 0 {
+		n := 1
+		switch c := b[0]; {
+		case c == 0x1b:
+			// Escape sequence of a special key like an arrow
+			if len(b) > 1 {
+				n = 2
+				if b[1] == '[' || b[1] == 'O' {
+					for n < len(b) {
+						n++
+						if b[n-1] >= 0x40 && b[n-1] <= 0x7e {
+							break
+						}
+					}
+				}
+			}
+		case c < ' ' || c == 0x7f:
+			// Control keys like enter or backspace
+		default:
+			for n < len(b) && b[n] >= ' ' && b[n] != 0x7f && b[n] != 0x1b {
+				n++
+			}
+		}
+		keys = append(keys, b[:n])
+		b = b[n:]
+	}
+	return keys
+}
+
+func (ip *ioParser) Setup() error {
+	ip.once.Do(ip.start)
+	return nil
+}
+
+func (ip *ioParser) TearDown() error { return nil }
+
+func (ip *ioParser) Read() ([]byte, error) {
+	ip.once.Do(ip.start)
+	select {
+	case b, ok := <-ip.ch:
+		if !ok {
+			return nil, ip.err
+		}
+		return b, nil
+	default:
+		return nil, syscall.EAGAIN
+	}
+}
+
+func (ip *ioParser) GetWinSize() *goprompt.WinSize {
+	return ip.remote.winSize()
+}
+
+// rerenderOnSizeSource rerenders the prompt for every size change
+// of the terminal of WithIO
+func (p *Prompt) rerenderOnSizeSource() {
+	defer p.recoverGoroutine("terminal size watcher")
+	r := p.remote
+	var tick <-chan time.Time
+	if r.src.size != nil {
+		t := time.NewTicker(r.src.every)
+		defer t.Stop()
+		tick = t.C
+	}
+	changes := r.src.changes
+	last := *r.winSize()
+	for {
+		select {
+		case e, ok := <-changes:
+			if !ok {
+				changes = nil
+				continue
+			}
+			r.mut.Lock()
+			r.size = e
+			r.mut.Unlock()
+		case <-tick:
+		case <-p.stopCh:
+			return
+		}
+		if size := *r.winSize(); size != last {
+			last = size
+		} else {
+			continue
+		}
+		if err := p.rerender(false); err != nil {
+			p.fail(fmt.Errorf("the rerender failed: %w", err))
+			return
+		}
+	}
+}
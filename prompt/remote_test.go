@@ -0,0 +1,68 @@
+package prompt
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestWithIOPipes runs go-prompt's loop over a pair of in-memory pipes
+// like an SSH session would. The client types a line, its command's
+// output comes back, a window change resizes and closing the input ends
+// the prompt.
+func TestWithIOPipes(t *testing.T) {
+	inR, inW := io.Pipe()
+	outR, outW := io.Pipe()
+	changes := make(chan ResizeEvent)
+	var p *Prompt
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		_, err := p.Writeln("deployed " + strings.Join(args, " ") + "\n")
+		return err
+	}}
+	p, err := New([]cmd.Cmd{deploy}, WithIO(inR, outW, SizeChannel(24, 80, changes)))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	t.Cleanup(p.Stop)
+
+	// The client reads everything the prompt draws
+	screen := &syncBuffer{}
+	go io.Copy(screen, outR)
+	done := make(chan error, 1)
+	go func() { done <- p.Run() }()
+	<-p.Ready()
+
+	typeKeys := func(keys string) {
+		t.Helper()
+		if _, err := inW.Write([]byte(keys)); err != nil {
+			t.Fatalf("typing %q: %s", keys, err)
+		}
+	}
+	shown := func(what, s string) {
+		t.Helper()
+		waitFor(t, what, func() bool { return strings.Contains(screen.String(), s) })
+	}
+
+	shown("the prompt", "\x1b[24;1H")
+	typeKeys("deploy api")
+	waitFor(t, "the typed text", func() bool { return p.input() == "deploy api" })
+	typeKeys("\r")
+	shown("the command's output", "deployed api")
+
+	changes <- ResizeEvent{Rows: 30, Cols: 100}
+	shown("the prompt after the resize", "\x1b[30;1H")
+	if size := *p.remote.winSize(); size.Row != 30 || size.Col != 100 {
+		t.Errorf("the size is %dx%d", size.Col, size.Row)
+	}
+
+	// A whole line in one read is split into the text and its enter
+	typeKeys("deploy web\r")
+	shown("the output of the line in one read", "deployed web")
+
+	inW.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Run: %s", err)
+	}
+}
@@ -0,0 +1,57 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+)
+
+// WithPreRender calls fn whenever the prompt is about to draw, before the
+// whole screen is drawn again and before output is printed. See
+// WithPostRender for what fn may do.
+func WithPreRender(fn func()) Option {
+	return func(p *Prompt) error {
+		if fn == nil {
+			return errors.New("the pre-render callback can't be nil")
+		}
+		p.preRender = fn
+		return nil
+	}
+}
+
+// WithPostRender calls fn once the prompt has drawn the whole screen again
+// or printed output, e.g. for the host to paint its own footer into rows it
+// keeps free. The prompt's drawing is on the terminal already, fn can write
+// to the terminal directly. The cursor goes back to the input afterwards.
+//
+// The callbacks run on the goroutine that renders with renderMutex held, so
+// they must be quick and must not call the prompt's methods. Those would
+// wait for the mutex forever.
+func WithPostRender(fn func()) Option {
+	return func(p *Prompt) error {
+		if fn == nil {
+			return errors.New("the post-render callback can't be nil")
+		}
+		p.postRender = fn
+		return nil
+	}
+}
+
+// runPreRender calls the pre-render callback. renderMutex must be held.
+func (p *Prompt) runPreRender() {
+	if p.preRender != nil {
+		p.preRender()
+	}
+}
+
+// runPostRender calls the post-render callback and moves the cursor
+// back to the input. renderMutex must be held.
+func (p *Prompt) runPostRender() {
+	if p.postRender == nil {
+		return
+	}
+	p.postRender()
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
@@ -0,0 +1,54 @@
+package prompt
+
+import (
+	"errors"
+	"strings"
+)
+
+// WithRepeatOnEmpty makes enter on an empty input run the last line again,
+// like the repeat-on-empty setting
+func WithRepeatOnEmpty() Option {
+	return func(p *Prompt) error {
+		p.repeatOnEmpty = true
+		return nil
+	}
+}
+
+// runLine executes a line the user typed or pasted and keeps it
+// for repeat-on-empty
+func (p *Prompt) runLine(s string) error {
+	err := p.Execute(s)
+	var nf *notFoundError
+	switch {
+	case errors.As(err, &nf):
+		// Neither an unknown command nor the line before it runs again
+		p.lastLine = ""
+	case strings.TrimSpace(s) != "":
+		p.lastLine = s
+	}
+	return err
+}
+
+// repeatLast runs the last line again if the input s is empty and the
+// repeat-on-empty setting is on. It returns false if it didn't.
+func (p *Prompt) repeatLast(s string) bool {
+	if s != "" || p.lastLine == "" {
+		return false
+	}
+	p.lockRender()
+	on := p.repeatOnEmpty
+	dim, endSeq := "\x1b[2m", "\x1b[22m"
+	if p.colorLevel == ColorNone {
+		dim, endSeq = "", ""
+	}
+	p.unlockRender()
+	if !on {
+		return false
+	}
+
+	// The line is in the history already
+	p.log.Debugf("Repeating the last line")
+	p.Writeln(dim + "↻ " + p.lastLine + endSeq + "\n")
+	p.runLine(p.lastLine)
+	return true
+}
@@ -0,0 +1,89 @@
+package prompt
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestRepeatChained presses enter on the empty input after lines that run
+// more commands: a command running others, a continued line and pasted
+// lines. The last line runs again as a whole and isn't added to the
+// history again, an unknown command anywhere in the chain stops it.
+func TestRepeatChained(t *testing.T) {
+	var p *Prompt
+	var ran []string
+	record := func(name string) *fakeCmd {
+		return &fakeCmd{name: name, run: func(args cmd.Args) error {
+			ran = append(ran, strings.Join(append([]string{name}, args...), " "))
+			return nil
+		}}
+	}
+	// ship chains build and deploy
+	ship := &fakeCmd{name: "ship", run: func(args cmd.Args) error {
+		for _, l := range []string{"build", "deploy " + strings.Join(args, " ")} {
+			if err := p.Execute(l); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+	broken := &fakeCmd{name: "broken", run: func(args cmd.Args) error {
+		return p.Execute("biuld")
+	}}
+	p, out := newTestPrompt(t, []cmd.Cmd{record("build"), record("deploy"), ship, broken}, "",
+		WithRepeatOnEmpty(), WithPasteMode(PasteQueue))
+	startPrompt(t, p)
+
+	// enter submits a line like go-prompt does and returns what ran
+	enter := func(lines ...string) []string {
+		t.Helper()
+		ran = nil
+		for _, l := range lines {
+			p.executor(l)
+		}
+		return ran
+	}
+	check := func(what string, got []string, want ...string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s ran %q, want %q", what, got, want)
+		}
+	}
+
+	check("enter after the start", enter(""))
+
+	check("ship", enter("ship api"), "build", "deploy api")
+	check("enter after ship", enter(""), "build", "deploy api")
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	if !strings.Contains(out.String(), "↻ ship api") {
+		t.Errorf("the repeated line wasn't shown: %q", out.String())
+	}
+
+	check("the continued line", enter(`deploy api \`, `--force`), "deploy api --force")
+	check("enter after the continued line", enter(""), "deploy api --force")
+
+	p.paste("build\ndeploy web\n")
+	check("enter after the paste", enter(""), "build", "deploy web")
+	check("enter after the pasted lines", enter(""), "deploy web")
+
+	p.paste("build\nbiuld\ndeploy web\n")
+	check("the paste with an unknown command", enter(""), "build")
+	check("enter after the unknown command", enter(""))
+
+	check("ship and broken", enter("ship api", "broken"), "build", "deploy api")
+	check("enter after the chained unknown command", enter(""))
+
+	p.history.mut.Lock()
+	history := p.history.lines
+	p.history.mut.Unlock()
+	want := []string{"ship api", "deploy api --force", "build", "deploy web", "build", "biuld", "ship api", "broken"}
+	if !reflect.DeepEqual(history, want) {
+		t.Errorf("the history is %q, want %q", history, want)
+	}
+}
@@ -0,0 +1,271 @@
+package prompt
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// castEvent is an event of a cast file after its header
+type castEvent struct {
+	time float64 // Seconds since the start
+	typ  string  // "o" for output, "r" for a resize, "i" for input
+	data string
+}
+
+// maxCastLine is the longest line of a cast file replay reads
+const maxCastLine = 16 * 1024 * 1024
+
+// readCast parses an asciinema v2 cast file
+func readCast(r io.Reader) (castHeader, []castEvent, error) {
+	var h castHeader
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, maxCastLine)
+	if !sc.Scan() {
+		if err := sc.Err(); err != nil {
+			return h, nil, err
+		}
+		return h, nil, errors.New("the file is empty")
+	}
+	if err := json.Unmarshal(sc.Bytes(), &h); err != nil || h.Version != 2 {
+		return h, nil, errors.New("not an asciinema v2 cast file")
+	}
+
+	var events []castEvent
+	for n := 2; sc.Scan(); n++ {
+		if len(strings.TrimSpace(sc.Text())) == 0 {
+			continue
+		}
+		var fields []interface{}
+		if err := json.Unmarshal(sc.Bytes(), &fields); err != nil || len(fields) < 3 {
+			return h, nil, fmt.Errorf("line %d isn't an event", n)
+		}
+		t, ok1 := fields[0].(float64)
+		typ, ok2 := fields[1].(string)
+		data, ok3 := fields[2].(string)
+		if !ok1 || !ok2 || !ok3 {
+			return h, nil, fmt.Errorf("line %d isn't an event", n)
+		}
+		events = append(events, castEvent{time: t, typ: typ, data: data})
+	}
+	return h, events, sc.Err()
+}
+
+// replayFilter keeps what of a recording makes sense in the output:
+// the text, the colors and the links. The cursor moves, the erases and
+// the titles of a full screen program are dropped, a move to a row
+// starts a new line instead.
+type replayFilter struct {
+	esc     escapeState
+	code    strings.Builder // The escape code so far
+	cr      bool            // A "\r" came last, "\r\n" is a single line break
+	midLine bool            // Text was written after the last line break
+}
+
+func (f *replayFilter) filter(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if f.esc != escNone || r == '\u001b' {
+			f.esc = f.esc.next(r)
+			if f.esc == escIntro {
+				f.code.Reset()
+			}
+			f.code.WriteRune(r)
+			if f.esc == escNone {
+				f.endCode(&b)
+			}
+			continue
+		}
+		if f.cr {
+			f.cr = false
+			if r != '\n' {
+				b.WriteByte('\r')
+			}
+		}
+		switch {
+		case r == '\r':
+			f.cr = true
+			continue
+		case r == '\n':
+			f.midLine = false
+		case r == '\t':
+			f.midLine = true
+		case unicode.IsControl(r):
+			continue
+		default:
+			f.midLine = true
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// endCode writes the escape code that just ended if it's kept
+func (f *replayFilter) endCode(b *strings.Builder) {
+	code := f.code.String()
+	switch {
+	case strings.HasPrefix(code, "\x1b]8;"):
+		b.WriteString(code)
+	case !strings.HasPrefix(code, "\x1b["):
+	case strings.HasSuffix(code, "m"):
+		b.WriteString(code)
+	case strings.HasSuffix(code, "H") || strings.HasSuffix(code, "f") || strings.HasSuffix(code, "d"):
+		b.WriteString(f.lineBreak())
+	}
+}
+
+// lineBreak returns the line break that ends the text written last
+func (f *replayFilter) lineBreak() string {
+	f.cr = false
+	if !f.midLine {
+		return ""
+	}
+	f.midLine = false
+	return "\n"
+}
+
+// replayPoll is how often a paused replay checks the keys
+const replayPoll = 100 * time.Millisecond
+
+// replay is a cast file being played by the replay command
+type replay struct {
+	p      *Prompt
+	path   string
+	speed  float64 // 0 plays as fast as possible
+	poll   func(d time.Duration) (Key, bool, error)
+	paused bool
+}
+
+// wait waits d, or longer if space pauses the replay. It returns false
+// once q or Ctrl+C stops it.
+func (r *replay) wait(d time.Duration) (bool, error) {
+	deadline := time.Now().Add(d)
+	var left time.Duration // What's left of d while the replay is paused
+	for {
+		wait := replayPoll
+		if !r.paused {
+			if wait = time.Until(deadline); wait < 0 {
+				wait = 0
+			}
+		}
+		k, ok, err := r.poll(wait)
+		if err != nil {
+			return false, err
+		}
+		select {
+		case <-r.p.stopCh:
+			return false, nil
+		default:
+		}
+		switch {
+		case ok && (k.Key == goprompt.ControlC || k.Rune == 'q'):
+			return false, nil
+		case ok && k.Rune == ' ':
+			r.paused = !r.paused
+			if r.paused {
+				left = time.Until(deadline)
+				r.p.SetInfoln(fmt.Sprintf("Replay of %s paused, space continues, q stops", r.path), InfoLineSeverityNormal)
+			} else {
+				deadline = time.Now().Add(left)
+				r.p.SetInfoln(fmt.Sprintf("Replaying %s, space pauses, q stops", r.path), InfoLineSeverityNormal)
+			}
+		case !ok && !r.paused:
+			return true, nil
+		}
+	}
+}
+
+// play writes the output events with their timing. It returns false
+// if the replay was stopped.
+func (r *replay) play(h castHeader, events []castEvent) (bool, error) {
+	r.p.lockRender()
+	dim, endSeq := "\x1b[2m", "\x1b[22m"
+	if r.p.colorLevel == ColorNone {
+		dim, endSeq = "", ""
+	}
+	r.p.unlockRender()
+
+	var f replayFilter
+	prev := 0.0
+	for _, e := range events {
+		pause := e.time - prev
+		prev = e.time
+		if h.IdleTimeLimit > 0 && pause > h.IdleTimeLimit {
+			pause = h.IdleTimeLimit
+		}
+		var d time.Duration
+		if r.speed > 0 && pause > 0 {
+			d = time.Duration(pause / r.speed * float64(time.Second))
+		}
+		if ok, err := r.wait(d); !ok || err != nil {
+			r.p.Writeln(f.lineBreak() + "\x1b[0m")
+			return false, err
+		}
+
+		switch e.typ {
+		case "o":
+			r.p.Writeln(f.filter(e.data))
+		case "r":
+			// The user's terminal keeps its size
+			r.p.Writeln(f.lineBreak() + dim + "── resized to " + e.data + " ──" + endSeq + "\n")
+		}
+	}
+	// The colors of the recording don't stay for the output after it
+	r.p.Writeln(f.lineBreak() + "\x1b[0m")
+	return true, nil
+}
+
+func runReplay(p *Prompt, args []string) error {
+	usage := errors.New("usage: replay FILE [--speed N|max]")
+	if len(args) != 1 && (len(args) != 3 || args[1] != "--speed") {
+		return usage
+	}
+	r := &replay{p: p, path: args[0], speed: 1}
+	if len(args) == 3 {
+		if args[2] == "max" {
+			r.speed = 0
+		} else if s, err := strconv.ParseFloat(args[2], 64); err == nil && s > 0 {
+			r.speed = s
+		} else {
+			return usage
+		}
+	}
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("replay failed: %w", err)
+	}
+	h, events, err := readCast(f)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("replay of %s failed: %w", r.path, err)
+	}
+
+	p.logWith("path", r.path, "events", len(events), "speed", r.speed).Debugf("Replaying")
+	p.SetInfoln(fmt.Sprintf("Replaying %s, space pauses, q stops", r.path), InfoLineSeverityNormal)
+	done := false
+	err = p.pollKeys(func(poll func(d time.Duration) (Key, bool, error)) error {
+		r.poll = poll
+		var err error
+		done, err = r.play(h, events)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("replay of %s failed: %w", r.path, err)
+	}
+	if done {
+		p.SetInfoln(fmt.Sprintf("Replay of %s done", r.path), InfoLineSeverityNormal)
+	} else {
+		p.SetInfoln(fmt.Sprintf("Replay of %s stopped", r.path), InfoLineSeverityNormal)
+	}
+	return nil
+}
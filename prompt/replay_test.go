@@ -0,0 +1,22 @@
+package prompt
+
+import (
+	"context"
+	"testing"
+)
+
+// TestReplayGolden replays testdata/replay.cast as fast as possible and
+// compares the screen with the golden file. The recording moves the
+// cursor, cuts a color code between two events, overwrites a line with
+// "\r", resizes and has an input event that isn't shown.
+func TestReplayGolden(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "replay testdata/replay.cast --speed max\n", WithScreenSnapshot())
+	startPrompt(t, p)
+	waitFor(t, "the end of the replay", func() bool {
+		return infoText(p) == "Replay of testdata/replay.cast done"
+	})
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	checkGolden(t, "replay.golden", p.Snapshot()+"\n")
+}
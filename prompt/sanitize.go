@@ -0,0 +1,33 @@
+package prompt
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// sanitizeLine removes what a line can't mean on purpose: the control
+// characters, e.g. a \r pasted from a file with Windows line endings,
+// and the whitespace at its end. Tabs separate the words like spaces.
+// The pasted line breaks stay, they aren't control characters.
+func sanitizeLine(s string) string {
+	clean := strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case unicode.IsControl(r):
+			return -1
+		}
+		return r
+	}, s)
+	return strings.TrimRightFunc(clean, unicode.IsSpace)
+}
+
+// sanitize returns the sanitized line and logs the raw one if it changed
+func (p *Prompt) sanitize(s string) string {
+	clean := sanitizeLine(s)
+	if clean != s {
+		p.logWith("raw", strconv.Quote(s), "sanitized", strconv.Quote(clean)).Debugf("Input line sanitized")
+	}
+	return clean
+}
@@ -0,0 +1,286 @@
+package prompt
+
+import (
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// WithScreenSnapshot keeps a model of the characters on the visible
+// terminal grid as the prompt renders, so Snapshot can return it.
+// Colors aren't tracked.
+func WithScreenSnapshot() Option {
+	return func(p *Prompt) error {
+		p.screen = &screen{}
+		return nil
+	}
+}
+
+// Snapshot returns the rows of the visible terminal grid as the prompt
+// rendered them, without trailing spaces. It's empty unless the prompt
+// was created with WithScreenSnapshot. Text that go-prompt itself
+// renders on the prompt row isn't included.
+func (p *Prompt) Snapshot() string {
+	if p.screen == nil {
+		return ""
+	}
+	return p.screen.String()
+}
+
+// screen is a minimal model of a terminal grid. Rows and columns are indexed from 1.
+type screen struct {
+	mut        sync.Mutex
+	rows, cols int
+	cells      [][]rune
+	row, col   int
+	saved      CursorPos
+	esc        escapeState
+}
+
+func (s *screen) resize(rows, cols int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.rows, s.cols = rows, cols
+	s.cells = make([][]rune, rows)
+	for i := range s.cells {
+		s.cells[i] = blankRow(cols)
+	}
+	s.row, s.col = 1, 1
+}
+
+func blankRow(cols int) []rune {
+	r := make([]rune, cols)
+	for i := range r {
+		r[i] = ' '
+	}
+	return r
+}
+
+func (s *screen) String() string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	lines := make([]string, len(s.cells))
+	for i, r := range s.cells {
+		lines[i] = strings.TrimRight(string(r), " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// clamp keeps the cursor inside the grid. s.mut must be held.
+func (s *screen) clamp() {
+	if s.row < 1 {
+		s.row = 1
+	}
+	if s.row > s.rows {
+		s.row = s.rows
+	}
+	if s.col < 1 {
+		s.col = 1
+	}
+	// The cursor can be one column past the last one until the next character wraps it
+	if s.col > s.cols+1 {
+		s.col = s.cols + 1
+	}
+}
+
+// lineFeed moves the cursor down and scrolls the grid at its bottom. s.mut must be held.
+func (s *screen) lineFeed() {
+	s.row++
+	if s.row > s.rows {
+		s.scrollUp()
+		s.row = s.rows
+	}
+}
+
+// scrollUp moves every row of the grid up by one. s.mut must be held.
+func (s *screen) scrollUp() {
+	if s.rows == 0 {
+		return
+	}
+	copy(s.cells, s.cells[1:])
+	s.cells[s.rows-1] = blankRow(s.cols)
+}
+
+func (s *screen) write(str string) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.rows == 0 || s.cols == 0 {
+		return
+	}
+
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		i += size
+
+		if s.esc != escNone || r == '\u001b' {
+			s.esc = s.esc.next(r)
+			continue
+		}
+
+		switch {
+		case r == '\n':
+			// The terminal translates a new line to "\r\n"
+			s.col = 1
+			s.lineFeed()
+		case r == '\r':
+			s.col = 1
+		case r < ' ':
+			// Other control characters don't take space
+		default:
+			if s.col > s.cols {
+				s.col = 1
+				s.lineFeed()
+			}
+			s.cells[s.row-1][s.col-1] = r
+			s.col++
+		}
+	}
+}
+
+// erase replaces columns from-to (including) of a row with spaces. s.mut must be held.
+func (s *screen) erase(row, from, to int) {
+	if row < 1 || row > s.rows {
+		return
+	}
+	if from < 1 {
+		from = 1
+	}
+	if to > s.cols {
+		to = s.cols
+	}
+	for c := from; c <= to; c++ {
+		s.cells[row-1][c-1] = ' '
+	}
+}
+
+// screenWriter updates the screen model as it passes everything to the real writer
+type screenWriter struct {
+	goprompt.ConsoleWriter
+	s *screen
+}
+
+func (w *screenWriter) WriteRaw(data []byte) {
+	w.ConsoleWriter.WriteRaw(data)
+	w.s.write(string(data))
+}
+
+func (w *screenWriter) Write(data []byte) {
+	w.ConsoleWriter.Write(data)
+	w.s.write(string(data))
+}
+
+func (w *screenWriter) WriteRawStr(data string) {
+	w.ConsoleWriter.WriteRawStr(data)
+	w.s.write(data)
+}
+
+func (w *screenWriter) WriteStr(data string) {
+	w.ConsoleWriter.WriteStr(data)
+	w.s.write(data)
+}
+
+func (w *screenWriter) EraseScreen() {
+	w.ConsoleWriter.EraseScreen()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	for r := 1; r <= w.s.rows; r++ {
+		w.s.erase(r, 1, w.s.cols)
+	}
+}
+
+func (w *screenWriter) EraseUp() {
+	w.ConsoleWriter.EraseUp()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	for r := 1; r < w.s.row; r++ {
+		w.s.erase(r, 1, w.s.cols)
+	}
+	w.s.erase(w.s.row, 1, w.s.col)
+}
+
+func (w *screenWriter) EraseDown() {
+	w.ConsoleWriter.EraseDown()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.erase(w.s.row, w.s.col, w.s.cols)
+	for r := w.s.row + 1; r <= w.s.rows; r++ {
+		w.s.erase(r, 1, w.s.cols)
+	}
+}
+
+func (w *screenWriter) EraseStartOfLine() {
+	w.ConsoleWriter.EraseStartOfLine()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.erase(w.s.row, 1, w.s.col)
+}
+
+func (w *screenWriter) EraseEndOfLine() {
+	w.ConsoleWriter.EraseEndOfLine()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.erase(w.s.row, w.s.col, w.s.cols)
+}
+
+func (w *screenWriter) EraseLine() {
+	w.ConsoleWriter.EraseLine()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.erase(w.s.row, 1, w.s.cols)
+}
+
+func (w *screenWriter) CursorGoTo(row, col int) {
+	w.ConsoleWriter.CursorGoTo(row, col)
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.row, w.s.col = row, col
+	w.s.clamp()
+}
+
+func (w *screenWriter) moveCursor(rows, cols int) {
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.row += rows
+	w.s.col += cols
+	w.s.clamp()
+}
+
+func (w *screenWriter) CursorUp(n int) {
+	w.ConsoleWriter.CursorUp(n)
+	w.moveCursor(-n, 0)
+}
+
+func (w *screenWriter) CursorDown(n int) {
+	w.ConsoleWriter.CursorDown(n)
+	w.moveCursor(n, 0)
+}
+
+func (w *screenWriter) CursorForward(n int) {
+	w.ConsoleWriter.CursorForward(n)
+	w.moveCursor(0, n)
+}
+
+func (w *screenWriter) CursorBackward(n int) {
+	w.ConsoleWriter.CursorBackward(n)
+	w.moveCursor(0, -n)
+}
+
+func (w *screenWriter) SaveCursor() {
+	w.ConsoleWriter.SaveCursor()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.saved = CursorPos{w.s.row, w.s.col}
+}
+
+func (w *screenWriter) UnSaveCursor() {
+	w.ConsoleWriter.UnSaveCursor()
+	w.s.mut.Lock()
+	defer w.s.mut.Unlock()
+	w.s.row, w.s.col = w.s.saved.Row, w.s.saved.Col
+	w.s.clamp()
+}
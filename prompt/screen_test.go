@@ -0,0 +1,59 @@
+package prompt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// TestSnapshotGolden renders output into a 24x80 prompt and compares the
+// screen with the golden files
+func TestSnapshotGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(p *Prompt)
+	}{
+		{"empty", func(*Prompt) {}},
+		{"output", func(p *Prompt) {
+			p.Writeln("plain line\n")
+			p.Writeln("\x1b[31mred\x1b[0m and \x1b[1;32mbold green\x1b[0m\n")
+			// The error would skip the lines still queued
+			p.Drain(context.Background())
+			p.ErrWriteln("something failed\n")
+			p.SetInfoln("3 functions deployed", InfoLineSeverityNormal)
+		}},
+		{"wrapped", func(p *Prompt) {
+			p.Writeln(strings.Repeat("0123456789", 20) + "\n")
+			p.Writeln("a progress bar 50%\ra progress bar 100%\n")
+		}},
+		{"scrolled", func(p *Prompt) {
+			for i := 1; i <= 40; i++ {
+				p.Writeln(fmt.Sprintf("line %d\n", i))
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "", WithScreenSnapshot(), WithColorLevel(ColorNone))
+			startPrompt(t, p)
+			tt.write(p)
+			if err := p.Drain(context.Background()); err != nil {
+				t.Fatalf("Drain: %s", err)
+			}
+			checkGolden(t, "snapshot_"+tt.name+".golden", p.Snapshot()+"\n")
+		})
+	}
+}
+
+func TestSnapshotWithoutOption(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "")
+	startPrompt(t, p)
+	p.Writeln("plain line\n")
+	if err := p.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %s", err)
+	}
+	if s := p.Snapshot(); s != "" {
+		t.Fatalf("Snapshot = %q without WithScreenSnapshot", s)
+	}
+}
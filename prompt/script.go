@@ -0,0 +1,106 @@
+package prompt
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func init() {
+	// runReload goes through the executor, which looks up builtinCmds,
+	// so it can't be a part of the builtinCmds initializer
+	builtinCmds = append(builtinCmds, &builtinCmd{
+		name: "reload",
+		desc: "Run the init script again",
+		run:  runReload,
+	})
+}
+
+// WithInitScript runs every line of the file at path through the executor
+// when the prompt starts, as if the user typed it. Empty lines and lines
+// starting with '#' are skipped. A missing file is ignored on start, the
+// reload command runs the file again.
+func WithInitScript(path string) Option {
+	return func(p *Prompt) error {
+		p.initScript = path
+		return nil
+	}
+}
+
+// scriptResult is what happened when a script was run
+type scriptResult struct {
+	ran    int
+	failed int
+}
+
+// runScript executes the lines of the file at path
+func (p *Prompt) runScript(path string) (res scriptResult, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return res, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if fields := strings.Fields(line); fields[0] == "reload" {
+			// The script would run itself forever
+			p.Writeln(fmt.Sprintf("%s:%d: skipping 'reload' in the init script", path, n))
+			continue
+		}
+
+		res.ran++
+		if err := p.Execute(line); err != nil {
+			res.failed++
+			p.Writeln(fmt.Sprintf("%s:%d: %s", path, n, err))
+		}
+	}
+	return res, scanner.Err()
+}
+
+func (p *Prompt) runInitScript() {
+	if p.initScript == "" {
+		return
+	}
+
+	res, err := p.runScript(p.initScript)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		p.logWith("path", p.initScript, "err", err).Errorf("Init script failed")
+		p.SetInfoln(fmt.Sprintf("Error running the init script: %s", err), InfoLineSeverityError)
+		return
+	}
+	p.logWith("path", p.initScript, "ran", res.ran, "failed", res.failed).Debugf("Init script finished")
+	if res.failed > 0 {
+		p.SetInfoln(fmt.Sprintf("%d of %d lines of the init script failed", res.failed, res.ran), InfoLineSeverityError)
+	}
+}
+
+func runReload(p *Prompt, args []string) error {
+	if p.initScript == "" {
+		return errors.New("no init script is configured")
+	}
+
+	res, err := p.runScript(p.initScript)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("init script '%s' doesn't exist anymore", p.initScript)
+	}
+	if err != nil {
+		return fmt.Errorf("error reloading the init script: %w", err)
+	}
+
+	if res.failed > 0 {
+		p.SetInfoln(fmt.Sprintf("Reloaded '%s': %d lines ran, %d failed", p.initScript, res.ran, res.failed), InfoLineSeverityError)
+	} else {
+		p.SetInfoln(fmt.Sprintf("Reloaded '%s': %d lines ran", p.initScript, res.ran), InfoLineSeverityNormal)
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package prompt
+
+import "fmt"
+
+// WithScrollMargin keeps n blank rows between the output and the info row.
+// The output scrolls once it reaches the margin, so the lines printed last
+// aren't right next to the prompt even when the output streams quickly.
+// On a terminal too small for the margin it shrinks so that at least one
+// row is left for the output.
+func WithScrollMargin(n int) Option {
+	return func(p *Prompt) error {
+		if n < 0 {
+			return fmt.Errorf("scroll margin can't be negative, got %d", n)
+		}
+		p.scrollMargin = n
+		return nil
+	}
+}
+
+// scrollThreshold returns the number of free rows at which the output
+// scrolls. renderMutex must be held.
+func (p *Prompt) scrollThreshold() int {
+	// One row is left for the output and one for the cursor below it
+	margin := p.scrollMargin
+	if max := p.totalRows - p.reservedRows() - p.outputStart().Row - 1; margin > max {
+		margin = max
+	}
+	if margin < 0 {
+		margin = 0
+	}
+	return p.reservedRows() + margin
+}
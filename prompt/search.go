@@ -0,0 +1,177 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// searchState is the reverse search of the history started by Ctrl+R. The
+// matching line is the input while it runs, enter runs it and escape brings
+// back what was typed before. Any other change of the input or a move of the
+// cursor keeps the match and ends the search. Guarded by renderMutex.
+type searchState struct {
+	active bool
+	term   string
+	failed bool   // No older line contains the term, the last match stays
+	match  int    // Index of the matching history line
+	shown  string // The input the search made
+	saved  string // The input before the search
+}
+
+// The prefixes of the search like bash's, %s is the term
+const (
+	defaultSearchFormat       = "(reverse-i-search)'%s': "
+	defaultFailedSearchFormat = "(failed reverse-i-search)'%s': "
+)
+
+// WithSearchPromptFormat sets what's in front of the match while Ctrl+R
+// searches the history, format while there's a match and failed once no
+// older line contains the term. Both need a single %s that's replaced by
+// the term, e.g. "(recherche)'%s': ". The cursor keeps to the display width.
+func WithSearchPromptFormat(format, failed string) Option {
+	return func(p *Prompt) error {
+		for _, f := range []string{format, failed} {
+			if strings.ContainsAny(f, "\r\n") {
+				return fmt.Errorf("the search prompt %q can't span more lines", f)
+			}
+			if strings.Count(f, "%s") != 1 || strings.Count(f, "%") != 1 {
+				return fmt.Errorf("the search prompt %q needs a single %%s for the term and no other verbs", f)
+			}
+		}
+		p.searchFormat = [2]string{format, failed}
+		return nil
+	}
+}
+
+// prefix returns what's in front of the match instead of the prefix
+func (s *searchState) prefix(formats [2]string) string {
+	if s.failed {
+		return fmt.Sprintf(formats[1], s.term)
+	}
+	return fmt.Sprintf(formats[0], s.term)
+}
+
+// searchOptions returns the go-prompt key binds of the search
+func (p *Prompt) searchOptions() []goprompt.Option {
+	backspace := func(buf *goprompt.Buffer) {
+		p.searchBackspace(buf)
+	}
+	return []goprompt.Option{goprompt.OptionAddKeyBind(
+		goprompt.KeyBind{Key: goprompt.ControlR, Fn: func(buf *goprompt.Buffer) {
+			p.searchOlder(buf)
+		}},
+		// The character before the cursor is deleted from the match first
+		goprompt.KeyBind{Key: goprompt.Backspace, Fn: backspace},
+		goprompt.KeyBind{Key: goprompt.ControlH, Fn: backspace},
+	)}
+}
+
+// searchOlder starts the search or goes to an older match of the term
+func (p *Prompt) searchOlder(buf *goprompt.Buffer) {
+	p.lockRender()
+	defer p.unlockRender()
+	s := &p.search
+	if !s.active {
+		text := buf.Text()
+		*s = searchState{active: true, match: p.history.size(), shown: text, saved: text}
+		atomic.StoreInt32(&p.searching, 1)
+		setInput(buf, text)
+		p.log.Debugf("History search started")
+		return
+	}
+	// The same line again isn't an older match
+	p.findMatch(buf, s.match, s.shown)
+}
+
+// searchKey adds r to the term if the search runs. It returns false if it doesn't.
+func (p *Prompt) searchKey(buf *goprompt.Buffer, r rune) bool {
+	p.lockRender()
+	defer p.unlockRender()
+	if !p.search.active {
+		return false
+	}
+	p.search.term += string(r)
+	// The match can contain the longer term too
+	p.findMatch(buf, p.search.match+1, "")
+	return true
+}
+
+// searchBackspace removes the last character of the term
+func (p *Prompt) searchBackspace(buf *goprompt.Buffer) {
+	p.lockRender()
+	defer p.unlockRender()
+	s := &p.search
+	if !s.active {
+		return
+	}
+	if term := []rune(s.term); len(term) > 0 {
+		s.term = string(term[:len(term)-1])
+	}
+	if s.term == "" {
+		s.failed = false
+		setInput(buf, s.shown)
+		return
+	}
+	p.findMatch(buf, s.match+1, "")
+}
+
+// findMatch makes the newest line before the index before that contains
+// the term and isn't skip the input. renderMutex must be held.
+func (p *Prompt) findMatch(buf *goprompt.Buffer, before int, skip string) {
+	s := &p.search
+	i, line := p.history.find(s.term, skip, before)
+	if i < 0 {
+		s.failed = true
+		setInput(buf, s.shown)
+		return
+	}
+	s.failed = false
+	s.match = i
+	s.shown = line
+	setInput(buf, line)
+}
+
+// cancelSearch ends the search and brings back the input from before it.
+// It returns false if the search doesn't run.
+func (p *Prompt) cancelSearch(buf *goprompt.Buffer) bool {
+	p.lockRender()
+	defer p.unlockRender()
+	if !p.search.active {
+		return false
+	}
+	setInput(buf, p.search.saved)
+	p.search = searchState{}
+	atomic.StoreInt32(&p.searching, 0)
+	p.log.Debugf("History search cancelled")
+	return true
+}
+
+// endSearch ends the search, the input stays. It returns false if the
+// search doesn't run. renderMutex must be held.
+func (p *Prompt) endSearch() bool {
+	if !p.search.active {
+		return false
+	}
+	p.search = searchState{}
+	atomic.StoreInt32(&p.searching, 0)
+	return true
+}
+
+// checkSearch ends the search if the input isn't the
+// search's anymore. renderMutex must be held.
+func (p *Prompt) checkSearch(d goprompt.Document) {
+	if p.search.active && (d.Text != p.search.shown || d.TextAfterCursor() != "") {
+		p.endSearch()
+		p.log.Debugf("History search ended by editing the match")
+	}
+}
+
+// setInput replaces the input by s, the cursor is at its end
+func setInput(buf *goprompt.Buffer, s string) {
+	buf.CursorRight(len([]rune(buf.Document().TextAfterCursor())))
+	buf.DeleteBeforeCursor(len([]rune(buf.Text())))
+	buf.InsertText(s, false, true)
+}
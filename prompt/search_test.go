@@ -0,0 +1,84 @@
+package prompt
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"foundry/cli/prompt/cmd"
+)
+
+// TestSearchHistory searches a history file with Ctrl+R in go-prompt's loop.
+// Ctrl+R again goes to older matches, backspace shortens the term, escape
+// brings back the typed text and enter runs the match.
+func TestSearchHistory(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	lines := "deploy api --force\nlogs api\ndeploy web\nls\n"
+	if err := ioutil.WriteFile(history, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ran := make(chan string, 1)
+	logs := &fakeCmd{name: "logs", run: func(args cmd.Args) error {
+		ran <- "logs " + strings.Join(args, " ")
+		return nil
+	}}
+	p, parser, _, _ := newTTYPrompt(t, []cmd.Cmd{logs}, WithHistoryFile(history))
+	go p.Run()
+	<-p.Ready()
+
+	// searching waits for the input and the prefix of the search
+	searching := func(what, input, prefix string) {
+		t.Helper()
+		waitFor(t, what, func() bool {
+			p.lockRender()
+			current := p.currentPrefix()
+			p.unlockRender()
+			return p.input() == input && current == prefix
+		})
+	}
+
+	parser.keys <- []byte("dep")
+	parser.keys <- []byte{0x12}
+	searching("the search to start", "dep", "(reverse-i-search)'': ")
+
+	parser.keys <- []byte("d")
+	parser.keys <- []byte("e")
+	searching("the newest match", "deploy web", "(reverse-i-search)'de': ")
+	parser.keys <- []byte{0x12}
+	searching("the older match", "deploy api --force", "(reverse-i-search)'de': ")
+	parser.keys <- []byte{0x12}
+	searching("no older match", "deploy api --force", "(failed reverse-i-search)'de': ")
+	parser.keys <- []byte("x")
+	searching("a term without a match", "deploy api --force", "(failed reverse-i-search)'dex': ")
+	parser.keys <- []byte{0x7f}
+	searching("the shorter term", "deploy api --force", "(reverse-i-search)'de': ")
+
+	parser.keys <- []byte{0x1b}
+	searching("the typed text", "dep", "> ")
+
+	// Typing ends the search, the match stays
+	parser.keys <- []byte{0x12}
+	parser.keys <- []byte("a")
+	parser.keys <- []byte("p")
+	parser.keys <- []byte("i")
+	searching("the match of api", "logs api", "(reverse-i-search)'api': ")
+	parser.keys <- []byte{0x12}
+	searching("the older match of api", "deploy api --force", "(reverse-i-search)'api': ")
+	parser.keys <- []byte{0x01}
+	parser.keys <- []byte("#")
+	searching("the edited match", "#deploy api --force", "> ")
+	parser.keys <- []byte{0x01}
+	parser.keys <- []byte{0x0b}
+	waitFor(t, "the cleared input", func() bool { return p.input() == "" })
+
+	parser.keys <- []byte{0x12}
+	parser.keys <- []byte("o")
+	parser.keys <- []byte("g")
+	searching("the match of og", "logs api", "(reverse-i-search)'og': ")
+	parser.keys <- []byte("\r")
+	if line := <-ran; line != "logs api" {
+		t.Fatalf("enter ran %q", line)
+	}
+	searching("the end of the search", "", "> ")
+}
@@ -0,0 +1,193 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// Select shows title and options below the output and lets the user
+// choose one with the arrows and enter. It returns the index of the chosen
+// option or -1 when the user presses escape, q or Ctrl+C. A list taller
+// than the output area scrolls. The list is erased once it's done and the
+// output continues where it was. Like ReadKey it can only be called from
+// a command while it runs, other output waits until the list is done.
+func (p *Prompt) Select(title string, options []string) (int, error) {
+	if len(options) == 0 {
+		return -1, errors.New("there are no options to select from")
+	}
+
+	p.consumeMutex.Lock()
+	defer p.consumeMutex.Unlock()
+	if err := p.drainLocked(context.Background()); err != nil {
+		return -1, err
+	}
+
+	p.lockRender()
+	// The title and the options
+	h := len(options) + 1
+	if max := p.pagerHeight(); h > max {
+		h = max
+	}
+	col := p.currentPos.Col
+	p.unlockRender()
+	if h < 2 {
+		return -1, errors.New("the terminal is too small for the list")
+	}
+
+	// The new lines make room for the list at the end of the output
+	p.print(Chunk{Data: []byte(strings.Repeat("\n", h)), Priority: true})
+
+	p.lockRender()
+	l := &selectList{
+		title:   title,
+		options: options,
+		start:   p.currentPos.Row - h + 1,
+		rows:    h - 1,
+		// Where the output stopped before the new lines, it moved up if the output scrolled
+		restore: CursorPos{Row: p.currentPos.Row - h, Col: col},
+	}
+	p.renderSelect(l)
+	p.unlockRender()
+
+	chosen := -1
+	err := p.readKeys(func(read func() (Key, error)) error {
+		for {
+			k, err := read()
+			if err != nil {
+				return err
+			}
+			cur, done := l.key(k)
+			if done {
+				chosen = cur
+				return nil
+			}
+			p.lockRender()
+			p.renderSelect(l)
+			p.unlockRender()
+		}
+	})
+
+	p.lockRender()
+	p.closeSelect(l)
+	p.unlockRender()
+	if err != nil {
+		return -1, err
+	}
+	p.logWith("title", title, "chosen", chosen).Debugf("Selected from a list")
+	return chosen, nil
+}
+
+// selectList is the state of the list shown by Select
+type selectList struct {
+	title   string
+	options []string
+	start   int // Row of the title
+	rows    int // Number of rows for the options
+	top     int // Index of the first visible option
+	cur     int // Index of the highlighted option
+	restore CursorPos
+}
+
+// key moves the highlight. It returns the chosen option
+// and true once the list is done.
+func (l *selectList) key(k Key) (int, bool) {
+	switch k.Key {
+	case goprompt.Enter:
+		return l.cur, true
+	case goprompt.Escape, goprompt.ControlC:
+		return -1, true
+	case goprompt.Up, goprompt.ControlP:
+		l.cur--
+	case goprompt.Down, goprompt.ControlN, goprompt.Tab:
+		l.cur++
+	case goprompt.PageUp:
+		l.cur -= l.rows
+	case goprompt.PageDown:
+		l.cur += l.rows
+	case goprompt.Home:
+		l.cur = 0
+	case goprompt.End:
+		l.cur = len(l.options) - 1
+	case goprompt.NotDefined:
+		switch k.Rune {
+		case 'k':
+			l.cur--
+		case 'j':
+			l.cur++
+		case 'q':
+			return -1, true
+		}
+	}
+
+	if l.cur < 0 {
+		l.cur = 0
+	}
+	if l.cur >= len(l.options) {
+		l.cur = len(l.options) - 1
+	}
+	// The highlighted option is always visible
+	if l.cur < l.top {
+		l.top = l.cur
+	}
+	if l.cur >= l.top+l.rows {
+		l.top = l.cur - l.rows + 1
+	}
+	return l.cur, false
+}
+
+// renderSelect draws the list. renderMutex must be held.
+func (p *Prompt) renderSelect(l *selectList) {
+	title := l.title
+	if len(l.options) > l.rows {
+		title = fmt.Sprintf("%s (%d/%d)", title, l.cur+1, len(l.options))
+	}
+	p.writer.CursorGoTo(l.start, 1)
+	p.writer.EraseLine()
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, true)
+	p.writer.WriteRawStr(clip(title, p.totalColumns))
+	p.writer.SetColor(goprompt.DefaultColor, goprompt.DefaultColor, false)
+
+	for r := 0; r < l.rows; r++ {
+		p.writer.CursorGoTo(l.start+1+r, 1)
+		p.writer.EraseLine()
+		i := l.top + r
+		if i >= len(l.options) {
+			continue
+		}
+		if i == l.cur {
+			line := clip("> "+l.options[i], p.totalColumns)
+			if p.colorLevel != ColorNone {
+				// Reversed colors
+				line = "\x1b[7m" + line + "\x1b[0m"
+			}
+			p.writer.WriteRawStr(line)
+		} else {
+			p.writer.WriteRawStr(clip("  "+l.options[i], p.totalColumns))
+		}
+	}
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
+
+// closeSelect erases the list and the output continues where it was
+// before the list. renderMutex must be held.
+func (p *Prompt) closeSelect(l *selectList) {
+	for r := l.start; r <= l.start+l.rows; r++ {
+		p.writer.CursorGoTo(r, 1)
+		p.writer.EraseLine()
+	}
+	p.currentPos = l.restore
+	p.savedPos = l.restore
+	p.freeRows = p.totalRows - (p.currentPos.Row - 1)
+
+	p.writer.CursorGoTo(p.promptRow, p.promptCursorCol())
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
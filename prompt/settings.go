@@ -0,0 +1,260 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// namedColor is a color the set command accepts. The info row is colored by
+// go-prompt's writer and the output streams by an escape code.
+type namedColor struct {
+	color goprompt.Color
+	code  string
+}
+
+var namedColors = map[string]namedColor{
+	"default": {goprompt.DefaultColor, "\x1b[39m"},
+	"black":   {goprompt.Black, "\x1b[30m"},
+	"red":     {goprompt.Red, "\x1b[31m"},
+	"green":   {goprompt.Green, "\x1b[32m"},
+	"yellow":  {goprompt.Yellow, "\x1b[33m"},
+	"blue":    {goprompt.Blue, "\x1b[34m"},
+	"magenta": {goprompt.Fuchsia, "\x1b[35m"},
+	"cyan":    {goprompt.Turquoise, "\x1b[36m"},
+	"white":   {goprompt.White, "\x1b[37m"},
+}
+
+// setting is a part of the prompt's configuration that can be changed
+// while it runs. Both functions are called with renderMutex held.
+type setting struct {
+	name  string
+	usage string
+	get   func(p *Prompt) string
+	set   func(p *Prompt, args []string) error
+}
+
+var settings = []*setting{
+	{
+		name:  "prefix",
+		usage: `set prefix TEXT, quote it to keep the spaces, e.g. set prefix "foo$ "`,
+		get:   func(p *Prompt) string { return strconv.Quote(p.promptPrefix) },
+		set:   setPrefix,
+	},
+	{
+		name:  "color",
+		usage: "set color info|prefix|stderr " + strings.Join(colorNames(), "|"),
+		get:   getColors,
+		set:   setColor,
+	},
+	{
+		name:  "pager",
+		usage: "set pager ROWS|off",
+		get: func(p *Prompt) string {
+			if p.pagerThreshold == 0 {
+				return "off"
+			}
+			return strconv.Itoa(p.pagerThreshold)
+		},
+		set: setPager,
+	},
+	{
+		name:  "editmode",
+		usage: "set editmode emacs|vi",
+		get:   func(p *Prompt) string { return p.editMode.String() },
+		set:   setEditMode,
+	},
+	{
+		name:  "repeat-on-empty",
+		usage: "set repeat-on-empty on|off",
+		get: func(p *Prompt) string {
+			if p.repeatOnEmpty {
+				return "on"
+			}
+			return "off"
+		},
+		set: setRepeatOnEmpty,
+	},
+}
+
+func getSetting(name string) *setting {
+	for _, s := range settings {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func colorNames() []string {
+	names := make([]string, 0, len(namedColors))
+	for n := range namedColors {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runSet changes a setting or lists them all without arguments
+func runSet(p *Prompt, args []string) error {
+	if len(args) == 0 {
+		width := 0
+		for _, s := range settings {
+			if len(s.name) > width {
+				width = len(s.name)
+			}
+		}
+		p.lockRender()
+		lines := make([]string, len(settings))
+		for i, s := range settings {
+			lines[i] = fmt.Sprintf("%-*s %s", width, s.name, s.get(p))
+		}
+		p.unlockRender()
+		_, err := p.WriteLines(lines)
+		return err
+	}
+
+	s := getSetting(args[0])
+	if s == nil {
+		names := make([]string, len(settings))
+		for i, s := range settings {
+			names[i] = s.name
+		}
+		return fmt.Errorf("unknown setting '%s', the settings are %s", args[0], strings.Join(names, ", "))
+	}
+
+	p.lockRender()
+	defer p.unlockRender()
+	if err := s.set(p, args[1:]); err != nil {
+		return fmt.Errorf("%s, usage: %s", err, s.usage)
+	}
+	value := s.get(p)
+	p.logWith("setting", s.name, "value", value).Debugf("Setting changed")
+	p.infoText = fmt.Sprintf("Set %s to %s", s.name, value)
+	p.repaint()
+	return nil
+}
+
+func setPrefix(p *Prompt, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing the prefix")
+	}
+	// The line was split on spaces, the quotes keep the ones around the prefix
+	prefix := strings.Join(args, " ")
+	if strings.HasPrefix(prefix, `"`) {
+		var err error
+		if prefix, err = strconv.Unquote(prefix); err != nil {
+			return fmt.Errorf("bad quoting of the prefix")
+		}
+	}
+	p.promptPrefix = prefix
+	return nil
+}
+
+func getColors(p *Prompt) string {
+	info, prefix, stderr := "?", "?", strconv.Quote(p.streamStyles[StreamStderr].Color)
+	for name, c := range namedColors {
+		if c.color == p.infoColor {
+			info = name
+		}
+		if c.color == p.prefixColor {
+			prefix = name
+		}
+		if c.code == p.streamStyles[StreamStderr].Color {
+			stderr = name
+		}
+	}
+	return fmt.Sprintf("info %s, prefix %s, stderr %s", info, prefix, stderr)
+}
+
+func setColor(p *Prompt, args []string) error {
+	if len(args) != 2 {
+		return errors.New("wrong number of arguments")
+	}
+	c, ok := namedColors[args[1]]
+	if !ok {
+		return fmt.Errorf("unknown color '%s'", args[1])
+	}
+
+	switch args[0] {
+	case "info":
+		p.infoColor = c.color
+	case "prefix":
+		p.prefixColor = c.color
+	case "stderr":
+		style := p.streamStyles[StreamStderr]
+		style.Color = c.code
+		p.streamStyles[StreamStderr] = style
+	default:
+		return fmt.Errorf("unknown part '%s'", args[0])
+	}
+	return nil
+}
+
+func setPager(p *Prompt, args []string) error {
+	if len(args) != 1 {
+		return errors.New("wrong number of arguments")
+	}
+	if args[0] == "off" {
+		p.pagerThreshold = 0
+		return nil
+	}
+	rows, err := strconv.Atoi(args[0])
+	if err != nil || rows < 1 {
+		return fmt.Errorf("the pager threshold must be at least 1 row")
+	}
+	p.pagerThreshold = rows
+	return nil
+}
+
+func setEditMode(p *Prompt, args []string) error {
+	if len(args) != 1 {
+		return errors.New("wrong number of arguments")
+	}
+	switch args[0] {
+	case "emacs":
+		p.editMode = EditEmacs
+	case "vi":
+		p.editMode = EditVi
+	default:
+		return fmt.Errorf("unknown edit mode '%s'", args[0])
+	}
+	p.vi = viState{}
+	return nil
+}
+
+func setRepeatOnEmpty(p *Prompt, args []string) error {
+	if len(args) != 1 {
+		return errors.New("wrong number of arguments")
+	}
+	switch args[0] {
+	case "on":
+		p.repeatOnEmpty = true
+	case "off":
+		p.repeatOnEmpty = false
+	default:
+		return fmt.Errorf("'%s' isn't on or off", args[0])
+	}
+	return nil
+}
+
+// repaint draws the info row and the prompt row again with the current
+// settings. The output stays. renderMutex must be held.
+func (p *Prompt) repaint() {
+	if p.totalRows == 0 {
+		// The initial render uses the settings
+		return
+	}
+	p.renderInfo()
+
+	p.writer.CursorGoTo(p.promptRow, 1)
+	p.writer.EraseLine()
+	p.renderPromptRow()
+	if err := p.flush(); err != nil {
+		p.fail(fmt.Errorf("flushing the prompt buffer failed: %w", err))
+	}
+}
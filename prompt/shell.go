@@ -0,0 +1,111 @@
+package prompt
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync/atomic"
+	"unicode"
+)
+
+// WithShellFallback makes the prompt run unknown commands as external
+// programs. Their stdout and stderr are streamed to the output and the
+// exit code is reported on the info row. The arguments are split like by
+// a shell, see WithCommandNotFoundHandler. Nothing is expanded, there are
+// no variables, globs or pipes.
+func WithShellFallback() Option {
+	return func(p *Prompt) error {
+		p.notFoundHandler = p.runExternal
+		return nil
+	}
+}
+
+func (p *Prompt) runExternal(name string, args []string) error {
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return fmt.Errorf("Unknown command '%s'", name)
+	}
+	p.logWith("path", path, "args", args).Debugf("Running external command")
+
+	c := exec.Command(path, args...)
+	// go-prompt stops reading the terminal and cooks it while a submitted
+	// line runs, the program can have the keys then. Otherwise the prompt
+	// reads them, e.g. when another goroutine calls Execute, and the
+	// program gets no input. A WithIO terminal isn't the process's stdin.
+	if atomic.LoadInt32(&p.cooked) == 1 && p.remote == nil {
+		c.Stdin = os.Stdin
+	}
+	c.Stdout = p.outBuf
+	c.Stderr = p.outBuf.StreamWriter(StreamStderr)
+
+	err = c.Run()
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		info := fmt.Sprintf("'%s' exited with code %d", name, exitErr.ExitCode())
+		return p.SetInfoln(info, InfoLineSeverityWarning)
+	} else if err != nil {
+		p.log.Errorf("Error running external command: %s", err)
+		return err
+	}
+
+	return p.SetInfoln(fmt.Sprintf("'%s' exited with code 0", name), InfoLineSeverityNormal)
+}
+
+// splitWords splits s into words like a shell without the expansions.
+// Spaces separate the words, quotes keep the spaces in a word. Outside of
+// quotes a backslash escapes the next character, in double quotes only
+// a double quote or a backslash. Single quotes keep everything as it is.
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	text := []rune(s)
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+		switch {
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\\' && i+1 < len(text) && (quote == 0 || text[i+1] == '"' || text[i+1] == '\\'):
+			i++
+			word.WriteRune(text[i])
+			inWord = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inWord = true
+		case unicode.IsSpace(r):
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("the %c quote isn't closed", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	if len(words) == 0 {
+		return nil, errors.New("nothing to run")
+	}
+	return words, nil
+}
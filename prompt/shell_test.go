@@ -0,0 +1,55 @@
+package prompt
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		line  string
+		words []string
+	}{
+		{"ls -la", []string{"ls", "-la"}},
+		{"  echo   a\tb  ", []string{"echo", "a", "b"}},
+		{`grep "two words" file`, []string{"grep", "two words", "file"}},
+		{`echo 'single $HOME "x"'`, []string{"echo", `single $HOME "x"`}},
+		{`echo a\ b`, []string{"echo", "a b"}},
+		{`echo "say \"hi\" \n"`, []string{"echo", `say "hi" \n`}},
+		{`echo 'it\'`, []string{"echo", `it\`}},
+		{`echo ""`, []string{"echo", ""}},
+		{`echo pre"fix"'es'`, []string{"echo", "prefixes"}},
+	}
+	for _, tt := range tests {
+		words, err := splitWords(tt.line)
+		if err != nil || !reflect.DeepEqual(words, tt.words) {
+			t.Errorf("splitWords(%q) = %q, %v, want %q", tt.line, words, err, tt.words)
+		}
+	}
+}
+
+func TestSplitWordsUnclosedQuote(t *testing.T) {
+	for _, line := range []string{`echo "open`, `echo 'open`} {
+		if _, err := splitWords(line); err == nil {
+			t.Errorf("splitWords(%q) didn't fail", line)
+		}
+	}
+}
+
+func TestShellFallbackKeepsQuotedArguments(t *testing.T) {
+	p, _ := newTestPrompt(t, nil, "", WithShellFallback())
+	if err := p.Execute(`printf "%s|" "two words" 'and more'`); err != nil {
+		t.Fatalf("Execute: %s", err)
+	}
+	var out strings.Builder
+	for _, c := range readChunks(p.outBuf) {
+		out.Write(c.Data)
+	}
+	if out.String() != "two words|and more|" {
+		t.Fatalf("output %q", out.String())
+	}
+	if info := p.infoText; !strings.Contains(info, "'printf' exited with code 0") {
+		t.Fatalf("info row %q", info)
+	}
+}
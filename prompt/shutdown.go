@@ -0,0 +1,202 @@
+package prompt
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// SignalError is returned by Run when the process got SIGINT or SIGTERM
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received signal: %s", e.Signal)
+}
+
+// ExitCode returns the shell's exit code of a process killed by the signal
+func (e *SignalError) ExitCode() int {
+	if s, ok := e.Signal.(syscall.Signal); ok {
+		return 128 + int(s)
+	}
+	return 1
+}
+
+// exitRequest asks Run to tear the prompt down and return err
+type exitRequest struct {
+	reason string
+	err    error
+}
+
+// requestExit makes Run return err. Only the first request counts.
+func (p *Prompt) requestExit(reason string, err error) {
+	select {
+	case p.exitCh <- exitRequest{reason, err}:
+	default:
+	}
+}
+
+// fail logs err and makes Run return it. The prompt can't continue after it.
+func (p *Prompt) fail(err error) {
+	p.log.Errorf("%s", err)
+	p.requestExit(err.Error(), err)
+}
+
+// wait blocks until something ends the prompt: Ctrl+C, Ctrl+D, Stop, an error,
+// ctx being done or the process getting SIGINT or SIGTERM. Without the
+// signal handler a `kill` would leave the terminal in the raw mode with
+// the scrolled output region. SIGINT while a command runs is Ctrl+C
+// pressed in the cooked mode, it's handled by the CtrlCMode instead.
+// SIGHUP means the terminal is gone, the prompt ends without an error.
+// SIGQUIT dumps the goroutines to the log and the prompt keeps running.
+// go-prompt's loop ends on these signals too, a new one is started from
+// here if the prompt goes on.
+func (p *Prompt) wait(ctx context.Context) error {
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
+	defer signal.Stop(sigCh)
+
+	for {
+		select {
+		case sig := <-sigCh:
+			if end, err := p.onSignal(sig); end {
+				return err
+			}
+		case <-ctx.Done():
+			p.log.Debugf("Shutting down, the context is done: %s", ctx.Err())
+			p.teardown(ctx.Err().Error())
+			return ctx.Err()
+		case req := <-p.exitCh:
+			p.teardown(req.reason)
+			return req.err
+		case <-p.stopCh:
+			p.teardown("stopped")
+			return nil
+		case <-p.inputLost:
+			// The signal that ended the loop may end the prompt too
+			select {
+			case sig := <-sigCh:
+				if end, err := p.onSignal(sig); end {
+					return err
+				}
+			default:
+			}
+			if err := p.startInput(p.history.snapshot()); err != nil {
+				p.fail(err)
+			}
+		}
+	}
+}
+
+// onSignal handles a signal wait got. It returns true and what Run returns
+// if the prompt ended.
+func (p *Prompt) onSignal(sig os.Signal) (bool, error) {
+	if sig == os.Interrupt && atomic.LoadInt32(&p.suspended) != 0 {
+		// Ctrl+C is for the program run by Suspend
+		return false, nil
+	}
+	if sig == os.Interrupt && p.interruptExec() {
+		return false, nil
+	}
+	if sig == syscall.SIGHUP {
+		p.lostTerminal(nil)
+		return false, nil
+	}
+	if sig == syscall.SIGQUIT {
+		p.dumpGoroutines()
+		return false, nil
+	}
+	p.logWith("signal", sig).Debugf("Shutting down on a signal")
+	p.teardown("signal " + sig.String())
+	return true, &SignalError{sig}
+}
+
+// teardown is the graceful shutdown shared by all the ways the prompt ends.
+// It stops the prompt after its output is printed and restores the terminal.
+// Only the first call does anything. The listeners get an exit event with the reason.
+func (p *Prompt) teardown(reason string) {
+	p.teardownOnce.Do(func() {
+		p.emit(NewExitEvent(reason))
+		p.Stop()
+		// go-prompt restores the terminal it set up first
+		p.waitInput(DefaultDrainTimeout)
+		p.restoreTerminal()
+		p.term.close()
+		// A recording that wasn't stopped ends with the prompt
+		if path, _, err := p.rec.end(); err == nil {
+			p.log.Debugf("Recording to %s stopped", path)
+		}
+		// The debug messages go to stdout again once nothing prints the output
+		p.debug.disable()
+	})
+}
+
+// ctrlDCode is fed to go-prompt in place of Ctrl+D, its binding ends the
+// prompt on an empty input. No terminal sends it.
+var ctrlDCode = []byte("\x1b]foundry-ctrl-d\x07")
+
+// stopKey is what stopParser reads once the prompt is stopped. go-prompt
+// ignores the key, its exit checker ends the loop after it.
+var stopKey = []byte{0x1b, 0x5b, 0x45}
+
+// stopParser ends go-prompt's loop once the prompt is stopped. The loop
+// only checks whether to end after a key. Without one it would keep
+// reading the terminal after Run returned and get the next prompt's keys.
+// Ctrl+D is read as ctrlDCode.
+//
+// A loop ended by a signal leaves its reader behind, lost is closed then.
+// The reader blocks for good so that the next loop gets the keys.
+type stopParser struct {
+	goprompt.ConsoleParser
+	p    *Prompt
+	lost chan struct{}
+}
+
+func (s *stopParser) Read() ([]byte, error) {
+	select {
+	case <-s.p.stopCh:
+		return stopKey, nil
+	case <-s.lost:
+		select {}
+	default:
+	}
+	b, err := s.ConsoleParser.Read()
+	if err == nil && len(b) == 1 && b[0] == 0x04 {
+		// go-prompt would end its loop on Ctrl+D with an empty input
+		return append([]byte(nil), ctrlDCode...), nil
+	}
+	if err == nil && bytes.Equal(b, ctrlDCode) {
+		// Typed or pasted, it's not Ctrl+D
+		return []byte{0}, nil
+	}
+	return b, err
+}
+
+// waitInput waits at most timeout for go-prompt's loop to end after the
+// prompt was stopped. While a line runs the loop waits for its command,
+// the command is cancelled like by Ctrl+C.
+func (p *Prompt) waitInput(timeout time.Duration) {
+	if p.inputDone == nil {
+		return
+	}
+	p.ctrlC.mut.Lock()
+	cancel := p.ctrlC.cancel
+	p.ctrlC.mut.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+
+	select {
+	case <-p.inputDone:
+	case <-time.After(timeout):
+		p.log.Errorf("go-prompt's loop didn't end %s after the prompt stopped", timeout)
+	}
+}
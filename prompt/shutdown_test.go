@@ -0,0 +1,246 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"foundry/cli/prompt/cmd"
+
+	"go.uber.org/goleak"
+)
+
+// TestSignalRestoresTerminal sends SIGINT and SIGTERM to a prompt run by
+// go-prompt's loop. Run must restore the terminal and return a SignalError,
+// nothing in the package may exit the process.
+func TestSignalRestoresTerminal(t *testing.T) {
+	tests := []struct {
+		sig  syscall.Signal
+		code int
+	}{
+		{syscall.SIGINT, 130},
+		{syscall.SIGTERM, 143},
+	}
+	for _, tt := range tests {
+		t.Run(tt.sig.String(), func(t *testing.T) {
+			// The test binary mustn't die of a signal sent before the prompt listens
+			sigCh := make(chan os.Signal, 1)
+			signal.Notify(sigCh, tt.sig)
+			defer signal.Stop(sigCh)
+
+			p, parser, _, term := newTTYPrompt(t, nil)
+			errCh := make(chan error, 1)
+			go func() { errCh <- p.Run() }()
+			<-p.Ready()
+
+			var err error
+			tick := time.NewTicker(20 * time.Millisecond)
+			defer tick.Stop()
+			timeout := time.After(5 * time.Second)
+		wait:
+			for {
+				select {
+				case err = <-errCh:
+					break wait
+				case <-tick.C:
+					syscall.Kill(os.Getpid(), tt.sig)
+				case <-timeout:
+					t.Fatalf("Run didn't return after %s", tt.sig)
+				}
+			}
+
+			absorbSignals(t, sigCh, tt.sig)
+
+			var sigErr *SignalError
+			if !errors.As(err, &sigErr) || sigErr.Signal != tt.sig {
+				t.Fatalf("Run returned %v, want the SignalError of %s", err, tt.sig)
+			}
+			if code := sigErr.ExitCode(); code != tt.code {
+				t.Errorf("exit code %d, want %d", code, tt.code)
+			}
+			restored := term.String()
+			for _, seq := range []string{"\x1b[0m\x1b[?25h", "\x1b[24;1H\n"} {
+				if !strings.Contains(restored, seq) {
+					t.Errorf("the terminal didn't get %q: %q", seq, restored)
+				}
+			}
+			if parser.isRaw() {
+				t.Error("the terminal was left in the raw mode")
+			}
+		})
+	}
+}
+
+// absorbSignals waits until sigCh gets sig sent once more. The signals
+// sent before were delivered by then and don't reach the next test.
+func absorbSignals(t *testing.T, sigCh chan os.Signal, sig syscall.Signal) {
+	t.Helper()
+	for len(sigCh) > 0 {
+		<-sigCh
+	}
+	syscall.Kill(os.Getpid(), sig)
+	select {
+	case <-sigCh:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("%s wasn't delivered", sig)
+	}
+}
+
+// TestRunContextEndsGoPromptLoop cancels the context and stops the prompt
+// while go-prompt's loop waits for a key. Run must return only once the
+// loop ended and gave the terminal back, it mustn't read it afterwards.
+func TestRunContextEndsGoPromptLoop(t *testing.T) {
+	tests := []struct {
+		name string
+		end  func(p *Prompt, cancel context.CancelFunc)
+		err  error
+	}{
+		{"cancel", func(p *Prompt, cancel context.CancelFunc) { cancel() }, context.Canceled},
+		{"stop", func(p *Prompt, cancel context.CancelFunc) { p.Stop() }, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, parser, _, _ := newTTYPrompt(t, nil)
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			errCh := make(chan error, 1)
+			go func() { errCh <- p.RunContext(ctx) }()
+			<-p.Ready()
+			waitFor(t, "go-prompt to read the terminal", func() bool {
+				return parser.readCount() > 0
+			})
+
+			tt.end(p, cancel)
+			select {
+			case err := <-errCh:
+				if err != tt.err {
+					t.Fatalf("RunContext returned %v, want %v", err, tt.err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("RunContext didn't return")
+			}
+
+			select {
+			case <-p.inputDone:
+			default:
+				t.Fatal("RunContext returned before go-prompt's loop ended")
+			}
+			if parser.isRaw() {
+				t.Fatal("the terminal was left in the raw mode")
+			}
+			reads := parser.readCount()
+			time.Sleep(50 * time.Millisecond)
+			if n := parser.readCount(); n != reads {
+				t.Fatalf("go-prompt read the terminal %d more times after RunContext returned", n-reads)
+			}
+		})
+	}
+}
+
+// TestSequentialPromptsDontLeak runs and stops several prompts one after
+// another on the same terminal. Each must take its goroutines and signal
+// handlers with it, and the next one must get all the keys.
+func TestSequentialPromptsDontLeak(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	parser := newFakeParser()
+	for i := 0; i < 3; i++ {
+		w, _ := fakeWriter()
+		p, err := New(nil,
+			WithConsoleParser(parser),
+			WithConsoleWriter(w),
+			WithTerminalSize(24, 80),
+			withTermOut(&syncBuffer{}),
+			WithLogger(&fakeLogger{}),
+		)
+		if err != nil {
+			t.Fatalf("New: %s", err)
+		}
+		errCh := make(chan error, 1)
+		go func() { errCh <- p.Run() }()
+		<-p.Ready()
+
+		parser.keys <- []byte("x")
+		waitFor(t, fmt.Sprintf("prompt %d to get the key", i), func() bool {
+			return p.input() == "x"
+		})
+
+		p.Stop()
+		if err := <-errCh; err != nil {
+			t.Fatalf("Run of prompt %d returned %v", i, err)
+		}
+	}
+}
+
+// TestCtrlDOnEmptyLine runs a line and presses Ctrl+D on the empty line
+// after it. The prompt ends without an error, the terminal is restored
+// and the line waiting for the history flush is in the file.
+func TestCtrlDOnEmptyLine(t *testing.T) {
+	history := filepath.Join(t.TempDir(), "history")
+	ran := make(chan string, 1)
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		ran <- strings.Join(args, " ")
+		return nil
+	}}
+	p, parser, _, term := newTTYPrompt(t, []cmd.Cmd{deploy},
+		WithHistoryFile(history), WithHistoryFlushInterval(time.Hour))
+	events, cancel := p.Subscribe(PromptEventTypeExit)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() { errCh <- p.Run() }()
+	<-p.Ready()
+
+	parser.keys <- []byte("deploy api")
+	parser.keys <- []byte("\r")
+	<-ran
+	parser.keys <- []byte{0x04}
+	select {
+	case err := <-errCh:
+		if err != nil {
+			t.Fatalf("Run returned %v after Ctrl+D", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Ctrl+D on the empty line didn't end the prompt")
+	}
+
+	if e := <-events; e.Data.(ExitEvent).Reason != "ctrl+d" {
+		t.Errorf("the prompt ended because of %q", e.Data.(ExitEvent).Reason)
+	}
+	if b, err := ioutil.ReadFile(history); err != nil || string(b) != "deploy api\n" {
+		t.Errorf("the history file has %q, %v", b, err)
+	}
+	if !strings.Contains(term.String(), "\x1b[0m\x1b[?25h") || parser.isRaw() {
+		t.Errorf("the terminal wasn't restored: %q", term.String())
+	}
+}
+
+// TestCtrlDWithText presses Ctrl+D with text in the line. Like in readline
+// it deletes the character under the cursor, or nothing at the end of the
+// line, and the prompt keeps running.
+func TestCtrlDWithText(t *testing.T) {
+	p, parser, _, _ := newTTYPrompt(t, nil)
+	go p.Run()
+	<-p.Ready()
+
+	parser.keys <- []byte("deploy")
+	waitFor(t, "the typed text", func() bool { return p.input() == "deploy" })
+	parser.keys <- []byte{0x04}
+	// Ctrl+A moves to the start of the line
+	parser.keys <- []byte{0x01}
+	parser.keys <- []byte{0x04}
+	waitFor(t, "the deleted character", func() bool { return p.input() == "eploy" })
+
+	select {
+	case <-p.doneCh:
+		t.Fatalf("Ctrl+D with text ended the prompt: %v", p.runErr)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,321 @@
+package prompt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// spoolLimit is how many bytes may wait for the disk. Output that comes
+// while more wait is missing from the file.
+const spoolLimit = 4 * 1024 * 1024
+
+// spool asynchronously copies everything written to a Buffer into a file
+// so there is a record of the output even if the process crashes. The
+// writes are collected in memory and the goroutine writes them in one go,
+// like the terminal queue, so a burst of small writes doesn't fill it up.
+type spool struct {
+	path    string
+	maxSize int64
+	size    int64
+	f       *os.File
+
+	// The escape codes are left out of the file
+	strip bool
+	esc   escapeState
+
+	mut      sync.Mutex
+	pending  []byte
+	closed   bool
+	disabled bool
+	dropped  int64         // Bytes that didn't fit under spoolLimit, guarded by mut
+	wake     chan struct{} // Tells the goroutine there's something to write
+	done     chan struct{} // Closed once everything is written and the file closed
+
+	// Called once with the error that disabled the spool
+	onError func(err error)
+}
+
+// startSpool starts writing to the open file f at path
+func startSpool(path string, f *os.File, strip bool, maxSize int64, onError func(err error)) *spool {
+	s := &spool{
+		path:    path,
+		maxSize: maxSize,
+		f:       f,
+		strip:   strip,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		onError: onError,
+	}
+	go s.run()
+	return s
+}
+
+func newSpool(maxSize int64, onError func(err error)) (*spool, error) {
+	f, err := ioutil.TempFile("", "foundry-output-*.log")
+	if err != nil {
+		return nil, err
+	}
+	return startSpool(f.Name(), f, false, maxSize, onError), nil
+}
+
+// newOutputLog appends the output to the file at path, it's never rotated
+func newOutputLog(path string, strip bool, onError func(err error)) (*spool, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return startSpool(path, f, strip, 0, onError), nil
+}
+
+// write never blocks. If the disk is too far behind p is dropped from the
+// spool (but it's still rendered). It returns true for the first write
+// that was dropped so the caller can warn once.
+func (s *spool) write(p []byte) (firstDrop bool) {
+	s.mut.Lock()
+	if s.disabled || s.closed {
+		s.mut.Unlock()
+		return false
+	}
+	if len(s.pending)+len(p) > spoolLimit {
+		firstDrop = s.dropped == 0
+		s.dropped += int64(len(p))
+		s.mut.Unlock()
+		return firstDrop
+	}
+	s.pending = append(s.pending, p...)
+	s.mut.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return false
+}
+
+func (s *spool) writeString(str string) (firstDrop bool) {
+	s.mut.Lock()
+	if s.disabled || s.closed {
+		s.mut.Unlock()
+		return false
+	}
+	if len(s.pending)+len(str) > spoolLimit {
+		firstDrop = s.dropped == 0
+		s.dropped += int64(len(str))
+		s.mut.Unlock()
+		return firstDrop
+	}
+	s.pending = append(s.pending, str...)
+	s.mut.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return false
+}
+
+func (s *spool) run() {
+	defer close(s.done)
+	var buf []byte
+	for range s.wake {
+		for {
+			s.mut.Lock()
+			if len(s.pending) == 0 {
+				closed := s.closed
+				s.mut.Unlock()
+				if closed {
+					if !s.isDisabled() {
+						s.f.Close()
+					}
+					return
+				}
+				break
+			}
+			// The buffers are swapped, write appends to the empty one
+			buf, s.pending = s.pending, buf[:0]
+			s.mut.Unlock()
+
+			if s.isDisabled() {
+				continue
+			}
+			b := buf
+			if s.strip {
+				b = stripANSI(buf[:0], buf, &s.esc)
+			}
+			if err := s.writeFile(b); err != nil {
+				s.mut.Lock()
+				s.disabled = true
+				s.pending = nil
+				s.mut.Unlock()
+				s.f.Close()
+				if s.onError != nil {
+					s.onError(err)
+				}
+			}
+		}
+	}
+}
+
+func (s *spool) isDisabled() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.disabled
+}
+
+func (s *spool) writeFile(b []byte) error {
+	if s.maxSize > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.f.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+// rotate keeps a single previous spool file next to the current one
+func (s *spool) rotate() error {
+	if err := s.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	s.f = f
+	s.size = 0
+	return nil
+}
+
+// close writes what's queued and closes the file. It waits at most
+// timeout for a slow disk. It returns how many bytes were dropped because
+// the disk was too slow, those written after the timeout included.
+func (s *spool) close(timeout time.Duration) (dropped int64) {
+	s.mut.Lock()
+	s.closed = true
+	s.mut.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-s.done:
+	case <-time.After(timeout):
+	}
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return s.dropped + int64(len(s.pending))
+}
+
+// EnableSpool starts copying every chunk written to the buffer into
+// a temporary file. Once the file grows over maxSize bytes it's rotated.
+// maxSize <= 0 means no limit.
+func (b *Buffer) EnableSpool(maxSize int64) error {
+	s, err := newSpool(maxSize, func(err error) {
+		b.mut.Lock()
+		log := b.log
+		b.mut.Unlock()
+		log.Errorf("Output spool disabled: %s", err)
+
+		bold := "\x1b[1m"
+		yellow := "\x1b[33m"
+		endSeq := "\x1b[0m"
+		msg := fmt.Sprintf("%s%sWARNING:%s Output spooling disabled: %s\n", bold, yellow, endSeq, err)
+		b.WritePriority([]byte(msg))
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mut.Lock()
+	b.spool = s
+	b.mut.Unlock()
+	return nil
+}
+
+// EnableOutputLog starts appending every chunk written to the buffer to the
+// file at path, without the escape codes if strip is true. The file is
+// written by another goroutine, a slow disk never blocks the output. If it
+// can't keep up some output is missing from the file.
+func (b *Buffer) EnableOutputLog(path string, strip bool) error {
+	s, err := newOutputLog(path, strip, func(err error) {
+		b.mut.Lock()
+		log := b.log
+		b.mut.Unlock()
+		log.Errorf("Output log %s disabled: %s", path, err)
+	})
+	if err != nil {
+		return err
+	}
+
+	b.mut.Lock()
+	b.outputLog = s
+	b.mut.Unlock()
+	return nil
+}
+
+// closeSpools writes everything queued for the spool and the output log
+// to their files and closes them. Later output isn't copied anymore. The
+// output missing from a file because the disk was too slow is logged.
+func (b *Buffer) closeSpools(timeout time.Duration) {
+	b.mut.Lock()
+	spools := []*spool{b.spool, b.outputLog}
+	b.spool, b.outputLog = nil, nil
+	log := b.log
+	b.mut.Unlock()
+
+	for _, s := range spools {
+		if s == nil {
+			continue
+		}
+		if dropped := s.close(timeout); dropped > 0 {
+			log.Errorf("%d bytes of output are missing from %s, the disk was too slow", dropped, s.path)
+		}
+	}
+}
+
+// copyToSpools queues p for the spool and the output log. b.mut must be held.
+func (b *Buffer) copyToSpools(p []byte) {
+	for _, s := range []*spool{b.spool, b.outputLog} {
+		if s != nil && s.write(p) {
+			b.warnSpoolDrop(s)
+		}
+	}
+}
+
+// copyStringToSpools is copyToSpools for a string. b.mut must be held.
+func (b *Buffer) copyStringToSpools(str string) {
+	for _, s := range []*spool{b.spool, b.outputLog} {
+		if s != nil && s.writeString(str) {
+			b.warnSpoolDrop(s)
+		}
+	}
+}
+
+// warnSpoolDrop logs that output is missing from the file of s right away,
+// there's a trace of it in the log even if the process crashes before the
+// spool closes. b.mut must be held.
+func (b *Buffer) warnSpoolDrop(s *spool) {
+	b.log.Errorf("The disk is too slow for %s, some output is missing from it", s.path)
+}
+
+// SpoolPath returns a path to the file where the output is spooled
+// or an empty string if spooling isn't enabled
+func (b *Buffer) SpoolPath() string {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.spool == nil {
+		return ""
+	}
+	return b.spool.path
+}
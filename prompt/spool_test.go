@@ -0,0 +1,145 @@
+package prompt
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeNumbered writes the lines from..to-1 one by one
+func writeNumbered(b *Buffer, from, to int) string {
+	var all strings.Builder
+	for i := from; i < to; i++ {
+		line := fmt.Sprintf("line %d\n", i)
+		b.WriteString(line)
+		all.WriteString(line)
+	}
+	return all.String()
+}
+
+// killConsumer delivers chunks to a consumer that goes away after it got
+// the first one, like a renderer that crashed. Nothing is read afterwards.
+func killConsumer(t *testing.T, b *Buffer) {
+	t.Helper()
+	bufCh := make(chan Chunk)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		b.Read(bufCh, stopCh)
+		close(done)
+	}()
+	select {
+	case <-bufCh:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no chunk was delivered")
+	}
+	close(stopCh)
+	<-done
+}
+
+func TestSpoolKeepsOutputAfterTheConsumerDies(t *testing.T) {
+	b := NewBuffer()
+	if err := b.EnableSpool(0); err != nil {
+		t.Fatal(err)
+	}
+	path := b.SpoolPath()
+	defer os.Remove(path)
+
+	want := writeNumbered(b, 0, 500)
+	killConsumer(t, b)
+	want += writeNumbered(b, 500, 1000)
+	b.closeSpools(5 * time.Second)
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("the spool has %d bytes, %d were written", len(got), len(want))
+	}
+}
+
+func TestOutputLogKeepsOutputAfterTheConsumerDies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.log")
+	b := NewBuffer()
+	if err := b.EnableOutputLog(path, true); err != nil {
+		t.Fatal(err)
+	}
+
+	want := writeNumbered(b, 0, 500)
+	killConsumer(t, b)
+	b.WriteString("\x1b[31mred\x1b[0m\n")
+	want += "red\n"
+	want += writeNumbered(b, 500, 1000)
+	b.closeSpools(5 * time.Second)
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Fatalf("the output log has %d bytes, %d were written", len(got), len(want))
+	}
+}
+
+// TestOutputLogStripsSplitCodes writes escape codes cut between writes,
+// the log gets the text without them
+func TestOutputLogStripsSplitCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "output.log")
+	b := NewBuffer()
+	if err := b.EnableOutputLog(path, true); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"\x1b[3", "1mred\x1b", "[0m \x1b]8;;https://", "example.com\x1b", "\\link\x1b]8;;\x1b\\\n"} {
+		b.WriteString(s)
+	}
+	b.closeSpools(5 * time.Second)
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "red link\n" {
+		t.Fatalf("the output log has %q", got)
+	}
+}
+
+func TestSpoolReportsDroppedOutput(t *testing.T) {
+	// Nobody reads the pipe, the spool's writes get stuck like on a dead disk
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	log := &fakeLogger{}
+	b := NewBuffer()
+	b.setLogger(log)
+	b.spool = startSpool("stuck.log", w, false, 0, nil)
+
+	chunk := strings.Repeat("x", 64*1024)
+	written := 0
+	for i := 0; i < 2*spoolLimit/len(chunk); i++ {
+		b.WriteString(chunk)
+		written += len(chunk)
+	}
+	if _, ok := log.errorContaining("The disk is too slow for stuck.log"); !ok {
+		t.Fatalf("the first dropped write wasn't logged: %q", log.errors)
+	}
+
+	b.closeSpools(100 * time.Millisecond)
+	msg, ok := log.errorContaining("bytes of output are missing from stuck.log")
+	if !ok {
+		t.Fatalf("the dropped bytes weren't logged when the spool closed: %q", log.errors)
+	}
+	var dropped int
+	fmt.Sscanf(msg, "%d", &dropped)
+	if dropped < written-spoolLimit-64*1024 || dropped > written {
+		t.Fatalf("%d bytes reported missing of %d written", dropped, written)
+	}
+	w.Close()
+}
@@ -0,0 +1,61 @@
+package prompt
+
+import (
+	"strings"
+)
+
+// StreamStyle describes how output from a single stream is rendered
+type StreamStyle struct {
+	Prefix string // Printed at the start of every line
+	Color  string // VT100 escape sequence applied to the text
+}
+
+func defaultStreamStyles() map[Stream]StreamStyle {
+	return map[Stream]StreamStyle{
+		StreamStderr: {Color: "\x1b[31m"},
+	}
+}
+
+// WithStreamStyle sets how output tagged with stream is rendered
+func WithStreamStyle(stream Stream, style StreamStyle) Option {
+	return func(p *Prompt) error {
+		p.streamStyles[stream] = style
+		return nil
+	}
+}
+
+// decorate returns the chunk's text with its stream style applied.
+// renderMutex must be held.
+func (p *Prompt) decorate(c Chunk) string {
+	s := string(c.Data)
+	endSeq := "\x1b[0m"
+
+	if style, ok := p.streamStyles[c.Stream]; ok {
+		if style.Prefix != "" {
+			trailingNewLine := strings.HasSuffix(s, "\n")
+			s = strings.Replace(s, "\n", "\n"+style.Prefix, -1)
+			if trailingNewLine {
+				// The line after the last new line belongs to the next chunk
+				s = strings.TrimSuffix(s, style.Prefix)
+			}
+			if p.currentPos.Col == 1 {
+				s = style.Prefix + s
+			}
+		}
+		if style.Color != "" && p.colorLevel != ColorNone {
+			s = style.Color + s + endSeq
+		}
+	}
+
+	if c.OutOfOrder {
+		// Let user know that some output that was written earlier
+		// will be printed after this chunk
+		mark := "⚠ shown out of order"
+		if p.colorLevel != ColorNone {
+			yellow := "\x1b[33m"
+			mark = yellow + mark + endSeq
+		}
+		s = mark + " " + s
+	}
+	return s
+}
@@ -0,0 +1,101 @@
+package prompt
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Number of events a subscriber can fall behind before its events are dropped
+const SubscriberBufferSize = 64
+
+type subscriber struct {
+	ch      chan PromptEvent
+	types   map[PromptEventType]bool // Nil means all types
+	dropped int64
+}
+
+type subscribers struct {
+	mut  sync.RWMutex
+	subs map[*subscriber]struct{}
+}
+
+// Subscribe returns a channel with the events of the given types, all of them
+// when no type is given. Every subscriber has its own buffered channel so a slow
+// one doesn't hold up the prompt or the others; the events it has no room for are
+// dropped and counted in Metrics. The returned function cancels the subscription
+// and closes the channel.
+func (p *Prompt) Subscribe(types ...PromptEventType) (<-chan PromptEvent, func()) {
+	return p.subs.subscribe(types)
+}
+
+// emit sends e to the subscribers that want it. It never blocks.
+func (p *Prompt) emit(e PromptEvent) {
+	if dropped := p.subs.emit(e); dropped > 0 {
+		atomic.AddInt64(&p.stats.eventsDropped, int64(dropped))
+	}
+}
+
+func (ss *subscribers) subscribe(types []PromptEventType) (<-chan PromptEvent, func()) {
+	s := &subscriber{ch: make(chan PromptEvent, SubscriberBufferSize)}
+	if len(types) > 0 {
+		s.types = make(map[PromptEventType]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	ss.mut.Lock()
+	if ss.subs == nil {
+		ss.subs = map[*subscriber]struct{}{}
+	}
+	ss.subs[s] = struct{}{}
+	ss.mut.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			// emit sends only while holding the read lock, so nothing
+			// is sent to the channel once it's closed
+			ss.mut.Lock()
+			delete(ss.subs, s)
+			ss.mut.Unlock()
+			close(s.ch)
+		})
+	}
+	return s.ch, cancel
+}
+
+// emit returns the number of subscribers that had no room for e
+func (ss *subscribers) emit(e PromptEvent) int {
+	ss.mut.RLock()
+	defer ss.mut.RUnlock()
+
+	dropped := 0
+	for s := range ss.subs {
+		if s.types != nil && !s.types[e.Type] {
+			continue
+		}
+		select {
+		case s.ch <- e:
+		default:
+			atomic.AddInt64(&s.dropped, 1)
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// forwardEvents feeds the legacy Events channel from its own subscription.
+// Events keeps its blocking semantics for the one listener, but only the
+// forwarding goroutine waits for it.
+func (p *Prompt) forwardEvents(ch <-chan PromptEvent, cancel func()) {
+	defer cancel()
+
+	for e := range ch {
+		select {
+		case p.Events <- e:
+		case <-p.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package prompt
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestSubscribersUnderLoad emits events from several goroutines to
+// subscribers that read them, one that never reads and ones that come and
+// go meanwhile. Emitting mustn't block on the stuck subscriber, the readers
+// get the events of each goroutine in order and only of the types they
+// asked for. Run it with -race.
+func TestSubscribersUnderLoad(t *testing.T) {
+	const emitters, events = 4, 500
+	p, _ := newTestPrompt(t, nil, "")
+
+	all, cancelAll := p.Subscribe()
+	cmds, cancelCmds := p.Subscribe(PromptEventTypeCommand)
+	stuck, cancelStuck := p.Subscribe()
+
+	type result struct {
+		received int
+		err      error
+	}
+	// read checks that the events of each emitter come in order
+	read := func(ch <-chan PromptEvent, only PromptEventType) <-chan result {
+		res := make(chan result, 1)
+		go func() {
+			var r result
+			last := map[string]int{}
+			for e := range ch {
+				r.received++
+				if only != "" && e.Type != only {
+					r.err = fmt.Errorf("got a %s event", e.Type)
+					continue
+				}
+				c, ok := e.Data.(CommandEvent)
+				if !ok {
+					continue
+				}
+				var seq int
+				fmt.Sscanf(c.Args[0], "%d", &seq)
+				if prev, ok := last[c.Name]; ok && seq <= prev && r.err == nil {
+					r.err = fmt.Errorf("event %d of %s came after %d", seq, c.Name, prev)
+				}
+				last[c.Name] = seq
+			}
+			res <- r
+		}()
+		return res
+	}
+	allRes, cmdsRes := read(all, ""), read(cmds, PromptEventTypeCommand)
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < emitters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("emitter%d", i)
+			for j := 0; j < events; j++ {
+				p.emit(NewCommandEvent(name, []string{fmt.Sprint(j)}, time.Millisecond, nil))
+				p.emit(NewOutputEvent(j))
+			}
+		}(i)
+	}
+	// Subscribers that cancel while the events are emitted
+	var churn sync.WaitGroup
+	churn.Add(1)
+	go func() {
+		defer churn.Done()
+		for {
+			ch, cancel := p.Subscribe()
+			select {
+			case <-ch:
+				cancel()
+			case <-done:
+				cancel()
+				return
+			}
+		}
+	}()
+
+	emitted := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(emitted)
+	}()
+	select {
+	case <-emitted:
+	case <-time.After(10 * time.Second):
+		t.Fatal("emitting blocked")
+	}
+	close(done)
+	churn.Wait()
+
+	cancelAll()
+	cancelCmds()
+	for _, r := range []result{<-allRes, <-cmdsRes} {
+		if r.err != nil {
+			t.Error(r.err)
+		}
+		if r.received == 0 {
+			t.Error("a reader got no events")
+		}
+	}
+
+	if n := len(stuck); n != SubscriberBufferSize {
+		t.Errorf("the stuck subscriber has %d events waiting, want %d", n, SubscriberBufferSize)
+	}
+	cancelStuck()
+	n := 0
+	for range stuck {
+		n++
+	}
+	if n != SubscriberBufferSize {
+		t.Errorf("the stuck subscriber's channel had %d events after the cancel, want %d", n, SubscriberBufferSize)
+	}
+	if dropped := p.Metrics().EventsDropped; dropped < 2*emitters*events-SubscriberBufferSize {
+		t.Errorf("%d events were dropped, the stuck subscriber alone dropped %d", dropped, 2*emitters*events-SubscriberBufferSize)
+	}
+}
@@ -0,0 +1,151 @@
+package prompt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// handleSuspend stops the process on Ctrl+Z or SIGTSTP like a shell does.
+// The terminal is in the raw mode while the prompt waits for input, Ctrl+Z
+// comes then as a key and the keybind sends it to suspendCh. While a line
+// runs the terminal is cooked and the terminal sends SIGTSTP.
+func (p *Prompt) handleSuspend() {
+	defer p.recoverGoroutine("suspend handler")
+	tstpCh := make(chan os.Signal, 1)
+	signal.Notify(tstpCh, syscall.SIGTSTP)
+	defer signal.Stop(tstpCh)
+
+	for {
+		var done chan struct{}
+		select {
+		case <-tstpCh:
+		case done = <-p.suspendCh:
+		case <-p.stopCh:
+			return
+		}
+		p.suspend()
+		if done != nil {
+			close(done)
+		}
+	}
+}
+
+// onCtrlZ is called when the user presses Ctrl+Z. It returns once the
+// process continues so go-prompt doesn't draw the input in the meantime.
+func (p *Prompt) onCtrlZ() {
+	done := make(chan struct{})
+	select {
+	case p.suspendCh <- done:
+	case <-p.stopCh:
+		return
+	}
+	select {
+	case <-done:
+	case <-p.stopCh:
+	}
+}
+
+// suspend gives the terminal back to the shell, stops the process and
+// draws the prompt again once it continues. No output is printed
+// in between. The output printed before isn't drawn again.
+func (p *Prompt) suspend() {
+	if atomic.LoadInt32(&p.suspended) != 0 {
+		// The program run by Suspend has the terminal and was stopped by
+		// the same Ctrl+Z. It draws itself again when it continues.
+		p.log.Debugf("Stopping the process while suspended")
+		if err := syscall.Kill(0, syscall.SIGSTOP); err != nil {
+			p.logWith("err", err).Errorf("Stopping the process failed")
+		}
+		return
+	}
+
+	p.lockRender()
+	p.log.Debugf("Suspending the process")
+	// go-prompt cooks the terminal itself while a line runs
+	raw := atomic.LoadInt32(&p.cooked) == 0
+	p.restoreTerminal()
+
+	contCh := make(chan os.Signal, 1)
+	signal.Notify(contCh, syscall.SIGCONT)
+	// The whole process group stops, like after Ctrl+Z in the cooked mode.
+	// SIGTSTP would only come back to handleSuspend, Go keeps its handler
+	// even after signal.Stop.
+	if err := syscall.Kill(0, syscall.SIGSTOP); err != nil {
+		p.logWith("err", err).Errorf("Stopping the process failed")
+	} else {
+		// The process is stopped once the signal is delivered, which can be
+		// after Kill returns. SIGCONT can be missed if it came right before
+		// the Notify, the time then runs out right after the process continues.
+		select {
+		case <-contCh:
+		case <-time.After(time.Second):
+		}
+	}
+	signal.Stop(contCh)
+
+	p.log.Debugf("The process continues")
+	if raw {
+		if err := p.parser.Setup(); err != nil {
+			p.logWith("err", err).Errorf("Setting the terminal up again failed")
+		}
+		p.bracketedPaste(true)
+	}
+	p.unlockRender()
+	p.redraw()
+}
+
+// redraw draws the whole prompt again after the shell or another program
+// drew over it. The output printed before isn't drawn again.
+func (p *Prompt) redraw() {
+	if err := p.rerender(false); err != nil {
+		p.fail(fmt.Errorf("the rerender failed: %w", err))
+		return
+	}
+	p.lockRender()
+	p.repaint()
+	p.unlockRender()
+}
+
+// Suspend gives the terminal to fn, e.g. to run an editor that takes the
+// whole screen, and draws the prompt again once fn returns. It can only be
+// called from a command while it runs, go-prompt has the terminal in the
+// cooked mode then. Output written in the meantime waits until fn returns.
+// Ctrl+C and resizes are left to whatever fn runs.
+func (p *Prompt) Suspend(fn func() error) error {
+	if atomic.LoadInt32(&p.cooked) == 0 {
+		return errors.New("the prompt can only be suspended while a command runs")
+	}
+
+	// The renderer waits for the lock
+	p.consumeMutex.Lock()
+	defer p.consumeMutex.Unlock()
+	if err := p.drainLocked(context.Background()); err != nil {
+		return err
+	}
+
+	p.lockRender()
+	p.log.Debugf("Suspending the prompt")
+	p.writer.WriteRawStr("\x1b[0m")
+	p.writer.ShowCursor()
+	p.writer.CursorGoTo(p.promptRow, 1)
+	p.writer.EraseLine()
+	if err := p.flush(); err != nil {
+		p.unlockRender()
+		return err
+	}
+	p.unlockRender()
+
+	atomic.StoreInt32(&p.suspended, 1)
+	err := p.safeRun("suspended", fn)
+	atomic.StoreInt32(&p.suspended, 0)
+
+	p.log.Debugf("Resuming the prompt")
+	p.redraw()
+	return err
+}
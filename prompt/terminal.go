@@ -0,0 +1,55 @@
+package prompt
+
+import (
+	"io"
+	"os"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// terminalMode is where the prompt is drawn, decided by which of stdout
+// and stderr is a terminal when the prompt is created
+type terminalMode int
+
+const (
+	// stdout is the terminal
+	termStdout terminalMode = iota
+	// stdout is redirected, e.g. to a file, and stderr is the terminal.
+	// The prompt is drawn on stderr, Stdout gets the results.
+	termStderr
+	// Neither is a terminal. Run runs the lines of stdin and the output
+	// is printed plainly like by ExecuteOnce.
+	termNone
+)
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func detectTerminalMode() terminalMode {
+	switch {
+	case isTerminal(os.Stdout):
+		return termStdout
+	case isTerminal(os.Stderr):
+		return termStderr
+	}
+	return termNone
+}
+
+// terminalWriter returns a new writer to the terminal. Its Flush only
+// queues the bytes, the terminal queue writes them to termOut.
+func (p *Prompt) terminalWriter() goprompt.ConsoleWriter {
+	return &ioWriter{w: p.term}
+}
+
+// Stdout returns where commands write their primary results, e.g. the JSON
+// of `foundry ... > results.json`. When stdout is redirected and the prompt
+// is drawn on stderr it's the real stdout, the results aren't mixed with
+// the escape codes of the rendering. Otherwise it's the output like Writeln.
+func (p *Prompt) Stdout() io.Writer {
+	if p.termMode == termStderr && p.remote == nil {
+		return os.Stdout
+	}
+	return p.outBuf
+}
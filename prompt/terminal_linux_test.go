@@ -0,0 +1,166 @@
+//go:build linux
+// +build linux
+
+package prompt
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"foundry/cli/logger"
+	"foundry/cli/prompt/cmd"
+)
+
+// The result deploy writes to Stdout and the progress it writes to the output
+const (
+	stdioResult   = `{"deployed":"api"}`
+	stdioProgress = "deploying api"
+)
+
+// runStdioPrompt is the child's part of TestTerminalModes: a prompt with
+// a deploy command writing its result to Stdout, on whatever the child
+// got as stdin, stdout and stderr
+func runStdioPrompt(t *testing.T) {
+	logger.SetOutput(ioutil.Discard)
+	var p *Prompt
+	deploy := &fakeCmd{name: "deploy", run: func(args cmd.Args) error {
+		p.Writeln(stdioProgress + "\n")
+		_, err := p.Stdout().Write([]byte(stdioResult + "\n"))
+		return err
+	}}
+	p, err := New([]cmd.Cmd{deploy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Run(); err != nil {
+		t.Fatal(err)
+	}
+	// Without the PASS of the test binary in the redirected stdout
+	os.Exit(0)
+}
+
+// TestTerminalModes runs the prompt with each of stdout and stderr a
+// terminal or a file. The prompt is drawn on the terminal, a redirected
+// stdout gets only the results and without a terminal the lines of stdin
+// run with plain output.
+func TestTerminalModes(t *testing.T) {
+	if os.Getenv(ptyChildEnv) == "stdio" {
+		runStdioPrompt(t)
+		return
+	}
+
+	tests := []struct {
+		name           string
+		stdout, stderr bool // Whether they're the terminal
+	}{
+		{"stdout", true, true},
+		{"stderr", false, true},
+		{"none", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			master, slave := openPTY(t)
+			term := &syncBuffer{}
+			go func() {
+				b := make([]byte, 4096)
+				for {
+					n, err := master.Read(b)
+					term.Write(b[:n])
+					if err != nil {
+						return
+					}
+				}
+			}()
+			dir := t.TempDir()
+			file := func(name string) *os.File {
+				f, err := os.Create(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				t.Cleanup(func() { f.Close() })
+				return f
+			}
+
+			c := exec.Command(os.Args[0], "-test.run=^TestTerminalModes$")
+			c.Env = append(os.Environ(), ptyChildEnv+"=stdio", "GORACE=atexit_sleep_ms=0")
+			c.Stdin, c.Stdout, c.Stderr = slave, slave, slave
+			c.SysProcAttr = &syscall.SysProcAttr{Setsid: true, Setctty: true}
+			if !tt.stdout {
+				c.Stdout = file("stdout")
+			}
+			if !tt.stderr {
+				// Without a terminal the lines come from a file too
+				in := file("stdin")
+				in.WriteString("deploy api\nbiuld\n")
+				in.Seek(0, 0)
+				c.Stdin, c.Stderr = in, file("stderr")
+				c.SysProcAttr = nil
+			}
+			done := make(chan error, 1)
+			if err := c.Start(); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Process.Kill() })
+			go func() { done <- c.Wait() }()
+
+			if tt.stderr {
+				waitFor(t, "the raw mode", func() bool { return !isCooked(slave.Fd()) })
+				// One read with the enter would insert it as text
+				master.Write([]byte("deploy api"))
+				waitFor(t, "the typed text", func() bool { return strings.Contains(term.String(), "deploy api") })
+				master.Write([]byte("\r"))
+				waitFor(t, "the progress", func() bool { return strings.Contains(term.String(), stdioProgress) })
+				// Ctrl+D is read once the command returned
+				master.Write([]byte{0x04})
+			}
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Fatalf("the child failed: %s, the terminal got %q", err, term.String())
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatalf("the child didn't exit, the terminal got %q", term.String())
+			}
+
+			read := func(name string) string {
+				b, err := ioutil.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				return string(b)
+			}
+			if tt.stderr && !strings.Contains(term.String(), "\x1b[24;1H") {
+				t.Errorf("the prompt wasn't drawn on the terminal: %q", term.String())
+			}
+			switch tt.name {
+			case "stdout":
+				if !strings.Contains(term.String(), stdioResult) {
+					t.Errorf("the result isn't in the output: %q", term.String())
+				}
+			case "stderr":
+				if out := read("stdout"); out != stdioResult+"\n" {
+					t.Errorf("stdout got %q", out)
+				}
+				if strings.Contains(term.String(), stdioResult) {
+					t.Errorf("the result is on the terminal: %q", term.String())
+				}
+			case "none":
+				if out := read("stdout"); out != stdioProgress+"\n"+stdioResult+"\n" {
+					t.Errorf("stdout got %q", out)
+				}
+				if out := read("stderr"); !strings.Contains(out, "biuld") || strings.Contains(out, "\x1b") {
+					t.Errorf("stderr got %q", out)
+				}
+				if out := term.String(); out != "" {
+					t.Errorf("the terminal got %q", out)
+				}
+			}
+		})
+	}
+}
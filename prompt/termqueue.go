@@ -0,0 +1,143 @@
+package prompt
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// termQueueLimit is how many bytes may wait for the terminal before the
+// output waits for it. The prompt's own drawing is never held back.
+const termQueueLimit = 64 * 1024
+
+// termQueue writes what the writers flush to the terminal on its own
+// goroutine. A slow terminal, e.g. over SSH, keeps the goroutine waiting
+// instead of whoever flushed with renderMutex held, the keys get the
+// mutex in between. The prompt's and go-prompt's writers share it, the
+// bytes reach the terminal in the order they were flushed.
+type termQueue struct {
+	w io.Writer
+
+	mut     sync.Mutex
+	pending []byte
+	writing int           // Bytes the goroutine is writing now
+	err     error         // Why the last write failed, returned by the next Write
+	started bool          // The goroutine runs
+	closed  bool          // Write writes right away
+	wake    chan struct{} // Tells the goroutine there's something to write
+	written *sync.Cond    // Signalled by the goroutine when a write ended
+}
+
+func newTermQueue(w io.Writer) *termQueue {
+	q := &termQueue{w: w, wake: make(chan struct{}, 1)}
+	q.written = sync.NewCond(&q.mut)
+	return q
+}
+
+// Write queues b. It returns the error of an earlier write, the terminal
+// is likely gone then. Once the queue is closed b is written right away,
+// after what's still queued.
+func (q *termQueue) Write(b []byte) (int, error) {
+	q.mut.Lock()
+	if q.closed {
+		for len(q.pending)+q.writing > 0 {
+			q.written.Wait()
+		}
+		q.mut.Unlock()
+		return q.w.Write(b)
+	}
+	if err := q.err; err != nil {
+		q.err = nil
+		q.mut.Unlock()
+		return 0, err
+	}
+	q.pending = append(q.pending, b...)
+	if !q.started {
+		q.started = true
+		go q.run()
+	}
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	q.mut.Unlock()
+	return len(b), nil
+}
+
+func (q *termQueue) run() {
+	var buf []byte
+	for range q.wake {
+		for {
+			q.mut.Lock()
+			q.written.Broadcast()
+			if len(q.pending) == 0 {
+				q.writing = 0
+				q.mut.Unlock()
+				break
+			}
+			// The buffers are swapped, Write appends to the empty one
+			buf, q.pending = q.pending, buf[:0]
+			q.writing = len(buf)
+			q.mut.Unlock()
+
+			if _, err := q.w.Write(buf); err != nil {
+				q.mut.Lock()
+				q.err = err
+				q.mut.Unlock()
+			}
+		}
+	}
+}
+
+// queued returns how many bytes didn't reach the terminal yet
+func (q *termQueue) queued() int {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.pending) + q.writing
+}
+
+// waitBelow waits until less than n bytes are queued or stop is closed
+func (q *termQueue) waitBelow(n int, stop <-chan struct{}) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	if len(q.pending)+q.writing < n {
+		return
+	}
+
+	// The goroutine wakes the wait up when stop is closed
+	stopped := false
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stop:
+			q.mut.Lock()
+			stopped = true
+			q.written.Broadcast()
+			q.mut.Unlock()
+		case <-done:
+		}
+	}()
+	for len(q.pending)+q.writing >= n && !stopped {
+		q.written.Wait()
+	}
+}
+
+// drain waits until everything queued reached the terminal, at most timeout
+func (q *termQueue) drain(timeout time.Duration) {
+	expired := make(chan struct{})
+	t := time.AfterFunc(timeout, func() { close(expired) })
+	defer t.Stop()
+	q.waitBelow(1, expired)
+}
+
+// close ends the goroutine once it wrote what's queued. Later writes
+// aren't queued, the terminal gets them right away.
+func (q *termQueue) close() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.wake)
+	}
+}
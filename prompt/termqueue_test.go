@@ -0,0 +1,111 @@
+package prompt
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter is a slow terminal. Every write waits for gate, the data
+// is kept in the order the writes started.
+type gatedWriter struct {
+	gate chan struct{}
+
+	mut     sync.Mutex
+	started []string
+	out     strings.Builder
+}
+
+func (w *gatedWriter) Write(b []byte) (int, error) {
+	w.mut.Lock()
+	w.started = append(w.started, string(b))
+	w.mut.Unlock()
+	<-w.gate
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return w.out.Write(b)
+}
+
+func (w *gatedWriter) writes() []string {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+	return append([]string(nil), w.started...)
+}
+
+// TestTermQueueWriteAfterClose writes to a closed queue while the slow
+// terminal still gets what was queued. The write waits for it.
+func TestTermQueueWriteAfterClose(t *testing.T) {
+	w := &gatedWriter{gate: make(chan struct{})}
+	q := newTermQueue(w)
+	q.Write([]byte("first "))
+	waitFor(t, "the first write", func() bool { return len(w.writes()) == 1 })
+	q.Write([]byte("second "))
+	q.close()
+
+	wrote := make(chan struct{})
+	go func() {
+		q.Write([]byte("third"))
+		close(wrote)
+	}()
+	// Time for a write that doesn't wait to get ahead
+	time.Sleep(20 * time.Millisecond)
+	if got := w.writes(); len(got) != 1 {
+		t.Fatalf("the terminal got %q while the first write was slow", got)
+	}
+	close(w.gate)
+	<-wrote
+
+	if got := strings.Join(w.writes(), ""); got != "first second third" {
+		t.Fatalf("the writes came in the order %q", w.writes())
+	}
+	if q.queued() != 0 {
+		t.Fatalf("%d bytes are queued", q.queued())
+	}
+}
+
+// TestTermQueueWaitBelow waits for a slow terminal to write the queue
+// and gives up when it's stopped
+func TestTermQueueWaitBelow(t *testing.T) {
+	w := &gatedWriter{gate: make(chan struct{})}
+	q := newTermQueue(w)
+	q.Write([]byte("progress 50%\n"))
+	waitFor(t, "the first write", func() bool { return len(w.writes()) == 1 })
+	q.Write([]byte("progress 100%\n"))
+
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		q.waitBelow(1, stop)
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("waitBelow returned while the terminal was stuck")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(stop)
+	<-stopped
+
+	start := time.Now()
+	q.drain(30 * time.Millisecond)
+	if d := time.Since(start); d < 30*time.Millisecond || q.queued() == 0 {
+		t.Fatalf("drain returned after %s with %d bytes queued", d, q.queued())
+	}
+
+	waited := make(chan struct{})
+	go func() {
+		q.waitBelow(1, nil)
+		close(waited)
+	}()
+	close(w.gate)
+	select {
+	case <-waited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("waitBelow didn't return once the terminal wrote the queue")
+	}
+	if q.queued() != 0 {
+		t.Fatalf("%d bytes are queued", q.queued())
+	}
+	q.close()
+}
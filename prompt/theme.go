@@ -0,0 +1,41 @@
+package prompt
+
+import (
+	"fmt"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// Theme is the colors of the prompt's rows and of the output's streams
+type Theme struct {
+	Prefix  goprompt.Color         // The prefix in front of the input
+	Info    goprompt.Color         // The info rows
+	Streams map[Stream]StreamStyle // Replaces the styles of these streams
+}
+
+// DefaultTheme returns the colors the prompt uses without WithTheme
+func DefaultTheme() Theme {
+	return Theme{
+		Prefix:  goprompt.Green,
+		Info:    goprompt.Red,
+		Streams: defaultStreamStyles(),
+	}
+}
+
+// WithTheme sets the colors of the prompt. Start from DefaultTheme to
+// change only some of them. The streams not in the theme keep their style.
+func WithTheme(t Theme) Option {
+	return func(p *Prompt) error {
+		for _, c := range []goprompt.Color{t.Prefix, t.Info} {
+			if c < goprompt.DefaultColor || c > goprompt.White {
+				return fmt.Errorf("unknown color %d in the theme", c)
+			}
+		}
+		p.prefixColor = t.Prefix
+		p.infoColor = t.Info
+		for stream, style := range t.Streams {
+			p.streamStyles[stream] = style
+		}
+		return nil
+	}
+}
@@ -0,0 +1,117 @@
+package prompt
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// transcriptSize is how many of the most recent output lines are kept in memory
+const transcriptSize = 1000
+
+// transcript keeps the last lines of the printed output without the escape
+// codes, the transcript command prints them again. Guarded by renderMutex.
+type transcript struct {
+	lines [transcriptSize]string
+	next  int
+	count int
+
+	line []byte // The last line until its line break
+	cr   bool   // A carriage return starts the line again, unless a line break follows
+	esc  escapeState
+}
+
+// write adds printed output to the transcript
+func (t *transcript) write(s string) {
+	for i := 0; i < len(s); {
+		if t.esc == escNone {
+			// Text without escape codes and line breaks is copied at once
+			n := strings.IndexAny(s[i:], "\x1b\n\r")
+			if n < 0 {
+				n = len(s) - i
+			}
+			if n > 0 {
+				t.text(s[i : i+n])
+				i += n
+				continue
+			}
+		}
+
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		if t.esc != escNone || r == '\u001b' {
+			t.esc = t.esc.next(r)
+			continue
+		}
+		switch r {
+		case '\n':
+			t.add(string(t.line))
+			t.line = t.line[:0]
+			t.cr = false
+		case '\r':
+			t.cr = true
+		}
+	}
+}
+
+// text adds text without line breaks to the last line
+func (t *transcript) text(s string) {
+	if t.cr {
+		// Like a progress bar, the text is printed over the line
+		t.line = t.line[:0]
+		t.cr = false
+	}
+	t.line = append(t.line, s...)
+}
+
+func (t *transcript) add(line string) {
+	t.lines[t.next] = line
+	t.next = (t.next + 1) % transcriptSize
+	if t.count < transcriptSize {
+		t.count++
+	}
+}
+
+// recent returns up to n most recent lines, oldest first. The last line
+// is there before its line break too.
+func (t *transcript) recent(n int) []string {
+	var last []string
+	if len(t.line) > 0 {
+		last = []string{string(t.line)}
+		n--
+	}
+	if n > t.count {
+		n = t.count
+	}
+	if n < 0 {
+		n = 0
+	}
+	lines := make([]string, n, n+len(last))
+	for i := range lines {
+		lines[i] = t.lines[(t.next-n+i+transcriptSize)%transcriptSize]
+	}
+	return append(lines, last...)
+}
+
+func runTranscript(p *Prompt, args []string) error {
+	p.lockRender()
+	// A screenful of output by default
+	n := p.pagerHeight()
+	p.unlockRender()
+	if len(args) > 0 {
+		var err error
+		if n, err = strconv.Atoi(args[0]); err != nil || n <= 0 || len(args) > 1 {
+			return fmt.Errorf("usage: transcript [number of lines, up to %d]", transcriptSize)
+		}
+	}
+
+	p.lockRender()
+	lines := p.transcript.recent(n)
+	p.unlockRender()
+	if len(lines) == 0 {
+		p.SetInfoln("No output yet", InfoLineSeverityNormal)
+		return nil
+	}
+	return p.Page(strings.Join(lines, "\n") + "\n")
+}
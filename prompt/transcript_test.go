@@ -0,0 +1,32 @@
+package prompt
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{"lines", []string{"one\ntwo\n"}, []string{"one", "two"}},
+		{"unfinished line", []string{"one\ntw", "o"}, []string{"one", "two"}},
+		{"escape codes", []string{"\x1b[31mred\x1b[0m \x1b[3", "2mgreen\x1b[0m\n"}, []string{"red green"}},
+		{"carriage return", []string{"10%\r50%\r100%\n"}, []string{"100%"}},
+		{"crlf", []string{"one\r\ntwo\r\n"}, []string{"one", "two"}},
+		{"wide characters", []string{"日本語 👩‍💻\n"}, []string{"日本語 👩‍💻"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tr transcript
+			for _, s := range tt.writes {
+				tr.write(s)
+			}
+			if got := tr.recent(10); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("recent(10) = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
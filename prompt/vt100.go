@@ -0,0 +1,108 @@
+package prompt
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// vt100 makes the same escape codes as go-prompt's VT100 writer. go-prompt
+// doesn't let anybody else flush its writer's buffer, the writers of the
+// recording and of WithIO need the bytes.
+type vt100 struct {
+	buf []byte
+}
+
+func (v *vt100) WriteRaw(data []byte) {
+	v.buf = append(v.buf, data...)
+}
+
+// Write doesn't let escape codes through, like go-prompt's
+func (v *vt100) Write(data []byte) {
+	v.WriteRaw(bytes.Replace(data, []byte{0x1b}, []byte{'?'}, -1))
+}
+
+func (v *vt100) WriteRawStr(data string) {
+	v.buf = append(v.buf, data...)
+}
+
+func (v *vt100) WriteStr(data string) {
+	v.Write([]byte(data))
+}
+
+// csi writes the control sequence with the parameters n and the final byte
+func (v *vt100) csi(final byte, n ...int) {
+	v.buf = append(v.buf, 0x1b, '[')
+	for i, p := range n {
+		if i > 0 {
+			v.buf = append(v.buf, ';')
+		}
+		v.buf = strconv.AppendInt(v.buf, int64(p), 10)
+	}
+	v.buf = append(v.buf, final)
+}
+
+func (v *vt100) EraseScreen()      { v.WriteRawStr("\x1b[2J") }
+func (v *vt100) EraseUp()          { v.WriteRawStr("\x1b[1J") }
+func (v *vt100) EraseDown()        { v.WriteRawStr("\x1b[J") }
+func (v *vt100) EraseStartOfLine() { v.WriteRawStr("\x1b[1K") }
+func (v *vt100) EraseEndOfLine()   { v.WriteRawStr("\x1b[K") }
+func (v *vt100) EraseLine()        { v.WriteRawStr("\x1b[2K") }
+func (v *vt100) ShowCursor()       { v.WriteRawStr("\x1b[?12l\x1b[?25h") }
+func (v *vt100) HideCursor()       { v.WriteRawStr("\x1b[?25l") }
+func (v *vt100) AskForCPR()        { v.WriteRawStr("\x1b[6n") }
+func (v *vt100) SaveCursor()       { v.WriteRawStr("\x1b[s") }
+func (v *vt100) UnSaveCursor()     { v.WriteRawStr("\x1b[u") }
+func (v *vt100) ScrollDown()       { v.WriteRawStr("\x1bD") }
+func (v *vt100) ScrollUp()         { v.WriteRawStr("\x1bM") }
+func (v *vt100) ClearTitle()       { v.WriteRawStr("\x1b]2;\a") }
+
+func (v *vt100) CursorGoTo(row, col int) {
+	if row == 0 && col == 0 {
+		v.WriteRawStr("\x1b[H")
+		return
+	}
+	v.csi('H', row, col)
+}
+
+// move writes a relative move, back is the final byte of the opposite direction
+func (v *vt100) move(n int, final, back byte) {
+	switch {
+	case n > 0:
+		v.csi(final, n)
+	case n < 0:
+		v.csi(back, -n)
+	}
+}
+
+func (v *vt100) CursorUp(n int)       { v.move(n, 'A', 'B') }
+func (v *vt100) CursorDown(n int)     { v.move(n, 'B', 'A') }
+func (v *vt100) CursorForward(n int)  { v.move(n, 'C', 'D') }
+func (v *vt100) CursorBackward(n int) { v.move(n, 'D', 'C') }
+
+func (v *vt100) SetTitle(title string) {
+	title = strings.NewReplacer("\x13", "", "\a", "").Replace(title)
+	v.WriteRawStr("\x1b]2;" + title + "\a")
+}
+
+func (v *vt100) SetColor(fg, bg goprompt.Color, bold bool) {
+	attr := goprompt.DisplayReset
+	if bold {
+		attr = goprompt.DisplayBold
+	}
+	v.csi('m', int(attr), ansiForeground(fg), ansiForeground(bg)+10)
+}
+
+// ansiForeground returns the SGR parameter of a foreground color,
+// the background's is 10 more
+func ansiForeground(c goprompt.Color) int {
+	switch {
+	case c >= goprompt.Black && c <= goprompt.LightGray:
+		return 30 + int(c-goprompt.Black)
+	case c >= goprompt.DarkGray && c <= goprompt.White:
+		return 90 + int(c-goprompt.DarkGray)
+	}
+	return 39
+}
@@ -0,0 +1,173 @@
+package prompt
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// WithMaxWidth caps the number of columns the prompt uses on wide terminals.
+// Output wraps at min(terminal width, cols), the rest of every row stays blank.
+// The info row and the prompt row are cut at the same width.
+func WithMaxWidth(cols int) Option {
+	return func(p *Prompt) error {
+		if cols < 1 {
+			return fmt.Errorf("max width must be at least 1 column, got %d", cols)
+		}
+		p.maxWidth = cols
+		return nil
+	}
+}
+
+// columns returns the number of columns the prompt uses on a terminal
+// that's termCols wide
+func (p *Prompt) columns(termCols int) int {
+	if p.maxWidth > 0 && p.maxWidth < termCols {
+		return p.maxWidth
+	}
+	return termCols
+}
+
+// stringWidth returns the number of columns s takes on the terminal. It goes
+// by grapheme clusters, the characters shown as one: a flag of two regional
+// indicators, an emoji with a skin tone or joined to others by ZWJ, or a
+// letter with its combining marks. A cluster is as wide as its first
+// character, an emoji shown as one takes 2 columns.
+func stringWidth(s string) int {
+	runes := []rune(s)
+	width := 0
+	for i := 0; i < len(runes); {
+		j, w := cluster(runes, i)
+		width += w
+		i = j
+	}
+	return width
+}
+
+// cluster returns the end of the grapheme cluster that starts at i and how
+// many columns it takes
+func cluster(runes []rune, i int) (end, width int) {
+	w := runewidth.RuneWidth(runes[i])
+	j := i + 1
+	if isRegionalIndicator(runes[i]) && j < len(runes) && isRegionalIndicator(runes[j]) {
+		w = 2
+		j++
+	}
+	for j < len(runes) {
+		r := runes[j]
+		if r == '\u200d' && j+1 < len(runes) {
+			// The joined character is a part of the cluster
+			j += 2
+			continue
+		}
+		if !extendsCluster(r) {
+			break
+		}
+		if r == '\ufe0f' || isEmojiModifier(r) {
+			// The emoji presentation of e.g. ❤ is 2 columns wide
+			w = 2
+		}
+		j++
+	}
+	return j, w
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
+
+func isEmojiModifier(r rune) bool {
+	return r >= 0x1f3fb && r <= 0x1f3ff
+}
+
+// extendsCluster returns true if r belongs to the grapheme cluster
+// of the character before it
+func extendsCluster(r rune) bool {
+	switch {
+	case r >= 0xfe00 && r <= 0xfe0f: // Variation selectors
+		return true
+	case r >= 0xe0020 && r <= 0xe007f: // Tags of e.g. the flag of Scotland
+		return true
+	case isEmojiModifier(r):
+		return true
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me)
+}
+
+// clip cuts s to cols columns of the terminal for a single row. Text that
+// doesn't fit ends with "…" instead, measured by the display width like
+// stringWidth. Escape codes don't take any space so the ones that are
+// shown are kept. Line breaks and tabs are shown as spaces, they'd move
+// the cursor off the row.
+func clip(s string, cols int) string {
+	runes := []rune(s)
+	var (
+		esc   escapeState
+		width int
+		fits  int // Length of the text that leaves a column for the "…"
+	)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if esc != escNone || r == '\u001b' {
+			esc = esc.next(r)
+			i++
+			if esc == escNone && width <= cols-1 {
+				fits = i
+			}
+			continue
+		}
+		switch r {
+		case '\n', '\r', '\t':
+			runes[i] = ' '
+		}
+		j, w := cluster(runes, i)
+		if width+w > cols {
+			// Reset the colors the cut off part would have reset
+			if cols < 1 {
+				return string(runes[:fits]) + "\x1b[0m"
+			}
+			return string(runes[:fits]) + "…\x1b[0m"
+		}
+		width += w
+		if width <= cols-1 {
+			fits = j
+		}
+		i = j
+	}
+	return string(runes)
+}
+
+// promptLine returns the user's input that fits the prompt's width after
+// the prefix. The user types at the end of the input, a longer one is cut
+// from the left and starts with "…" instead. renderMutex must be held.
+func (p *Prompt) promptLine() string {
+	input := p.input()
+	if p.totalColumns < 1 {
+		return input
+	}
+	cols := p.totalColumns - p.prefixWidth()
+	if utf8.RuneCountInString(input) <= cols {
+		return input
+	}
+	if cols < 1 {
+		return ""
+	}
+	text := []rune(input)
+	return "…" + string(text[len(text)-(cols-1):])
+}
+
+// widthParser reports the terminal narrower to go-prompt so it wraps
+// the input and the suggestions at the same width as the output
+type widthParser struct {
+	goprompt.ConsoleParser
+	p *Prompt
+}
+
+func (w *widthParser) GetWinSize() *goprompt.WinSize {
+	size := *w.ConsoleParser.GetWinSize()
+	size.Col = uint16(w.p.columns(int(size.Col)))
+	return &size
+}
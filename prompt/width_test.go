@@ -0,0 +1,128 @@
+package prompt
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// Prefixes with characters of more code points and their widths
+var emojiPrefixes = []struct {
+	name   string
+	prefix string
+	width  int
+}{
+	{"rocket", "🚀 ", 3},
+	{"flag", "🇩🇪 > ", 5},
+	{"two flags", "🇺🇸🇬🇧> ", 6},
+	{"ZWJ sequence", "👩‍💻 ", 3},
+	{"family", "👨‍👩‍👧‍👦 ", 3},
+	{"skin tone", "👍🏽 ", 3},
+	{"tag sequence", "🏴\U000e0067\U000e0062\U000e0073\U000e0063\U000e0074\U000e007f ", 3},
+	{"emoji presentation", "❤️ ", 3},
+	{"keycap", "1️⃣ ", 3},
+	{"combining mark", "é> ", 3},
+	{"wide", "本番> ", 6},
+}
+
+func TestStringWidth(t *testing.T) {
+	for _, tt := range emojiPrefixes {
+		if w := stringWidth(tt.prefix); w != tt.width {
+			t.Errorf("%s: stringWidth(%q) = %d, want %d", tt.name, tt.prefix, w, tt.width)
+		}
+	}
+}
+
+// TestClipClusters cuts text between the characters of more code points,
+// never inside one
+func TestClipClusters(t *testing.T) {
+	tests := []struct {
+		s    string
+		cols int
+		want string
+	}{
+		{"🇺🇸🇬🇧🇩🇪", 6, "🇺🇸🇬🇧🇩🇪"},
+		{"🇺🇸🇬🇧🇩🇪", 5, "🇺🇸🇬🇧…\x1b[0m"},
+		{"🇺🇸🇬🇧🇩🇪", 4, "🇺🇸…\x1b[0m"},
+		{"👨‍👩‍👧 deploy", 5, "👨‍👩‍👧 d…\x1b[0m"},
+		{"👍🏽👍🏽", 3, "👍🏽…\x1b[0m"},
+		{"éééé", 3, "éé…\x1b[0m"},
+	}
+	for _, tt := range tests {
+		if got := clip(tt.s, tt.cols); got != tt.want {
+			t.Errorf("clip(%q, %d) = %q, want %q", tt.s, tt.cols, got, tt.want)
+		}
+	}
+}
+
+// TestEmojiPrefixCursor types after the prefixes on a terminal that has
+// 5 columns left for the input. The cursor is right after the input and
+// the input is cut to the columns left.
+func TestEmojiPrefixCursor(t *testing.T) {
+	for _, tt := range emojiPrefixes {
+		t.Run(tt.name, func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "deploy", WithTerminalSize(24, uint16(tt.width+5)))
+			p.SetPrefix(tt.prefix)
+			startPrompt(t, p)
+			waitFor(t, "the typed text", func() bool { return p.input() == "deploy" })
+
+			p.lockRender()
+			defer p.unlockRender()
+			if line := p.promptLine(); line != "…ploy" {
+				t.Errorf("the input is shown as %q", line)
+			}
+			if col := p.promptCursorCol(); col != tt.width+6 {
+				t.Errorf("the cursor is at column %d, want %d", col, tt.width+6)
+			}
+		})
+	}
+}
+
+// TestNarrowInfoAndHeader shows a header and an info message wider than
+// the terminal. They're cut by their display width with an ellipsis and
+// stay on their rows, the output rows below the header stay empty.
+func TestNarrowInfoAndHeader(t *testing.T) {
+	const (
+		header = "connected to 本番 production 🚀"
+		info   = "✓ \x1b[32mdeployed\x1b[0m 🇩🇪 api to\tproduction"
+	)
+	tests := []struct {
+		cols         int
+		header, info string
+	}{
+		{16, "connected to 本…", "✓ deployed 🇩🇪 a…"},
+		{12, "connected t…", "✓ deployed …"},
+		{4, "con…", "✓ d…"},
+		{1, "…", "…"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.cols), func(t *testing.T) {
+			p, _ := newTestPrompt(t, nil, "", WithScreenSnapshot(), WithTerminalSize(24, uint16(tt.cols)))
+			startPrompt(t, p)
+			p.SetHeaderln(header)
+			p.SetInfoln(info, InfoLineSeverityNormal)
+
+			rows := strings.Split(p.Snapshot(), "\n")
+			if got := strings.TrimRight(rows[0], " "); got != tt.header {
+				t.Errorf("the header row is %q, want %q", got, tt.header)
+			}
+			if got := strings.TrimRight(rows[22], " "); got != tt.info {
+				t.Errorf("the info row is %q, want %q", got, tt.info)
+			}
+			for i := 1; i < 22; i++ {
+				if strings.TrimSpace(rows[i]) != "" {
+					t.Errorf("row %d got %q", i+1, rows[i])
+				}
+			}
+
+			p.lockRender()
+			lines := append(p.infoLines(), clip(p.headerText, p.totalColumns))
+			p.unlockRender()
+			for _, l := range lines {
+				if w := stringWidth(string(StripANSI([]byte(l)))); w != tt.cols {
+					t.Errorf("%q takes %d columns, want %d", l, w, tt.cols)
+				}
+			}
+		})
+	}
+}
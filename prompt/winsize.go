@@ -0,0 +1,73 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// A terminal reported smaller than this isn't believed. Some fake
+// terminals, like emacs' shell-mode and CI runners, report 0x0 or garbage.
+const (
+	minTerminalRows = 2
+	minTerminalCols = 10
+)
+
+// sizeCheck remembers where the size came from the last time so it's
+// logged only when that changes. The size is checked whenever it's asked
+// for, a fake terminal may report the real size later.
+type sizeCheck struct {
+	mut    sync.Mutex
+	source string
+}
+
+// checkSize replaces the rows or columns of size that are implausible by
+// LINES or COLUMNS, or by 80x24 if those aren't set either. The variables
+// describe the process's terminal, a remote terminal of WithIO skips them.
+func (p *Prompt) checkSize(size *goprompt.WinSize) *goprompt.WinSize {
+	if size.Row >= minTerminalRows && size.Col >= minTerminalCols {
+		p.sizeCheck.logSource(p, "terminal", size)
+		return size
+	}
+
+	checked := *size
+	env := p.remote == nil
+	rowsSource, colsSource := "terminal", "terminal"
+	if checked.Row < minTerminalRows {
+		checked.Row, rowsSource = sizeFallback(env, "LINES", minTerminalRows, defaultReaderRows)
+	}
+	if checked.Col < minTerminalCols {
+		checked.Col, colsSource = sizeFallback(env, "COLUMNS", minTerminalCols, defaultReaderCols)
+	}
+	source := fmt.Sprintf("rows from %s, columns from %s", rowsSource, colsSource)
+	if rowsSource == colsSource {
+		source = rowsSource
+	}
+	p.sizeCheck.logSource(p, source, size)
+	return &checked
+}
+
+// sizeFallback returns the value of the environment variable name if it's
+// a plausible size, otherwise def
+func sizeFallback(env bool, name string, min, def uint16) (uint16, string) {
+	if env {
+		if n, err := strconv.ParseUint(os.Getenv(name), 10, 16); err == nil && n >= uint64(min) {
+			return uint16(n), name
+		}
+	}
+	return def, "default"
+}
+
+func (c *sizeCheck) logSource(p *Prompt, source string, reported *goprompt.WinSize) {
+	c.mut.Lock()
+	changed := source != c.source
+	c.source = source
+	c.mut.Unlock()
+	if !changed {
+		return
+	}
+	p.logWith("source", source, "reported", fmt.Sprintf("%dx%d", reported.Col, reported.Row)).Debugf("Terminal size source changed")
+}
@@ -0,0 +1,82 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	goprompt "github.com/mlejva/go-prompt"
+)
+
+// sizedParser is a terminal for go-prompt's loop reporting size, like the
+// fake terminals reporting 0x0 or garbage
+type sizedParser struct {
+	*fakeParser
+	size goprompt.WinSize
+}
+
+func (s *sizedParser) GetWinSize() *goprompt.WinSize {
+	size := s.size
+	return &size
+}
+
+// TestImplausibleSize runs go-prompt's loop on terminals reporting a size
+// too small to believe and checks the layout of the rows and columns that
+// replace it
+func TestImplausibleSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		reported      goprompt.WinSize
+		lines, cols   string // LINES and COLUMNS
+		rows, columns int
+	}{
+		{"0x0", goprompt.WinSize{}, "", "", 24, 80},
+		{"0x0 with LINES and COLUMNS", goprompt.WinSize{}, "40", "132", 40, 132},
+		{"0x0 with implausible LINES and COLUMNS", goprompt.WinSize{}, "1", "wide", 24, 80},
+		{"no columns", goprompt.WinSize{Row: 30}, "50", "100", 30, 100},
+		{"one row", goprompt.WinSize{Row: 1, Col: 120}, "", "", 24, 120},
+		{"plausible", goprompt.WinSize{Row: 30, Col: 100}, "50", "200", 30, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LINES", tt.lines)
+			t.Setenv("COLUMNS", tt.cols)
+			parser := &sizedParser{fakeParser: newFakeParser(), size: tt.reported}
+			w, _ := fakeWriter()
+			p, err := New(nil, WithConsoleWriter(w), withTermOut(&syncBuffer{}), func(p *Prompt) error {
+				// Like run does with the process's terminal
+				p.parser = &ttyParser{ConsoleParser: parser, p: p}
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("New: %s", err)
+			}
+			t.Cleanup(p.Stop)
+			startPrompt(t, p)
+
+			p.lockRender()
+			defer p.unlockRender()
+			if p.totalRows != tt.rows || p.totalColumns != tt.columns {
+				t.Errorf("the layout is %dx%d, want %dx%d", p.totalColumns, p.totalRows, tt.columns, tt.rows)
+			}
+			if p.promptRow != tt.rows || p.infoRow != tt.rows-1 {
+				t.Errorf("the prompt row is %d and the info row %d", p.promptRow, p.infoRow)
+			}
+		})
+	}
+}
+
+// TestImplausibleRemoteSize gives a WithIO terminal of 0x0 the default
+// size, LINES and COLUMNS are of the process's terminal
+func TestImplausibleRemoteSize(t *testing.T) {
+	t.Setenv("LINES", "40")
+	t.Setenv("COLUMNS", "132")
+	zero := SizeFunc(func() (int, int) { return 0, 0 }, time.Second)
+	p, err := New(nil, WithIO(strings.NewReader(""), &syncBuffer{}, zero))
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+	if size := p.winSize(); size.Row != defaultReaderRows || size.Col != defaultReaderCols {
+		t.Fatalf("the size is %dx%d", size.Col, size.Row)
+	}
+}